@@ -0,0 +1,169 @@
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/jasoet/fhir-worker/internal/audit"
+	"github.com/spf13/cobra"
+	"os"
+	"time"
+)
+
+func newAuditCommand() *cobra.Command {
+	var auditCmd = &cobra.Command{
+		Use:   "audit",
+		Short: "Export and verify the audit log of SatuSehat requests",
+	}
+
+	auditCmd.AddCommand(newAuditExportCommand())
+	auditCmd.AddCommand(newAuditVerifyCommand())
+	auditCmd.AddCommand(newAuditPublicKeyCommand())
+
+	return auditCmd
+}
+
+func newAuditExportCommand() *cobra.Command {
+	var from string
+	var to string
+	var format string
+	var output string
+
+	var exportCmd = &cobra.Command{
+		Use:   "export",
+		Short: "Export the SatuSehat requests between --from and --to as a signed CSV or JSONL file",
+		Long: `This command writes the audit log entries between --from and --to (inclusive) to --output and its ed25519
+signature, made with audit.signing_key_file, to <output>.sig. Auditors check it with "audit verify" and the key
+printed by "audit public-key".`,
+		PreRunE: loadConfigContext,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := configFromContext(cmd)
+			if err != nil {
+				return err
+			}
+
+			if config.Audit == nil || config.Audit.SigningKeyFile == "" {
+				return fmt.Errorf("audit.signing_key_file is required to sign the export")
+			}
+
+			privateKey, err := audit.LoadPrivateKey(config.Audit.SigningKeyFile)
+			if err != nil {
+				return fmt.Errorf("failed to load the signing key: %w", err)
+			}
+
+			fromDate, err := time.ParseInLocation(backfillDateLayout, from, time.Local)
+			if err != nil {
+				return fmt.Errorf("invalid --from: %w", err)
+			}
+
+			toDate, err := time.ParseInLocation(backfillDateLayout, to, time.Local)
+			if err != nil {
+				return fmt.Errorf("invalid --to: %w", err)
+			}
+
+			repository, err := config.Database.Repository()
+			if err != nil {
+				return fmt.Errorf("failed to create Repository: %w", err)
+			}
+
+			entries, err := repository.AuditEntries(cmd.Context(), fromDate, toDate.AddDate(0, 0, 1))
+			if err != nil {
+				return err
+			}
+
+			var buffer bytes.Buffer
+			if err := audit.Write(&buffer, format, entries); err != nil {
+				return err
+			}
+
+			if err := os.WriteFile(output, buffer.Bytes(), 0644); err != nil {
+				return err
+			}
+
+			if err := os.WriteFile(output+".sig", audit.Sign(privateKey, buffer.Bytes()), 0644); err != nil {
+				return err
+			}
+
+			fmt.Printf("Exported %d entries to %s, signature %s.sig\n", len(entries), output, output)
+			return nil
+		},
+	}
+
+	exportCmd.Flags().StringVar(&from, "from", "", "first day to export, format YYYY-MM-DD")
+	exportCmd.Flags().StringVar(&to, "to", "", "last day to export, format YYYY-MM-DD")
+	exportCmd.Flags().StringVar(&format, "format", audit.FormatCsv, "export format, csv or jsonl")
+	exportCmd.Flags().StringVarP(&output, "output", "o", "", "file to write, the signature goes to <output>.sig")
+	_ = exportCmd.MarkFlagRequired("from")
+	_ = exportCmd.MarkFlagRequired("to")
+	_ = exportCmd.MarkFlagRequired("output")
+
+	return exportCmd
+}
+
+func newAuditVerifyCommand() *cobra.Command {
+	var publicKeyFile string
+
+	var verifyCmd = &cobra.Command{
+		Use:   "verify <export file>",
+		Short: "Verify an audit export against its <export file>.sig signature",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			publicKey, err := audit.LoadPublicKey(publicKeyFile)
+			if err != nil {
+				return fmt.Errorf("failed to load the public key: %w", err)
+			}
+
+			data, err := os.ReadFile(args[0])
+			if err != nil {
+				return err
+			}
+
+			signature, err := os.ReadFile(args[0] + ".sig")
+			if err != nil {
+				return err
+			}
+
+			if err := audit.Verify(publicKey, data, signature); err != nil {
+				return err
+			}
+
+			fmt.Printf("%s: signature valid\n", args[0])
+			return nil
+		},
+	}
+
+	verifyCmd.Flags().StringVar(&publicKeyFile, "public-key", "", "PEM public key printed by audit public-key")
+	_ = verifyCmd.MarkFlagRequired("public-key")
+
+	return verifyCmd
+}
+
+func newAuditPublicKeyCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:     "public-key",
+		Short:   "Print the PEM public key of audit.signing_key_file, to hand out to auditors",
+		PreRunE: loadConfigContext,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := configFromContext(cmd)
+			if err != nil {
+				return err
+			}
+
+			if config.Audit == nil || config.Audit.SigningKeyFile == "" {
+				return fmt.Errorf("audit.signing_key_file is not configured")
+			}
+
+			privateKey, err := audit.LoadPrivateKey(config.Audit.SigningKeyFile)
+			if err != nil {
+				return fmt.Errorf("failed to load the signing key: %w", err)
+			}
+
+			publicKey, err := audit.PublicKeyPem(privateKey)
+			if err != nil {
+				return err
+			}
+
+			_, err = os.Stdout.Write(publicKey)
+			return err
+		},
+	}
+}
@@ -0,0 +1,117 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"github.com/jasoet/fhir-worker/internal/entity"
+	"github.com/jasoet/fhir-worker/job"
+	"github.com/jasoet/fhir-worker/pkg/util"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"os"
+	"os/signal"
+	"text/tabwriter"
+	"time"
+)
+
+const backfillDateLayout = "2006-01-02"
+
+type backfillFlags struct {
+	from  string
+	to    string
+	chunk string
+	delay time.Duration
+}
+
+func newBackfillCommand() *cobra.Command {
+	flags := backfillFlags{}
+
+	var backfillCmd = &cobra.Command{
+		Use:   "backfill",
+		Short: "Fetch historical visits for a date range",
+		Long: `This command fetches visits between --from and --to (inclusive) in chunks and stores them in the internal database.
+Finished chunks are checkpointed, running the same command again resumes from the first unfinished chunk and retries
+the chunks with failed visits.`,
+		PreRunE: loadConfigContext,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := configFromContext(cmd)
+			if err != nil {
+				return err
+			}
+
+			return backfillFunc(config, flags)
+		},
+	}
+
+	backfillCmd.Flags().StringVar(&flags.from, "from", "", "first visit date to fetch, format YYYY-MM-DD")
+	backfillCmd.Flags().StringVar(&flags.to, "to", "", "last visit date to fetch, format YYYY-MM-DD")
+	backfillCmd.Flags().StringVar(&flags.chunk, "chunk", "1d", "size of each fetch window, e.g. 1d, 7d or 12h")
+	backfillCmd.Flags().DurationVar(&flags.delay, "delay", 5*time.Second, "pause between chunks to throttle SIMRS load")
+	_ = backfillCmd.MarkFlagRequired("from")
+	_ = backfillCmd.MarkFlagRequired("to")
+
+	return backfillCmd
+}
+
+func backfillFunc(config *Config, flags backfillFlags) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	from, err := time.ParseInLocation(backfillDateLayout, flags.from, time.Local)
+	if err != nil {
+		return fmt.Errorf("invalid --from: %w", err)
+	}
+
+	to, err := time.ParseInLocation(backfillDateLayout, flags.to, time.Local)
+	if err != nil {
+		return fmt.Errorf("invalid --to: %w", err)
+	}
+
+	chunkSize, err := util.ParseDuration(flags.chunk)
+	if err != nil {
+		return fmt.Errorf("invalid --chunk: %w", err)
+	}
+
+	mappingJob, repository, err := newMappingJob(config)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to create Mapping Job")
+		return err
+	}
+
+	backfill, err := job.NewBackfill(
+		job.WithBackfillRange(from, to.AddDate(0, 0, 1)),
+		job.WithChunkSize(chunkSize),
+		job.WithChunkDelay(flags.delay),
+		job.WithMappingAndRepository(mappingJob, repository),
+	)
+	if err != nil {
+		return err
+	}
+
+	chunks, err := backfill.Run(ctx)
+	printBackfillChunks(chunks)
+
+	return err
+}
+
+func printBackfillChunks(chunks []entity.BackfillChunk) {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(writer, "CHUNK START\tCHUNK END\tVISITS\tVALID\tINVALID\tEXISTING\tFAILED")
+
+	total := entity.BackfillChunk{}
+	for _, chunk := range chunks {
+		_, _ = fmt.Fprintf(writer, "%s\t%s\t%d\t%d\t%d\t%d\t%d\n",
+			chunk.ChunkStart.Format(time.DateTime), chunk.ChunkEnd.Format(time.DateTime),
+			chunk.VisitCount, chunk.ValidCount, chunk.InvalidCount, chunk.ExistingCount, chunk.FailedCount)
+
+		total.VisitCount += chunk.VisitCount
+		total.ValidCount += chunk.ValidCount
+		total.InvalidCount += chunk.InvalidCount
+		total.ExistingCount += chunk.ExistingCount
+		total.FailedCount += chunk.FailedCount
+	}
+
+	_, _ = fmt.Fprintf(writer, "TOTAL\t\t%d\t%d\t%d\t%d\t%d\n",
+		total.VisitCount, total.ValidCount, total.InvalidCount, total.ExistingCount, total.FailedCount)
+	_ = writer.Flush()
+}
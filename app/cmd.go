@@ -5,12 +5,22 @@ import (
 	_ "embed"
 	"fmt"
 	"github.com/go-co-op/gocron/v2"
+	"github.com/jasoet/fhir-worker/internal/admin"
+	"github.com/jasoet/fhir-worker/internal/dashboard"
+	internalDb "github.com/jasoet/fhir-worker/internal/db"
+	"github.com/jasoet/fhir-worker/internal/metrics"
+	"github.com/jasoet/fhir-worker/internal/satusehat"
+	"github.com/jasoet/fhir-worker/internal/tracing"
 	"github.com/jasoet/fhir-worker/job"
+	"github.com/jasoet/fhir-worker/pkg/redact"
 	"github.com/jasoet/fhir-worker/pkg/server"
+	"github.com/jasoet/fhir-worker/simrs"
 	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
+	"time"
 )
 
 func startFunc(config *Config) error {
@@ -22,41 +32,52 @@ func startFunc(config *Config) error {
 	_log.Info().
 		Msg("initializing Application")
 
-	var err error
-	queryOps, err := config.Database.QueryOps()
-	if err != nil {
-		_log.Error().Err(err).Msg("failed to create QueryOps")
+	if config.Admin != nil {
+		if err := config.Admin.Validate(); err != nil {
+			_log.Error().Err(err).Msg("invalid admin configuration")
+			return err
+		}
+	}
+
+	if config.Dashboard != nil && config.Dashboard.Enabled && (config.Admin == nil || config.Admin.ApiKey == "") {
+		err := fmt.Errorf("dashboard requires admin.api_key")
+		_log.Error().Err(err).Msg("invalid dashboard configuration")
 		return err
 	}
 
-	repository, err := config.Database.Repository()
+	simrsPool, err := config.Database.Simrs.Pool()
 	if err != nil {
-		_log.Error().Err(err).Msg("failed to create Repository")
+		_log.Error().Err(err).Msg("failed to connect to SIMRS")
 		return err
 	}
 
-	var mappingJob *job.Mapping
-
-	mappingOptions := []job.MappingOption{
-		job.WithQueryAndRepository(queryOps, repository),
+	shutdownTracing := func(ctx context.Context) error { return nil }
+	if config.Tracing != nil {
+		shutdownTracing, err = tracing.Setup(ctx, *config.Tracing, tracing.OrganizationID.String(config.Satusehat.OrganizationID))
+		if err != nil {
+			_log.Error().Err(err).Msg("failed to set up tracing")
+			return err
+		}
 	}
 
-	if config.Mapping != nil {
-		mappingOptions = append(mappingOptions, job.WithDisableConfigs(config.Mapping.DisableDiagnosis, config.Mapping.DisableLab, config.Mapping.DisableRadiology, config.Mapping.DisableProcedure, config.Mapping.DisableMedication))
-		mappingOptions = append(mappingOptions, job.WithConfigDays(config.Mapping.MarkCompleteDays, config.Mapping.LastVisitDays))
+	queryOps, err := newQueryOps(simrsPool)
+	if err != nil {
+		_log.Error().Err(err).Msg("failed to create QueryOps")
+		return err
 	}
 
-	mappingJob, err = job.NewMapping(mappingOptions...)
+	mappingJob, repository, err := newMappingJobWithQuery(config, queryOps)
 	if err != nil {
 		_log.Error().Err(err).Msg("failed to create Mapping Job")
 		return err
 	}
 
-	satuSehatClient := config.Satusehat.Client()
+	satuSehatClient := config.Satusehat.Client(satusehat.WithAudit(repository.InsertAuditEntry))
 	publishOptions := []job.PublishOption{
 		job.WithOrganizationId(config.Satusehat.OrganizationID),
 		job.WithClientAndRepository(satuSehatClient, repository),
 		job.WithConvertUtc(config.Satusehat.ConvertToUtc),
+		job.WithPublishTerminology(mappingJob.Terminology()),
 	}
 
 	if config.Publish != nil {
@@ -64,6 +85,7 @@ func startFunc(config *Config) error {
 			job.WithSendDelay(config.Publish.PublishDelay),
 			job.WithSimulationDir(config.Publish.SimulationDir),
 			job.WithSimulationMode(config.Publish.SimulationMode),
+			job.WithPlausibility(config.Publish.PlausibilityRules()),
 		)
 	}
 
@@ -73,6 +95,18 @@ func startFunc(config *Config) error {
 		return err
 	}
 
+	var metricLabels map[string]string
+	if config.Metrics != nil {
+		metricLabels = config.Metrics.Labels
+	}
+
+	if err := metrics.Register(prometheus.DefaultRegisterer, config.Satusehat.OrganizationID, metricLabels, repository); err != nil {
+		_log.Error().Err(err).Msg("failed to register metrics")
+		return err
+	}
+
+	tracker := job.NewTracker(repository, config.Job.RunRetentionOrDefault())
+
 	scheduler, err := gocron.NewScheduler(gocron.WithLimitConcurrentJobs(2, gocron.LimitModeReschedule))
 	if err != nil {
 		_log.Error().Err(err).Msg("failed to create Gocron Scheduler ")
@@ -85,8 +119,9 @@ func startFunc(config *Config) error {
 				config.Job.VisitFetchInterval,
 			),
 			gocron.NewTask(
-				mappingJob.FetchVisit, ctx,
+				tracker.Track(job.FetchVisitJob, mappingJob.FetchVisit), ctx,
 			),
+			gocron.WithName(job.FetchVisitJob),
 			gocron.WithSingletonMode(gocron.LimitModeReschedule),
 		)
 
@@ -106,8 +141,9 @@ func startFunc(config *Config) error {
 				config.Job.VisitFillInterval,
 			),
 			gocron.NewTask(
-				mappingJob.FillVisit, ctx,
+				tracker.Track(job.FillVisitJob, mappingJob.FillVisit), ctx,
 			),
+			gocron.WithName(job.FillVisitJob),
 			gocron.WithSingletonMode(gocron.LimitModeReschedule),
 		)
 
@@ -126,8 +162,9 @@ func startFunc(config *Config) error {
 				config.Job.MarkCompleteInterval,
 			),
 			gocron.NewTask(
-				mappingJob.CheckComplete, ctx,
+				tracker.Track(job.CheckCompleteJob, mappingJob.CheckComplete), ctx,
 			),
+			gocron.WithName(job.CheckCompleteJob),
 			gocron.WithSingletonMode(gocron.LimitModeReschedule),
 		)
 
@@ -146,8 +183,9 @@ func startFunc(config *Config) error {
 				config.Job.PublishInterval,
 			),
 			gocron.NewTask(
-				publishJob.Process, ctx,
+				tracker.Track(job.PublishJob, publishJob.Process), ctx,
 			),
+			gocron.WithName(job.PublishJob),
 		)
 
 		_log.Info().
@@ -159,6 +197,23 @@ func startFunc(config *Config) error {
 			Msg("Publish task disabled")
 	}
 
+	routes := []server.Route{
+		healthRoute(config, repository, simrsPool, satuSehatClient, scheduler, tracker),
+	}
+	if config.Admin != nil && config.Admin.ApiKey != "" {
+		adminHandler := admin.NewHandler(repository, mappingJob, publishJob)
+		routes = append(routes, func(e *echo.Echo) {
+			adminHandler.Register(e, config.Admin.ApiKey)
+		})
+	}
+
+	if config.Dashboard != nil && config.Dashboard.Enabled {
+		dashboardHandler := dashboard.NewHandler(repository, config.Dashboard.Days)
+		routes = append(routes, func(e *echo.Echo) {
+			dashboardHandler.Register(e, admin.KeyAuth(config.Admin.ApiKey))
+		})
+	}
+
 	server.Start(config.Port,
 		func(e *echo.Echo) {
 			log.Info().
@@ -175,12 +230,94 @@ func startFunc(config *Config) error {
 
 			log.Info().
 				Msgf("scheduler stopped")
+
+			flushCtx, flushCancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer flushCancel()
+			if err := shutdownTracing(flushCtx); err != nil {
+				log.Error().Err(err).Msg("failed to flush traces")
+			}
 		},
+		routes...,
 	)
 
 	return nil
 }
 
+func newMappingJob(config *Config) (*job.Mapping, *internalDb.Repository, error) {
+	queryOps, err := config.Database.QueryOps()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create QueryOps: %w", err)
+	}
+
+	return newMappingJobWithQuery(config, queryOps)
+}
+
+func newMappingJobWithQuery(config *Config, queryOps simrs.Query) (*job.Mapping, *internalDb.Repository, error) {
+	repository, err := config.Database.Repository()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create Repository: %w", err)
+	}
+
+	mappingOptions := []job.MappingOption{
+		job.WithQueryAndRepository(queryOps, repository),
+	}
+
+	if config.Mapping != nil {
+		mappingOptions = append(mappingOptions, job.WithDisableConfigs(config.Mapping.DisableDiagnosis, config.Mapping.DisableLab, config.Mapping.DisableRadiology, config.Mapping.DisableProcedure, config.Mapping.DisableMedication, config.Mapping.DisableAnamnesis, config.Mapping.DisableAllergy, config.Mapping.DisableImmunization, config.Mapping.DisableClinicalNote))
+		mappingOptions = append(mappingOptions, job.WithConfigDays(config.Mapping.MarkCompleteDays, config.Mapping.LastVisitDays))
+		mappingOptions = append(mappingOptions, job.WithFillBatchSize(config.Mapping.FillBatchSize))
+	}
+
+	if config.Terminology != nil && config.Terminology.Enabled {
+		codeTables, err := config.Terminology.Terminology()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load Terminology: %w", err)
+		}
+		mappingOptions = append(mappingOptions, job.WithTerminology(codeTables))
+	}
+
+	mappingJob, err := job.NewMapping(mappingOptions...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return mappingJob, repository, nil
+}
+
+func loadConfigContext(cmd *cobra.Command, args []string) error {
+	log.Debug().
+		Str("config", cfgFile).
+		Msgf("loading config file")
+
+	config, err := loadConfig(cfgFile)
+	if err != nil {
+		log.Error().Err(err).Msg("config file invalid")
+		return err
+	}
+
+	if config.Logging != nil && config.Logging.DisableRedaction {
+		redact.SetEnabled(false)
+		log.Warn().Msg("log redaction disabled, logs contain patient data")
+	}
+
+	ctx := context.WithValue(context.Background(), "config", config)
+	cmd.SetContext(ctx)
+
+	return nil
+}
+
+func configFromContext(cmd *cobra.Command) (*Config, error) {
+	config, ok := cmd.Context().Value("config").(*Config)
+	if !ok || config == nil {
+		log.Error().
+			Str("config_type", fmt.Sprintf("%T", config)).
+			Msg("config file invalid")
+		return nil, fmt.Errorf("config file invalid")
+	}
+
+	return config, nil
+}
+
 var cfgFile string
 var debugMode bool
 
@@ -198,34 +335,16 @@ func NewCommand() *cobra.Command {
 	}
 
 	var startCmd = &cobra.Command{
-		Use:   "start",
-		Short: "Starts the application",
-		Long:  `This command starts the worker`,
-		PreRunE: func(cmd *cobra.Command, args []string) error {
-			log.Debug().
-				Str("config", cfgFile).
-				Msgf("loading config file")
-
-			config, err := loadConfig(cfgFile)
+		Use:     "start",
+		Short:   "Starts the application",
+		Long:    `This command starts the worker`,
+		PreRunE: loadConfigContext,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := configFromContext(cmd)
 			if err != nil {
-				log.Error().Err(err).Msg("config file invalid")
 				return err
 			}
 
-			ctx := context.WithValue(context.Background(), "config", config)
-			cmd.SetContext(ctx)
-
-			return nil
-		},
-		RunE: func(cmd *cobra.Command, args []string) error {
-			config, ok := cmd.Context().Value("config").(*Config)
-			if !ok || config == nil {
-				log.Error().
-					Str("config_type", fmt.Sprintf("%T", config)).
-					Msg("config file invalid")
-				return fmt.Errorf("config file invalid")
-			}
-
 			return startFunc(config)
 		},
 	}
@@ -247,6 +366,13 @@ func NewCommand() *cobra.Command {
 
 	rootCmd.AddCommand(configCmd)
 	rootCmd.AddCommand(startCmd)
+	rootCmd.AddCommand(newBackfillCommand())
+	rootCmd.AddCommand(newExplainCommand())
+	rootCmd.AddCommand(newKfaCommand())
+	rootCmd.AddCommand(newConceptMapCommand())
+	rootCmd.AddCommand(newJobsCommand())
+	rootCmd.AddCommand(newAuditCommand())
+	rootCmd.AddCommand(newRotateKeyCommand())
 
 	return rootCmd
 }
@@ -0,0 +1,252 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/jasoet/fhir-worker/internal/conceptmap"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+func newConceptMapCommand() *cobra.Command {
+	var conceptMapCmd = &cobra.Command{
+		Use:   "concept-map",
+		Short: "Maintain the versioned local lab code to LOINC concept map",
+		Long: `Lab parameters without a LOINC code from SIMRS are completed from the active concept map version during visit fill.
+Export the backlog with 'unmapped', fill the loinc_code, loinc_display and ucum_unit columns and load it back with 'import',
+every import creates a new version that can be rolled back with 'activate'.`,
+	}
+
+	conceptMapCmd.AddCommand(newConceptMapImportCommand())
+	conceptMapCmd.AddCommand(newConceptMapExportCommand())
+	conceptMapCmd.AddCommand(newConceptMapVersionsCommand())
+	conceptMapCmd.AddCommand(newConceptMapActivateCommand())
+	conceptMapCmd.AddCommand(newConceptMapUnmappedCommand())
+
+	return conceptMapCmd
+}
+
+func newConceptMapImportCommand() *cobra.Command {
+	var description string
+	var createdBy string
+	var replace bool
+
+	var importCmd = &cobra.Command{
+		Use:   "import <file.csv>",
+		Short: "Import a CSV file as new active concept map version",
+		Long: `This command creates a new version from the active one with the entries of every row with a loinc_code,
+rows without one are skipped. With --replace the new version only holds the entries of the file.`,
+		Args:    cobra.ExactArgs(1),
+		PreRunE: loadConfigContext,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := configFromContext(cmd)
+			if err != nil {
+				return err
+			}
+
+			if createdBy == "" {
+				return fmt.Errorf("--by is required")
+			}
+
+			return conceptMapImportFunc(cmd.Context(), config, args[0], description, createdBy, replace)
+		},
+	}
+
+	importCmd.Flags().StringVar(&description, "description", "", "description of the changes in this version")
+	importCmd.Flags().StringVar(&createdBy, "by", "", "name of the staff who made the mapping")
+	importCmd.Flags().BoolVar(&replace, "replace", false, "don't carry over the entries of the active version")
+
+	return importCmd
+}
+
+func conceptMapImportFunc(ctx context.Context, config *Config, path string, description string, createdBy string, replace bool) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	entries, err := conceptmap.ReadEntries(file)
+	if err != nil {
+		return fmt.Errorf("invalid concept map file %s: %w", path, err)
+	}
+
+	repository, err := config.Database.Repository()
+	if err != nil {
+		return fmt.Errorf("failed to create Repository: %w", err)
+	}
+
+	if !replace {
+		active, err := repository.ActiveConceptMap(ctx)
+		if err != nil {
+			return err
+		}
+		entries = conceptmap.Merge(active, entries)
+	}
+
+	version, err := repository.CreateConceptMapVersion(ctx, description, createdBy, entries)
+	if err != nil {
+		return err
+	}
+
+	log.Info().Int64("version", version).Int("entry-count", len(entries)).Str("file", path).
+		Msg("Concept map version imported and activated")
+	return nil
+}
+
+func newConceptMapExportCommand() *cobra.Command {
+	var output string
+	var version int64
+
+	var exportCmd = &cobra.Command{
+		Use:     "export",
+		Short:   "Export the entries of a concept map version as CSV",
+		PreRunE: loadConfigContext,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := configFromContext(cmd)
+			if err != nil {
+				return err
+			}
+
+			repository, err := config.Database.Repository()
+			if err != nil {
+				return fmt.Errorf("failed to create Repository: %w", err)
+			}
+
+			entries, err := repository.ActiveConceptMap(cmd.Context())
+			if version > 0 {
+				entries, err = repository.ConceptMapEntries(cmd.Context(), version)
+			}
+			if err != nil {
+				return err
+			}
+
+			return writeOutput(output, func(writer io.Writer) error {
+				return conceptmap.WriteEntries(writer, entries)
+			})
+		},
+	}
+
+	exportCmd.Flags().StringVarP(&output, "output", "o", "", "CSV file to write, defaults to stdout")
+	exportCmd.Flags().Int64Var(&version, "version", 0, "version to export, defaults to the active version")
+
+	return exportCmd
+}
+
+func newConceptMapVersionsCommand() *cobra.Command {
+	var versionsCmd = &cobra.Command{
+		Use:     "versions",
+		Short:   "List the concept map versions",
+		PreRunE: loadConfigContext,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := configFromContext(cmd)
+			if err != nil {
+				return err
+			}
+
+			repository, err := config.Database.Repository()
+			if err != nil {
+				return fmt.Errorf("failed to create Repository: %w", err)
+			}
+
+			versions, err := repository.ConceptMapVersions(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			_, _ = fmt.Fprintln(writer, "VERSION\tACTIVE\tENTRIES\tCREATED BY\tCREATED AT\tDESCRIPTION")
+			for _, version := range versions {
+				active := ""
+				if version.Active {
+					active = "*"
+				}
+
+				_, _ = fmt.Fprintf(writer, "%d\t%s\t%d\t%s\t%s\t%s\n", version.Version, active, version.EntryCount,
+					version.CreatedBy, version.CreatedAt.Format(time.RFC3339), version.Description)
+			}
+			return writer.Flush()
+		},
+	}
+
+	return versionsCmd
+}
+
+func newConceptMapActivateCommand() *cobra.Command {
+	var activateCmd = &cobra.Command{
+		Use:     "activate <version>",
+		Short:   "Activate a concept map version, e.g. to roll back a faulty import",
+		Args:    cobra.ExactArgs(1),
+		PreRunE: loadConfigContext,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := configFromContext(cmd)
+			if err != nil {
+				return err
+			}
+
+			version, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid version %q: %w", args[0], err)
+			}
+
+			repository, err := config.Database.Repository()
+			if err != nil {
+				return fmt.Errorf("failed to create Repository: %w", err)
+			}
+
+			err = repository.ActivateConceptMapVersion(cmd.Context(), version)
+			if errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("concept map version %d doesn't exist", version)
+			}
+			if err != nil {
+				return err
+			}
+
+			log.Info().Int64("version", version).Msg("Concept map version activated")
+			return nil
+		},
+	}
+
+	return activateCmd
+}
+
+func newConceptMapUnmappedCommand() *cobra.Command {
+	var output string
+
+	var unmappedCmd = &cobra.Command{
+		Use:     "unmapped",
+		Short:   "Export the local lab codes without concept map entry as CSV, most frequent first",
+		PreRunE: loadConfigContext,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := configFromContext(cmd)
+			if err != nil {
+				return err
+			}
+
+			repository, err := config.Database.Repository()
+			if err != nil {
+				return fmt.Errorf("failed to create Repository: %w", err)
+			}
+
+			unmapped, err := repository.UnmappedLabCodes(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			return writeOutput(output, func(writer io.Writer) error {
+				return conceptmap.WriteUnmapped(writer, unmapped)
+			})
+		},
+	}
+
+	unmappedCmd.Flags().StringVarP(&output, "output", "o", "", "CSV file to write, defaults to stdout")
+
+	return unmappedCmd
+}
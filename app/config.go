@@ -1,11 +1,17 @@
 package app
 
 import (
+	"fmt"
 	internalDb "github.com/jasoet/fhir-worker/internal/db"
+	"github.com/jasoet/fhir-worker/internal/resource"
 	"github.com/jasoet/fhir-worker/internal/satusehat"
+	"github.com/jasoet/fhir-worker/internal/terminology"
+	"github.com/jasoet/fhir-worker/internal/tracing"
 	"github.com/jasoet/fhir-worker/pkg/db"
+	"github.com/jasoet/fhir-worker/pkg/encryption"
 	"github.com/jasoet/fhir-worker/pkg/util"
 	"github.com/jasoet/fhir-worker/simrs"
+	"github.com/jmoiron/sqlx"
 	"github.com/spf13/viper"
 	"time"
 )
@@ -30,25 +36,91 @@ func loadConfig(configFile string) (*Config, error) {
 }
 
 type MappingConfig struct {
-	MarkCompleteDays  int  `yaml:"mark_complete_days" mapstructure:"mark_complete_days"`
-	LastVisitDays     int  `yaml:"last_visit_days" mapstructure:"last_visit_days"`
-	DisableDiagnosis  bool `yaml:"disable_diagnosis" mapstructure:"disable_diagnosis"`
-	DisableLab        bool `yaml:"disable_lab" mapstructure:"disable_lab"`
-	DisableRadiology  bool `yaml:"disable_radiology" mapstructure:"disable_radiology"`
-	DisableProcedure  bool `yaml:"disable_procedure" mapstructure:"disable_procedure"`
-	DisableMedication bool `yaml:"disable_medication" mapstructure:"disable_medication"`
+	MarkCompleteDays    int  `yaml:"mark_complete_days" mapstructure:"mark_complete_days"`
+	LastVisitDays       int  `yaml:"last_visit_days" mapstructure:"last_visit_days"`
+	FillBatchSize       int  `yaml:"fill_batch_size" mapstructure:"fill_batch_size"`
+	DisableDiagnosis    bool `yaml:"disable_diagnosis" mapstructure:"disable_diagnosis"`
+	DisableLab          bool `yaml:"disable_lab" mapstructure:"disable_lab"`
+	DisableRadiology    bool `yaml:"disable_radiology" mapstructure:"disable_radiology"`
+	DisableProcedure    bool `yaml:"disable_procedure" mapstructure:"disable_procedure"`
+	DisableMedication   bool `yaml:"disable_medication" mapstructure:"disable_medication"`
+	DisableAnamnesis    bool `yaml:"disable_anamnesis" mapstructure:"disable_anamnesis"`
+	DisableAllergy      bool `yaml:"disable_allergy" mapstructure:"disable_allergy"`
+	DisableImmunization bool `yaml:"disable_immunization" mapstructure:"disable_immunization"`
+	DisableClinicalNote bool `yaml:"disable_clinical_note" mapstructure:"disable_clinical_note"`
+}
+
+type TerminologyConfig struct {
+	Enabled    bool   `yaml:"enabled" mapstructure:"enabled"`
+	Icd10File  string `yaml:"icd10_file" mapstructure:"icd10_file"`
+	Icd9cmFile string `yaml:"icd9cm_file" mapstructure:"icd9cm_file"`
+	LoincFile  string `yaml:"loinc_file" mapstructure:"loinc_file"`
+	KfaFile    string `yaml:"kfa_file" mapstructure:"kfa_file"`
 }
 
 type DatabaseConfig struct {
-	Path  *string             `yaml:"path" mapstructure:"path"`
-	Paths []string            `yaml:"paths" mapstructure:"paths"`
-	Simrs db.ConnectionConfig `yaml:"simrs" mapstructure:"simrs"`
+	Path       *string             `yaml:"path" mapstructure:"path"`
+	Paths      []string            `yaml:"paths" mapstructure:"paths"`
+	Simrs      db.ConnectionConfig `yaml:"simrs" mapstructure:"simrs"`
+	Encryption *encryption.Config  `yaml:"encryption" mapstructure:"encryption"`
 }
 
 type PublishConfig struct {
-	SimulationMode bool          `yaml:"simulation_mode" mapstructure:"simulation_mode"`
-	SimulationDir  string        `yaml:"simulation_dir" mapstructure:"simulation_dir"`
-	PublishDelay   time.Duration `yaml:"publish_delay" mapstructure:"publish_delay"`
+	SimulationMode bool                          `yaml:"simulation_mode" mapstructure:"simulation_mode"`
+	SimulationDir  string                        `yaml:"simulation_dir" mapstructure:"simulation_dir"`
+	PublishDelay   time.Duration                 `yaml:"publish_delay" mapstructure:"publish_delay"`
+	Plausibility   map[string]PlausibilityConfig `yaml:"plausibility" mapstructure:"plausibility"`
+}
+
+type AdminConfig struct {
+	ApiKey string `yaml:"api_key" mapstructure:"api_key"`
+}
+
+// minApiKeyLength is the shortest admin API key accepted, e.g. openssl rand -hex 32 prints 64 characters.
+const minApiKeyLength = 32
+
+// Validate refuses an api key short enough to be guessed, an empty key disables the admin API.
+func (a *AdminConfig) Validate() error {
+	if a.ApiKey != "" && len(a.ApiKey) < minApiKeyLength {
+		return fmt.Errorf("admin.api_key is %d characters, at least %d are required", len(a.ApiKey), minApiKeyLength)
+	}
+	return nil
+}
+
+type DashboardConfig struct {
+	Enabled bool `yaml:"enabled" mapstructure:"enabled"`
+	Days    int  `yaml:"days" mapstructure:"days"`
+}
+
+type MetricsConfig struct {
+	Labels map[string]string `yaml:"labels" mapstructure:"labels"`
+}
+
+type HealthConfig struct {
+	PublishStuckAfter time.Duration `yaml:"publish_stuck_after" mapstructure:"publish_stuck_after"`
+}
+
+type AuditConfig struct {
+	SigningKeyFile string `yaml:"signing_key_file" mapstructure:"signing_key_file"`
+}
+
+type LoggingConfig struct {
+	// DisableRedaction logs NIK, names, addresses and credentials unmasked, only for audited debug sessions.
+	DisableRedaction bool `yaml:"disable_redaction" mapstructure:"disable_redaction"`
+}
+
+type PlausibilityConfig struct {
+	Min float64 `yaml:"min" mapstructure:"min"`
+	Max float64 `yaml:"max" mapstructure:"max"`
+}
+
+// PlausibilityRules returns the configured plausible ranges by LOINC code.
+func (p *PublishConfig) PlausibilityRules() map[string]resource.Plausibility {
+	rules := make(map[string]resource.Plausibility, len(p.Plausibility))
+	for code, rule := range p.Plausibility {
+		rules[code] = resource.Plausibility{Min: rule.Min, Max: rule.Max}
+	}
+	return rules
 }
 
 type SatuSehatConfig struct {
@@ -67,18 +139,37 @@ type JobConfig struct {
 	VisitFetchInterval   time.Duration `yaml:"visit_fetch_interval" mapstructure:"visit_fetch_interval"`
 	VisitFillInterval    time.Duration `yaml:"visit_fill_interval" mapstructure:"visit_fill_interval"`
 	MarkCompleteInterval time.Duration `yaml:"mark_complete_interval" mapstructure:"mark_complete_interval"`
+	RunRetention         time.Duration `yaml:"run_retention" mapstructure:"run_retention"`
+}
+
+const defaultRunRetention = 30 * 24 * time.Hour
+
+// RunRetentionOrDefault returns how long job runs are kept, 30 days unless configured.
+func (j JobConfig) RunRetentionOrDefault() time.Duration {
+	if j.RunRetention <= 0 {
+		return defaultRunRetention
+	}
+	return j.RunRetention
 }
 
 type Config struct {
-	Port      int             `yaml:"port" mapstructure:"port"`
-	Job       JobConfig       `yaml:"job" mapstructure:"job"`
-	Mapping   *MappingConfig  `yaml:"mapping" mapstructure:"mapping"`
-	Publish   *PublishConfig  `yaml:"publish" mapstructure:"publish"`
-	Database  DatabaseConfig  `yaml:"database" mapstructure:"database"`
-	Satusehat SatuSehatConfig `yaml:"satusehat" mapstructure:"satusehat"`
+	Port        int                `yaml:"port" mapstructure:"port"`
+	Job         JobConfig          `yaml:"job" mapstructure:"job"`
+	Mapping     *MappingConfig     `yaml:"mapping" mapstructure:"mapping"`
+	Terminology *TerminologyConfig `yaml:"terminology" mapstructure:"terminology"`
+	Publish     *PublishConfig     `yaml:"publish" mapstructure:"publish"`
+	Database    DatabaseConfig     `yaml:"database" mapstructure:"database"`
+	Satusehat   SatuSehatConfig    `yaml:"satusehat" mapstructure:"satusehat"`
+	Admin       *AdminConfig       `yaml:"admin" mapstructure:"admin"`
+	Dashboard   *DashboardConfig   `yaml:"dashboard" mapstructure:"dashboard"`
+	Metrics     *MetricsConfig     `yaml:"metrics" mapstructure:"metrics"`
+	Health      *HealthConfig      `yaml:"health" mapstructure:"health"`
+	Tracing     *tracing.Config    `yaml:"tracing" mapstructure:"tracing"`
+	Logging     *LoggingConfig     `yaml:"logging" mapstructure:"logging"`
+	Audit       *AuditConfig       `yaml:"audit" mapstructure:"audit"`
 }
 
-func (s *SatuSehatConfig) Client() *satusehat.Client {
+func (s *SatuSehatConfig) Client(extra ...satusehat.ClientOption) *satusehat.Client {
 	options := []satusehat.ClientOption{
 		satusehat.WithCredential(s.SatuSehat),
 	}
@@ -87,7 +178,16 @@ func (s *SatuSehatConfig) Client() *satusehat.Client {
 		options = append(options, satusehat.WithRestConfig(*s.HttpClient))
 	}
 
-	return satusehat.NewClient(options...)
+	return satusehat.NewClient(append(options, extra...)...)
+}
+
+func (t *TerminologyConfig) Terminology() (*terminology.Terminology, error) {
+	return terminology.New(
+		terminology.WithCodeFile(terminology.ICD10, t.Icd10File),
+		terminology.WithCodeFile(terminology.ICD9CM, t.Icd9cmFile),
+		terminology.WithCodeFile(terminology.LOINC, t.LoincFile),
+		terminology.WithCodeFile(terminology.KFA, t.KfaFile),
+	)
 }
 
 func (d *DatabaseConfig) QueryOps() (simrs.Query, error) {
@@ -97,7 +197,16 @@ func (d *DatabaseConfig) QueryOps() (simrs.Query, error) {
 		return nil, err
 	}
 
-	return simrs.NewQuery(dbPool)
+	return newQueryOps(dbPool)
+}
+
+func newQueryOps(pool *sqlx.DB) (simrs.Query, error) {
+	query, err := simrs.NewQuery(pool)
+	if err != nil {
+		return nil, err
+	}
+
+	return simrs.Instrumented(query), nil
 }
 
 func (d *DatabaseConfig) Repository() (*internalDb.Repository, error) {
@@ -108,5 +217,18 @@ func (d *DatabaseConfig) Repository() (*internalDb.Repository, error) {
 		paths = d.Paths
 	}
 
-	return internalDb.DefaultRepository(paths...)
+	repository, err := internalDb.DefaultRepository(paths...)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.Encryption != nil {
+		keyring, err := d.Encryption.Keyring()
+		if err != nil {
+			return nil, err
+		}
+		repository.SetKeyring(keyring)
+	}
+
+	return repository, nil
 }
@@ -5,6 +5,7 @@ import (
 	"github.com/jasoet/fhir-worker/pkg/db"
 	"github.com/stretchr/testify/assert"
 	"os"
+	"strings"
 	"testing"
 	"time"
 )
@@ -38,6 +39,9 @@ func TestLoadConfig(t *testing.T) {
 	assert.Equal(t, db.Mysql, config.Database.Simrs.DbType, "Expected db_type to be MYSQL")
 	assert.Equal(t, "internal.db", *config.Database.Path, "Expected Path to be internal.db ")
 	assert.Equal(t, "localhost", config.Database.Simrs.Host, "Expected host to be localhost")
+	assert.Equal(t, 100, config.Mapping.FillBatchSize, "Expected fill_batch_size to be 100")
+	assert.True(t, config.Terminology.Enabled, "Expected terminology to be enabled")
+	assert.Equal(t, PlausibilityConfig{Min: 30, Max: 45}, config.Publish.Plausibility["8310-5"], "Expected plausibility of body temperature")
 }
 func TestLoadOptionalConfig(t *testing.T) {
 	// Write config to a temporary file
@@ -64,6 +68,13 @@ func TestLoadOptionalConfig(t *testing.T) {
 	assert.Equal(t, 1*time.Second, config.Job.MarkCompleteInterval, "Expected mark_complete_interval to be 1s")
 	assert.Nil(t, config.Database.Path, "Expected path to be null")
 	assert.Nil(t, config.Mapping, "Expected Mapping to be null")
+	assert.Nil(t, config.Terminology, "Expected Terminology to be null")
 	assert.Nil(t, config.Publish, "Expected publish to be null")
 	assert.Nil(t, config.Satusehat.HttpClient, "Expected HttpClient to be null")
 }
+
+func TestAdminConfig_Validate(t *testing.T) {
+	assert.NoError(t, (&AdminConfig{}).Validate(), "Expected an empty key to disable the admin API")
+	assert.Error(t, (&AdminConfig{ApiKey: "change-me"}).Validate(), "Expected a short key to be refused")
+	assert.NoError(t, (&AdminConfig{ApiKey: strings.Repeat("k", minApiKeyLength)}).Validate())
+}
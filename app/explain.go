@@ -0,0 +1,83 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/jasoet/fhir-worker/internal/entity"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+type explainOutput struct {
+	VisitId       string                        `json:"visit_id"`
+	MappingStatus entity.MappingStatus          `json:"mapping_status"`
+	PublishStatus entity.PublishStatus          `json:"publish_status"`
+	MappingErrors *string                       `json:"mapping_errors"`
+	StoredReport  *entity.MappingReport         `json:"stored_report"`
+	CurrentReport *entity.MappingReport         `json:"current_report"`
+	Exclusions    []entity.ObservationExclusion `json:"observation_exclusions"`
+}
+
+func newExplainCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:     "explain <visit-id>",
+		Short:   "Explain the mapping status of a visit",
+		Long:    `This command shows the completeness of every clinical resource of a visit, why it is or isn't READY to publish and which observations the last bundle left out`,
+		Args:    cobra.ExactArgs(1),
+		PreRunE: loadConfigContext,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := configFromContext(cmd)
+			if err != nil {
+				return err
+			}
+
+			return explainFunc(config, args[0])
+		},
+	}
+}
+
+func explainFunc(config *Config, visitId string) error {
+	ctx := context.Background()
+
+	mappingJob, _, err := newMappingJob(config)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to create Mapping Job")
+		return err
+	}
+
+	internal, report, err := mappingJob.Explain(ctx, visitId)
+	if err != nil {
+		return err
+	}
+
+	if internal == nil {
+		return fmt.Errorf("visit %s not found in internal database", visitId)
+	}
+
+	repository, err := config.Database.Repository()
+	if err != nil {
+		return fmt.Errorf("failed to create Repository: %w", err)
+	}
+
+	exclusions, err := repository.ObservationExclusions(ctx, visitId)
+	if err != nil {
+		return err
+	}
+
+	output, err := json.MarshalIndent(explainOutput{
+		VisitId:       internal.VisitID,
+		MappingStatus: internal.MappingStatus,
+		PublishStatus: internal.PublishStatus,
+		MappingErrors: internal.MappingErrors,
+		StoredReport:  internal.MappingReport(),
+		CurrentReport: report,
+		Exclusions:    exclusions,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(string(output))
+	return nil
+}
@@ -0,0 +1,89 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-co-op/gocron/v2"
+	internalDb "github.com/jasoet/fhir-worker/internal/db"
+	"github.com/jasoet/fhir-worker/internal/satusehat"
+	"github.com/jasoet/fhir-worker/job"
+	"github.com/jasoet/fhir-worker/pkg/server"
+	"github.com/jmoiron/sqlx"
+	"sync"
+	"time"
+)
+
+const (
+	defaultPublishStuckAfter = time.Hour
+	tokenFailureTTL          = 30 * time.Second
+)
+
+// healthRoute serves /healthz from the checks of the worker itself, and /readyz from its dependencies as well:
+// a worker that lost SIMRS or SatuSehat is alive but not ready, restarting it wouldn't help.
+func healthRoute(config *Config, repository *internalDb.Repository, simrsPool *sqlx.DB, client *satusehat.Client,
+	scheduler gocron.Scheduler, tracker *job.Tracker) server.Route {
+	liveness := []server.Check{
+		{Name: "internal_db", Check: repository.Ping},
+		{Name: "scheduler", Check: schedulerCheck(scheduler)},
+	}
+
+	readiness := []server.Check{
+		{Name: "simrs", Check: simrsPool.PingContext},
+	}
+
+	if !config.Job.PublishDisabled && !(config.Publish != nil && config.Publish.SimulationMode) {
+		readiness = append(readiness, server.Check{Name: "satusehat_token", Check: tokenCheck(client.Token)})
+	}
+
+	if !config.Job.PublishDisabled {
+		stuckAfter := defaultPublishStuckAfter
+		if config.Health != nil && config.Health.PublishStuckAfter > 0 {
+			stuckAfter = config.Health.PublishStuckAfter
+		}
+
+		readiness = append(readiness, server.Check{Name: "publish", Check: func(ctx context.Context) error {
+			return tracker.Progressing(ctx, job.PublishJob, stuckAfter)
+		}})
+	}
+
+	return server.Health(liveness, readiness)
+}
+
+// schedulerCheck fails when a job of scheduler has no upcoming run, the scheduler isn't started or was shut down.
+func schedulerCheck(scheduler gocron.Scheduler) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		for _, scheduled := range scheduler.Jobs() {
+			nextRun, err := scheduled.NextRun()
+			if err != nil {
+				return fmt.Errorf("job %s: %w", scheduled.Name(), err)
+			}
+			if nextRun.IsZero() {
+				return fmt.Errorf("job %s is not scheduled", scheduled.Name())
+			}
+		}
+		return nil
+	}
+}
+
+// tokenCheck fails while no SatuSehat token can be fetched. The client only caches tokens, a failure is kept for
+// tokenFailureTTL so probes don't call the OAuth endpoint each time while the credentials are broken.
+func tokenCheck(token func(ctx context.Context) (string, error)) func(ctx context.Context) error {
+	var (
+		mu       sync.Mutex
+		lastErr  error
+		failedAt time.Time
+	)
+
+	return func(ctx context.Context) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if lastErr != nil && time.Since(failedAt) < tokenFailureTTL {
+			return lastErr
+		}
+
+		_, lastErr = token(ctx)
+		failedAt = time.Now()
+		return lastErr
+	}
+}
@@ -0,0 +1,31 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestTokenCheck(t *testing.T) {
+	calls := 0
+	tokenErr := errors.New("invalid client credentials")
+	check := tokenCheck(func(ctx context.Context) (string, error) {
+		calls++
+		return "", tokenErr
+	})
+
+	ctx := context.Background()
+	assert.ErrorIs(t, check(ctx), tokenErr)
+	assert.ErrorIs(t, check(ctx), tokenErr)
+	assert.Equal(t, 1, calls, "a failure is cached for tokenFailureTTL")
+
+	calls = 0
+	check = tokenCheck(func(ctx context.Context) (string, error) {
+		calls++
+		return "token", nil
+	})
+	assert.NoError(t, check(ctx))
+	assert.NoError(t, check(ctx))
+	assert.Equal(t, 2, calls, "successes are cached by the client")
+}
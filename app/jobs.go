@@ -0,0 +1,60 @@
+package app
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+func newJobsCommand() *cobra.Command {
+	var jobName string
+	var limit int
+
+	var jobsCmd = &cobra.Command{
+		Use:     "jobs",
+		Short:   "List the latest runs of the scheduled jobs",
+		Long:    `This command lists the latest runs of fetch-visit, fill-visit, check-complete and publish, most recent first.`,
+		PreRunE: loadConfigContext,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := configFromContext(cmd)
+			if err != nil {
+				return err
+			}
+
+			repository, err := config.Database.Repository()
+			if err != nil {
+				return fmt.Errorf("failed to create Repository: %w", err)
+			}
+
+			runs, err := repository.JobRuns(cmd.Context(), jobName, limit)
+			if err != nil {
+				return err
+			}
+
+			writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			_, _ = fmt.Fprintln(writer, "ID\tJOB\tSTARTED AT\tDURATION\tSCANNED\tPROCESSED\tFAILED\tERROR")
+			for _, run := range runs {
+				duration := "unfinished"
+				if run.FinishedAt != nil {
+					duration = run.FinishedAt.Sub(run.StartedAt).Round(time.Millisecond).String()
+				}
+
+				runError := ""
+				if run.Error != nil {
+					runError = *run.Error
+				}
+
+				_, _ = fmt.Fprintf(writer, "%d\t%s\t%s\t%s\t%d\t%d\t%d\t%s\n", run.ID, run.JobName,
+					run.StartedAt.Format(time.RFC3339), duration, run.Scanned, run.Processed, run.Failed, runError)
+			}
+			return writer.Flush()
+		},
+	}
+
+	jobsCmd.Flags().StringVar(&jobName, "job", "", "only list the runs of this job, e.g. publish")
+	jobsCmd.Flags().IntVar(&limit, "limit", 20, "number of runs to list")
+
+	return jobsCmd
+}
@@ -0,0 +1,214 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"github.com/jasoet/fhir-worker/internal/kfa"
+	"github.com/jasoet/fhir-worker/internal/terminology"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"io"
+	"os"
+	"time"
+)
+
+func newKfaCommand() *cobra.Command {
+	var kfaCmd = &cobra.Command{
+		Use:   "kfa",
+		Short: "Maintain the local medicine code to KFA mapping",
+		Long: `Prescriptions without a KFA code from SIMRS are completed from the local mapping table during visit fill.
+Export the backlog with 'unmapped' or 'suggest', fill the kfa_code and kfa_display columns and load it back with 'import'.`,
+	}
+
+	kfaCmd.AddCommand(newKfaImportCommand())
+	kfaCmd.AddCommand(newKfaExportCommand())
+	kfaCmd.AddCommand(newKfaUnmappedCommand())
+	kfaCmd.AddCommand(newKfaSuggestCommand())
+
+	return kfaCmd
+}
+
+func newKfaImportCommand() *cobra.Command {
+	var mappedBy string
+
+	var importCmd = &cobra.Command{
+		Use:     "import <file.csv>",
+		Short:   "Import KFA mappings from a CSV file",
+		Long:    `This command inserts or replaces the mappings of every row with a kfa_code, rows without one are skipped`,
+		Args:    cobra.ExactArgs(1),
+		PreRunE: loadConfigContext,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := configFromContext(cmd)
+			if err != nil {
+				return err
+			}
+
+			return kfaImportFunc(config, args[0], mappedBy)
+		},
+	}
+
+	importCmd.Flags().StringVar(&mappedBy, "by", "", "name of the staff who made the mapping, used for rows without mapped_by")
+
+	return importCmd
+}
+
+func kfaImportFunc(config *Config, path string, mappedBy string) error {
+	ctx := context.Background()
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	mappings, err := kfa.ReadMappings(file, mappedBy, time.Now())
+	if err != nil {
+		return fmt.Errorf("invalid mapping file %s: %w", path, err)
+	}
+
+	repository, err := config.Database.Repository()
+	if err != nil {
+		return fmt.Errorf("failed to create Repository: %w", err)
+	}
+
+	if err := repository.SaveKfaMappings(ctx, mappings); err != nil {
+		return err
+	}
+
+	log.Info().Int("mapping-count", len(mappings)).Str("file", path).Msg("KFA mappings imported")
+	return nil
+}
+
+func newKfaExportCommand() *cobra.Command {
+	var output string
+
+	var exportCmd = &cobra.Command{
+		Use:     "export",
+		Short:   "Export every KFA mapping as CSV",
+		PreRunE: loadConfigContext,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := configFromContext(cmd)
+			if err != nil {
+				return err
+			}
+
+			repository, err := config.Database.Repository()
+			if err != nil {
+				return fmt.Errorf("failed to create Repository: %w", err)
+			}
+
+			mappings, err := repository.KfaMappings(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			return writeOutput(output, func(writer io.Writer) error {
+				return kfa.WriteMappings(writer, mappings)
+			})
+		},
+	}
+
+	exportCmd.Flags().StringVarP(&output, "output", "o", "", "CSV file to write, defaults to stdout")
+
+	return exportCmd
+}
+
+func newKfaUnmappedCommand() *cobra.Command {
+	var output string
+
+	var unmappedCmd = &cobra.Command{
+		Use:     "unmapped",
+		Short:   "Export the medicines still waiting for a KFA mapping as CSV",
+		PreRunE: loadConfigContext,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := configFromContext(cmd)
+			if err != nil {
+				return err
+			}
+
+			return kfaBacklogFunc(cmd.Context(), config, output, nil, 0)
+		},
+	}
+
+	unmappedCmd.Flags().StringVarP(&output, "output", "o", "", "CSV file to write, defaults to stdout")
+
+	return unmappedCmd
+}
+
+func newKfaSuggestCommand() *cobra.Command {
+	var output string
+	var catalog string
+	var limit int
+	var minScore float64
+
+	var suggestCmd = &cobra.Command{
+		Use:   "suggest",
+		Short: "Export the unmapped medicines with KFA candidates matched by drug name",
+		Long: `This command fuzzy matches the name of every unmapped medicine against a KFA catalog CSV (code,display)
+and writes the best candidates next to the backlog rows. Review them, copy the right one into kfa_code and kfa_display and import the file.`,
+		PreRunE: loadConfigContext,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := configFromContext(cmd)
+			if err != nil {
+				return err
+			}
+
+			if catalog == "" && config.Terminology != nil {
+				catalog = config.Terminology.KfaFile
+			}
+
+			if catalog == "" {
+				return fmt.Errorf("a KFA catalog is required, pass --catalog or set terminology.kfa_file")
+			}
+
+			codeTables, err := terminology.New(terminology.WithCodeFile(terminology.KFA, catalog))
+			if err != nil {
+				return err
+			}
+
+			suggester := kfa.NewSuggester(codeTables.CodeSystem(terminology.KFA).Codes(), minScore)
+			return kfaBacklogFunc(cmd.Context(), config, output, suggester, limit)
+		},
+	}
+
+	suggestCmd.Flags().StringVarP(&output, "output", "o", "", "CSV file to write, defaults to stdout")
+	suggestCmd.Flags().StringVar(&catalog, "catalog", "", "KFA catalog CSV with a code,display header, defaults to terminology.kfa_file")
+	suggestCmd.Flags().IntVar(&limit, "limit", 3, "number of candidates per medicine")
+	suggestCmd.Flags().Float64Var(&minScore, "min-score", 0.4, "minimum similarity between 0 and 1 of a candidate")
+
+	return suggestCmd
+}
+
+func kfaBacklogFunc(ctx context.Context, config *Config, output string, suggester *kfa.Suggester, limit int) error {
+	repository, err := config.Database.Repository()
+	if err != nil {
+		return fmt.Errorf("failed to create Repository: %w", err)
+	}
+
+	unmapped, err := repository.UnmappedMedicines(ctx)
+	if err != nil {
+		return err
+	}
+
+	return writeOutput(output, func(writer io.Writer) error {
+		return kfa.WriteUnmapped(writer, unmapped, suggester, limit)
+	})
+}
+
+// writeOutput writes to the given file, or to stdout when no file is given.
+func writeOutput(path string, write func(writer io.Writer) error) error {
+	if path == "" || path == "-" {
+		return write(os.Stdout)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := write(file); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
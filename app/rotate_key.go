@@ -0,0 +1,57 @@
+package app
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+)
+
+func newRotateKeyCommand() *cobra.Command {
+	var batchSize int
+	var vacuum bool
+
+	var rotateKeyCmd = &cobra.Command{
+		Use:   "rotate-key",
+		Short: "Re-encrypt visit_detail and publish_request with the current encryption key",
+		Long: `This command re-encrypts every visit not encrypted with the current key, the first of database.encryption,
+including visits stored before encryption was enabled. To rotate:
+  1. generate a key with: openssl rand -base64 32
+  2. put it first in the keys, keeping the previous ones after it
+  3. restart the worker, new visits use the new key
+  4. run this command, then drop the previous keys`,
+		PreRunE: loadConfigContext,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := configFromContext(cmd)
+			if err != nil {
+				return err
+			}
+
+			if config.Database.Encryption == nil {
+				return fmt.Errorf("database.encryption is not configured")
+			}
+
+			repository, err := config.Database.Repository()
+			if err != nil {
+				return fmt.Errorf("failed to create Repository: %w", err)
+			}
+
+			scanned, rotated, err := repository.RotateKey(cmd.Context(), batchSize)
+			fmt.Printf("Re-encrypted %d of %d visits scanned\n", rotated, scanned)
+			if err != nil {
+				return err
+			}
+
+			if vacuum {
+				if err := repository.Vacuum(cmd.Context()); err != nil {
+					return fmt.Errorf("failed to vacuum the internal database: %w", err)
+				}
+			}
+
+			return nil
+		},
+	}
+
+	rotateKeyCmd.Flags().IntVar(&batchSize, "batch", 500, "visits re-encrypted per transaction")
+	rotateKeyCmd.Flags().BoolVar(&vacuum, "vacuum", true, "rebuild the database file afterwards so no previous value is left in free pages")
+
+	return rotateKeyCmd
+}
@@ -0,0 +1,380 @@
+package admin
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"github.com/jasoet/fhir-worker/internal/db"
+	"github.com/jasoet/fhir-worker/internal/entity"
+	"github.com/jasoet/fhir-worker/job"
+	shared "github.com/jasoet/fhir-worker/shared/model"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultLimit = 50
+	maxLimit     = 500
+	dateLayout   = "2006-01-02"
+)
+
+// Handler serves the admin JSON API the support desk uses to inspect and operate on the visits of the internal database.
+type Handler struct {
+	repository *db.Repository
+	mapping    *job.Mapping
+	publish    *job.Publish
+}
+
+func NewHandler(repository *db.Repository, mapping *job.Mapping, publish *job.Publish) *Handler {
+	return &Handler{
+		repository: repository,
+		mapping:    mapping,
+		publish:    publish,
+	}
+}
+
+// KeyAuth rejects the requests not carrying apiKey in the X-API-Key header.
+func KeyAuth(apiKey string) echo.MiddlewareFunc {
+	return middleware.KeyAuthWithConfig(middleware.KeyAuthConfig{
+		KeyLookup: "header:X-API-Key",
+		Validator: func(key string, c echo.Context) (bool, error) {
+			return subtle.ConstantTimeCompare([]byte(key), []byte(apiKey)) == 1, nil
+		},
+	})
+}
+
+// Register adds the admin API under /api/admin, every request has to carry apiKey in the X-API-Key header.
+func (h *Handler) Register(e *echo.Echo, apiKey string) {
+	group := e.Group("/api/admin", KeyAuth(apiKey))
+
+	group.GET("/visits", h.listVisits)
+	group.GET("/visits/:id", h.getVisit)
+	group.GET("/visits/:id/bundle", h.previewBundle)
+	group.GET("/visits/:id/publish", h.lastPublish)
+	group.POST("/visits/:id/refill", h.refill)
+	group.POST("/visits/:id/revalidate", h.revalidate)
+	group.POST("/visits/:id/requeue", h.requeue)
+	group.POST("/visits/:id/skip", h.skip)
+	group.GET("/job-runs", h.jobRuns)
+}
+
+type visitOutput struct {
+	entity.VisitSummary
+	VisitDetail           json.RawMessage               `json:"visit_detail"`
+	VitalSign             json.RawMessage               `json:"vital_sign"`
+	Clinical              map[string]*json.RawMessage   `json:"clinical"`
+	MappingReport         *entity.MappingReport         `json:"mapping_report"`
+	ValidationIssues      []shared.ValidationIssue      `json:"validation_issues"`
+	ObservationExclusions []entity.ObservationExclusion `json:"observation_exclusions"`
+}
+
+type exclusionOutput struct {
+	LoincCode    string `json:"loinc_code"`
+	LoincDisplay string `json:"loinc_display"`
+	Value        string `json:"value"`
+	Message      string `json:"message"`
+}
+
+type bundleOutput struct {
+	Bundle     json.RawMessage   `json:"bundle"`
+	Exclusions []exclusionOutput `json:"observation_exclusions"`
+}
+
+type publishOutput struct {
+	VisitID       string               `json:"visit_id"`
+	PublishStatus entity.PublishStatus `json:"publish_status"`
+	PublishDate   *time.Time           `json:"publish_date"`
+	Request       json.RawMessage      `json:"request"`
+	Response      json.RawMessage      `json:"response"`
+}
+
+type statusOutput struct {
+	VisitID       string                `json:"visit_id"`
+	MappingStatus entity.MappingStatus  `json:"mapping_status"`
+	PublishStatus entity.PublishStatus  `json:"publish_status"`
+	MappingReport *entity.MappingReport `json:"mapping_report,omitempty"`
+}
+
+// visitFilter reads mapping_status, publish_status, from and to (inclusive, yyyy-mm-dd), limit and offset.
+func visitFilter(c echo.Context) (entity.VisitFilter, error) {
+	filter := entity.VisitFilter{
+		MappingStatus: entity.MappingStatus(c.QueryParam("mapping_status")),
+		PublishStatus: entity.PublishStatus(c.QueryParam("publish_status")),
+	}
+
+	if from := c.QueryParam("from"); from != "" {
+		date, err := time.ParseInLocation(dateLayout, from, time.Local)
+		if err != nil {
+			return filter, fmt.Errorf("from %q is not a yyyy-mm-dd date", from)
+		}
+		filter.VisitDateFrom = &date
+	}
+
+	if to := c.QueryParam("to"); to != "" {
+		date, err := time.ParseInLocation(dateLayout, to, time.Local)
+		if err != nil {
+			return filter, fmt.Errorf("to %q is not a yyyy-mm-dd date", to)
+		}
+		date = date.AddDate(0, 0, 1)
+		filter.VisitDateTo = &date
+	}
+
+	limit, err := queryLimit(c)
+	if err != nil {
+		return filter, err
+	}
+	filter.Limit = limit
+
+	if offset := c.QueryParam("offset"); offset != "" {
+		value, err := strconv.Atoi(offset)
+		if err != nil || value < 0 {
+			return filter, fmt.Errorf("offset %q is not a positive number", offset)
+		}
+		filter.Offset = value
+	}
+
+	return filter, nil
+}
+
+// queryLimit reads the limit query parameter, 50 when it is missing.
+func queryLimit(c echo.Context) (int, error) {
+	limit := c.QueryParam("limit")
+	if limit == "" {
+		return defaultLimit, nil
+	}
+
+	value, err := strconv.Atoi(limit)
+	if err != nil || value < 1 || value > maxLimit {
+		return 0, fmt.Errorf("limit %q is not between 1 and %d", limit, maxLimit)
+	}
+	return value, nil
+}
+
+func (h *Handler) listVisits(c echo.Context) error {
+	filter, err := visitFilter(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	visits, err := h.repository.Visits(c.Request().Context(), filter)
+	if err != nil {
+		return err
+	}
+
+	if visits == nil {
+		visits = []entity.VisitSummary{}
+	}
+	return c.JSON(http.StatusOK, visits)
+}
+
+// stored returns the visit of the id path parameter, or a not found error.
+func (h *Handler) stored(c echo.Context) (*entity.SatuSehatInternal, error) {
+	internal, err := h.repository.GetByVisitId(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return nil, err
+	}
+	if internal == nil {
+		return nil, visitNotFound(c)
+	}
+	return internal, nil
+}
+
+func visitNotFound(c echo.Context) error {
+	return echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("visit %s not found", c.Param("id")))
+}
+
+func (h *Handler) getVisit(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	internal, err := h.stored(c)
+	if err != nil {
+		return err
+	}
+
+	issues, err := h.repository.ValidationIssues(ctx, internal.VisitID)
+	if err != nil {
+		return err
+	}
+
+	exclusions, err := h.repository.ObservationExclusions(ctx, internal.VisitID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, visitOutput{
+		VisitSummary: summary(internal),
+		VisitDetail:  internal.VisitDetailJson,
+		VitalSign:    internal.VitalSignJson,
+		Clinical: map[string]*json.RawMessage{
+			"diagnosis":           internal.DiagnosisJsonArr,
+			"lab":                 internal.LabJsonArr,
+			"radiology":           internal.RadiologyJsonArr,
+			"medication_request":  internal.MedicationRequestJsonArr,
+			"medication_dispense": internal.MedicationDispenseJsonArr,
+			"procedure":           internal.ProcedureJsonArr,
+			"anamnesis":           internal.AnamnesisJsonArr,
+			"allergy":             internal.AllergyJsonArr,
+			"immunization":        internal.ImmunizationJsonArr,
+			"clinical_note":       internal.ClinicalNoteJsonArr,
+		},
+		MappingReport:         internal.MappingReport(),
+		ValidationIssues:      issues,
+		ObservationExclusions: exclusions,
+	})
+}
+
+func summary(internal *entity.SatuSehatInternal) entity.VisitSummary {
+	return entity.VisitSummary{
+		VisitID:            internal.VisitID,
+		VisitDate:          internal.VisitDate,
+		SatusehatPatientID: internal.SatusehatPatientID,
+		MappingStatus:      internal.MappingStatus,
+		PublishStatus:      internal.PublishStatus,
+		PublishDate:        internal.PublishDate,
+		MappingErrors:      internal.MappingErrors,
+	}
+}
+
+func (h *Handler) previewBundle(c echo.Context) error {
+	bundle, exclusions, err := h.publish.Preview(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return err
+	}
+	if bundle == nil {
+		return visitNotFound(c)
+	}
+
+	payload, err := bundle.MarshalJSON()
+	if err != nil {
+		return err
+	}
+
+	output := bundleOutput{Bundle: payload, Exclusions: []exclusionOutput{}}
+	for _, exclusion := range exclusions {
+		output.Exclusions = append(output.Exclusions, exclusionOutput{
+			LoincCode:    exclusion.LoincCode,
+			LoincDisplay: exclusion.LoincDisplay,
+			Value:        exclusion.Value,
+			Message:      exclusion.Reason.Error(),
+		})
+	}
+	return c.JSON(http.StatusOK, output)
+}
+
+func (h *Handler) lastPublish(c echo.Context) error {
+	internal, err := h.stored(c)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, publishOutput{
+		VisitID:       internal.VisitID,
+		PublishStatus: internal.PublishStatus,
+		PublishDate:   internal.PublishDate,
+		Request:       rawJson(internal.PublishRequest),
+		Response:      rawJson(internal.PublishResponse),
+	})
+}
+
+// rawJson returns s as is when it is JSON, SatuSehat errors aren't always, and as JSON string otherwise.
+func rawJson(s *string) json.RawMessage {
+	if s == nil || *s == "" {
+		return json.RawMessage("null")
+	}
+	if json.Valid([]byte(*s)) {
+		return json.RawMessage(*s)
+	}
+
+	quoted, _ := json.Marshal(*s)
+	return quoted
+}
+
+func (h *Handler) refill(c echo.Context) error {
+	report, err := h.mapping.Refill(c.Request().Context(), c.Param("id"))
+	return h.respondReport(c, report, err)
+}
+
+func (h *Handler) revalidate(c echo.Context) error {
+	report, err := h.mapping.Revalidate(c.Request().Context(), c.Param("id"))
+	return h.respondReport(c, report, err)
+}
+
+func (h *Handler) respondReport(c echo.Context, report *entity.MappingReport, err error) error {
+	if err != nil {
+		return err
+	}
+	if report == nil {
+		return visitNotFound(c)
+	}
+	return h.respondStatus(c, report)
+}
+
+// requeue marks the visit to be published again by the next publish job. Only a visit already READY or whose publish
+// failed is requeued as is, any other visit is revalidated and only becomes READY when it is complete.
+func (h *Handler) requeue(c echo.Context) error {
+	internal, err := h.stored(c)
+	if err != nil {
+		return err
+	}
+
+	if internal.MappingStatus != entity.Ready && internal.PublishStatus != entity.RequestError {
+		return h.revalidate(c)
+	}
+	return h.updateStatus(c, entity.Ready, entity.Preparing)
+}
+
+// skip leaves the visit out of filling and publishing until it is requeued.
+func (h *Handler) skip(c echo.Context) error {
+	internal, err := h.stored(c)
+	if err != nil {
+		return err
+	}
+	return h.updateStatus(c, entity.Skipped, internal.PublishStatus)
+}
+
+func (h *Handler) updateStatus(c echo.Context, mappingStatus entity.MappingStatus, publishStatus entity.PublishStatus) error {
+	result, err := h.repository.UpdateStatus(c.Request().Context(), c.Param("id"), mappingStatus, publishStatus)
+	if err != nil {
+		return err
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return visitNotFound(c)
+	}
+	return h.respondStatus(c, nil)
+}
+
+func (h *Handler) respondStatus(c echo.Context, report *entity.MappingReport) error {
+	internal, err := h.stored(c)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, statusOutput{
+		VisitID:       internal.VisitID,
+		MappingStatus: internal.MappingStatus,
+		PublishStatus: internal.PublishStatus,
+		MappingReport: report,
+	})
+}
+
+// jobRuns lists the latest runs of the job query parameter, or of every job.
+func (h *Handler) jobRuns(c echo.Context) error {
+	limit, err := queryLimit(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	runs, err := h.repository.JobRuns(c.Request().Context(), c.QueryParam("job"), limit)
+	if err != nil {
+		return err
+	}
+
+	if runs == nil {
+		runs = []entity.JobRun{}
+	}
+	return c.JSON(http.StatusOK, runs)
+}
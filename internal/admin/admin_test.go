@@ -0,0 +1,135 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/jasoet/fhir-worker/internal/db"
+	"github.com/jasoet/fhir-worker/internal/db/dbtest"
+	"github.com/jasoet/fhir-worker/internal/entity"
+	"github.com/jasoet/fhir-worker/job"
+	shared "github.com/jasoet/fhir-worker/shared/model"
+	"github.com/jasoet/fhir-worker/simrs"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const testApiKey = "secret"
+
+func newTestServer(t *testing.T) (*echo.Echo, *db.Repository) {
+	repository := dbtest.Repository(t)
+
+	ctx := context.Background()
+	for id, date := range map[string]time.Time{
+		"V1": time.Date(2024, 5, 1, 9, 0, 0, 0, time.Local),
+		"V2": time.Date(2024, 5, 2, 9, 0, 0, 0, time.Local),
+	} {
+		_, err := repository.InsertValid(ctx, id, date, "P-"+id, shared.VisitDetail{VisitId: id}, shared.VitalSign{})
+		require.NoError(t, err)
+	}
+
+	// revalidating reads the stored visit only, SIMRS isn't queried
+	mapping, err := job.NewMapping(job.WithQueryAndRepository(struct{ simrs.Query }{}, repository))
+	require.NoError(t, err)
+
+	e := echo.New()
+	NewHandler(repository, mapping, nil).Register(e, testApiKey)
+	return e, repository
+}
+
+func serve(e *echo.Echo, method string, target string, apiKey string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, target, nil)
+	if apiKey != "" {
+		request.Header.Set("X-API-Key", apiKey)
+	}
+
+	recorder := httptest.NewRecorder()
+	e.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestHandler(t *testing.T) {
+	e, repository := newTestServer(t)
+
+	t.Run("rejects requests without a valid key", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, serve(e, http.MethodGet, "/api/admin/visits", "").Code)
+		assert.Equal(t, http.StatusUnauthorized, serve(e, http.MethodGet, "/api/admin/visits", "wrong").Code)
+	})
+
+	t.Run("lists visits by date", func(t *testing.T) {
+		recorder := serve(e, http.MethodGet, "/api/admin/visits?from=2024-05-02&to=2024-05-02", testApiKey)
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		var visits []entity.VisitSummary
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &visits))
+		require.Len(t, visits, 1)
+		assert.Equal(t, "V2", visits[0].VisitID)
+	})
+
+	t.Run("rejects invalid filters", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, serve(e, http.MethodGet, "/api/admin/visits?from=05-2024", testApiKey).Code)
+		assert.Equal(t, http.StatusBadRequest, serve(e, http.MethodGet, "/api/admin/visits?limit=0", testApiKey).Code)
+	})
+
+	t.Run("gets a visit", func(t *testing.T) {
+		recorder := serve(e, http.MethodGet, "/api/admin/visits/V1", testApiKey)
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		var visit map[string]any
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &visit))
+		assert.Equal(t, "V1", visit["visit_id"])
+		assert.Equal(t, "P-V1", visit["satusehat_patient_id"])
+		assert.Contains(t, visit, "visit_detail")
+
+		assert.Equal(t, http.StatusNotFound, serve(e, http.MethodGet, "/api/admin/visits/V9", testApiKey).Code)
+	})
+
+	t.Run("skips and requeues a visit", func(t *testing.T) {
+		recorder := serve(e, http.MethodPost, "/api/admin/visits/V1/skip", testApiKey)
+		require.Equal(t, http.StatusOK, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"mapping_status":"SKIPPED"`)
+
+		recorder = serve(e, http.MethodGet, "/api/admin/visits?mapping_status=SKIPPED", testApiKey)
+		require.Equal(t, http.StatusOK, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"visit_id":"V1"`)
+		assert.NotContains(t, recorder.Body.String(), `"visit_id":"V2"`)
+
+		// requeuing a skipped visit revalidates it, complete as it is older than the mark complete days
+		recorder = serve(e, http.MethodPost, "/api/admin/visits/V1/requeue", testApiKey)
+		require.Equal(t, http.StatusOK, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"mapping_status":"READY"`)
+		assert.Contains(t, recorder.Body.String(), `"mapping_report":`)
+	})
+
+	t.Run("requeues an incomplete visit only after its publish failed", func(t *testing.T) {
+		ctx := context.Background()
+		_, err := repository.InsertValid(ctx, "V3", time.Now(), "P-V3", shared.VisitDetail{VisitId: "V3"}, shared.VitalSign{})
+		require.NoError(t, err)
+
+		recorder := serve(e, http.MethodPost, "/api/admin/visits/V3/requeue", testApiKey)
+		require.Equal(t, http.StatusOK, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"mapping_status":"INCOMPLETE"`)
+
+		_, err = repository.UpdateStatus(ctx, "V3", entity.Incomplete, entity.RequestError)
+		require.NoError(t, err)
+
+		recorder = serve(e, http.MethodPost, "/api/admin/visits/V3/requeue", testApiKey)
+		require.Equal(t, http.StatusOK, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"mapping_status":"READY"`)
+		assert.Contains(t, recorder.Body.String(), `"publish_status":"PREPARING"`)
+
+		assert.Equal(t, http.StatusNotFound, serve(e, http.MethodPost, "/api/admin/visits/V9/requeue", testApiKey).Code)
+	})
+
+	t.Run("lists job runs", func(t *testing.T) {
+		recorder := serve(e, http.MethodGet, "/api/admin/job-runs?job=publish", testApiKey)
+		require.Equal(t, http.StatusOK, recorder.Code)
+		assert.JSONEq(t, "[]", recorder.Body.String())
+
+		assert.Equal(t, http.StatusBadRequest, serve(e, http.MethodGet, "/api/admin/job-runs?limit=x", testApiKey).Code)
+	})
+}
@@ -0,0 +1,151 @@
+// Package audit exports the log of SatuSehat exchanges for auditors, signed with the hospital's ed25519 key.
+package audit
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"github.com/jasoet/fhir-worker/internal/entity"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	FormatCsv   = "csv"
+	FormatJsonl = "jsonl"
+)
+
+var Header = []string{
+	"id", "occurred_at", "method", "url", "status_code", "latency_ms", "request_hash", "response_hash", "visit_id",
+	"client_id", "error",
+}
+
+// Write writes entries to writer as CSV or JSON lines.
+func Write(writer io.Writer, format string, entries []entity.AuditEntry) error {
+	switch format {
+	case FormatCsv:
+		return writeCsv(writer, entries)
+	case FormatJsonl:
+		encoder := json.NewEncoder(writer)
+		for _, entry := range entries {
+			if err := encoder.Encode(entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown audit export format %q, use %s or %s", format, FormatCsv, FormatJsonl)
+	}
+}
+
+func writeCsv(writer io.Writer, entries []entity.AuditEntry) error {
+	csvWriter := csv.NewWriter(writer)
+	if err := csvWriter.Write(Header); err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		err := csvWriter.Write([]string{
+			strconv.FormatInt(entry.ID, 10),
+			entry.OccurredAt.UTC().Format(time.RFC3339Nano),
+			entry.Method,
+			entry.Url,
+			strconv.Itoa(entry.StatusCode),
+			strconv.FormatInt(entry.LatencyMs, 10),
+			entry.RequestHash,
+			entry.ResponseHash,
+			optional(entry.VisitID),
+			entry.ClientID,
+			optional(entry.Error),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+func optional(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// LoadPrivateKey reads an ed25519 private key in PKCS #8 PEM, e.g. from `openssl genpkey -algorithm ed25519`.
+func LoadPrivateKey(path string) (ed25519.PrivateKey, error) {
+	key, err := parsePem(path, "PRIVATE KEY", x509.ParsePKCS8PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s holds a %T, not an ed25519 private key", path, key)
+	}
+	return privateKey, nil
+}
+
+// LoadPublicKey reads an ed25519 public key in PKIX PEM, e.g. from `openssl pkey -pubout` or PublicKeyPem.
+func LoadPublicKey(path string) (ed25519.PublicKey, error) {
+	key, err := parsePem(path, "PUBLIC KEY", x509.ParsePKIXPublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%s holds a %T, not an ed25519 public key", path, key)
+	}
+	return publicKey, nil
+}
+
+// PublicKeyPem encodes the public key of privateKey for auditors to verify the exports with.
+func PublicKeyPem(privateKey ed25519.PrivateKey) ([]byte, error) {
+	der, err := x509.MarshalPKIXPublicKey(privateKey.Public())
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+}
+
+func parsePem(path string, blockType string, parse func(der []byte) (any, error)) (any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != blockType {
+		return nil, fmt.Errorf("%s is not a PEM encoded %s", path, strings.ToLower(blockType))
+	}
+	return parse(block.Bytes)
+}
+
+// Sign returns the base64 ed25519 signature of data, written next to an export as <file>.sig.
+func Sign(privateKey ed25519.PrivateKey, data []byte) []byte {
+	signature := ed25519.Sign(privateKey, data)
+	return []byte(base64.StdEncoding.EncodeToString(signature) + "\n")
+}
+
+// Verify checks signature, as written by Sign, is the signature of data by publicKey.
+func Verify(publicKey ed25519.PublicKey, data []byte, signature []byte) error {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+	if err != nil {
+		return fmt.Errorf("signature is not base64: %w", err)
+	}
+
+	if !ed25519.Verify(publicKey, data, raw) {
+		return fmt.Errorf("signature does not match, the export was modified or signed with another key")
+	}
+	return nil
+}
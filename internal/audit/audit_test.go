@@ -0,0 +1,82 @@
+package audit
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"github.com/jasoet/fhir-worker/internal/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testEntries() []entity.AuditEntry {
+	visitId := "V-1"
+	failure := "connection refused"
+	occurredAt := time.Date(2026, 10, 1, 8, 30, 0, 0, time.UTC)
+
+	return []entity.AuditEntry{
+		{ID: 1, OccurredAt: occurredAt, Method: "POST", Url: "https://api/fhir", StatusCode: 200, LatencyMs: 120,
+			RequestHash: "aa", ResponseHash: "bb", VisitID: &visitId, ClientID: "client-1"},
+		{ID: 2, OccurredAt: occurredAt, Method: "GET", Url: "https://api/Patient", RequestHash: "cc",
+			ClientID: "client-1", Error: &failure},
+	}
+}
+
+func TestWrite(t *testing.T) {
+	var csv bytes.Buffer
+	require.NoError(t, Write(&csv, FormatCsv, testEntries()))
+	assert.Equal(t, strings.Join([]string{
+		"id,occurred_at,method,url,status_code,latency_ms,request_hash,response_hash,visit_id,client_id,error",
+		"1,2026-10-01T08:30:00Z,POST,https://api/fhir,200,120,aa,bb,V-1,client-1,",
+		"2,2026-10-01T08:30:00Z,GET,https://api/Patient,0,0,cc,,,client-1,connection refused",
+		"",
+	}, "\n"), csv.String())
+
+	var jsonl bytes.Buffer
+	require.NoError(t, Write(&jsonl, FormatJsonl, testEntries()))
+	lines := strings.Split(strings.TrimSpace(jsonl.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[0], `"visit_id":"V-1"`)
+	assert.Contains(t, lines[1], `"error":"connection refused"`)
+
+	assert.Error(t, Write(&jsonl, "xml", testEntries()))
+}
+
+func TestSignAndVerify(t *testing.T) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	privateKeyFile := filepath.Join(dir, "audit-key.pem")
+	require.NoError(t, os.WriteFile(privateKeyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600))
+
+	loaded, err := LoadPrivateKey(privateKeyFile)
+	require.NoError(t, err)
+
+	publicKeyPem, err := PublicKeyPem(loaded)
+	require.NoError(t, err)
+	publicKeyFile := filepath.Join(dir, "audit-key.pub.pem")
+	require.NoError(t, os.WriteFile(publicKeyFile, publicKeyPem, 0644))
+
+	publicKey, err := LoadPublicKey(publicKeyFile)
+	require.NoError(t, err)
+
+	data := []byte("id,occurred_at\n1,2026-10-01T08:30:00Z\n")
+	signature := Sign(loaded, data)
+
+	assert.NoError(t, Verify(publicKey, data, signature))
+	assert.ErrorContains(t, Verify(publicKey, append(data, '2'), signature), "does not match")
+
+	_, err = LoadPrivateKey(publicKeyFile)
+	assert.ErrorContains(t, err, "not a PEM encoded private key")
+}
@@ -0,0 +1,267 @@
+package conceptmap
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/jasoet/fhir-worker/internal/entity"
+	"github.com/jasoet/fhir-worker/internal/terminology"
+	"github.com/jasoet/fhir-worker/pkg/util"
+	"github.com/jasoet/fhir-worker/shared/model"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Header is the CSV layout of a concept map, the unmapped export starts with the same columns
+// so a filled in backlog can be imported as is.
+var Header = []string{"local_code", "parameter", "loinc_code", "loinc_display", "ucum_unit"}
+
+type key struct {
+	localCode string
+	parameter string
+}
+
+// ConceptMap translates local lab test codes and parameter names to LOINC.
+type ConceptMap struct {
+	entries map[key]entity.ConceptMapEntry
+}
+
+// Miss is a lab parameter without LOINC code the ConceptMap has no entry for.
+type Miss struct {
+	LocalCode string
+	Parameter string
+}
+
+func New(entries []entity.ConceptMapEntry) *ConceptMap {
+	conceptMap := &ConceptMap{
+		entries: make(map[key]entity.ConceptMapEntry, len(entries)),
+	}
+
+	for _, entry := range entries {
+		conceptMap.entries[key{entry.LocalCode, NormalizeParameter(entry.Parameter)}] = entry
+	}
+	return conceptMap
+}
+
+func (c *ConceptMap) Len() int {
+	return len(c.entries)
+}
+
+// NormalizeParameter makes parameter names typed differently across SIMRS screens comparable, e.g. " Hemoglobin  (HB)" and "hemoglobin (hb)".
+func NormalizeParameter(parameter string) string {
+	return strings.ToLower(strings.Join(strings.Fields(parameter), " "))
+}
+
+// LocalCode identifies the lab test of an observation, the test name is used when SIMRS has no test code.
+func LocalCode(o *model.ObservationLab) string {
+	if code := strings.TrimSpace(o.LabCode); code != "" {
+		return code
+	}
+	return strings.TrimSpace(o.LabName)
+}
+
+// Lookup returns the entry of a test parameter, falling back to the entry of the whole test (empty parameter).
+func (c *ConceptMap) Lookup(localCode string, parameter string) (entity.ConceptMapEntry, bool) {
+	if entry, ok := c.entries[key{localCode, NormalizeParameter(parameter)}]; ok {
+		return entry, true
+	}
+
+	entry, ok := c.entries[key{localCode, ""}]
+	return entry, ok
+}
+
+// Apply fills the LOINC code, display and UCUM unit of every parameter SIMRS has no LOINC code for,
+// it returns the parameters the concept map couldn't translate.
+func (c *ConceptMap) Apply(o *model.ObservationLab) []Miss {
+	localCode := LocalCode(o)
+	if localCode == "" {
+		return nil
+	}
+
+	parameters, single := util.JsonStrings(o.LabParameter)
+	if len(parameters) == 0 {
+		parameters, single = []string{""}, true
+	}
+
+	codes := resized(o.LabLoincCode, len(parameters))
+	displays := resized(o.LabLoincName, len(parameters))
+	units := resized(o.LabUnit, len(parameters))
+
+	var misses []Miss
+	changed := false
+	for i, parameter := range parameters {
+		if strings.TrimSpace(codes[i]) != "" {
+			continue
+		}
+
+		entry, ok := c.Lookup(localCode, parameter)
+		if !ok {
+			misses = append(misses, Miss{LocalCode: localCode, Parameter: NormalizeParameter(parameter)})
+			continue
+		}
+
+		codes[i] = entry.LoincCode
+		displays[i] = entry.LoincDisplay
+		if entry.UcumUnit != "" {
+			units[i] = entry.UcumUnit
+		}
+		changed = true
+	}
+
+	if changed {
+		o.LabLoincCode = util.JsonStringsOf(codes, single)
+		o.LabLoincName = util.JsonStringsOf(displays, single)
+		o.LabUnit = util.JsonStringsOf(units, single)
+	}
+
+	return misses
+}
+
+// resized decodes a JSON string column to exactly length values, padding missing ones with empty strings.
+func resized(raw *json.RawMessage, length int) []string {
+	values, _ := util.JsonStrings(raw)
+	result := make([]string, length)
+	copy(result, values)
+	return result
+}
+
+// ReadEntries reads a concept map CSV by header name, rows without a loinc_code are skipped
+// so a partially filled backlog can be imported.
+func ReadEntries(reader io.Reader) ([]entity.ConceptMapEntry, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1
+
+	header, err := csvReader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	for _, required := range []string{"local_code", "parameter", "loinc_code", "loinc_display"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("column %s is required", required)
+		}
+	}
+
+	value := func(record []string, column string) string {
+		i, ok := columns[column]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	seen := map[key]int{}
+	var entries []entity.ConceptMapEntry
+	for {
+		record, err := csvReader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		line, _ := csvReader.FieldPos(0)
+
+		entry := entity.ConceptMapEntry{
+			LocalCode:    value(record, "local_code"),
+			Parameter:    NormalizeParameter(value(record, "parameter")),
+			LoincCode:    terminology.Normalize(terminology.LOINC, value(record, "loinc_code")),
+			LoincDisplay: value(record, "loinc_display"),
+			UcumUnit:     value(record, "ucum_unit"),
+		}
+
+		if entry.LoincCode == "" {
+			continue
+		}
+
+		switch {
+		case entry.LocalCode == "":
+			return nil, fmt.Errorf("line %d: local_code is required", line)
+		case !terminology.WellFormed(terminology.LOINC, entry.LoincCode):
+			return nil, fmt.Errorf("line %d: loinc_code %q is malformed", line, entry.LoincCode)
+		case entry.LoincDisplay == "":
+			return nil, fmt.Errorf("line %d: loinc_display is required", line)
+		}
+
+		entryKey := key{entry.LocalCode, entry.Parameter}
+		if previous, ok := seen[entryKey]; ok {
+			return nil, fmt.Errorf("line %d: %s %q is already mapped on line %d", line, entry.LocalCode, entry.Parameter, previous)
+		}
+		seen[entryKey] = line
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// Merge returns base with the entries of update added, update wins for the same local code and parameter.
+func Merge(base []entity.ConceptMapEntry, update []entity.ConceptMapEntry) []entity.ConceptMapEntry {
+	updated := make(map[key]bool, len(update))
+	for _, entry := range update {
+		updated[key{entry.LocalCode, NormalizeParameter(entry.Parameter)}] = true
+	}
+
+	merged := make([]entity.ConceptMapEntry, 0, len(base)+len(update))
+	for _, entry := range base {
+		if !updated[key{entry.LocalCode, NormalizeParameter(entry.Parameter)}] {
+			merged = append(merged, entry)
+		}
+	}
+	return append(merged, update...)
+}
+
+func WriteEntries(writer io.Writer, entries []entity.ConceptMapEntry) error {
+	csvWriter := csv.NewWriter(writer)
+	if err := csvWriter.Write(Header); err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		err := csvWriter.Write([]string{entry.LocalCode, entry.Parameter, entry.LoincCode, entry.LoincDisplay, entry.UcumUnit})
+		if err != nil {
+			return err
+		}
+	}
+
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+// WriteUnmapped writes the backlog ordered by frequency with empty LOINC columns for lab staff to fill.
+func WriteUnmapped(writer io.Writer, unmapped []entity.UnmappedLabCode) error {
+	csvWriter := csv.NewWriter(writer)
+
+	header := append(append([]string{}, Header...), "lab_name", "occurrences", "last_seen_at")
+	if err := csvWriter.Write(header); err != nil {
+		return err
+	}
+
+	for _, code := range unmapped {
+		err := csvWriter.Write([]string{
+			code.LocalCode,
+			code.Parameter,
+			"",
+			"",
+			"",
+			code.LabName,
+			strconv.Itoa(code.Occurrences),
+			code.LastSeenAt.Format(time.RFC3339),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
@@ -0,0 +1,157 @@
+package conceptmap
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/jasoet/fhir-worker/internal/entity"
+	"github.com/jasoet/fhir-worker/pkg/util"
+	"github.com/jasoet/fhir-worker/shared/model"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func raw(s string) *json.RawMessage {
+	message := json.RawMessage(s)
+	return &message
+}
+
+var entries = []entity.ConceptMapEntry{
+	{LocalCode: "101", Parameter: "hemoglobin", LoincCode: "718-7", LoincDisplay: "Hemoglobin [Mass/volume] in Blood", UcumUnit: "g/dL"},
+	{LocalCode: "101", Parameter: "trombosit", LoincCode: "777-3", LoincDisplay: "Platelets [#/volume] in Blood by Automated count", UcumUnit: "10*3/uL"},
+	{LocalCode: "GDS", Parameter: "", LoincCode: "2345-7", LoincDisplay: "Glucose [Mass/volume] in Serum or Plasma", UcumUnit: "mg/dL"},
+}
+
+func TestConceptMap_Lookup(t *testing.T) {
+	conceptMap := New(entries)
+
+	tests := []struct {
+		name      string
+		localCode string
+		parameter string
+		wantCode  string
+		wantOk    bool
+	}{
+		{"Exact", "101", "hemoglobin", "718-7", true},
+		{"NormalizedParameter", "101", "  Hemoglobin ", "718-7", true},
+		{"WholeTest", "GDS", "Gula Darah Sewaktu", "2345-7", true},
+		{"UnknownParameter", "101", "leukosit", "", false},
+		{"UnknownCode", "999", "hemoglobin", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, ok := conceptMap.Lookup(tt.localCode, tt.parameter)
+			assert.Equal(t, tt.wantOk, ok)
+			assert.Equal(t, tt.wantCode, entry.LoincCode)
+		})
+	}
+}
+
+func TestConceptMap_Apply(t *testing.T) {
+	conceptMap := New(entries)
+
+	lab := model.ObservationLab{
+		LabCode:      "101",
+		LabName:      "Darah Lengkap",
+		LabParameter: raw(`["Hemoglobin","Trombosit","Leukosit"]`),
+		LabUnit:      raw(`["g/dl","rb/ul","rb/ul"]`),
+	}
+
+	misses := conceptMap.Apply(&lab)
+	assert.Equal(t, []Miss{{LocalCode: "101", Parameter: "leukosit"}}, misses)
+
+	codes, single := util.JsonStrings(lab.LabLoincCode)
+	assert.False(t, single)
+	assert.Equal(t, []string{"718-7", "777-3", ""}, codes)
+
+	units, _ := util.JsonStrings(lab.LabUnit)
+	assert.Equal(t, []string{"g/dL", "10*3/uL", "rb/ul"}, units)
+
+	t.Run("KeepsSimrsCode", func(t *testing.T) {
+		lab := model.ObservationLab{
+			LabName:      "GDS",
+			LabParameter: raw(`"Gula Darah Sewaktu"`),
+			LabLoincCode: raw(`"2339-0"`),
+		}
+
+		assert.Empty(t, conceptMap.Apply(&lab))
+		codes, single := util.JsonStrings(lab.LabLoincCode)
+		assert.True(t, single)
+		assert.Equal(t, []string{"2339-0"}, codes)
+	})
+
+	t.Run("LabNameAsLocalCode", func(t *testing.T) {
+		lab := model.ObservationLab{LabName: "GDS"}
+
+		assert.Empty(t, conceptMap.Apply(&lab))
+		codes, single := util.JsonStrings(lab.LabLoincCode)
+		assert.True(t, single)
+		assert.Equal(t, []string{"2345-7"}, codes)
+	})
+}
+
+func TestReadEntries(t *testing.T) {
+	input := `local_code,parameter,loinc_code,loinc_display,ucum_unit,lab_name,occurrences
+101,Hemoglobin,718-7,Hemoglobin [Mass/volume] in Blood,g/dL,Darah Lengkap,12
+101,Leukosit,,,,Darah Lengkap,12
+GDS,,2345-7,Glucose [Mass/volume] in Serum or Plasma,mg/dL,GDS,3
+`
+
+	result, err := ReadEntries(strings.NewReader(input))
+	assert.NoError(t, err)
+	if assert.Len(t, result, 2) {
+		assert.Equal(t, entries[0], result[0])
+		assert.Equal(t, entries[2], result[1])
+	}
+}
+
+func TestReadEntries_Invalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"MissingColumn", "local_code,loinc_code,loinc_display\n101,718-7,Hemoglobin\n"},
+		{"MalformedCode", "local_code,parameter,loinc_code,loinc_display\n101,hemoglobin,718-8,Hemoglobin\n"},
+		{"MissingDisplay", "local_code,parameter,loinc_code,loinc_display\n101,hemoglobin,718-7,\n"},
+		{"Duplicate", "local_code,parameter,loinc_code,loinc_display\n101,hemoglobin,718-7,Hemoglobin\n101,HEMOGLOBIN,718-7,Hemoglobin\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadEntries(strings.NewReader(tt.input))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestMerge(t *testing.T) {
+	update := []entity.ConceptMapEntry{
+		{LocalCode: "101", Parameter: "trombosit", LoincCode: "26515-7", LoincDisplay: "Platelets [#/volume] in Blood"},
+	}
+
+	merged := Merge(entries, update)
+	if assert.Len(t, merged, 3) {
+		assert.Equal(t, update[0], merged[2])
+	}
+}
+
+func TestWriteUnmapped(t *testing.T) {
+	unmapped := []entity.UnmappedLabCode{
+		{LocalCode: "101", Parameter: "leukosit", LabName: "Darah Lengkap", Occurrences: 12, LastSeenAt: time.Now()},
+	}
+
+	var buffer bytes.Buffer
+	assert.NoError(t, WriteUnmapped(&buffer, unmapped))
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	if assert.Len(t, lines, 2) {
+		assert.True(t, strings.HasPrefix(lines[0], "local_code,parameter,loinc_code,loinc_display,ucum_unit,lab_name,occurrences,last_seen_at"))
+		assert.True(t, strings.HasPrefix(lines[1], "101,leukosit,,,,Darah Lengkap,12,"))
+	}
+
+	result, err := ReadEntries(strings.NewReader(buffer.String()))
+	assert.NoError(t, err)
+	assert.Empty(t, result, "an unfilled backlog imports nothing")
+}
@@ -0,0 +1,246 @@
+package dashboard
+
+import (
+	"context"
+	_ "embed"
+	"github.com/jasoet/fhir-worker/internal/db"
+	"github.com/jasoet/fhir-worker/internal/entity"
+	"github.com/jasoet/fhir-worker/pkg/redact"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"sort"
+	"time"
+)
+
+const (
+	defaultDays   = 14
+	topIssues     = 20
+	recentErrors  = 20
+	recentRuns    = 20
+	responseLimit = 500
+)
+
+//go:embed static/index.html
+var indexHtml []byte
+
+// Handler serves a read-only dashboard of the sync status for the ops staff, built from the internal database.
+type Handler struct {
+	repository *db.Repository
+	days       int
+}
+
+// NewHandler returns a dashboard showing the daily counts of the last days, 14 when days isn't positive.
+func NewHandler(repository *db.Repository, days int) *Handler {
+	if days <= 0 {
+		days = defaultDays
+	}
+
+	return &Handler{
+		repository: repository,
+		days:       days,
+	}
+}
+
+// Register adds the dashboard page under /dashboard and its data under /dashboard/api/summary behind middleware, e.g.
+// the admin API key. The page is static so a browser can open it, it asks for the key and sends it with the data.
+func (h *Handler) Register(e *echo.Echo, middleware ...echo.MiddlewareFunc) {
+	e.GET("/dashboard", h.index)
+	e.GET("/dashboard/", h.index)
+	e.GET("/dashboard/api/summary", h.summary, middleware...)
+}
+
+type DailyCount struct {
+	Day     string                       `json:"day"`
+	Total   int                          `json:"total"`
+	Mapping map[entity.MappingStatus]int `json:"mapping"`
+	Publish map[entity.PublishStatus]int `json:"publish"`
+}
+
+type BacklogAge struct {
+	entity.Backlog
+	AgeDays int `json:"age_days"`
+}
+
+type JobRun struct {
+	Job        string     `json:"job"`
+	Detail     string     `json:"detail"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+	Duration   string     `json:"duration"`
+	Scanned    int        `json:"scanned"`
+	Processed  int        `json:"processed"`
+	Failed     int        `json:"failed"`
+}
+
+type Summary struct {
+	GeneratedAt         time.Time                     `json:"generated_at"`
+	Days                int                           `json:"days"`
+	Daily               []DailyCount                  `json:"daily"`
+	Backlog             []BacklogAge                  `json:"backlog"`
+	TopValidationIssues []entity.ValidationIssueCount `json:"top_validation_issues"`
+	RecentPublishErrors []entity.PublishError         `json:"recent_publish_errors"`
+	JobRuns             []JobRun                      `json:"job_runs"`
+}
+
+func (h *Handler) index(c echo.Context) error {
+	return c.HTMLBlob(http.StatusOK, indexHtml)
+}
+
+func (h *Handler) summary(c echo.Context) error {
+	summary, err := h.Summary(c.Request().Context(), time.Now())
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, summary)
+}
+
+// Summary collects every panel of the dashboard as of now.
+func (h *Handler) Summary(ctx context.Context, now time.Time) (*Summary, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	counts, err := h.repository.DailyStatusCounts(ctx, today.AddDate(0, 0, 1-h.days))
+	if err != nil {
+		return nil, err
+	}
+
+	backlog, err := h.repository.Backlog(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	issues, err := h.repository.TopValidationIssues(ctx, topIssues)
+	if err != nil {
+		return nil, err
+	}
+
+	publishErrors, err := h.repository.RecentPublishErrors(ctx, recentErrors)
+	if err != nil {
+		return nil, err
+	}
+
+	chunks, err := h.repository.RecentBackfillChunks(ctx, recentRuns)
+	if err != nil {
+		return nil, err
+	}
+
+	runs, err := h.repository.JobRuns(ctx, "", recentRuns)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range publishErrors {
+		if response := publishErrors[i].PublishResponse; response != nil {
+			redacted := redact.String(*response)
+			publishErrors[i].PublishResponse = truncate(&redacted)
+		}
+	}
+
+	summary := &Summary{
+		GeneratedAt:         now,
+		Days:                h.days,
+		Daily:               dailyCounts(counts),
+		Backlog:             backlogAges(backlog, today),
+		TopValidationIssues: issues,
+		RecentPublishErrors: publishErrors,
+		JobRuns:             jobRuns(runs, chunks),
+	}
+
+	if summary.TopValidationIssues == nil {
+		summary.TopValidationIssues = []entity.ValidationIssueCount{}
+	}
+	if summary.RecentPublishErrors == nil {
+		summary.RecentPublishErrors = []entity.PublishError{}
+	}
+	return summary, nil
+}
+
+// dailyCounts folds the counts, ordered by day, into one row per day.
+func dailyCounts(counts []entity.DailyStatusCount) []DailyCount {
+	daily := []DailyCount{}
+	for _, count := range counts {
+		if len(daily) == 0 || daily[len(daily)-1].Day != count.Day {
+			daily = append(daily, DailyCount{
+				Day:     count.Day,
+				Mapping: map[entity.MappingStatus]int{},
+				Publish: map[entity.PublishStatus]int{},
+			})
+		}
+
+		day := &daily[len(daily)-1]
+		day.Total += count.VisitCount
+		day.Mapping[count.MappingStatus] += count.VisitCount
+		day.Publish[count.PublishStatus] += count.VisitCount
+	}
+	return daily
+}
+
+func backlogAges(backlog []entity.Backlog, today time.Time) []BacklogAge {
+	ages := []BacklogAge{}
+	for _, group := range backlog {
+		age := BacklogAge{Backlog: group}
+		if oldest, err := time.ParseInLocation(time.DateOnly, group.OldestVisitDay, today.Location()); err == nil {
+			age.AgeDays = int(today.Sub(oldest).Hours() / 24)
+		}
+		ages = append(ages, age)
+	}
+	return ages
+}
+
+// jobRuns merges the scheduled job runs with the backfill chunks, latest first. Chunks only know when they finished.
+func jobRuns(runs []entity.JobRun, chunks []entity.BackfillChunk) []JobRun {
+	merged := []JobRun{}
+	for _, run := range runs {
+		detail := ""
+		if run.Error != nil {
+			detail = *run.Error
+		}
+
+		duration := "unfinished"
+		if run.FinishedAt != nil {
+			duration = run.FinishedAt.Sub(run.StartedAt).Round(time.Millisecond).String()
+		}
+
+		merged = append(merged, JobRun{
+			Job:        run.JobName,
+			Detail:     detail,
+			StartedAt:  run.StartedAt,
+			FinishedAt: run.FinishedAt,
+			Duration:   duration,
+			Scanned:    run.Scanned,
+			Processed:  run.Processed,
+			Failed:     run.Failed,
+		})
+	}
+
+	for _, chunk := range chunks {
+		merged = append(merged, JobRun{
+			Job:        "backfill",
+			Detail:     chunk.ChunkStart.Format(time.DateOnly) + " - " + chunk.ChunkEnd.Format(time.DateOnly),
+			StartedAt:  chunk.FinishedAt,
+			FinishedAt: &chunk.FinishedAt,
+			Scanned:    chunk.VisitCount,
+			Processed:  chunk.ValidCount + chunk.InvalidCount,
+			Failed:     chunk.FailedCount,
+		})
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].StartedAt.After(merged[j].StartedAt)
+	})
+	return merged[:min(len(merged), recentRuns)]
+}
+
+// truncate shortens SatuSehat responses, an OperationOutcome can be a few kilobytes.
+func truncate(response *string) *string {
+	if response == nil {
+		return nil
+	}
+
+	runes := []rune(*response)
+	if len(runes) <= responseLimit {
+		return response
+	}
+
+	truncated := string(runes[:responseLimit]) + "…"
+	return &truncated
+}
@@ -0,0 +1,125 @@
+package dashboard
+
+import (
+	"context"
+	"github.com/jasoet/fhir-worker/internal/admin"
+	"github.com/jasoet/fhir-worker/internal/db/dbtest"
+	"github.com/jasoet/fhir-worker/internal/entity"
+	shared "github.com/jasoet/fhir-worker/shared/model"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHandler_Summary(t *testing.T) {
+	ctx := context.Background()
+	repository := dbtest.Repository(t)
+	var err error
+
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.Local)
+	for id, date := range map[string]time.Time{
+		"V1": time.Date(2024, 5, 9, 9, 0, 0, 0, time.Local),
+		"V2": time.Date(2024, 5, 9, 10, 0, 0, 0, time.Local),
+		"V3": time.Date(2024, 5, 1, 9, 0, 0, 0, time.Local),
+		"V4": time.Date(2024, 4, 1, 9, 0, 0, 0, time.Local),
+	} {
+		_, err = repository.InsertValid(ctx, id, date, "P-"+id, shared.VisitDetail{VisitId: id}, shared.VitalSign{})
+		require.NoError(t, err)
+	}
+
+	_, err = repository.UpdateStatus(ctx, "V2", entity.Ready, entity.Preparing)
+	require.NoError(t, err)
+	_, err = repository.UpdatePublishStatus(ctx, "V2", "{}", `{"resourceType":"OperationOutcome","issue":[{"diagnostics":"Patient 3404000000000001 not found"}]}`, now, entity.RequestError)
+	require.NoError(t, err)
+
+	issue := shared.ValidationIssue{Resource: "Encounter", Field: "PractitionerId", Rule: "required", MessageEn: "PractitionerId is required"}
+	require.NoError(t, repository.ReplaceValidationIssues(ctx, "V1", []shared.ValidationIssue{issue}))
+	require.NoError(t, repository.ReplaceValidationIssues(ctx, "V3", []shared.ValidationIssue{issue, issue}))
+
+	_, err = repository.SaveBackfillChunk(ctx, entity.BackfillChunk{
+		RangeKey:   "2024-04-01/2024-04-30",
+		ChunkStart: time.Date(2024, 4, 1, 0, 0, 0, 0, time.Local),
+		ChunkEnd:   time.Date(2024, 4, 8, 0, 0, 0, 0, time.Local),
+		VisitCount: 10,
+		ValidCount: 7, InvalidCount: 2, FailedCount: 1,
+		FinishedAt: now,
+	})
+	require.NoError(t, err)
+
+	summary, err := NewHandler(repository, 14).Summary(ctx, now)
+	require.NoError(t, err)
+
+	require.Len(t, summary.Daily, 2)
+	assert.Equal(t, "2024-05-09", summary.Daily[0].Day)
+	assert.Equal(t, 2, summary.Daily[0].Total)
+	assert.Equal(t, 1, summary.Daily[0].Mapping[entity.Ready])
+	assert.Equal(t, 1, summary.Daily[0].Publish[entity.RequestError])
+	assert.Equal(t, "2024-05-01", summary.Daily[1].Day)
+
+	require.Len(t, summary.Backlog, 2)
+	assert.Equal(t, entity.Incomplete, summary.Backlog[0].MappingStatus)
+	assert.Equal(t, 3, summary.Backlog[0].VisitCount)
+	assert.Equal(t, 39, summary.Backlog[0].AgeDays)
+
+	require.Len(t, summary.TopValidationIssues, 1)
+	assert.Equal(t, 3, summary.TopValidationIssues[0].Occurrences)
+	assert.Equal(t, 2, summary.TopValidationIssues[0].VisitCount)
+
+	require.Len(t, summary.RecentPublishErrors, 1)
+	assert.Equal(t, "V2", summary.RecentPublishErrors[0].VisitID)
+	assert.NotContains(t, *summary.RecentPublishErrors[0].PublishResponse, "3404000000000001")
+
+	require.Len(t, summary.JobRuns, 1)
+	assert.Equal(t, 9, summary.JobRuns[0].Processed)
+	assert.Equal(t, 1, summary.JobRuns[0].Failed)
+
+	e := echo.New()
+	NewHandler(repository, 14).Register(e, admin.KeyAuth("secret"))
+
+	serve := func(target string, apiKey string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodGet, target, nil)
+		request.Header.Set("X-API-Key", apiKey)
+
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	recorder := serve("/dashboard", "secret")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "Sync Status")
+
+	recorder = serve("/dashboard/api/summary", "secret")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"top_validation_issues"`)
+
+	assert.Equal(t, http.StatusUnauthorized, serve("/dashboard/api/summary", "wrong").Code)
+
+	t.Run("Browser", func(t *testing.T) {
+		// a browser opens the page without the header, the page sends the key it asks for with the data
+		request := httptest.NewRequest(http.MethodGet, "/dashboard", nil)
+		request.Header.Set("Accept", "text/html")
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"X-API-Key"`)
+
+		request = httptest.NewRequest(http.MethodGet, "/dashboard/api/summary", nil)
+		recorder = httptest.NewRecorder()
+		e.ServeHTTP(recorder, request)
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+}
+
+func TestTruncate(t *testing.T) {
+	short := "error"
+	assert.Equal(t, &short, truncate(&short))
+	assert.Nil(t, truncate(nil))
+
+	long := string(make([]rune, responseLimit+10))
+	assert.Len(t, []rune(*truncate(&long)), responseLimit+1)
+}
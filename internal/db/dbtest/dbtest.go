@@ -0,0 +1,21 @@
+// Package dbtest opens internal databases for the tests of the packages built on the repository.
+package dbtest
+
+import (
+	"github.com/jasoet/fhir-worker/internal/db"
+	"path/filepath"
+	"testing"
+)
+
+// Repository opens a new repository in a temporary directory, both are closed and removed with the test.
+func Repository(t testing.TB) *db.Repository {
+	t.Helper()
+
+	repository, err := db.OpenRepository(filepath.Join(t.TempDir(), "internal.db"))
+	if err != nil {
+		t.Fatalf("failed to open the test repository: %v", err)
+	}
+	t.Cleanup(func() { _ = repository.Close() })
+
+	return repository
+}
@@ -22,29 +22,33 @@ var (
 func DefaultRepository(filePath ...string) (*Repository, error) {
 	var err error
 	once.Do(func() {
-		config, ierr := defaultInternalConfig(filePath...)
-		if ierr != nil {
-			err = ierr
-			return
-		}
+		instance, err = OpenRepository(filePath...)
+	})
 
-		pool, ierr := config.Pool()
-		if ierr != nil {
-			err = ierr
-			return
-		}
+	return instance, err
 
-		// Run migrations
-		if ierr = runMigrations(pool.DB); ierr != nil {
-			err = ierr
-			return
-		}
+}
 
-		instance, err = newRepository(pool)
-	})
+// OpenRepository opens and migrates a new repository at filePath, ~/internal.db when empty. Unlike DefaultRepository
+// every call opens its own database, e.g. one per test.
+func OpenRepository(filePath ...string) (*Repository, error) {
+	config, err := defaultInternalConfig(filePath...)
+	if err != nil {
+		return nil, err
+	}
 
-	return instance, err
+	pool, err := config.Pool()
+	if err != nil {
+		return nil, err
+	}
+
+	// Run migrations
+	if err = runMigrations(pool.DB); err != nil {
+		_ = pool.Close()
+		return nil, err
+	}
 
+	return newRepository(pool)
 }
 
 func runMigrations(db *sql.DB) error {
@@ -0,0 +1 @@
+DROP TABLE backfill_chunk;
\ No newline at end of file
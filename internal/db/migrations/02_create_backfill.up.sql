@@ -0,0 +1,13 @@
+CREATE TABLE backfill_chunk
+(
+    range_key      TEXT     NOT NULL,
+    chunk_start    DATETIME NOT NULL,
+    chunk_end      DATETIME NOT NULL,
+    visit_count    INTEGER  NOT NULL,
+    valid_count    INTEGER  NOT NULL,
+    invalid_count  INTEGER  NOT NULL,
+    existing_count INTEGER  NOT NULL,
+    failed_count   INTEGER  NOT NULL,
+    finished_at    DATETIME NOT NULL,
+    PRIMARY KEY (range_key, chunk_start)
+);
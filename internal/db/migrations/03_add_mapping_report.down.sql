@@ -0,0 +1 @@
+ALTER TABLE satusehat DROP COLUMN mapping_report;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE satusehat ADD COLUMN mapping_report TEXT;
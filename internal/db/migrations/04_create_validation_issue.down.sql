@@ -0,0 +1,2 @@
+DROP INDEX validation_issue_visit_id_idx;
+DROP TABLE validation_issue;
\ No newline at end of file
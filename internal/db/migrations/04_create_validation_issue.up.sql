@@ -0,0 +1,16 @@
+CREATE TABLE validation_issue
+(
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    visit_id   TEXT     NOT NULL,
+    resource   TEXT     NOT NULL,
+    record_id  TEXT     NOT NULL,
+    field      TEXT     NOT NULL,
+    rule       TEXT     NOT NULL,
+    param      TEXT     NOT NULL,
+    value      TEXT     NOT NULL,
+    message_id TEXT     NOT NULL,
+    message_en TEXT     NOT NULL,
+    created_at DATETIME NOT NULL
+);
+
+CREATE INDEX validation_issue_visit_id_idx ON validation_issue (visit_id);
@@ -0,0 +1,3 @@
+DROP TABLE kfa_unmapped_visit;
+DROP TABLE kfa_unmapped;
+DROP TABLE kfa_mapping;
\ No newline at end of file
@@ -0,0 +1,24 @@
+CREATE TABLE kfa_mapping
+(
+    medicine_code TEXT PRIMARY KEY,
+    medicine_name TEXT     NOT NULL,
+    kfa_code      TEXT     NOT NULL,
+    kfa_display   TEXT     NOT NULL,
+    mapped_by     TEXT     NOT NULL,
+    mapped_at     DATETIME NOT NULL
+);
+
+CREATE TABLE kfa_unmapped
+(
+    medicine_code TEXT PRIMARY KEY,
+    medicine_name TEXT     NOT NULL,
+    first_seen_at DATETIME NOT NULL,
+    last_seen_at  DATETIME NOT NULL
+);
+
+CREATE TABLE kfa_unmapped_visit
+(
+    medicine_code TEXT NOT NULL,
+    visit_id      TEXT NOT NULL,
+    PRIMARY KEY (medicine_code, visit_id)
+);
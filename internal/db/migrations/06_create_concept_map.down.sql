@@ -0,0 +1,4 @@
+DROP TABLE concept_map_miss_visit;
+DROP TABLE concept_map_miss;
+DROP TABLE concept_map;
+DROP TABLE concept_map_version;
\ No newline at end of file
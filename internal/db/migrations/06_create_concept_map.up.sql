@@ -0,0 +1,37 @@
+CREATE TABLE concept_map_version
+(
+    version     INTEGER PRIMARY KEY AUTOINCREMENT,
+    description TEXT     NOT NULL,
+    created_by  TEXT     NOT NULL,
+    created_at  DATETIME NOT NULL,
+    active      BOOLEAN  NOT NULL DEFAULT FALSE
+);
+
+CREATE TABLE concept_map
+(
+    version       INTEGER NOT NULL REFERENCES concept_map_version (version),
+    local_code    TEXT    NOT NULL,
+    parameter     TEXT    NOT NULL,
+    loinc_code    TEXT    NOT NULL,
+    loinc_display TEXT    NOT NULL,
+    ucum_unit     TEXT    NOT NULL,
+    PRIMARY KEY (version, local_code, parameter)
+);
+
+CREATE TABLE concept_map_miss
+(
+    local_code    TEXT     NOT NULL,
+    parameter     TEXT     NOT NULL,
+    lab_name      TEXT     NOT NULL,
+    first_seen_at DATETIME NOT NULL,
+    last_seen_at  DATETIME NOT NULL,
+    PRIMARY KEY (local_code, parameter)
+);
+
+CREATE TABLE concept_map_miss_visit
+(
+    local_code TEXT NOT NULL,
+    parameter  TEXT NOT NULL,
+    visit_id   TEXT NOT NULL,
+    PRIMARY KEY (local_code, parameter, visit_id)
+);
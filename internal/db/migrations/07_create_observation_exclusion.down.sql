@@ -0,0 +1 @@
+DROP TABLE observation_exclusion;
\ No newline at end of file
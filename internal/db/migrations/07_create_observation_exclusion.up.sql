@@ -0,0 +1,13 @@
+CREATE TABLE observation_exclusion
+(
+    id            INTEGER PRIMARY KEY AUTOINCREMENT,
+    visit_id      TEXT     NOT NULL,
+    loinc_code    TEXT     NOT NULL,
+    loinc_display TEXT     NOT NULL,
+    value         TEXT     NOT NULL,
+    reason        TEXT     NOT NULL,
+    message       TEXT     NOT NULL,
+    excluded_at   DATETIME NOT NULL
+);
+
+CREATE INDEX observation_exclusion_visit_id_idx ON observation_exclusion (visit_id);
@@ -0,0 +1 @@
+ALTER TABLE satusehat DROP COLUMN anamnesis;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE satusehat ADD COLUMN anamnesis TEXT;
\ No newline at end of file
@@ -0,0 +1,2 @@
+DROP TABLE published_allergy;
+ALTER TABLE satusehat DROP COLUMN allergy;
\ No newline at end of file
@@ -0,0 +1,10 @@
+ALTER TABLE satusehat ADD COLUMN allergy TEXT;
+
+CREATE TABLE published_allergy
+(
+    patient_satusehat_id TEXT     NOT NULL,
+    allergy_key          TEXT     NOT NULL,
+    visit_id             TEXT     NOT NULL,
+    published_at         DATETIME NOT NULL,
+    PRIMARY KEY (patient_satusehat_id, allergy_key)
+);
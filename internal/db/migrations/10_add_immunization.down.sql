@@ -0,0 +1 @@
+ALTER TABLE satusehat DROP COLUMN immunization;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE satusehat ADD COLUMN immunization TEXT;
\ No newline at end of file
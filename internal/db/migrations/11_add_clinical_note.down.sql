@@ -0,0 +1 @@
+ALTER TABLE satusehat DROP COLUMN clinical_note;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE satusehat ADD COLUMN clinical_note TEXT;
\ No newline at end of file
@@ -0,0 +1,13 @@
+CREATE TABLE job_runs
+(
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    job_name    TEXT     NOT NULL,
+    started_at  DATETIME NOT NULL,
+    finished_at DATETIME,
+    scanned     INTEGER  NOT NULL DEFAULT 0,
+    processed   INTEGER  NOT NULL DEFAULT 0,
+    failed      INTEGER  NOT NULL DEFAULT 0,
+    error       TEXT
+);
+
+CREATE INDEX job_runs_started_at_idx ON job_runs (started_at);
@@ -0,0 +1,30 @@
+CREATE TABLE audit_log
+(
+    id            INTEGER PRIMARY KEY AUTOINCREMENT,
+    occurred_at   DATETIME NOT NULL,
+    method        TEXT     NOT NULL,
+    url           TEXT     NOT NULL,
+    status_code   INTEGER  NOT NULL DEFAULT 0,
+    latency_ms    INTEGER  NOT NULL DEFAULT 0,
+    request_hash  TEXT     NOT NULL,
+    response_hash TEXT     NOT NULL,
+    visit_id      TEXT,
+    client_id     TEXT     NOT NULL,
+    error         TEXT
+);
+
+CREATE INDEX audit_log_occurred_at_idx ON audit_log (occurred_at);
+
+CREATE TRIGGER audit_log_no_update
+    BEFORE UPDATE
+    ON audit_log
+BEGIN
+    SELECT RAISE(ABORT, 'audit_log is append-only');
+END;
+
+CREATE TRIGGER audit_log_no_delete
+    BEFORE DELETE
+    ON audit_log
+BEGIN
+    SELECT RAISE(ABORT, 'audit_log is append-only');
+END;
@@ -3,10 +3,15 @@ package db
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/jasoet/fhir-worker/internal/entity"
+	"github.com/jasoet/fhir-worker/pkg/encryption"
 	"github.com/jasoet/fhir-worker/pkg/util"
 	shared "github.com/jasoet/fhir-worker/shared/model"
 	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog/log"
 	"sync"
 	"time"
 )
@@ -25,11 +30,16 @@ const (
 			si.medication_request, 
 			si.medication_dispense, 
 			si.medical_procedure, 
+			si.anamnesis, 
+			si.allergy, 
+			si.immunization, 
+			si.clinical_note, 
 			si.publish_date, 
 			si.publish_request, 
 			si.publish_response, 
 			si.publish_status, 
 			si.mapping_errors,
+			si.mapping_report,
 			si.mapping_status 
 		FROM 
 			satusehat AS si
@@ -37,6 +47,36 @@ const (
 			si.mapping_status = :mapping_status;
    `
 
+	GetByVisitId = `
+		SELECT 
+			si.visit_id, 
+			si.visit_date,
+			si.satusehat_patient_id, 
+			si.visit_detail, 
+			si.vital_sign, 
+			si.diagnosis, 
+			si.lab, 
+			si.radiology, 
+			si.medication_request, 
+			si.medication_dispense, 
+			si.medical_procedure, 
+			si.anamnesis, 
+			si.allergy, 
+			si.immunization, 
+			si.clinical_note, 
+			si.publish_date, 
+			si.publish_request, 
+			si.publish_response, 
+			si.publish_status, 
+			si.mapping_errors,
+			si.mapping_report,
+			si.mapping_status 
+		FROM 
+			satusehat AS si
+		WHERE
+			si.visit_id = :visit_id;
+   `
+
 	Insert = `
 		INSERT INTO satusehat (
 			visit_id, 
@@ -96,6 +136,30 @@ const (
 		WHERE visit_id = :visit_id;
 	`
 
+	UpdateAnamnesis = `
+		UPDATE satusehat
+		SET anamnesis = :anamnesis
+		WHERE visit_id = :visit_id;
+	`
+
+	UpdateAllergy = `
+		UPDATE satusehat
+		SET allergy = :allergy
+		WHERE visit_id = :visit_id;
+	`
+
+	UpdateImmunization = `
+		UPDATE satusehat
+		SET immunization = :immunization
+		WHERE visit_id = :visit_id;
+	`
+
+	UpdateClinicalNote = `
+		UPDATE satusehat
+		SET clinical_note = :clinical_note
+		WHERE visit_id = :visit_id;
+	`
+
 	UpdatePublishStatus = `
 		UPDATE satusehat
 		SET publish_response = :publish_response,
@@ -117,26 +181,690 @@ const (
 		WHERE visit_id = :visit_id;
 	`
 
+	UpdateMappingReport = `
+		UPDATE satusehat
+		SET mapping_report = :mapping_report,
+		    mapping_errors = :mapping_errors
+		WHERE visit_id = :visit_id;
+	`
+
 	IsExists = `
         SELECT count(visit_id) FROM satusehat WHERE visit_id = :visit_id;
 	`
+
+	GetBackfillChunks = `
+		SELECT 
+			range_key, 
+			chunk_start, 
+			chunk_end, 
+			visit_count, 
+			valid_count, 
+			invalid_count, 
+			existing_count, 
+			failed_count, 
+			finished_at
+		FROM 
+			backfill_chunk
+		WHERE 
+			range_key = :range_key
+		ORDER BY chunk_start;
+	`
+
+	InsertBackfillChunk = `
+		INSERT OR REPLACE INTO backfill_chunk (
+			range_key, 
+			chunk_start, 
+			chunk_end, 
+			visit_count, 
+			valid_count, 
+			invalid_count, 
+			existing_count, 
+			failed_count, 
+			finished_at
+		) 
+		VALUES (
+			:range_key, 
+			:chunk_start, 
+			:chunk_end, 
+			:visit_count, 
+			:valid_count, 
+			:invalid_count, 
+			:existing_count, 
+			:failed_count, 
+			:finished_at
+		);
+	`
+
+	GetValidationIssues = `
+		SELECT 
+			resource, 
+			record_id, 
+			field, 
+			rule, 
+			param, 
+			value, 
+			message_id, 
+			message_en
+		FROM 
+			validation_issue
+		WHERE 
+			visit_id = :visit_id
+		ORDER BY id;
+	`
+
+	DeleteValidationIssues = `
+		DELETE FROM validation_issue WHERE visit_id = :visit_id;
+	`
+
+	InsertValidationIssue = `
+		INSERT INTO validation_issue (
+			visit_id, 
+			resource, 
+			record_id, 
+			field, 
+			rule, 
+			param, 
+			value, 
+			message_id, 
+			message_en, 
+			created_at
+		) 
+		VALUES (
+			:visit_id, 
+			:resource, 
+			:record_id, 
+			:field, 
+			:rule, 
+			:param, 
+			:value, 
+			:message_id, 
+			:message_en, 
+			:created_at
+		);
+	`
+	GetKfaMappings = `
+		SELECT 
+			medicine_code, 
+			medicine_name, 
+			kfa_code, 
+			kfa_display, 
+			mapped_by, 
+			mapped_at
+		FROM 
+			kfa_mapping
+		ORDER BY medicine_code;
+	`
+
+	InsertKfaMapping = `
+		INSERT OR REPLACE INTO kfa_mapping (
+			medicine_code, 
+			medicine_name, 
+			kfa_code, 
+			kfa_display, 
+			mapped_by, 
+			mapped_at
+		) 
+		VALUES (
+			:medicine_code, 
+			:medicine_name, 
+			:kfa_code, 
+			:kfa_display, 
+			:mapped_by, 
+			:mapped_at
+		);
+	`
+
+	GetUnmappedMedicines = `
+		SELECT 
+			ku.medicine_code, 
+			ku.medicine_name, 
+			(SELECT count(kuv.visit_id) FROM kfa_unmapped_visit AS kuv WHERE kuv.medicine_code = ku.medicine_code) AS occurrences, 
+			ku.first_seen_at, 
+			ku.last_seen_at
+		FROM 
+			kfa_unmapped AS ku
+		WHERE 
+			ku.medicine_code NOT IN (SELECT medicine_code FROM kfa_mapping)
+		ORDER BY occurrences DESC, ku.medicine_code;
+	`
+
+	UpsertUnmappedMedicine = `
+		INSERT INTO kfa_unmapped (
+			medicine_code, 
+			medicine_name, 
+			first_seen_at, 
+			last_seen_at
+		) 
+		VALUES (
+			:medicine_code, 
+			:medicine_name, 
+			:seen_at, 
+			:seen_at
+		)
+		ON CONFLICT (medicine_code) DO UPDATE 
+		SET medicine_name = excluded.medicine_name,
+		    last_seen_at = excluded.last_seen_at;
+	`
+
+	InsertUnmappedMedicineVisit = `
+		INSERT OR IGNORE INTO kfa_unmapped_visit (
+			medicine_code, 
+			visit_id
+		) 
+		VALUES (
+			:medicine_code, 
+			:visit_id
+		);
+	`
+	GetConceptMapVersions = `
+		SELECT 
+			cmv.version, 
+			cmv.description, 
+			cmv.created_by, 
+			cmv.created_at, 
+			cmv.active,
+			(SELECT count(cm.local_code) FROM concept_map AS cm WHERE cm.version = cmv.version) AS entry_count
+		FROM 
+			concept_map_version AS cmv
+		ORDER BY cmv.version DESC;
+	`
+
+	GetConceptMapEntries = `
+		SELECT 
+			cm.version, 
+			cm.local_code, 
+			cm.parameter, 
+			cm.loinc_code, 
+			cm.loinc_display, 
+			cm.ucum_unit
+		FROM 
+			concept_map AS cm
+		WHERE 
+			cm.version = :version
+		ORDER BY cm.local_code, cm.parameter;
+	`
+
+	GetActiveConceptMap = `
+		SELECT 
+			cm.version, 
+			cm.local_code, 
+			cm.parameter, 
+			cm.loinc_code, 
+			cm.loinc_display, 
+			cm.ucum_unit
+		FROM 
+			concept_map AS cm
+			JOIN concept_map_version AS cmv ON (cmv.version = cm.version)
+		WHERE 
+			cmv.active
+		ORDER BY cm.local_code, cm.parameter;
+	`
+
+	InsertConceptMapVersion = `
+		INSERT INTO concept_map_version (
+			description, 
+			created_by, 
+			created_at, 
+			active
+		) 
+		VALUES (
+			:description, 
+			:created_by, 
+			:created_at, 
+			FALSE
+		);
+	`
+
+	InsertConceptMapEntry = `
+		INSERT INTO concept_map (
+			version, 
+			local_code, 
+			parameter, 
+			loinc_code, 
+			loinc_display, 
+			ucum_unit
+		) 
+		VALUES (
+			:version, 
+			:local_code, 
+			:parameter, 
+			:loinc_code, 
+			:loinc_display, 
+			:ucum_unit
+		);
+	`
+
+	IsConceptMapVersionExists = `
+		SELECT count(version) FROM concept_map_version WHERE version = :version;
+	`
+
+	ActivateConceptMapVersion = `
+		UPDATE concept_map_version
+		SET active = (version = :version)
+		WHERE version = :version OR active;
+	`
+
+	GetUnmappedLabCodes = `
+		SELECT 
+			cmm.local_code, 
+			cmm.parameter, 
+			cmm.lab_name, 
+			(SELECT count(cmmv.visit_id) 
+			 FROM concept_map_miss_visit AS cmmv 
+			 WHERE cmmv.local_code = cmm.local_code AND cmmv.parameter = cmm.parameter) AS occurrences, 
+			cmm.first_seen_at, 
+			cmm.last_seen_at
+		FROM 
+			concept_map_miss AS cmm
+		WHERE 
+			NOT EXISTS (
+				SELECT 1 
+				FROM concept_map AS cm 
+					JOIN concept_map_version AS cmv ON (cmv.version = cm.version)
+				WHERE cmv.active 
+				  AND cm.local_code = cmm.local_code 
+				  AND (cm.parameter = cmm.parameter OR cm.parameter = '')
+			)
+		ORDER BY occurrences DESC, cmm.local_code, cmm.parameter;
+	`
+
+	UpsertConceptMapMiss = `
+		INSERT INTO concept_map_miss (
+			local_code, 
+			parameter, 
+			lab_name, 
+			first_seen_at, 
+			last_seen_at
+		) 
+		VALUES (
+			:local_code, 
+			:parameter, 
+			:lab_name, 
+			:seen_at, 
+			:seen_at
+		)
+		ON CONFLICT (local_code, parameter) DO UPDATE 
+		SET lab_name = excluded.lab_name,
+		    last_seen_at = excluded.last_seen_at;
+	`
+
+	InsertConceptMapMissVisit = `
+		INSERT OR IGNORE INTO concept_map_miss_visit (
+			local_code, 
+			parameter, 
+			visit_id
+		) 
+		VALUES (
+			:local_code, 
+			:parameter, 
+			:visit_id
+		);
+	`
+
+	GetObservationExclusions = `
+		SELECT 
+			visit_id, 
+			loinc_code, 
+			loinc_display, 
+			value, 
+			reason, 
+			message, 
+			excluded_at
+		FROM 
+			observation_exclusion
+		WHERE 
+			visit_id = :visit_id
+		ORDER BY id;
+	`
+
+	DeleteObservationExclusions = `
+		DELETE FROM observation_exclusion WHERE visit_id = :visit_id;
+	`
+
+	InsertObservationExclusion = `
+		INSERT INTO observation_exclusion (
+			visit_id, 
+			loinc_code, 
+			loinc_display, 
+			value, 
+			reason, 
+			message, 
+			excluded_at
+		) 
+		VALUES (
+			:visit_id, 
+			:loinc_code, 
+			:loinc_display, 
+			:value, 
+			:reason, 
+			:message, 
+			:excluded_at
+		);
+	`
+
+	GetPublishedAllergyKeys = `
+		SELECT 
+			allergy_key
+		FROM 
+			published_allergy
+		WHERE 
+			patient_satusehat_id = :patient_satusehat_id;
+	`
+
+	InsertPublishedAllergy = `
+		INSERT OR IGNORE INTO published_allergy (
+			patient_satusehat_id, 
+			allergy_key, 
+			visit_id, 
+			published_at
+		) 
+		VALUES (
+			:patient_satusehat_id, 
+			:allergy_key, 
+			:visit_id, 
+			:published_at
+		);
+	`
+
+	GetVisits = `
+		SELECT 
+			si.visit_id, 
+			si.visit_date,
+			si.satusehat_patient_id, 
+			si.mapping_status,
+			si.publish_status, 
+			si.publish_date, 
+			si.mapping_errors
+		FROM 
+			satusehat AS si
+		WHERE
+			(:mapping_status = '' OR si.mapping_status = :mapping_status)
+			AND (:publish_status = '' OR si.publish_status = :publish_status)
+			AND (:visit_date_from IS NULL OR si.visit_date >= :visit_date_from)
+			AND (:visit_date_to IS NULL OR si.visit_date < :visit_date_to)
+		ORDER BY 
+			si.visit_date DESC, si.visit_id
+		LIMIT :limit OFFSET :offset;
+	`
+
+	UpdateStatus = `
+		UPDATE satusehat
+		SET mapping_status = :mapping_status,
+			publish_status = :publish_status
+		WHERE visit_id = :visit_id;
+	`
+
+	GetDailyStatusCounts = `
+		SELECT 
+			substr(visit_date, 1, 10) AS day,
+			mapping_status,
+			publish_status,
+			COUNT(*) AS visit_count
+		FROM 
+			satusehat
+		WHERE 
+			visit_date >= :visit_date_from
+		GROUP BY 
+			day, mapping_status, publish_status
+		ORDER BY 
+			day DESC;
+	`
+
+	GetBacklog = `
+		SELECT 
+			mapping_status,
+			publish_status,
+			COUNT(*) AS visit_count,
+			substr(MIN(visit_date), 1, 10) AS oldest_visit_day
+		FROM 
+			satusehat
+		WHERE 
+			mapping_status IN (:ready, :incomplete)
+			AND publish_status != :success
+		GROUP BY 
+			mapping_status, publish_status
+		ORDER BY 
+			oldest_visit_day;
+	`
+
+	GetTopValidationIssues = `
+		SELECT 
+			resource,
+			field,
+			rule,
+			message_en,
+			COUNT(*) AS occurrences,
+			COUNT(DISTINCT visit_id) AS visit_count
+		FROM 
+			validation_issue
+		GROUP BY 
+			resource, field, rule, message_en
+		ORDER BY 
+			occurrences DESC, resource, field
+		LIMIT :limit;
+	`
+
+	GetRecentPublishErrors = `
+		SELECT 
+			visit_id,
+			visit_date,
+			publish_status,
+			publish_date,
+			publish_response
+		FROM 
+			satusehat
+		WHERE 
+			publish_status IN (:request_error, :payload_invalid)
+		ORDER BY 
+			publish_date DESC
+		LIMIT :limit;
+	`
+
+	GetRecentBackfillChunks = `
+		SELECT 
+			range_key, 
+			chunk_start, 
+			chunk_end, 
+			visit_count, 
+			valid_count, 
+			invalid_count, 
+			existing_count, 
+			failed_count, 
+			finished_at
+		FROM 
+			backfill_chunk
+		ORDER BY 
+			finished_at DESC
+		LIMIT :limit;
+	`
+
+	InsertJobRun = `
+		INSERT INTO job_runs (job_name, started_at)
+		VALUES (:job_name, :started_at)
+		RETURNING id;
+	`
+
+	FinishJobRun = `
+		UPDATE job_runs
+		SET finished_at = :finished_at,
+			scanned = :scanned,
+			processed = :processed,
+			failed = :failed,
+			error = :error
+		WHERE id = :id;
+	`
+
+	GetJobRuns = `
+		SELECT 
+			id,
+			job_name,
+			started_at,
+			finished_at,
+			scanned,
+			processed,
+			failed,
+			error
+		FROM 
+			job_runs
+		WHERE 
+			(:job_name = '' OR job_name = :job_name)
+		ORDER BY 
+			started_at DESC, id DESC
+		LIMIT :limit;
+	`
+
+	DeleteJobRuns = `
+		DELETE FROM job_runs
+		WHERE started_at < :started_before;
+	`
+
+	GetStatusCounts = `
+		SELECT 
+			mapping_status,
+			publish_status,
+			COUNT(*) AS visit_count
+		FROM 
+			satusehat
+		GROUP BY 
+			mapping_status, publish_status;
+	`
+
+	GetLastProgressedJobRun = `
+		SELECT 
+			id,
+			job_name,
+			started_at,
+			finished_at,
+			scanned,
+			processed,
+			failed,
+			error
+		FROM 
+			job_runs
+		WHERE 
+			job_name = :job_name
+			AND finished_at IS NOT NULL
+			AND error IS NULL
+			AND (scanned = 0 OR processed > 0)
+		ORDER BY 
+			started_at DESC, id DESC
+		LIMIT 1;
+	`
+
+	InsertAuditEntry = `
+		INSERT INTO audit_log (occurred_at, method, url, status_code, latency_ms, request_hash, response_hash, visit_id, client_id, error)
+		VALUES (:occurred_at, :method, :url, :status_code, :latency_ms, :request_hash, :response_hash, :visit_id, :client_id, :error);
+	`
+
+	GetAuditEntries = `
+		SELECT 
+			id,
+			occurred_at,
+			method,
+			url,
+			status_code,
+			latency_ms,
+			request_hash,
+			response_hash,
+			visit_id,
+			client_id,
+			error
+		FROM 
+			audit_log
+		WHERE 
+			occurred_at >= :from AND occurred_at < :to
+		ORDER BY 
+			id;
+	`
+
+	GetEncryptedColumns = `
+		SELECT 
+			visit_id,
+			visit_detail,
+			publish_request
+		FROM 
+			satusehat
+		WHERE 
+			visit_id > :after
+		ORDER BY 
+			visit_id
+		LIMIT :limit;
+	`
+
+	UpdateEncryptedColumns = `
+		UPDATE satusehat
+		SET visit_detail = :visit_detail,
+			publish_request = :publish_request
+		WHERE visit_id = :visit_id
+			AND CAST(visit_detail AS BLOB) = CAST(:old_visit_detail AS BLOB)
+			AND CAST(publish_request AS BLOB) IS CAST(:old_publish_request AS BLOB);
+	`
 )
 
 type Repository struct {
-	db                       *sqlx.DB
-	insert                   *sqlx.NamedStmt
-	isExists                 *sqlx.NamedStmt
-	getByStatus              *sqlx.NamedStmt
-	updateDiagnosis          *sqlx.NamedStmt
-	updateLab                *sqlx.NamedStmt
-	updateRadiology          *sqlx.NamedStmt
-	updateMedicationRequest  *sqlx.NamedStmt
-	updateMedicationDispense *sqlx.NamedStmt
-	updateMedicalProcedure   *sqlx.NamedStmt
-	updatePublishStatus      *sqlx.NamedStmt
-	updateMappingStatus      *sqlx.NamedStmt
-	updateMappingErrors      *sqlx.NamedStmt
-	mu                       sync.Mutex // Mutex for thread-safety
+	db                          *sqlx.DB
+	insert                      *sqlx.NamedStmt
+	isExists                    *sqlx.NamedStmt
+	getByStatus                 *sqlx.NamedStmt
+	getByVisitId                *sqlx.NamedStmt
+	updateDiagnosis             *sqlx.NamedStmt
+	updateLab                   *sqlx.NamedStmt
+	updateRadiology             *sqlx.NamedStmt
+	updateMedicationRequest     *sqlx.NamedStmt
+	updateMedicationDispense    *sqlx.NamedStmt
+	updateMedicalProcedure      *sqlx.NamedStmt
+	updateAnamnesis             *sqlx.NamedStmt
+	updateAllergy               *sqlx.NamedStmt
+	updateImmunization          *sqlx.NamedStmt
+	updateClinicalNote          *sqlx.NamedStmt
+	updatePublishStatus         *sqlx.NamedStmt
+	updateMappingStatus         *sqlx.NamedStmt
+	updateMappingErrors         *sqlx.NamedStmt
+	updateMappingReport         *sqlx.NamedStmt
+	getBackfillChunks           *sqlx.NamedStmt
+	insertBackfillChunk         *sqlx.NamedStmt
+	getValidationIssues         *sqlx.NamedStmt
+	deleteValidationIssues      *sqlx.NamedStmt
+	insertValidationIssue       *sqlx.NamedStmt
+	getKfaMappings              *sqlx.NamedStmt
+	insertKfaMapping            *sqlx.NamedStmt
+	getUnmappedMedicines        *sqlx.NamedStmt
+	upsertUnmappedMedicine      *sqlx.NamedStmt
+	insertUnmappedVisit         *sqlx.NamedStmt
+	getConceptMapVersions       *sqlx.NamedStmt
+	getConceptMapEntries        *sqlx.NamedStmt
+	getActiveConceptMap         *sqlx.NamedStmt
+	insertConceptMapVersion     *sqlx.NamedStmt
+	insertConceptMapEntry       *sqlx.NamedStmt
+	activateConceptMapVersion   *sqlx.NamedStmt
+	isConceptMapVersionExists   *sqlx.NamedStmt
+	getUnmappedLabCodes         *sqlx.NamedStmt
+	upsertConceptMapMiss        *sqlx.NamedStmt
+	insertConceptMapMissVisit   *sqlx.NamedStmt
+	getObservationExclusions    *sqlx.NamedStmt
+	deleteObservationExclusions *sqlx.NamedStmt
+	insertObservationExclusion  *sqlx.NamedStmt
+	getPublishedAllergyKeys     *sqlx.NamedStmt
+	insertPublishedAllergy      *sqlx.NamedStmt
+	getVisits                   *sqlx.NamedStmt
+	updateStatus                *sqlx.NamedStmt
+	getDailyStatusCounts        *sqlx.NamedStmt
+	getBacklog                  *sqlx.NamedStmt
+	getTopValidationIssues      *sqlx.NamedStmt
+	getRecentPublishErrors      *sqlx.NamedStmt
+	getRecentBackfillChunks     *sqlx.NamedStmt
+	insertJobRun                *sqlx.NamedStmt
+	finishJobRun                *sqlx.NamedStmt
+	getJobRuns                  *sqlx.NamedStmt
+	deleteJobRuns               *sqlx.NamedStmt
+	getStatusCounts             *sqlx.NamedStmt
+	getLastProgressedJobRun     *sqlx.NamedStmt
+	insertAuditEntry            *sqlx.NamedStmt
+	getAuditEntries             *sqlx.NamedStmt
+	getEncryptedColumns         *sqlx.NamedStmt
+	updateEncryptedColumns      *sqlx.NamedStmt
+	keyring                     *encryption.Keyring // seals visit_detail and publish_request, nil stores them as plaintext
+	mu                          sync.Mutex          // Mutex for thread-safety
 }
 
 func newRepository(db *sqlx.DB) (*Repository, error) {
@@ -155,6 +883,11 @@ func newRepository(db *sqlx.DB) (*Repository, error) {
 		return nil, err
 	}
 
+	getByVisitIdStmt, err := db.PrepareNamed(GetByVisitId)
+	if err != nil {
+		return nil, err
+	}
+
 	updateDiagnosisStmt, err := db.PrepareNamed(UpdateDiagnosis)
 	if err != nil {
 		return nil, err
@@ -185,55 +918,338 @@ func newRepository(db *sqlx.DB) (*Repository, error) {
 		return nil, err
 	}
 
-	updatePublishStatusStmt, err := db.PrepareNamed(UpdatePublishStatus)
+	updateAnamnesisStmt, err := db.PrepareNamed(UpdateAnamnesis)
 	if err != nil {
 		return nil, err
 	}
 
-	updateMappingStatusStmt, err := db.PrepareNamed(UpdateMappingStatus)
+	updateAllergyStmt, err := db.PrepareNamed(UpdateAllergy)
 	if err != nil {
 		return nil, err
 	}
 
-	updateMappingErrorsStmt, err := db.PrepareNamed(UpdateMappingErrors)
+	updateImmunizationStmt, err := db.PrepareNamed(UpdateImmunization)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Repository{
-		db:                       db,
-		insert:                   insertNewStmt,
-		isExists:                 isExists,
-		getByStatus:              getByStatusStmt,
-		updateDiagnosis:          updateDiagnosisStmt,
-		updateLab:                updateLabStmt,
-		updateRadiology:          updateRadiologyStmt,
-		updateMedicationRequest:  updateMedicationRequestStmt,
-		updateMedicationDispense: updateMedicationDispenseStmt,
-		updateMedicalProcedure:   updateMedicalProcedureStmt,
-		updatePublishStatus:      updatePublishStatusStmt,
-		updateMappingStatus:      updateMappingStatusStmt,
-		updateMappingErrors:      updateMappingErrorsStmt,
-		mu:                       sync.Mutex{},
-	}, nil
-}
-
-func (r *Repository) IsExists(ctx context.Context, visitId string) (bool, error) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
+	updateClinicalNoteStmt, err := db.PrepareNamed(UpdateClinicalNote)
+	if err != nil {
+		return nil, err
+	}
 
-	var count int
+	updatePublishStatusStmt, err := db.PrepareNamed(UpdatePublishStatus)
+	if err != nil {
+		return nil, err
+	}
 
-	err := r.isExists.GetContext(ctx, &count, map[string]any{
-		"visit_id": visitId,
-	})
+	updateMappingStatusStmt, err := db.PrepareNamed(UpdateMappingStatus)
+	if err != nil {
+		return nil, err
+	}
 
+	updateMappingErrorsStmt, err := db.PrepareNamed(UpdateMappingErrors)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 
-	return count > 0, nil
-}
+	updateMappingReportStmt, err := db.PrepareNamed(UpdateMappingReport)
+	if err != nil {
+		return nil, err
+	}
+
+	getBackfillChunksStmt, err := db.PrepareNamed(GetBackfillChunks)
+	if err != nil {
+		return nil, err
+	}
+
+	insertBackfillChunkStmt, err := db.PrepareNamed(InsertBackfillChunk)
+	if err != nil {
+		return nil, err
+	}
+
+	getValidationIssuesStmt, err := db.PrepareNamed(GetValidationIssues)
+	if err != nil {
+		return nil, err
+	}
+
+	deleteValidationIssuesStmt, err := db.PrepareNamed(DeleteValidationIssues)
+	if err != nil {
+		return nil, err
+	}
+
+	insertValidationIssueStmt, err := db.PrepareNamed(InsertValidationIssue)
+	if err != nil {
+		return nil, err
+	}
+
+	getKfaMappingsStmt, err := db.PrepareNamed(GetKfaMappings)
+	if err != nil {
+		return nil, err
+	}
+
+	insertKfaMappingStmt, err := db.PrepareNamed(InsertKfaMapping)
+	if err != nil {
+		return nil, err
+	}
+
+	getUnmappedMedicinesStmt, err := db.PrepareNamed(GetUnmappedMedicines)
+	if err != nil {
+		return nil, err
+	}
+
+	upsertUnmappedMedicineStmt, err := db.PrepareNamed(UpsertUnmappedMedicine)
+	if err != nil {
+		return nil, err
+	}
+
+	insertUnmappedVisitStmt, err := db.PrepareNamed(InsertUnmappedMedicineVisit)
+	if err != nil {
+		return nil, err
+	}
+
+	getConceptMapVersionsStmt, err := db.PrepareNamed(GetConceptMapVersions)
+	if err != nil {
+		return nil, err
+	}
+
+	getConceptMapEntriesStmt, err := db.PrepareNamed(GetConceptMapEntries)
+	if err != nil {
+		return nil, err
+	}
+
+	getActiveConceptMapStmt, err := db.PrepareNamed(GetActiveConceptMap)
+	if err != nil {
+		return nil, err
+	}
+
+	insertConceptMapVersionStmt, err := db.PrepareNamed(InsertConceptMapVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	insertConceptMapEntryStmt, err := db.PrepareNamed(InsertConceptMapEntry)
+	if err != nil {
+		return nil, err
+	}
+
+	activateConceptMapVersionStmt, err := db.PrepareNamed(ActivateConceptMapVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	isConceptMapVersionExistsStmt, err := db.PrepareNamed(IsConceptMapVersionExists)
+	if err != nil {
+		return nil, err
+	}
+
+	getUnmappedLabCodesStmt, err := db.PrepareNamed(GetUnmappedLabCodes)
+	if err != nil {
+		return nil, err
+	}
+
+	upsertConceptMapMissStmt, err := db.PrepareNamed(UpsertConceptMapMiss)
+	if err != nil {
+		return nil, err
+	}
+
+	insertConceptMapMissVisitStmt, err := db.PrepareNamed(InsertConceptMapMissVisit)
+	if err != nil {
+		return nil, err
+	}
+
+	getObservationExclusionsStmt, err := db.PrepareNamed(GetObservationExclusions)
+	if err != nil {
+		return nil, err
+	}
+
+	deleteObservationExclusionsStmt, err := db.PrepareNamed(DeleteObservationExclusions)
+	if err != nil {
+		return nil, err
+	}
+
+	insertObservationExclusionStmt, err := db.PrepareNamed(InsertObservationExclusion)
+	if err != nil {
+		return nil, err
+	}
+
+	getPublishedAllergyKeysStmt, err := db.PrepareNamed(GetPublishedAllergyKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	insertPublishedAllergyStmt, err := db.PrepareNamed(InsertPublishedAllergy)
+	if err != nil {
+		return nil, err
+	}
+
+	getVisitsStmt, err := db.PrepareNamed(GetVisits)
+	if err != nil {
+		return nil, err
+	}
+
+	updateStatusStmt, err := db.PrepareNamed(UpdateStatus)
+	if err != nil {
+		return nil, err
+	}
+
+	getDailyStatusCountsStmt, err := db.PrepareNamed(GetDailyStatusCounts)
+	if err != nil {
+		return nil, err
+	}
+
+	getBacklogStmt, err := db.PrepareNamed(GetBacklog)
+	if err != nil {
+		return nil, err
+	}
+
+	getTopValidationIssuesStmt, err := db.PrepareNamed(GetTopValidationIssues)
+	if err != nil {
+		return nil, err
+	}
+
+	getRecentPublishErrorsStmt, err := db.PrepareNamed(GetRecentPublishErrors)
+	if err != nil {
+		return nil, err
+	}
+
+	getRecentBackfillChunksStmt, err := db.PrepareNamed(GetRecentBackfillChunks)
+	if err != nil {
+		return nil, err
+	}
+
+	insertJobRunStmt, err := db.PrepareNamed(InsertJobRun)
+	if err != nil {
+		return nil, err
+	}
+
+	finishJobRunStmt, err := db.PrepareNamed(FinishJobRun)
+	if err != nil {
+		return nil, err
+	}
+
+	getJobRunsStmt, err := db.PrepareNamed(GetJobRuns)
+	if err != nil {
+		return nil, err
+	}
+
+	deleteJobRunsStmt, err := db.PrepareNamed(DeleteJobRuns)
+	if err != nil {
+		return nil, err
+	}
+
+	getStatusCountsStmt, err := db.PrepareNamed(GetStatusCounts)
+	if err != nil {
+		return nil, err
+	}
+
+	getLastProgressedJobRunStmt, err := db.PrepareNamed(GetLastProgressedJobRun)
+	if err != nil {
+		return nil, err
+	}
+
+	insertAuditEntryStmt, err := db.PrepareNamed(InsertAuditEntry)
+	if err != nil {
+		return nil, err
+	}
+
+	getAuditEntriesStmt, err := db.PrepareNamed(GetAuditEntries)
+	if err != nil {
+		return nil, err
+	}
+
+	getEncryptedColumnsStmt, err := db.PrepareNamed(GetEncryptedColumns)
+	if err != nil {
+		return nil, err
+	}
+
+	updateEncryptedColumnsStmt, err := db.PrepareNamed(UpdateEncryptedColumns)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Repository{
+		db:                          db,
+		insert:                      insertNewStmt,
+		isExists:                    isExists,
+		getByStatus:                 getByStatusStmt,
+		getByVisitId:                getByVisitIdStmt,
+		updateDiagnosis:             updateDiagnosisStmt,
+		updateLab:                   updateLabStmt,
+		updateRadiology:             updateRadiologyStmt,
+		updateMedicationRequest:     updateMedicationRequestStmt,
+		updateMedicationDispense:    updateMedicationDispenseStmt,
+		updateMedicalProcedure:      updateMedicalProcedureStmt,
+		updateAnamnesis:             updateAnamnesisStmt,
+		updateAllergy:               updateAllergyStmt,
+		updateImmunization:          updateImmunizationStmt,
+		updateClinicalNote:          updateClinicalNoteStmt,
+		updatePublishStatus:         updatePublishStatusStmt,
+		updateMappingStatus:         updateMappingStatusStmt,
+		updateMappingErrors:         updateMappingErrorsStmt,
+		updateMappingReport:         updateMappingReportStmt,
+		getBackfillChunks:           getBackfillChunksStmt,
+		insertBackfillChunk:         insertBackfillChunkStmt,
+		getValidationIssues:         getValidationIssuesStmt,
+		deleteValidationIssues:      deleteValidationIssuesStmt,
+		insertValidationIssue:       insertValidationIssueStmt,
+		getKfaMappings:              getKfaMappingsStmt,
+		insertKfaMapping:            insertKfaMappingStmt,
+		getUnmappedMedicines:        getUnmappedMedicinesStmt,
+		upsertUnmappedMedicine:      upsertUnmappedMedicineStmt,
+		insertUnmappedVisit:         insertUnmappedVisitStmt,
+		getConceptMapVersions:       getConceptMapVersionsStmt,
+		getConceptMapEntries:        getConceptMapEntriesStmt,
+		getActiveConceptMap:         getActiveConceptMapStmt,
+		insertConceptMapVersion:     insertConceptMapVersionStmt,
+		insertConceptMapEntry:       insertConceptMapEntryStmt,
+		activateConceptMapVersion:   activateConceptMapVersionStmt,
+		isConceptMapVersionExists:   isConceptMapVersionExistsStmt,
+		getUnmappedLabCodes:         getUnmappedLabCodesStmt,
+		upsertConceptMapMiss:        upsertConceptMapMissStmt,
+		insertConceptMapMissVisit:   insertConceptMapMissVisitStmt,
+		getObservationExclusions:    getObservationExclusionsStmt,
+		deleteObservationExclusions: deleteObservationExclusionsStmt,
+		insertObservationExclusion:  insertObservationExclusionStmt,
+		getPublishedAllergyKeys:     getPublishedAllergyKeysStmt,
+		insertPublishedAllergy:      insertPublishedAllergyStmt,
+		getVisits:                   getVisitsStmt,
+		updateStatus:                updateStatusStmt,
+		getDailyStatusCounts:        getDailyStatusCountsStmt,
+		getBacklog:                  getBacklogStmt,
+		getTopValidationIssues:      getTopValidationIssuesStmt,
+		getRecentPublishErrors:      getRecentPublishErrorsStmt,
+		getRecentBackfillChunks:     getRecentBackfillChunksStmt,
+		insertJobRun:                insertJobRunStmt,
+		finishJobRun:                finishJobRunStmt,
+		getJobRuns:                  getJobRunsStmt,
+		deleteJobRuns:               deleteJobRunsStmt,
+		getStatusCounts:             getStatusCountsStmt,
+		getLastProgressedJobRun:     getLastProgressedJobRunStmt,
+		insertAuditEntry:            insertAuditEntryStmt,
+		getAuditEntries:             getAuditEntriesStmt,
+		getEncryptedColumns:         getEncryptedColumnsStmt,
+		updateEncryptedColumns:      updateEncryptedColumnsStmt,
+		mu:                          sync.Mutex{},
+	}, nil
+}
+
+func (r *Repository) IsExists(ctx context.Context, visitId string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var count int
+
+	err := r.isExists.GetContext(ctx, &count, map[string]any{
+		"visit_id": visitId,
+	})
+
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
 
 func (r *Repository) ReadyToPublish(ctx context.Context) ([]entity.SatuSehatInternal, error) {
 	r.mu.Lock()
@@ -250,7 +1266,7 @@ func (r *Repository) ReadyToPublish(ctx context.Context) ([]entity.SatuSehatInte
 		return nil, err
 	}
 
-	return results, nil
+	return r.decryptAll(ctx, results), nil
 }
 
 func (r *Repository) Incomplete(ctx context.Context) ([]entity.SatuSehatInternal, error) {
@@ -268,9 +1284,54 @@ func (r *Repository) Incomplete(ctx context.Context) ([]entity.SatuSehatInternal
 		return nil, err
 	}
 
+	return r.decryptAll(ctx, results), nil
+}
+
+// Visits returns the visits matching filter, most recent visit first.
+func (r *Repository) Visits(ctx context.Context, filter entity.VisitFilter) ([]entity.VisitSummary, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var results []entity.VisitSummary
+	err := r.getVisits.SelectContext(ctx, &results, map[string]any{
+		"mapping_status":  filter.MappingStatus,
+		"publish_status":  filter.PublishStatus,
+		"visit_date_from": filter.VisitDateFrom,
+		"visit_date_to":   filter.VisitDateTo,
+		"limit":           filter.Limit,
+		"offset":          filter.Offset,
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	return results, nil
 }
 
+// GetByVisitId returns nil when the visit is not stored in the internal database.
+func (r *Repository) GetByVisitId(ctx context.Context, visitId string) (*entity.SatuSehatInternal, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var result entity.SatuSehatInternal
+
+	err := r.getByVisitId.GetContext(ctx, &result, map[string]any{
+		"visit_id": visitId,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.decrypt(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
 func (r *Repository) InsertValid(
 	ctx context.Context,
 	visitId string,
@@ -282,11 +1343,16 @@ func (r *Repository) InsertValid(
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	visitDetailValue, err := r.encrypt("visit_detail", visitId, util.MarshalToJson(visitDetail))
+	if err != nil {
+		return nil, err
+	}
+
 	return r.insert.ExecContext(ctx, map[string]any{
 		"visit_id":             visitId,
 		"visit_date":           visitDate,
 		"satusehat_patient_id": satusehatPatientId,
-		"visit_detail":         util.MarshalToJson(visitDetail),
+		"visit_detail":         visitDetailValue,
 		"vital_sign":           util.MarshalToJson(visitSign),
 		"publish_status":       entity.Preparing,
 		"mapping_status":       entity.Incomplete,
@@ -306,11 +1372,16 @@ func (r *Repository) InsertInvalid(
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	visitDetailValue, err := r.encrypt("visit_detail", visitId, util.MarshalToJson(visitDetail))
+	if err != nil {
+		return nil, err
+	}
+
 	return r.insert.ExecContext(ctx, map[string]any{
 		"visit_id":             visitId,
 		"visit_date":           visitDate,
 		"satusehat_patient_id": satusehatPatientId,
-		"visit_detail":         util.MarshalToJson(visitDetail),
+		"visit_detail":         visitDetailValue,
 		"vital_sign":           util.MarshalToJson(visitSign),
 		"mapping_errors":       mappingErrors,
 		"publish_status":       entity.Preparing,
@@ -378,16 +1449,61 @@ func (r *Repository) UpdateMedicalProcedure(ctx context.Context, visitId string,
 	})
 }
 
+func (r *Repository) UpdateAnamnesis(ctx context.Context, visitId string, anamnesis []shared.Anamnesis) (sql.Result, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.updateAnamnesis.ExecContext(ctx, map[string]any{
+		"visit_id":  visitId,
+		"anamnesis": util.MarshalToJson(anamnesis),
+	})
+}
+
+func (r *Repository) UpdateAllergy(ctx context.Context, visitId string, allergy []shared.Allergy) (sql.Result, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.updateAllergy.ExecContext(ctx, map[string]any{
+		"visit_id": visitId,
+		"allergy":  util.MarshalToJson(allergy),
+	})
+}
+
+func (r *Repository) UpdateImmunization(ctx context.Context, visitId string, immunization []shared.Immunization) (sql.Result, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.updateImmunization.ExecContext(ctx, map[string]any{
+		"visit_id":     visitId,
+		"immunization": util.MarshalToJson(immunization),
+	})
+}
+
+func (r *Repository) UpdateClinicalNote(ctx context.Context, visitId string, clinicalNote []shared.ClinicalNote) (sql.Result, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.updateClinicalNote.ExecContext(ctx, map[string]any{
+		"visit_id":      visitId,
+		"clinical_note": util.MarshalToJson(clinicalNote),
+	})
+}
+
 func (r *Repository) UpdatePublishStatus(ctx context.Context, visitId string, publishRequest string, publishResponse string, publishDate time.Time, status entity.PublishStatus) (sql.Result, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	publishRequestValue, err := r.encrypt("publish_request", visitId, publishRequest)
+	if err != nil {
+		return nil, err
+	}
+
 	return r.updatePublishStatus.ExecContext(ctx, map[string]any{
 		"visit_id":         visitId,
 		"publish_response": publishResponse,
 		"publish_date":     publishDate,
 		"publish_status":   status,
-		"publish_request":  publishRequest,
+		"publish_request":  publishRequestValue,
 	})
 }
 
@@ -401,7 +1517,20 @@ func (r *Repository) UpdateMappingStatus(ctx context.Context, visitId string, ma
 	})
 
 }
-func (r *Repository) UpdateMappingErrors(ctx context.Context, visitId string, mappingErrors string) (sql.Result, error) {
+
+// UpdateStatus sets both statuses of a visit, e.g. to queue it for publishing again.
+func (r *Repository) UpdateStatus(ctx context.Context, visitId string, mappingStatus entity.MappingStatus, publishStatus entity.PublishStatus) (sql.Result, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.updateStatus.ExecContext(ctx, map[string]any{
+		"visit_id":       visitId,
+		"mapping_status": mappingStatus,
+		"publish_status": publishStatus,
+	})
+}
+
+func (r *Repository) UpdateMappingErrors(ctx context.Context, visitId string, mappingErrors string) (sql.Result, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
@@ -411,3 +1540,857 @@ func (r *Repository) UpdateMappingErrors(ctx context.Context, visitId string, ma
 	})
 
 }
+
+func (r *Repository) UpdateMappingReport(ctx context.Context, visitId string, report entity.MappingReport, mappingErrors string) (sql.Result, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.updateMappingReport.ExecContext(ctx, map[string]any{
+		"visit_id":       visitId,
+		"mapping_report": util.MarshalToJson(report),
+		"mapping_errors": mappingErrors,
+	})
+}
+
+func (r *Repository) BackfillChunks(ctx context.Context, rangeKey string) ([]entity.BackfillChunk, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var results []entity.BackfillChunk
+
+	err := r.getBackfillChunks.SelectContext(ctx, &results, map[string]any{
+		"range_key": rangeKey,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func (r *Repository) SaveBackfillChunk(ctx context.Context, chunk entity.BackfillChunk) (sql.Result, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.insertBackfillChunk.ExecContext(ctx, chunk)
+}
+
+func (r *Repository) ValidationIssues(ctx context.Context, visitId string) ([]shared.ValidationIssue, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var results []shared.ValidationIssue
+
+	err := r.getValidationIssues.SelectContext(ctx, &results, map[string]any{
+		"visit_id": visitId,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// ReplaceValidationIssues stores the latest issues of a visit, dropping the ones of the previous validation.
+func (r *Repository) ReplaceValidationIssues(ctx context.Context, visitId string, issues []shared.ValidationIssue) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.NamedStmtContext(ctx, r.deleteValidationIssues).ExecContext(ctx, map[string]any{
+		"visit_id": visitId,
+	})
+	if err != nil {
+		return err
+	}
+
+	createdAt := time.Now()
+	insert := tx.NamedStmtContext(ctx, r.insertValidationIssue)
+	for _, issue := range issues {
+		_, err = insert.ExecContext(ctx, map[string]any{
+			"visit_id":   visitId,
+			"resource":   issue.Resource,
+			"record_id":  issue.RecordId,
+			"field":      issue.Field,
+			"rule":       issue.Rule,
+			"param":      issue.Param,
+			"value":      issue.Value,
+			"message_id": issue.MessageId,
+			"message_en": issue.MessageEn,
+			"created_at": createdAt,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *Repository) KfaMappings(ctx context.Context) ([]entity.KfaMapping, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var results []entity.KfaMapping
+
+	err := r.getKfaMappings.SelectContext(ctx, &results, map[string]any{})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// SaveKfaMappings inserts or replaces the mappings in a single transaction, an invalid row leaves the table untouched.
+func (r *Repository) SaveKfaMappings(ctx context.Context, mappings []entity.KfaMapping) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	insert := tx.NamedStmtContext(ctx, r.insertKfaMapping)
+	for _, mapping := range mappings {
+		if _, err := insert.ExecContext(ctx, mapping); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *Repository) UnmappedMedicines(ctx context.Context) ([]entity.UnmappedMedicine, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var results []entity.UnmappedMedicine
+
+	err := r.getUnmappedMedicines.SelectContext(ctx, &results, map[string]any{})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// RecordUnmappedMedicine remembers a medicine prescribed in a visit without KFA code, visits are counted once.
+func (r *Repository) RecordUnmappedMedicine(ctx context.Context, medicineCode string, visitId string, medicineName string, seenAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.NamedStmtContext(ctx, r.upsertUnmappedMedicine).ExecContext(ctx, map[string]any{
+		"medicine_code": medicineCode,
+		"medicine_name": medicineName,
+		"seen_at":       seenAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.NamedStmtContext(ctx, r.insertUnmappedVisit).ExecContext(ctx, map[string]any{
+		"medicine_code": medicineCode,
+		"visit_id":      visitId,
+	})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *Repository) ConceptMapVersions(ctx context.Context) ([]entity.ConceptMapVersion, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var results []entity.ConceptMapVersion
+
+	err := r.getConceptMapVersions.SelectContext(ctx, &results, map[string]any{})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func (r *Repository) ConceptMapEntries(ctx context.Context, version int64) ([]entity.ConceptMapEntry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var results []entity.ConceptMapEntry
+
+	err := r.getConceptMapEntries.SelectContext(ctx, &results, map[string]any{
+		"version": version,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// ActiveConceptMap returns the entries of the active version, none when no version was activated yet.
+func (r *Repository) ActiveConceptMap(ctx context.Context) ([]entity.ConceptMapEntry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var results []entity.ConceptMapEntry
+
+	err := r.getActiveConceptMap.SelectContext(ctx, &results, map[string]any{})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// CreateConceptMapVersion stores the entries as a new version and activates it, the previous versions are kept for rollback.
+func (r *Repository) CreateConceptMapVersion(ctx context.Context, description string, createdBy string, entries []entity.ConceptMapEntry) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	result, err := tx.NamedStmtContext(ctx, r.insertConceptMapVersion).ExecContext(ctx, map[string]any{
+		"description": description,
+		"created_by":  createdBy,
+		"created_at":  time.Now(),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	version, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	insert := tx.NamedStmtContext(ctx, r.insertConceptMapEntry)
+	for _, entry := range entries {
+		entry.Version = version
+		if _, err := insert.ExecContext(ctx, entry); err != nil {
+			return 0, err
+		}
+	}
+
+	_, err = tx.NamedStmtContext(ctx, r.activateConceptMapVersion).ExecContext(ctx, map[string]any{
+		"version": version,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return version, tx.Commit()
+}
+
+// ActivateConceptMapVersion makes version the only active one, it returns sql.ErrNoRows for an unknown version.
+func (r *Repository) ActivateConceptMapVersion(ctx context.Context, version int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var exists int
+	err = tx.NamedStmtContext(ctx, r.isConceptMapVersionExists).GetContext(ctx, &exists, map[string]any{
+		"version": version,
+	})
+	if err != nil {
+		return err
+	}
+	if exists == 0 {
+		return sql.ErrNoRows
+	}
+
+	_, err = tx.NamedStmtContext(ctx, r.activateConceptMapVersion).ExecContext(ctx, map[string]any{
+		"version": version,
+	})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *Repository) UnmappedLabCodes(ctx context.Context) ([]entity.UnmappedLabCode, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var results []entity.UnmappedLabCode
+
+	err := r.getUnmappedLabCodes.SelectContext(ctx, &results, map[string]any{})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// RecordConceptMapMiss remembers a lab parameter of a visit the active concept map has no entry for, visits are counted once.
+func (r *Repository) RecordConceptMapMiss(ctx context.Context, localCode string, parameter string, labName string, visitId string, seenAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.NamedStmtContext(ctx, r.upsertConceptMapMiss).ExecContext(ctx, map[string]any{
+		"local_code": localCode,
+		"parameter":  parameter,
+		"lab_name":   labName,
+		"seen_at":    seenAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.NamedStmtContext(ctx, r.insertConceptMapMissVisit).ExecContext(ctx, map[string]any{
+		"local_code": localCode,
+		"parameter":  parameter,
+		"visit_id":   visitId,
+	})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *Repository) ObservationExclusions(ctx context.Context, visitId string) ([]entity.ObservationExclusion, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var results []entity.ObservationExclusion
+
+	err := r.getObservationExclusions.SelectContext(ctx, &results, map[string]any{
+		"visit_id": visitId,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// ReplaceObservationExclusions stores the exclusions of the latest bundle of a visit, dropping the ones of the previous bundle.
+func (r *Repository) ReplaceObservationExclusions(ctx context.Context, visitId string, exclusions []entity.ObservationExclusion) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.NamedStmtContext(ctx, r.deleteObservationExclusions).ExecContext(ctx, map[string]any{
+		"visit_id": visitId,
+	})
+	if err != nil {
+		return err
+	}
+
+	insert := tx.NamedStmtContext(ctx, r.insertObservationExclusion)
+	for _, exclusion := range exclusions {
+		exclusion.VisitId = visitId
+		if _, err := insert.ExecContext(ctx, exclusion); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// PublishedAllergyKeys returns the keys of the allergies already sent to SatuSehat for a patient.
+func (r *Repository) PublishedAllergyKeys(ctx context.Context, patientSatuSehatId string) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var results []string
+
+	err := r.getPublishedAllergyKeys.SelectContext(ctx, &results, map[string]any{
+		"patient_satusehat_id": patientSatuSehatId,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// InsertPublishedAllergies records the allergies sent to SatuSehat in the bundle of a visit, keys already recorded are kept.
+func (r *Repository) InsertPublishedAllergies(ctx context.Context, patientSatuSehatId string, visitId string, keys []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	insert := tx.NamedStmtContext(ctx, r.insertPublishedAllergy)
+	publishedAt := time.Now()
+	for _, key := range keys {
+		_, err := insert.ExecContext(ctx, map[string]any{
+			"patient_satusehat_id": patientSatuSehatId,
+			"allergy_key":          key,
+			"visit_id":             visitId,
+			"published_at":         publishedAt,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// DailyStatusCounts counts the visits since from by day, mapping and publish status.
+func (r *Repository) DailyStatusCounts(ctx context.Context, from time.Time) ([]entity.DailyStatusCount, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var results []entity.DailyStatusCount
+
+	err := r.getDailyStatusCounts.SelectContext(ctx, &results, map[string]any{
+		"visit_date_from": from,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// Backlog counts the visits not published yet, leaving out invalid and skipped ones.
+func (r *Repository) Backlog(ctx context.Context) ([]entity.Backlog, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var results []entity.Backlog
+
+	err := r.getBacklog.SelectContext(ctx, &results, map[string]any{
+		"ready":      entity.Ready,
+		"incomplete": entity.Incomplete,
+		"success":    entity.Success,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// TopValidationIssues returns the most frequent validation issues over all visits.
+func (r *Repository) TopValidationIssues(ctx context.Context, limit int) ([]entity.ValidationIssueCount, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var results []entity.ValidationIssueCount
+
+	err := r.getTopValidationIssues.SelectContext(ctx, &results, map[string]any{
+		"limit": limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// RecentPublishErrors returns the latest visits SatuSehat didn't accept, most recent attempt first.
+func (r *Repository) RecentPublishErrors(ctx context.Context, limit int) ([]entity.PublishError, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var results []entity.PublishError
+
+	err := r.getRecentPublishErrors.SelectContext(ctx, &results, map[string]any{
+		"request_error":   entity.RequestError,
+		"payload_invalid": entity.PayloadInvalid,
+		"limit":           limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// RecentBackfillChunks returns the latest finished backfill chunks of every range.
+func (r *Repository) RecentBackfillChunks(ctx context.Context, limit int) ([]entity.BackfillChunk, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var results []entity.BackfillChunk
+
+	err := r.getRecentBackfillChunks.SelectContext(ctx, &results, map[string]any{
+		"limit": limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// StartJobRun records a job starting at startedAt and returns the id of the run to finish.
+func (r *Repository) StartJobRun(ctx context.Context, jobName string, startedAt time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var id int64
+	err := r.insertJobRun.GetContext(ctx, &id, map[string]any{
+		"job_name":   jobName,
+		"started_at": startedAt,
+	})
+	return id, err
+}
+
+// FinishJobRun stores the outcome of a run started by StartJobRun.
+func (r *Repository) FinishJobRun(ctx context.Context, run entity.JobRun) (sql.Result, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.finishJobRun.ExecContext(ctx, run)
+}
+
+// JobRuns returns the latest runs of jobName, or of every job when it is empty.
+func (r *Repository) JobRuns(ctx context.Context, jobName string, limit int) ([]entity.JobRun, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var results []entity.JobRun
+
+	err := r.getJobRuns.SelectContext(ctx, &results, map[string]any{
+		"job_name": jobName,
+		"limit":    limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// DeleteJobRuns removes the runs started before startedBefore.
+func (r *Repository) DeleteJobRuns(ctx context.Context, startedBefore time.Time) (sql.Result, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.deleteJobRuns.ExecContext(ctx, map[string]any{
+		"started_before": startedBefore,
+	})
+}
+
+// StatusCounts counts every visit by mapping and publish status.
+func (r *Repository) StatusCounts(ctx context.Context) ([]entity.StatusCount, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var results []entity.StatusCount
+
+	err := r.getStatusCounts.SelectContext(ctx, &results, map[string]any{})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// LastProgressedJobRun returns the latest run of jobName that finished without error and either found nothing to do
+// or processed at least one item, nil when there is none.
+func (r *Repository) LastProgressedJobRun(ctx context.Context, jobName string) (*entity.JobRun, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var result entity.JobRun
+
+	err := r.getLastProgressedJobRun.GetContext(ctx, &result, map[string]any{
+		"job_name": jobName,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// Ping checks the internal database is still reachable.
+func (r *Repository) Ping(ctx context.Context) error {
+	return r.db.PingContext(ctx)
+}
+
+// InsertAuditEntry appends entry to the audit log, entries can never be updated nor deleted.
+func (r *Repository) InsertAuditEntry(ctx context.Context, entry entity.AuditEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, err := r.insertAuditEntry.ExecContext(ctx, entry)
+	return err
+}
+
+// AuditEntries returns the audit log entries that occurred in [from, to), oldest first.
+func (r *Repository) AuditEntries(ctx context.Context, from time.Time, to time.Time) ([]entity.AuditEntry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var results []entity.AuditEntry
+
+	err := r.getAuditEntries.SelectContext(ctx, &results, map[string]any{
+		"from": from.UTC(),
+		"to":   to.UTC(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// SetKeyring enables the encryption of visit_detail and publish_request, rows written before stay readable.
+func (r *Repository) SetKeyring(keyring *encryption.Keyring) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.keyring = keyring
+}
+
+// Close closes the database, the repository can't be used afterwards.
+func (r *Repository) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.db.Close()
+}
+
+// encrypt seals value, a string or *json.RawMessage, for column of visitId when a keyring is set. Callers hold r.mu.
+func (r *Repository) encrypt(column string, visitId string, value any) (any, error) {
+	if r.keyring == nil {
+		return value, nil
+	}
+
+	switch v := value.(type) {
+	case string:
+		return r.keyring.Seal([]byte(v), associatedData(column, visitId))
+	case *json.RawMessage:
+		if v == nil {
+			return nil, nil
+		}
+		// Stored as a BLOB like the plaintext JSON, scanning a TEXT value into json.RawMessage fails.
+		sealed, err := r.keyring.Seal(*v, associatedData(column, visitId))
+		return []byte(sealed), err
+	default:
+		return nil, fmt.Errorf("cannot encrypt %s of type %T", column, value)
+	}
+}
+
+// decryptAll opens the sealed columns of every visit, a visit that can't be opened is logged and left out so the
+// others are still processed. Callers hold r.mu.
+func (r *Repository) decryptAll(ctx context.Context, results []entity.SatuSehatInternal) []entity.SatuSehatInternal {
+	opened := results[:0]
+	for _, internal := range results {
+		if err := r.decrypt(&internal); err != nil {
+			log.Error().Ctx(ctx).Err(err).Str("VisitId", internal.VisitID).Msg("Failed to decrypt visit, skipping it.")
+			continue
+		}
+		opened = append(opened, internal)
+	}
+	return opened
+}
+
+// decrypt opens the sealed columns of internal in place. Callers hold r.mu.
+func (r *Repository) decrypt(internal *entity.SatuSehatInternal) error {
+	visitDetail, err := r.open("visit_detail", internal.VisitID, internal.VisitDetailJson)
+	if err != nil {
+		return err
+	}
+	internal.VisitDetailJson = visitDetail
+
+	if internal.PublishRequest != nil {
+		publishRequest, err := r.open("publish_request", internal.VisitID, []byte(*internal.PublishRequest))
+		if err != nil {
+			return err
+		}
+		request := string(publishRequest)
+		internal.PublishRequest = &request
+	}
+
+	return nil
+}
+
+func (r *Repository) open(column string, visitId string, value []byte) ([]byte, error) {
+	if !encryption.Sealed(value) {
+		return value, nil
+	}
+	if r.keyring == nil {
+		return nil, fmt.Errorf("%s of visit %s is encrypted but no encryption key is configured", column, visitId)
+	}
+
+	plaintext, err := r.keyring.Open(value, associatedData(column, visitId))
+	if err != nil {
+		return nil, fmt.Errorf("%s of visit %s: %w", column, visitId, err)
+	}
+	return plaintext, nil
+}
+
+func associatedData(column string, visitId string) []byte {
+	return []byte(column + "/" + visitId)
+}
+
+type encryptedColumns struct {
+	VisitID        string  `db:"visit_id"`
+	VisitDetail    string  `db:"visit_detail"`
+	PublishRequest *string `db:"publish_request"`
+}
+
+// RotateKey re-encrypts, batchSize visits at a time, the visit_detail and publish_request not sealed with the current
+// key of the keyring: plaintext rows and rows sealed with a previous key. It returns the number of visits scanned and
+// rewritten, a failed run can be repeated.
+func (r *Repository) RotateKey(ctx context.Context, batchSize int) (scanned int, rotated int, err error) {
+	if batchSize <= 0 {
+		return 0, 0, fmt.Errorf("batch size must be positive")
+	}
+
+	after := ""
+	for {
+		count, batchRotated, last, err := r.rotateBatch(ctx, after, batchSize)
+		scanned += count
+		rotated += batchRotated
+		if err != nil || count < batchSize {
+			return scanned, rotated, err
+		}
+		after = last
+	}
+}
+
+func (r *Repository) rotateBatch(ctx context.Context, after string, limit int) (count int, rotated int, last string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.keyring == nil {
+		return 0, 0, "", fmt.Errorf("no encryption key is configured")
+	}
+
+	var rows []encryptedColumns
+	err = r.getEncryptedColumns.SelectContext(ctx, &rows, map[string]any{
+		"after": after,
+		"limit": limit,
+	})
+	if err != nil || len(rows) == 0 {
+		return 0, 0, after, err
+	}
+
+	rotated, err = r.rotateRows(ctx, rows)
+	if err != nil {
+		return 0, 0, after, err
+	}
+
+	return len(rows), rotated, rows[len(rows)-1].VisitID, nil
+}
+
+// rotateRows reseals rows in a single transaction. A row is only updated when it still holds the values read, the worker
+// may have published the visit in between: it is then left as the worker wrote it, for the next rotation to pick up if
+// needed. Callers hold r.mu.
+func (r *Repository) rotateRows(ctx context.Context, rows []encryptedColumns) (rotated int, err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	update := tx.NamedStmtContext(ctx, r.updateEncryptedColumns)
+	for _, row := range rows {
+		if r.keyring.Current([]byte(row.VisitDetail)) &&
+			(row.PublishRequest == nil || r.keyring.Current([]byte(*row.PublishRequest))) {
+			continue
+		}
+
+		visitDetail, err := r.reseal("visit_detail", row.VisitID, row.VisitDetail)
+		if err != nil {
+			return 0, err
+		}
+
+		var publishRequest *string
+		if row.PublishRequest != nil {
+			request, err := r.reseal("publish_request", row.VisitID, *row.PublishRequest)
+			if err != nil {
+				return 0, err
+			}
+			publishRequest = &request
+		}
+
+		result, err := update.ExecContext(ctx, map[string]any{
+			"visit_id":            row.VisitID,
+			"visit_detail":        []byte(visitDetail),
+			"publish_request":     publishRequest,
+			"old_visit_detail":    row.VisitDetail,
+			"old_publish_request": row.PublishRequest,
+		})
+		if err != nil {
+			return 0, err
+		}
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		if affected > 0 {
+			rotated++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return rotated, nil
+}
+
+// reseal opens value, sealed with any key of the keyring or plaintext, and seals it with the current key.
+func (r *Repository) reseal(column string, visitId string, value string) (string, error) {
+	if r.keyring.Current([]byte(value)) {
+		return value, nil
+	}
+
+	plaintext, err := r.open(column, visitId, []byte(value))
+	if err != nil {
+		return "", err
+	}
+	return r.keyring.Seal(plaintext, associatedData(column, visitId))
+}
+
+// Vacuum rebuilds the database file, dropping the free pages that may still hold values before their encryption.
+func (r *Repository) Vacuum(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, err := r.db.ExecContext(ctx, "VACUUM")
+	return err
+}
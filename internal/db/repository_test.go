@@ -1,8 +1,16 @@
 package db
 
 import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/jasoet/fhir-worker/internal/entity"
+	"github.com/jasoet/fhir-worker/pkg/encryption"
+	shared "github.com/jasoet/fhir-worker/shared/model"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"testing"
+	"time"
 )
 
 func TestRepository_IsExists(t *testing.T) {
@@ -16,3 +24,169 @@ func TestRepository_IsExists(t *testing.T) {
 	//assert.NoError(t, err)
 	//assert.False(t, exists)
 }
+
+func TestRepository_AuditLog(t *testing.T) {
+	repository, err := DefaultRepository()
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	occurredAt := time.Now().UTC()
+	visitId := "AUDIT-V-1"
+
+	err = repository.InsertAuditEntry(ctx, entity.AuditEntry{
+		OccurredAt:   occurredAt,
+		Method:       "POST",
+		Url:          "https://api/fhir",
+		StatusCode:   200,
+		LatencyMs:    120,
+		RequestHash:  "aa",
+		ResponseHash: "bb",
+		VisitID:      &visitId,
+		ClientID:     "client-1",
+	})
+	require.NoError(t, err)
+
+	entries, err := repository.AuditEntries(ctx, occurredAt.Add(-time.Second), occurredAt.Add(time.Second))
+	require.NoError(t, err)
+	require.NotEmpty(t, entries)
+
+	entry := entries[len(entries)-1]
+	assert.Equal(t, "client-1", entry.ClientID)
+	require.NotNil(t, entry.VisitID)
+	assert.Equal(t, visitId, *entry.VisitID)
+
+	_, err = repository.db.ExecContext(ctx, "UPDATE audit_log SET status_code = 500 WHERE id = ?", entry.ID)
+	assert.ErrorContains(t, err, "append-only")
+
+	_, err = repository.db.ExecContext(ctx, "DELETE FROM audit_log WHERE id = ?", entry.ID)
+	assert.ErrorContains(t, err, "append-only")
+}
+
+func TestRepository_Encryption(t *testing.T) {
+	repository, err := DefaultRepository()
+	require.NoError(t, err)
+	defer repository.SetKeyring(nil)
+
+	// The default repository outlives the test run, the visit ids must be new each run.
+	ctx := context.Background()
+	plainId := fmt.Sprintf("ENC-%d-1", time.Now().UnixNano())
+	sealedId := fmt.Sprintf("ENC-%d-2", time.Now().UnixNano())
+	visitDetail := shared.VisitDetail{VisitId: plainId, PatientNik: "3404000000000001", PatientName: "Budi"}
+
+	rawVisitDetail := func(visitId string) string {
+		var value string
+		require.NoError(t, repository.db.GetContext(ctx, &value, "SELECT visit_detail FROM satusehat WHERE visit_id = ?", visitId))
+		return value
+	}
+
+	_, err = repository.InsertValid(ctx, plainId, time.Now(), "P-1", visitDetail, shared.VitalSign{})
+	require.NoError(t, err)
+	assert.Contains(t, rawVisitDetail(plainId), "3404000000000001")
+
+	first, err := encryption.NewKeyring(bytes.Repeat([]byte{1}, encryption.KeySize))
+	require.NoError(t, err)
+	repository.SetKeyring(first)
+
+	visitDetail.VisitId = sealedId
+	_, err = repository.InsertValid(ctx, sealedId, time.Now(), "P-1", visitDetail, shared.VitalSign{})
+	require.NoError(t, err)
+	_, err = repository.UpdatePublishStatus(ctx, sealedId, `{"resourceType":"Bundle"}`, "{}", time.Now(), entity.Success)
+	require.NoError(t, err)
+	assert.NotContains(t, rawVisitDetail(sealedId), "3404000000000001")
+
+	for _, visitId := range []string{plainId, sealedId} {
+		internal, err := repository.GetByVisitId(ctx, visitId)
+		require.NoError(t, err)
+		assert.Equal(t, "3404000000000001", internal.VisitDetail().PatientNik)
+	}
+
+	second, err := encryption.NewKeyring(bytes.Repeat([]byte{2}, encryption.KeySize), bytes.Repeat([]byte{1}, encryption.KeySize))
+	require.NoError(t, err)
+	repository.SetKeyring(second)
+
+	_, rotated, err := repository.RotateKey(ctx, 1)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, rotated, 2)
+	assert.True(t, second.Current([]byte(rawVisitDetail(plainId))))
+	assert.True(t, second.Current([]byte(rawVisitDetail(sealedId))))
+
+	_, rotated, err = repository.RotateKey(ctx, 100)
+	require.NoError(t, err)
+	assert.Zero(t, rotated)
+
+	internal, err := repository.GetByVisitId(ctx, sealedId)
+	require.NoError(t, err)
+	assert.Equal(t, "Budi", internal.VisitDetail().PatientName)
+	require.NotNil(t, internal.PublishRequest)
+	assert.Equal(t, `{"resourceType":"Bundle"}`, *internal.PublishRequest)
+
+	repository.SetKeyring(nil)
+	_, err = repository.GetByVisitId(ctx, sealedId)
+	assert.ErrorContains(t, err, "no encryption key is configured")
+
+	t.Run("SkipsUndecryptable", func(t *testing.T) {
+		for _, visitId := range []string{plainId, sealedId} {
+			_, err := repository.UpdateStatus(ctx, visitId, entity.Ready, entity.Preparing)
+			require.NoError(t, err)
+		}
+		_, err := repository.db.ExecContext(ctx, "UPDATE satusehat SET visit_detail = ? WHERE visit_id = ?", []byte(`{"visit_id":"plain"}`), plainId)
+		require.NoError(t, err)
+
+		ready, err := repository.ReadyToPublish(ctx)
+		require.NoError(t, err)
+
+		var visitIds []string
+		for _, internal := range ready {
+			visitIds = append(visitIds, internal.VisitID)
+		}
+		assert.Contains(t, visitIds, plainId)
+		assert.NotContains(t, visitIds, sealedId)
+
+		for _, visitId := range []string{plainId, sealedId} {
+			_, err := repository.UpdateStatus(ctx, visitId, entity.Skipped, entity.Preparing)
+			require.NoError(t, err)
+		}
+	})
+
+	t.Run("ConcurrentPublish", func(t *testing.T) {
+		repository.SetKeyring(first)
+		visitId := fmt.Sprintf("ENC-%d-3", time.Now().UnixNano())
+		_, err := repository.InsertValid(ctx, visitId, time.Now(), "P-1", visitDetail, shared.VitalSign{})
+		require.NoError(t, err)
+		_, err = repository.UpdatePublishStatus(ctx, visitId, `{"attempt":1}`, "{}", time.Now(), entity.RequestError)
+		require.NoError(t, err)
+
+		// The rotation reads the row, then the worker publishes the visit again before the rotation writes it.
+		var stale []encryptedColumns
+		require.NoError(t, repository.getEncryptedColumns.SelectContext(ctx, &stale, map[string]any{
+			"after": visitId[:len(visitId)-1],
+			"limit": 1,
+		}))
+		require.Len(t, stale, 1)
+		require.Equal(t, visitId, stale[0].VisitID)
+
+		repository.SetKeyring(second)
+		_, err = repository.UpdatePublishStatus(ctx, visitId, `{"attempt":2}`, "{}", time.Now(), entity.Success)
+		require.NoError(t, err)
+
+		rotated, err := repository.rotateRows(ctx, stale)
+		require.NoError(t, err)
+		assert.Zero(t, rotated)
+
+		internal, err := repository.GetByVisitId(ctx, visitId)
+		require.NoError(t, err)
+		require.NotNil(t, internal.PublishRequest)
+		assert.Equal(t, `{"attempt":2}`, *internal.PublishRequest)
+
+		// Rows still holding the values read are rotated.
+		stale = nil
+		require.NoError(t, repository.getEncryptedColumns.SelectContext(ctx, &stale, map[string]any{
+			"after": visitId[:len(visitId)-1],
+			"limit": 1,
+		}))
+		rotated, err = repository.rotateRows(ctx, stale)
+		require.NoError(t, err)
+		assert.Equal(t, 1, rotated)
+		assert.True(t, second.Current([]byte(rawVisitDetail(visitId))))
+	})
+}
@@ -0,0 +1,19 @@
+package entity
+
+import "time"
+
+// AuditEntry is one HTTP exchange with SatuSehat. The hashes are hex SHA-256 of the exact bytes sent and received,
+// the URL has NIKs masked, hashing the unmasked URL and body proves what was sent without storing patient data.
+type AuditEntry struct {
+	ID           int64     `db:"id" json:"id"`
+	OccurredAt   time.Time `db:"occurred_at" json:"occurred_at"`
+	Method       string    `db:"method" json:"method"`
+	Url          string    `db:"url" json:"url"`
+	StatusCode   int       `db:"status_code" json:"status_code"`
+	LatencyMs    int64     `db:"latency_ms" json:"latency_ms"`
+	RequestHash  string    `db:"request_hash" json:"request_hash"`
+	ResponseHash string    `db:"response_hash" json:"response_hash"`
+	VisitID      *string   `db:"visit_id" json:"visit_id"`
+	ClientID     string    `db:"client_id" json:"client_id"`
+	Error        *string   `db:"error" json:"error"`
+}
@@ -0,0 +1,15 @@
+package entity
+
+import "time"
+
+type BackfillChunk struct {
+	RangeKey      string    `db:"range_key"`
+	ChunkStart    time.Time `db:"chunk_start"`
+	ChunkEnd      time.Time `db:"chunk_end"`
+	VisitCount    int       `db:"visit_count"`
+	ValidCount    int       `db:"valid_count"`
+	InvalidCount  int       `db:"invalid_count"`
+	ExistingCount int       `db:"existing_count"`
+	FailedCount   int       `db:"failed_count"`
+	FinishedAt    time.Time `db:"finished_at"`
+}
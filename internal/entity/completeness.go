@@ -0,0 +1,45 @@
+package entity
+
+import (
+	shared "github.com/jasoet/fhir-worker/shared/model"
+	"time"
+)
+
+type ResourceStatus string
+
+const (
+	ResourceMissing  ResourceStatus = "MISSING"  // no record found in SIMRS yet
+	ResourceInvalid  ResourceStatus = "INVALID"  // records found, but at least one fails validation
+	ResourceComplete ResourceStatus = "COMPLETE" // every record is valid
+	ResourceDisabled ResourceStatus = "DISABLED" // resource is disabled by mapping configuration
+	ResourceNone     ResourceStatus = "NONE"     // optional resource, nothing charted for the visit
+)
+
+type ResourceCompleteness struct {
+	Resource string                   `json:"resource"`
+	Status   ResourceStatus           `json:"status"`
+	Count    int                      `json:"count"`
+	Issues   []shared.ValidationIssue `json:"issues,omitempty"`
+}
+
+// Satisfied reports whether the resource no longer blocks the visit from being READY.
+func (r ResourceCompleteness) Satisfied() bool {
+	return r.Status == ResourceComplete || r.Status == ResourceDisabled || r.Status == ResourceNone
+}
+
+// MappingReport explains why a visit is or isn't READY to publish.
+type MappingReport struct {
+	Ready     bool                   `json:"ready"`
+	Reason    string                 `json:"reason"`
+	CheckedAt time.Time              `json:"checked_at"`
+	Resources []ResourceCompleteness `json:"resources"`
+}
+
+// Issues returns the validation issues of every resource in the report.
+func (m MappingReport) Issues() []shared.ValidationIssue {
+	var issues []shared.ValidationIssue
+	for _, resource := range m.Resources {
+		issues = append(issues, resource.Issues...)
+	}
+	return issues
+}
@@ -0,0 +1,33 @@
+package entity
+
+import "time"
+
+// ConceptMapVersion groups the entries of one concept map import, only the Active version is applied during visit fill.
+type ConceptMapVersion struct {
+	Version     int64     `db:"version"`
+	Description string    `db:"description"`
+	CreatedBy   string    `db:"created_by"`
+	CreatedAt   time.Time `db:"created_at"`
+	Active      bool      `db:"active"`
+	EntryCount  int       `db:"entry_count"`
+}
+
+// ConceptMapEntry maps a local lab test code and parameter name to LOINC, an empty Parameter matches every parameter of the test.
+type ConceptMapEntry struct {
+	Version      int64  `db:"version"`
+	LocalCode    string `db:"local_code"`
+	Parameter    string `db:"parameter"`
+	LoincCode    string `db:"loinc_code"`
+	LoincDisplay string `db:"loinc_display"`
+	UcumUnit     string `db:"ucum_unit"`
+}
+
+// UnmappedLabCode is a local lab code and parameter without LOINC code, Occurrences counts the visits it was seen in.
+type UnmappedLabCode struct {
+	LocalCode   string    `db:"local_code"`
+	Parameter   string    `db:"parameter"`
+	LabName     string    `db:"lab_name"`
+	Occurrences int       `db:"occurrences"`
+	FirstSeenAt time.Time `db:"first_seen_at"`
+	LastSeenAt  time.Time `db:"last_seen_at"`
+}
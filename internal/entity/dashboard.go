@@ -0,0 +1,45 @@
+package entity
+
+import "time"
+
+// DailyStatusCount is the number of visits of a day having the same mapping and publish status.
+type DailyStatusCount struct {
+	Day           string        `db:"day" json:"day"`
+	MappingStatus MappingStatus `db:"mapping_status" json:"mapping_status"`
+	PublishStatus PublishStatus `db:"publish_status" json:"publish_status"`
+	VisitCount    int           `db:"visit_count" json:"visit_count"`
+}
+
+// Backlog is the number of visits still waiting to be published, grouped by status, with the day of the oldest one.
+type Backlog struct {
+	MappingStatus  MappingStatus `db:"mapping_status" json:"mapping_status"`
+	PublishStatus  PublishStatus `db:"publish_status" json:"publish_status"`
+	VisitCount     int           `db:"visit_count" json:"visit_count"`
+	OldestVisitDay string        `db:"oldest_visit_day" json:"oldest_visit_day"`
+}
+
+// ValidationIssueCount is how often the same validation rule failed on a field.
+type ValidationIssueCount struct {
+	Resource    string `db:"resource" json:"resource"`
+	Field       string `db:"field" json:"field"`
+	Rule        string `db:"rule" json:"rule"`
+	MessageEn   string `db:"message_en" json:"message_en"`
+	Occurrences int    `db:"occurrences" json:"occurrences"`
+	VisitCount  int    `db:"visit_count" json:"visit_count"`
+}
+
+// PublishError is the last unsuccessful SatuSehat response of a visit.
+type PublishError struct {
+	VisitID         string        `db:"visit_id" json:"visit_id"`
+	VisitDate       time.Time     `db:"visit_date" json:"visit_date"`
+	PublishStatus   PublishStatus `db:"publish_status" json:"publish_status"`
+	PublishDate     *time.Time    `db:"publish_date" json:"publish_date"`
+	PublishResponse *string       `db:"publish_response" json:"publish_response"`
+}
+
+// StatusCount is the number of visits having the same mapping and publish status.
+type StatusCount struct {
+	MappingStatus MappingStatus `db:"mapping_status" json:"mapping_status"`
+	PublishStatus PublishStatus `db:"publish_status" json:"publish_status"`
+	VisitCount    int           `db:"visit_count" json:"visit_count"`
+}
@@ -18,10 +18,15 @@ type SatuSehatInternal struct {
 	MedicationRequestJsonArr  *json.RawMessage `db:"medication_request"`  //Json Array
 	MedicationDispenseJsonArr *json.RawMessage `db:"medication_dispense"` //Json Array
 	ProcedureJsonArr          *json.RawMessage `db:"medical_procedure"`   //Json Array
+	AnamnesisJsonArr          *json.RawMessage `db:"anamnesis"`           //Json Array
+	AllergyJsonArr            *json.RawMessage `db:"allergy"`             //Json Array
+	ImmunizationJsonArr       *json.RawMessage `db:"immunization"`        //Json Array
+	ClinicalNoteJsonArr       *json.RawMessage `db:"clinical_note"`       //Json Array
 	PublishDate               *time.Time       `db:"publish_date"`
 	PublishRequest            *string          `db:"publish_request"`
 	PublishResponse           *string          `db:"publish_response"`
 	MappingErrors             *string          `db:"mapping_errors"`
+	MappingReportJson         *json.RawMessage `db:"mapping_report"`
 	MappingStatus             MappingStatus    `db:"mapping_status"`
 	PublishStatus             PublishStatus    `db:"publish_status"`
 }
@@ -82,7 +87,7 @@ func (s *SatuSehatInternal) Radiology() *shared.ObservationRadiologyList {
 }
 
 func (s *SatuSehatInternal) MedicationDispense() *shared.MedicationDispenseList {
-	if s.MedicationRequestJsonArr == nil {
+	if s.MedicationDispenseJsonArr == nil {
 		return nil
 	}
 	var o shared.MedicationDispenseList
@@ -116,3 +121,63 @@ func (s *SatuSehatInternal) Procedure() *shared.ProcedureList {
 	}
 	return &o
 }
+
+func (s *SatuSehatInternal) Anamnesis() *shared.AnamnesisList {
+	if s.AnamnesisJsonArr == nil {
+		return nil
+	}
+	var o shared.AnamnesisList
+	err := json.Unmarshal(*s.AnamnesisJsonArr, &o)
+	if err != nil || len(o) == 0 {
+		return nil
+	}
+	return &o
+}
+
+func (s *SatuSehatInternal) Allergy() *shared.AllergyList {
+	if s.AllergyJsonArr == nil {
+		return nil
+	}
+	var o shared.AllergyList
+	err := json.Unmarshal(*s.AllergyJsonArr, &o)
+	if err != nil || len(o) == 0 {
+		return nil
+	}
+	return &o
+}
+
+func (s *SatuSehatInternal) Immunization() *shared.ImmunizationList {
+	if s.ImmunizationJsonArr == nil {
+		return nil
+	}
+	var o shared.ImmunizationList
+	err := json.Unmarshal(*s.ImmunizationJsonArr, &o)
+	if err != nil || len(o) == 0 {
+		return nil
+	}
+	return &o
+}
+
+func (s *SatuSehatInternal) ClinicalNote() *shared.ClinicalNoteList {
+	if s.ClinicalNoteJsonArr == nil {
+		return nil
+	}
+	var o shared.ClinicalNoteList
+	err := json.Unmarshal(*s.ClinicalNoteJsonArr, &o)
+	if err != nil || len(o) == 0 {
+		return nil
+	}
+	return &o
+}
+
+func (s *SatuSehatInternal) MappingReport() *MappingReport {
+	if s.MappingReportJson == nil {
+		return nil
+	}
+	var o MappingReport
+	err := json.Unmarshal(*s.MappingReportJson, &o)
+	if err != nil {
+		return nil
+	}
+	return &o
+}
@@ -0,0 +1,15 @@
+package entity
+
+import "time"
+
+// JobRun is one execution of a scheduled job, FinishedAt is nil while it is still running or when it crashed.
+type JobRun struct {
+	ID         int64      `db:"id" json:"id"`
+	JobName    string     `db:"job_name" json:"job_name"`
+	StartedAt  time.Time  `db:"started_at" json:"started_at"`
+	FinishedAt *time.Time `db:"finished_at" json:"finished_at"`
+	Scanned    int        `db:"scanned" json:"scanned"`
+	Processed  int        `db:"processed" json:"processed"`
+	Failed     int        `db:"failed" json:"failed"`
+	Error      *string    `db:"error" json:"error"`
+}
@@ -0,0 +1,23 @@
+package entity
+
+import "time"
+
+// KfaMapping maps a SIMRS medicine code to its KFA code, maintained by pharmacy staff.
+type KfaMapping struct {
+	MedicineCode string    `db:"medicine_code"`
+	MedicineName string    `db:"medicine_name"`
+	KfaCode      string    `db:"kfa_code"`
+	KfaDisplay   string    `db:"kfa_display"`
+	MappedBy     string    `db:"mapped_by"`
+	MappedAt     time.Time `db:"mapped_at"`
+}
+
+// UnmappedMedicine is a medicine prescribed without a KFA code from SIMRS nor from KfaMapping,
+// Occurrences counts the visits it was prescribed in.
+type UnmappedMedicine struct {
+	MedicineCode string    `db:"medicine_code"`
+	MedicineName string    `db:"medicine_name"`
+	Occurrences  int       `db:"occurrences"`
+	FirstSeenAt  time.Time `db:"first_seen_at"`
+	LastSeenAt   time.Time `db:"last_seen_at"`
+}
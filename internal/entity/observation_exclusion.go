@@ -0,0 +1,22 @@
+package entity
+
+import "time"
+
+type ExclusionReason string
+
+const (
+	ExclusionValue ExclusionReason = "VALUE" // value is not a number or range
+	ExclusionUnit  ExclusionReason = "UNIT"  // unit is unknown or doesn't fit the observation
+	ExclusionRange ExclusionReason = "RANGE" // value is outside the plausible range of the observation
+)
+
+// ObservationExclusion is an observation left out of the published bundle because its value couldn't be normalized or isn't plausible.
+type ObservationExclusion struct {
+	VisitId      string          `db:"visit_id" json:"visit_id"`
+	LoincCode    string          `db:"loinc_code" json:"loinc_code"`
+	LoincDisplay string          `db:"loinc_display" json:"loinc_display"`
+	Value        string          `db:"value" json:"value"`
+	Reason       ExclusionReason `db:"reason" json:"reason"`
+	Message      string          `db:"message" json:"message"`
+	ExcludedAt   time.Time       `db:"excluded_at" json:"excluded_at"`
+}
@@ -6,6 +6,7 @@ const (
 	Ready      MappingStatus = "READY"      // ready to push to SatuSehat
 	Incomplete MappingStatus = "INCOMPLETE" // need to re-fetch the detail, if data exists but incomplete
 	Invalid    MappingStatus = "INVALID"    // mark the data invalid, no need to continue the process
+	Skipped    MappingStatus = "SKIPPED"    // skipped by an operator, neither filled nor published
 )
 
 type PublishStatus string
@@ -0,0 +1,24 @@
+package entity
+
+import "time"
+
+// VisitFilter selects visits of the internal database, empty statuses and nil dates match every visit.
+type VisitFilter struct {
+	MappingStatus MappingStatus
+	PublishStatus PublishStatus
+	VisitDateFrom *time.Time
+	VisitDateTo   *time.Time // exclusive
+	Limit         int
+	Offset        int
+}
+
+// VisitSummary is a visit of the internal database without its clinical data.
+type VisitSummary struct {
+	VisitID            string        `db:"visit_id" json:"visit_id"`
+	VisitDate          time.Time     `db:"visit_date" json:"visit_date"`
+	SatusehatPatientID string        `db:"satusehat_patient_id" json:"satusehat_patient_id"`
+	MappingStatus      MappingStatus `db:"mapping_status" json:"mapping_status"`
+	PublishStatus      PublishStatus `db:"publish_status" json:"publish_status"`
+	PublishDate        *time.Time    `db:"publish_date" json:"publish_date"`
+	MappingErrors      *string       `db:"mapping_errors" json:"mapping_errors"`
+}
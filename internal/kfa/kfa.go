@@ -0,0 +1,218 @@
+package kfa
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/jasoet/fhir-worker/internal/entity"
+	"github.com/jasoet/fhir-worker/internal/terminology"
+	"github.com/jasoet/fhir-worker/pkg/fuzzy"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MappingHeader is the CSV layout exchanged with pharmacy staff, the unmapped export uses the same first columns
+// so a filled in backlog file can be imported as is.
+var MappingHeader = []string{"medicine_code", "medicine_name", "kfa_code", "kfa_display", "mapped_by", "mapped_at"}
+
+// synonyms expands the abbreviations commonly used in SIMRS drug names to the words used by KFA displays.
+var synonyms = map[string]string{
+	"tab":  "tablet",
+	"tabs": "tablet",
+	"kap":  "kapsul",
+	"kaps": "kapsul",
+	"cap":  "kapsul",
+	"caps": "kapsul",
+	"syr":  "sirup",
+	"sir":  "sirup",
+	"inj":  "injeksi",
+	"amp":  "ampul",
+	"tts":  "tetes",
+	"supp": "supositoria",
+	"susp": "suspensi",
+}
+
+// ReadMappings reads a mapping CSV by header name, rows without a kfa_code are skipped so a partially filled
+// backlog can be imported. mappedBy is used for rows without their own mapped_by.
+func ReadMappings(reader io.Reader, mappedBy string, mappedAt time.Time) ([]entity.KfaMapping, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1
+
+	header, err := csvReader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	for _, required := range []string{"medicine_code", "kfa_code", "kfa_display"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("column %s is required", required)
+		}
+	}
+
+	value := func(record []string, column string) string {
+		i, ok := columns[column]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var mappings []entity.KfaMapping
+	for {
+		record, err := csvReader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		line, _ := csvReader.FieldPos(0)
+
+		mapping := entity.KfaMapping{
+			MedicineCode: value(record, "medicine_code"),
+			MedicineName: value(record, "medicine_name"),
+			KfaCode:      terminology.Normalize(terminology.KFA, value(record, "kfa_code")),
+			KfaDisplay:   value(record, "kfa_display"),
+			MappedBy:     value(record, "mapped_by"),
+			MappedAt:     mappedAt,
+		}
+
+		if mapping.KfaCode == "" {
+			continue
+		}
+
+		if mapping.MappedBy == "" {
+			mapping.MappedBy = mappedBy
+		}
+
+		switch {
+		case mapping.MedicineCode == "":
+			return nil, fmt.Errorf("line %d: medicine_code is required", line)
+		case !terminology.WellFormed(terminology.KFA, mapping.KfaCode):
+			return nil, fmt.Errorf("line %d: kfa_code %q is malformed", line, mapping.KfaCode)
+		case mapping.KfaDisplay == "":
+			return nil, fmt.Errorf("line %d: kfa_display is required", line)
+		case mapping.MappedBy == "":
+			return nil, fmt.Errorf("line %d: mapped_by is required, fill the column or pass it as flag", line)
+		}
+
+		mappings = append(mappings, mapping)
+	}
+
+	return mappings, nil
+}
+
+func WriteMappings(writer io.Writer, mappings []entity.KfaMapping) error {
+	csvWriter := csv.NewWriter(writer)
+	if err := csvWriter.Write(MappingHeader); err != nil {
+		return err
+	}
+
+	for _, mapping := range mappings {
+		err := csvWriter.Write([]string{
+			mapping.MedicineCode,
+			mapping.MedicineName,
+			mapping.KfaCode,
+			mapping.KfaDisplay,
+			mapping.MappedBy,
+			mapping.MappedAt.Format(time.RFC3339),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+// WriteUnmapped writes the mapping backlog with empty kfa_code and kfa_display columns for staff to fill,
+// followed by the candidates of the Suggester when it is not nil.
+func WriteUnmapped(writer io.Writer, unmapped []entity.UnmappedMedicine, suggester *Suggester, limit int) error {
+	csvWriter := csv.NewWriter(writer)
+
+	header := append(append([]string{}, MappingHeader...), "occurrences", "last_seen_at")
+	if suggester != nil {
+		for i := 1; i <= limit; i++ {
+			header = append(header, fmt.Sprintf("suggestion_%d", i))
+		}
+	}
+
+	if err := csvWriter.Write(header); err != nil {
+		return err
+	}
+
+	for _, medicine := range unmapped {
+		record := []string{
+			medicine.MedicineCode,
+			medicine.MedicineName,
+			"",
+			"",
+			"",
+			"",
+			strconv.Itoa(medicine.Occurrences),
+			medicine.LastSeenAt.Format(time.RFC3339),
+		}
+
+		if suggester != nil {
+			for _, suggestion := range suggester.Suggest(medicine.MedicineName, limit) {
+				record = append(record, suggestion.String())
+			}
+		}
+
+		if err := csvWriter.Write(record); err != nil {
+			return err
+		}
+	}
+
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+type Suggestion struct {
+	terminology.Code
+	Score float64
+}
+
+func (s Suggestion) String() string {
+	return fmt.Sprintf("%s | %s (%.2f)", s.Code.Code, s.Display, s.Score)
+}
+
+// Suggester proposes KFA codes for a drug name by fuzzy matching it against the displays of a KFA catalog.
+type Suggester struct {
+	codes    []terminology.Code
+	index    *fuzzy.Index
+	minScore float64
+}
+
+func NewSuggester(codes []terminology.Code, minScore float64) *Suggester {
+	displays := make([]string, 0, len(codes))
+	for _, code := range codes {
+		displays = append(displays, code.Display)
+	}
+
+	return &Suggester{
+		codes:    codes,
+		index:    fuzzy.Matcher{Synonyms: synonyms}.Index(displays),
+		minScore: minScore,
+	}
+}
+
+func (s *Suggester) Suggest(medicineName string, limit int) []Suggestion {
+	var suggestions []Suggestion
+	for _, match := range s.index.Rank(medicineName, limit, s.minScore) {
+		suggestions = append(suggestions, Suggestion{
+			Code:  s.codes[match.Position],
+			Score: match.Score,
+		})
+	}
+	return suggestions
+}
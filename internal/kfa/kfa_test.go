@@ -0,0 +1,78 @@
+package kfa
+
+import (
+	"bytes"
+	"github.com/jasoet/fhir-worker/internal/entity"
+	"github.com/jasoet/fhir-worker/internal/terminology"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadMappings(t *testing.T) {
+	now := time.Now()
+
+	input := `medicine_code,medicine_name,kfa_code,kfa_display,mapped_by,occurrences
+OBT-001,PARACETAMOL 500MG TAB,9300 1019,Parasetamol 500 mg Tablet,,12
+OBT-002,AMOXICILLIN 500MG,,,,3
+OBT-003,OMEPRAZOLE 20MG,93002000,Omeprazol 20 mg Kapsul,apoteker-1,1
+`
+
+	mappings, err := ReadMappings(strings.NewReader(input), "farmasi", now)
+	assert.NoError(t, err)
+	if assert.Len(t, mappings, 2) {
+		assert.Equal(t, entity.KfaMapping{
+			MedicineCode: "OBT-001",
+			MedicineName: "PARACETAMOL 500MG TAB",
+			KfaCode:      "93001019",
+			KfaDisplay:   "Parasetamol 500 mg Tablet",
+			MappedBy:     "farmasi",
+			MappedAt:     now,
+		}, mappings[0])
+		assert.Equal(t, "apoteker-1", mappings[1].MappedBy)
+	}
+}
+
+func TestReadMappings_Invalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"MissingColumn", "medicine_code,kfa_code\nOBT-001,93001019\n"},
+		{"MalformedCode", "medicine_code,kfa_code,kfa_display\nOBT-001,KFA-1,Parasetamol\n"},
+		{"MissingDisplay", "medicine_code,kfa_code,kfa_display\nOBT-001,93001019,\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadMappings(strings.NewReader(tt.input), "farmasi", time.Now())
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestWriteUnmapped(t *testing.T) {
+	suggester := NewSuggester([]terminology.Code{
+		{Code: "93001019", Display: "Parasetamol 500 mg Tablet"},
+		{Code: "93002000", Display: "Omeprazol 20 mg Kapsul"},
+	}, 0.3)
+
+	unmapped := []entity.UnmappedMedicine{
+		{MedicineCode: "OBT-001", MedicineName: "PARACETAMOL 500MG TAB", Occurrences: 12, LastSeenAt: time.Now()},
+	}
+
+	var buffer bytes.Buffer
+	assert.NoError(t, WriteUnmapped(&buffer, unmapped, suggester, 1))
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	if assert.Len(t, lines, 2) {
+		assert.True(t, strings.HasPrefix(lines[0], "medicine_code,medicine_name,kfa_code,kfa_display,mapped_by,mapped_at,occurrences,last_seen_at,suggestion_1"))
+		assert.True(t, strings.HasPrefix(lines[1], "OBT-001,PARACETAMOL 500MG TAB,,,,,12,"))
+		assert.Contains(t, lines[1], "93001019 | Parasetamol 500 mg Tablet")
+	}
+
+	mappings, err := ReadMappings(strings.NewReader(buffer.String()), "farmasi", time.Now())
+	assert.NoError(t, err)
+	assert.Empty(t, mappings, "an unfilled backlog imports nothing")
+}
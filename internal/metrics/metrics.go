@@ -0,0 +1,122 @@
+package metrics
+
+import (
+	"context"
+	"github.com/jasoet/fhir-worker/internal/entity"
+	"github.com/prometheus/client_golang/prometheus"
+	"strconv"
+	"time"
+)
+
+const namespace = "fhir_worker"
+
+// OrganizationLabel identifies the hospital on every metric, so one Prometheus can scrape the workers of many hospitals.
+const OrganizationLabel = "organization_id"
+
+var (
+	PublishAttempts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "publish_attempts_total",
+		Help:      "Bundles sent to SatuSehat, partitioned by outcome and HTTP status code.",
+	}, []string{"outcome", "status_code"})
+
+	SatuSehatRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "satusehat_request_duration_seconds",
+		Help:      "Latency of SatuSehat requests traced by the HTTP client, partitioned by operation and HTTP status code.",
+		Buckets:   []float64{.1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"operation", "status_code"})
+
+	TokenRefreshes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "satusehat_token_refreshes_total",
+		Help:      "SatuSehat access token refreshes, partitioned by outcome.",
+	}, []string{"outcome"})
+
+	SimrsQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "simrs_query_duration_seconds",
+		Help:      "Latency of SIMRS queries, partitioned by simrs.Query method and outcome.",
+		Buckets:   []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+	}, []string{"method", "outcome"})
+
+	JobDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "job_duration_seconds",
+		Help:      "Duration of the scheduled job runs, partitioned by job and outcome.",
+		Buckets:   []float64{.1, .5, 1, 5, 15, 30, 60, 120, 300, 600, 1800},
+	}, []string{"job", "outcome"})
+
+	JobItems = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "job_items_total",
+		Help:      "Visits went through by the scheduled jobs, partitioned by job and kind: scanned, processed or failed.",
+	}, []string{"job", "kind"})
+
+	JobLastSuccess = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "job_last_success_timestamp_seconds",
+		Help:      "Unix time of the last run of each job that finished without error.",
+	}, []string{"job"})
+)
+
+const (
+	Success = "success"
+	Failure = "failure"
+)
+
+// Outcome is the outcome label of err.
+func Outcome(err error) string {
+	if err != nil {
+		return Failure
+	}
+	return Success
+}
+
+// StatusCode is the status_code label of an HTTP status, "none" when no response was received.
+func StatusCode(statusCode int) string {
+	if statusCode == 0 {
+		return "none"
+	}
+	return strconv.Itoa(statusCode)
+}
+
+// ObserveSince records the seconds elapsed since start on observer.
+func ObserveSince(observer prometheus.Observer, start time.Time) {
+	observer.Observe(time.Since(start).Seconds())
+}
+
+// StatusCounter counts the visits of the internal database by mapping and publish status.
+type StatusCounter interface {
+	StatusCounts(ctx context.Context) ([]entity.StatusCount, error)
+}
+
+// Register adds every collector to registerer, labelled with organizationId and the given extra labels.
+// counter, when not nil, is queried on each scrape for the number of visits per status.
+func Register(registerer prometheus.Registerer, organizationId string, labels map[string]string, counter StatusCounter) error {
+	constLabels := prometheus.Labels{OrganizationLabel: organizationId}
+	for name, value := range labels {
+		constLabels[name] = value
+	}
+	registerer = prometheus.WrapRegistererWith(constLabels, registerer)
+
+	collectors := []prometheus.Collector{
+		PublishAttempts,
+		SatuSehatRequestDuration,
+		TokenRefreshes,
+		SimrsQueryDuration,
+		JobDuration,
+		JobItems,
+		JobLastSuccess,
+	}
+	if counter != nil {
+		collectors = append(collectors, newVisitCollector(counter))
+	}
+
+	for _, collector := range collectors {
+		if err := registerer.Register(collector); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"github.com/jasoet/fhir-worker/internal/entity"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+type statusCounterFunc func(ctx context.Context) ([]entity.StatusCount, error)
+
+func (f statusCounterFunc) StatusCounts(ctx context.Context) ([]entity.StatusCount, error) {
+	return f(ctx)
+}
+
+func TestRegister(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	counter := statusCounterFunc(func(ctx context.Context) ([]entity.StatusCount, error) {
+		return []entity.StatusCount{
+			{MappingStatus: entity.Ready, PublishStatus: entity.Success, VisitCount: 12},
+			{MappingStatus: entity.Incomplete, PublishStatus: entity.Preparing, VisitCount: 3},
+		}, nil
+	})
+
+	require.NoError(t, Register(registry, "ORG-1", map[string]string{"hospital": "rs-sleman"}, counter))
+
+	PublishAttempts.WithLabelValues(Success, StatusCode(200)).Inc()
+
+	expected := `
+# HELP fhir_worker_visits Visits of the internal database, partitioned by mapping and publish status.
+# TYPE fhir_worker_visits gauge
+fhir_worker_visits{hospital="rs-sleman",mapping_status="INCOMPLETE",organization_id="ORG-1",publish_status="PREPARING"} 3
+fhir_worker_visits{hospital="rs-sleman",mapping_status="READY",organization_id="ORG-1",publish_status="SUCCESS"} 12
+# HELP fhir_worker_publish_attempts_total Bundles sent to SatuSehat, partitioned by outcome and HTTP status code.
+# TYPE fhir_worker_publish_attempts_total counter
+fhir_worker_publish_attempts_total{hospital="rs-sleman",organization_id="ORG-1",outcome="success",status_code="200"} 1
+`
+	assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expected),
+		"fhir_worker_visits", "fhir_worker_publish_attempts_total"))
+}
+
+func TestVisitCollector_Error(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	counter := statusCounterFunc(func(ctx context.Context) ([]entity.StatusCount, error) {
+		return nil, errors.New("database is locked")
+	})
+
+	require.NoError(t, registry.Register(newVisitCollector(counter)))
+
+	_, err := registry.Gather()
+	assert.ErrorContains(t, err, "database is locked")
+}
+
+func TestStatusCode(t *testing.T) {
+	assert.Equal(t, "none", StatusCode(0))
+	assert.Equal(t, "422", StatusCode(422))
+}
@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog/log"
+	"time"
+)
+
+const scrapeTimeout = 5 * time.Second
+
+var visitsDesc = prometheus.NewDesc(
+	prometheus.BuildFQName(namespace, "", "visits"),
+	"Visits of the internal database, partitioned by mapping and publish status.",
+	[]string{"mapping_status", "publish_status"}, nil,
+)
+
+// visitCollector counts the visits per status at scrape time, the internal database being the source of truth.
+type visitCollector struct {
+	counter StatusCounter
+}
+
+func newVisitCollector(counter StatusCounter) *visitCollector {
+	return &visitCollector{counter: counter}
+}
+
+func (v *visitCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- visitsDesc
+}
+
+func (v *visitCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), scrapeTimeout)
+	defer cancel()
+
+	counts, err := v.counter.StatusCounts(ctx)
+	if err != nil {
+		log.Error().Err(err).Str("function", "visitCollector").Msg("Failed to count visits per status.")
+		ch <- prometheus.NewInvalidMetric(visitsDesc, err)
+		return
+	}
+
+	for _, count := range counts {
+		ch <- prometheus.MustNewConstMetric(visitsDesc, prometheus.GaugeValue, float64(count.VisitCount),
+			string(count.MappingStatus), string(count.PublishStatus))
+	}
+}
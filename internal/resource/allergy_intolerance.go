@@ -0,0 +1,104 @@
+package resource
+
+import (
+	"github.com/jasoet/fhir-worker/pkg/util"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+const kfaSystem = "http://sys-ids.kemkes.go.id/kfa"
+
+var allergyCategories = map[string]fhir.AllergyIntoleranceCategory{
+	"medication":  fhir.AllergyIntoleranceCategoryMedication,
+	"food":        fhir.AllergyIntoleranceCategoryFood,
+	"environment": fhir.AllergyIntoleranceCategoryEnvironment,
+}
+
+type AllergyIntolerance struct {
+	AllergyIntoleranceId    string `validate:"required"`
+	EncounterId             string `validate:"required"`
+	PatientSatuSehatId      string `validate:"required"`
+	PatientName             string `validate:"required"`
+	Time                    string `validate:"required"`
+	PractitionerSatuSehatId string
+	PractitionerName        string
+	Category                string `validate:"required"` // medication, food or environment
+	Code                    string // KFA for medication, SNOMED CT otherwise, the allergen is sent as text only when empty
+	Name                    string `validate:"required"`
+	Reaction                string
+}
+
+func (o *AllergyIntolerance) BundleEntry() (*fhir.BundleEntry, error) {
+	return BundleEntry(o.Resource(), o.AllergyIntoleranceId, "AllergyIntolerance")
+}
+
+func (o *AllergyIntolerance) Resource() fhir.AllergyIntolerance {
+	allergy := fhir.AllergyIntolerance{
+		ClinicalStatus: &fhir.CodeableConcept{
+			Coding: []fhir.Coding{
+				{
+					System:  util.StrPtr("http://terminology.hl7.org/CodeSystem/allergyintolerance-clinical"),
+					Code:    util.StrPtr("active"),
+					Display: util.StrPtr("Active"),
+				},
+			},
+		},
+		VerificationStatus: &fhir.CodeableConcept{
+			Coding: []fhir.Coding{
+				{
+					System:  util.StrPtr("http://terminology.hl7.org/CodeSystem/allergyintolerance-verification"),
+					Code:    util.StrPtr("confirmed"),
+					Display: util.StrPtr("Confirmed"),
+				},
+			},
+		},
+		Code: &fhir.CodeableConcept{
+			Text: util.StrPtr(o.Name),
+		},
+		Patient: fhir.Reference{
+			Reference: util.StrPtrFmt("Patient/%s", o.PatientSatuSehatId),
+			Display:   util.StrPtr(o.PatientName),
+		},
+		Encounter: &fhir.Reference{
+			Reference: util.StrPtrFmt("Encounter/%s", o.EncounterId),
+			Display:   util.StrPtrFmt("Kunjungan %s. Di tanggal %s", o.PatientName, o.Time),
+		},
+		RecordedDate: util.StrPtr(o.Time),
+	}
+
+	if category, ok := allergyCategories[o.Category]; ok {
+		allergy.Category = []fhir.AllergyIntoleranceCategory{category}
+	}
+
+	if util.StringNotEmpty(o.Code) {
+		system := snomedSystem
+		if o.Category == "medication" {
+			system = kfaSystem
+		}
+		allergy.Code.Coding = []fhir.Coding{
+			{
+				System:  util.StrPtr(system),
+				Code:    util.StrPtr(o.Code),
+				Display: util.StrPtr(o.Name),
+			},
+		}
+	}
+
+	if o.PractitionerSatuSehatId != "" {
+		allergy.Recorder = &fhir.Reference{
+			Reference: util.StrPtrFmt("Practitioner/%s", o.PractitionerSatuSehatId),
+			Display:   util.StrPtr(o.PractitionerName),
+		}
+	}
+
+	if util.StringNotEmpty(o.Reaction) {
+		allergy.Reaction = []fhir.AllergyIntoleranceReaction{
+			{
+				Manifestation: []fhir.CodeableConcept{
+					{Text: util.StrPtr(o.Reaction)},
+				},
+			},
+		}
+	}
+
+	return allergy
+}
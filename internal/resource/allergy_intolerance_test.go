@@ -0,0 +1,43 @@
+package resource
+
+import (
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestAllergyIntolerance_Resource(t *testing.T) {
+	tests := []struct {
+		name         string
+		category     string
+		code         string
+		wantCategory fhir.AllergyIntoleranceCategory
+		wantSystem   string
+	}{
+		{"Medication", "medication", "93001019", fhir.AllergyIntoleranceCategoryMedication, kfaSystem},
+		{"Food", "food", "227205009", fhir.AllergyIntoleranceCategoryFood, snomedSystem},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allergy := AllergyIntolerance{
+				Category: tt.category,
+				Code:     tt.code,
+				Name:     "Alergen",
+				Reaction: "Gatal",
+			}
+
+			resource := allergy.Resource()
+			assert.Equal(t, []fhir.AllergyIntoleranceCategory{tt.wantCategory}, resource.Category)
+			assert.Equal(t, tt.wantSystem, *resource.Code.Coding[0].System)
+			assert.Equal(t, "Gatal", *resource.Reaction[0].Manifestation[0].Text)
+		})
+	}
+
+	t.Run("TextOnly", func(t *testing.T) {
+		resource := (&AllergyIntolerance{Category: "food", Name: "Udang"}).Resource()
+		assert.Empty(t, resource.Code.Coding)
+		assert.Equal(t, "Udang", *resource.Code.Text)
+		assert.Empty(t, resource.Reaction)
+	})
+}
@@ -0,0 +1,131 @@
+package resource
+
+import (
+	"github.com/jasoet/fhir-worker/pkg/util"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// Anamnesis builds the chief complaint Condition and the physical examination Observation of a visit.
+type Anamnesis struct {
+	ConditionId             string `validate:"required"`
+	ExamObservationId       string `validate:"required"`
+	EncounterId             string `validate:"required"`
+	PatientSatuSehatId      string `validate:"required"`
+	PatientName             string `validate:"required"`
+	Time                    string `validate:"required"`
+	PractitionerSatuSehatId string
+	PractitionerName        string
+	ChiefComplaint          string `validate:"required"`
+	ChiefComplaintCode      string // SNOMED CT, the complaint is sent as text only when empty
+	ChiefComplaintDisplay   string
+	PresentIllness          string
+	PastIllness             string
+	PhysicalExam            string
+}
+
+func (o *Anamnesis) BundleEntries() ([]fhir.BundleEntry, error) {
+	condition, err := BundleEntry(o.Condition(), o.ConditionId, "Condition")
+	if err != nil {
+		return nil, err
+	}
+	entries := []fhir.BundleEntry{*condition}
+
+	if exam := o.ExamObservation(); exam != nil {
+		entry, err := exam.BundleEntry()
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, *entry)
+	}
+
+	return entries, nil
+}
+
+// Condition returns the chief complaint, the history of present and past illness are added as notes.
+func (o *Anamnesis) Condition() *fhir.Condition {
+	condition := &fhir.Condition{
+		ClinicalStatus: &fhir.CodeableConcept{
+			Coding: []fhir.Coding{
+				{
+					System:  util.StrPtr("http://terminology.hl7.org/CodeSystem/condition-clinical"),
+					Code:    util.StrPtr("active"),
+					Display: util.StrPtr("Active"),
+				},
+			},
+		},
+		Category: []fhir.CodeableConcept{
+			{
+				Coding: []fhir.Coding{
+					{
+						System:  util.StrPtr("http://terminology.hl7.org/CodeSystem/condition-category"),
+						Code:    util.StrPtr("problem-list-item"),
+						Display: util.StrPtr("Problem List Item"),
+					},
+				},
+			},
+		},
+		Code: &fhir.CodeableConcept{
+			Text: util.StrPtr(o.ChiefComplaint),
+		},
+		Subject: fhir.Reference{
+			Reference: util.StrPtrFmt("Patient/%s", o.PatientSatuSehatId),
+			Display:   util.StrPtr(o.PatientName),
+		},
+		Encounter: &fhir.Reference{
+			Reference: util.StrPtrFmt("Encounter/%s", o.EncounterId),
+			Display:   util.StrPtrFmt("Kunjungan %s. Di tanggal %s", o.PatientName, o.Time),
+		},
+		RecordedDate: util.StrPtr(o.Time),
+	}
+
+	if util.StringNotEmpty(o.ChiefComplaintCode) {
+		display := o.ChiefComplaintDisplay
+		if display == "" {
+			display = o.ChiefComplaint
+		}
+		condition.Code.Coding = []fhir.Coding{
+			{
+				System:  util.StrPtr(snomedSystem),
+				Code:    util.StrPtr(o.ChiefComplaintCode),
+				Display: util.StrPtr(display),
+			},
+		}
+	}
+
+	if o.PractitionerSatuSehatId != "" {
+		condition.Recorder = &fhir.Reference{
+			Reference: util.StrPtrFmt("Practitioner/%s", o.PractitionerSatuSehatId),
+			Display:   util.StrPtr(o.PractitionerName),
+		}
+	}
+
+	if util.StringNotEmpty(o.PresentIllness) {
+		condition.Note = append(condition.Note, fhir.Annotation{Text: "Riwayat penyakit sekarang: " + o.PresentIllness})
+	}
+	if util.StringNotEmpty(o.PastIllness) {
+		condition.Note = append(condition.Note, fhir.Annotation{Text: "Riwayat penyakit dahulu: " + o.PastIllness})
+	}
+
+	return condition
+}
+
+// ExamObservation returns the physical examination findings as narrative, or nil when none are charted.
+func (o *Anamnesis) ExamObservation() *Observation {
+	if !util.StringNotEmpty(o.PhysicalExam) {
+		return nil
+	}
+
+	return &Observation{
+		ObservationId:           o.ExamObservationId,
+		EncounterId:             o.EncounterId,
+		PatientSatuSehatId:      o.PatientSatuSehatId,
+		PatientName:             o.PatientName,
+		PractitionerSatuSehatId: o.PractitionerSatuSehatId,
+		PractitionerName:        o.PractitionerName,
+		Time:                    o.Time,
+		Category:                CategoryExam,
+		LoincCode:               "29545-1",
+		LoincDisplay:            "Physical findings Narrative",
+		ValueString:             util.StrPtr(o.PhysicalExam),
+	}
+}
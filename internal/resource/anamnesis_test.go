@@ -0,0 +1,49 @@
+package resource
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestAnamnesis_BundleEntries(t *testing.T) {
+	anamnesis := Anamnesis{
+		ConditionId:        "condition",
+		ExamObservationId:  "exam",
+		EncounterId:        "encounter",
+		PatientSatuSehatId: "P01",
+		PatientName:        "Budi",
+		Time:               "2024-01-02T08:00:00+07:00",
+		ChiefComplaint:     "Nyeri perut sejak 2 hari",
+		ChiefComplaintCode: "21522001",
+		PresentIllness:     "Mual, tidak muntah",
+		PhysicalExam:       "Nyeri tekan epigastrium",
+	}
+
+	entries, err := anamnesis.BundleEntries()
+	assert.NoError(t, err)
+	if !assert.Len(t, entries, 2) {
+		return
+	}
+	assert.Equal(t, "Condition", entries[0].Request.Url)
+	assert.Equal(t, "Observation", entries[1].Request.Url)
+
+	condition := anamnesis.Condition()
+	assert.Equal(t, "21522001", *condition.Code.Coding[0].Code)
+	assert.Equal(t, "Nyeri perut sejak 2 hari", *condition.Code.Coding[0].Display)
+	assert.Equal(t, "problem-list-item", *condition.Category[0].Coding[0].Code)
+	assert.Len(t, condition.Note, 1)
+
+	var exam map[string]any
+	assert.NoError(t, json.Unmarshal(entries[1].Resource, &exam))
+	assert.Equal(t, "Nyeri tekan epigastrium", exam["valueString"])
+
+	t.Run("TextOnly", func(t *testing.T) {
+		anamnesis := Anamnesis{ChiefComplaint: "Batuk"}
+
+		condition := anamnesis.Condition()
+		assert.Empty(t, condition.Code.Coding)
+		assert.Equal(t, "Batuk", *condition.Code.Text)
+		assert.Nil(t, anamnesis.ExamObservation())
+	})
+}
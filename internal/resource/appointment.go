@@ -0,0 +1,77 @@
+package resource
+
+import (
+	"github.com/jasoet/fhir-worker/pkg/util"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// FollowUp is the Appointment a patient is asked to come back for, at the clinic of the visit.
+type FollowUp struct {
+	AppointmentId           string `validate:"required"`
+	ServiceRequestId        string // the referral the follow-up is based on, if any
+	PatientSatuSehatId      string `validate:"required"`
+	PatientName             string `validate:"required"`
+	PractitionerSatuSehatId string `validate:"required"`
+	PractitionerName        string
+	LocationId              string `validate:"required"`
+	LocationName            string
+	Created                 string
+	Start                   string `validate:"required"`
+	Description             string
+}
+
+func (o *FollowUp) BundleEntry() (*fhir.BundleEntry, error) {
+	return BundleEntry(o.Resource(), o.AppointmentId, "Appointment")
+}
+
+func (o *FollowUp) Resource() fhir.Appointment {
+	appointment := fhir.Appointment{
+		// SIMRS only charts the day of the follow-up, without an end FHIR only allows a proposed appointment
+		Status: fhir.AppointmentStatusProposed,
+		AppointmentType: &fhir.CodeableConcept{
+			Coding: []fhir.Coding{
+				{
+					System:  util.StrPtr("http://terminology.hl7.org/CodeSystem/v2-0276"),
+					Code:    util.StrPtr("FOLLOWUP"),
+					Display: util.StrPtr("A follow up visit from a previous appointment"),
+				},
+			},
+		},
+		Start: util.StrPtr(o.Start),
+		Participant: []fhir.AppointmentParticipant{
+			{
+				Actor: &fhir.Reference{
+					Reference: util.StrPtrFmt("Patient/%s", o.PatientSatuSehatId),
+					Display:   util.StrPtr(o.PatientName),
+				},
+				Status: fhir.ParticipationStatusAccepted,
+			},
+			{
+				Actor: &fhir.Reference{
+					Reference: util.StrPtrFmt("Practitioner/%s", o.PractitionerSatuSehatId),
+					Display:   util.StrPtr(o.PractitionerName),
+				},
+				Status: fhir.ParticipationStatusAccepted,
+			},
+			{
+				Actor: &fhir.Reference{
+					Reference: util.StrPtrFmt("Location/%s", o.LocationId),
+					Display:   util.StrPtr(o.LocationName),
+				},
+				Status: fhir.ParticipationStatusAccepted,
+			},
+		},
+	}
+
+	if util.StringNotEmpty(o.Created) {
+		appointment.Created = util.StrPtr(o.Created)
+	}
+	if util.StringNotEmpty(o.Description) {
+		appointment.Description = util.StrPtr(o.Description)
+	}
+	if util.StringNotEmpty(o.ServiceRequestId) {
+		appointment.BasedOn = []fhir.Reference{{Reference: util.StrPtrFmt("urn:uuid:%s", o.ServiceRequestId)}}
+	}
+
+	return appointment
+}
@@ -0,0 +1,76 @@
+package resource
+
+import (
+	"github.com/jasoet/fhir-worker/pkg/util"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// prognoses maps the prognoses charted in SIMRS, lower cased, to SNOMED CT.
+var prognoses = map[string]fhir.Coding{
+	"baik":           {System: util.StrPtr(snomedSystem), Code: util.StrPtr("170968001"), Display: util.StrPtr("Prognosis good")},
+	"bonam":          {System: util.StrPtr(snomedSystem), Code: util.StrPtr("170968001"), Display: util.StrPtr("Prognosis good")},
+	"ad bonam":       {System: util.StrPtr(snomedSystem), Code: util.StrPtr("170968001"), Display: util.StrPtr("Prognosis good")},
+	"dubia ad bonam": {System: util.StrPtr(snomedSystem), Code: util.StrPtr("65872000"), Display: util.StrPtr("Fair prognosis")},
+	"sedang":         {System: util.StrPtr(snomedSystem), Code: util.StrPtr("65872000"), Display: util.StrPtr("Fair prognosis")},
+	"dubia ad malam": {System: util.StrPtr(snomedSystem), Code: util.StrPtr("67334001"), Display: util.StrPtr("Guarded prognosis")},
+	"buruk":          {System: util.StrPtr(snomedSystem), Code: util.StrPtr("170969009"), Display: util.StrPtr("Prognosis bad")},
+	"malam":          {System: util.StrPtr(snomedSystem), Code: util.StrPtr("170969009"), Display: util.StrPtr("Prognosis bad")},
+	"ad malam":       {System: util.StrPtr(snomedSystem), Code: util.StrPtr("170969009"), Display: util.StrPtr("Prognosis bad")},
+}
+
+// ClinicalImpression is the assessment of the practitioner, the diagnoses of the encounter are referenced as findings.
+type ClinicalImpression struct {
+	ClinicalImpressionId    string `validate:"required"`
+	EncounterId             string `validate:"required"`
+	PatientSatuSehatId      string `validate:"required"`
+	PatientName             string `validate:"required"`
+	Time                    string `validate:"required"`
+	PractitionerSatuSehatId string
+	PractitionerName        string
+	Summary                 string `validate:"required"`
+	Prognosis               string // sent as text when not a known prognosis
+	Findings                []EncounterDiagnosis
+}
+
+func (o *ClinicalImpression) BundleEntry() (*fhir.BundleEntry, error) {
+	return BundleEntry(o.Resource(), o.ClinicalImpressionId, "ClinicalImpression")
+}
+
+func (o *ClinicalImpression) Resource() fhir.ClinicalImpression {
+	impression := fhir.ClinicalImpression{
+		Status: fhir.ClinicalImpressionStatusCompleted,
+		Subject: fhir.Reference{
+			Reference: util.StrPtrFmt("Patient/%s", o.PatientSatuSehatId),
+			Display:   util.StrPtr(o.PatientName),
+		},
+		Encounter: &fhir.Reference{
+			Reference: util.StrPtrFmt("Encounter/%s", o.EncounterId),
+			Display:   util.StrPtrFmt("Kunjungan %s. Di tanggal %s", o.PatientName, o.Time),
+		},
+		EffectiveDateTime: util.StrPtr(o.Time),
+		Date:              util.StrPtr(o.Time),
+		Summary:           util.StrPtr(o.Summary),
+	}
+
+	if o.PractitionerSatuSehatId != "" {
+		impression.Assessor = &fhir.Reference{
+			Reference: util.StrPtrFmt("Practitioner/%s", o.PractitionerSatuSehatId),
+			Display:   util.StrPtr(o.PractitionerName),
+		}
+	}
+
+	for _, finding := range o.Findings {
+		impression.Finding = append(impression.Finding, fhir.ClinicalImpressionFinding{
+			ItemReference: &fhir.Reference{
+				Reference: util.StrPtrFmt("urn:uuid:%s", finding.Id),
+				Display:   util.StrPtr(finding.Display),
+			},
+		})
+	}
+
+	if prognosis := codeableConcept(prognoses, o.Prognosis); prognosis != nil {
+		impression.PrognosisCodeableConcept = []fhir.CodeableConcept{*prognosis}
+	}
+
+	return impression
+}
@@ -0,0 +1,40 @@
+package resource
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestClinicalImpression_Resource(t *testing.T) {
+	impression := ClinicalImpression{
+		Summary:   "Dispepsia, membaik",
+		Prognosis: "Dubia  ad Bonam",
+		Findings:  []EncounterDiagnosis{{Id: "condition", Display: "Dyspepsia"}},
+	}
+
+	resource := impression.Resource()
+	assert.Equal(t, "Dispepsia, membaik", *resource.Summary)
+	assert.Equal(t, "urn:uuid:condition", *resource.Finding[0].ItemReference.Reference)
+	assert.Equal(t, "65872000", *resource.PrognosisCodeableConcept[0].Coding[0].Code)
+
+	t.Run("UnknownPrognosis", func(t *testing.T) {
+		resource := (&ClinicalImpression{Prognosis: "tergantung kepatuhan"}).Resource()
+		assert.Equal(t, "tergantung kepatuhan", *resource.PrognosisCodeableConcept[0].Text)
+	})
+}
+
+func TestComposition_Resource(t *testing.T) {
+	composition := Composition{
+		Diet:     "Rendah garam",
+		FollowUp: "Kontrol 1 minggu\nBawa hasil lab <HbA1c>",
+	}
+	assert.False(t, composition.Empty())
+
+	resource := composition.Resource()
+	if assert.Len(t, resource.Section, 2) {
+		assert.Equal(t, "61144-2", *resource.Section[0].Code.Coding[0].Code)
+		assert.Equal(t, `<div xmlns="http://www.w3.org/1999/xhtml">Kontrol 1 minggu<br/>Bawa hasil lab &lt;HbA1c&gt;</div>`, resource.Section[1].Text.Div)
+	}
+
+	assert.True(t, (&Composition{Plan: " "}).Empty())
+}
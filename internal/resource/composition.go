@@ -0,0 +1,121 @@
+package resource
+
+import (
+	"fmt"
+	"github.com/jasoet/fhir-worker/pkg/util"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+	"html"
+	"strings"
+)
+
+// Composition is the care plan note of a visit, every instruction charted in SIMRS becomes one section.
+type Composition struct {
+	CompositionId           string `validate:"required"`
+	EncounterId             string `validate:"required"`
+	PatientSatuSehatId      string `validate:"required"`
+	PatientName             string `validate:"required"`
+	Time                    string `validate:"required"`
+	PractitionerSatuSehatId string `validate:"required"`
+	PractitionerName        string
+	Plan                    string
+	Diet                    string
+	Education               string
+	FollowUp                string
+}
+
+// compositionSection describes one section of Composition by its LOINC code.
+type compositionSection struct {
+	text         string
+	title        string
+	loincCode    string
+	loincDisplay string
+}
+
+func (o *Composition) sections() []compositionSection {
+	return []compositionSection{
+		{o.Plan, "Rencana Tata Laksana", "18776-5", "Plan of care note"},
+		{o.Diet, "Diet", "61144-2", "Diet and nutrition"},
+		{o.Education, "Edukasi", "34895-3", "Education note"},
+		{o.FollowUp, "Instruksi Tindak Lanjut", "69730-0", "Instructions"},
+	}
+}
+
+// Empty reports whether no section is charted, an empty Composition is left out of the bundle.
+func (o *Composition) Empty() bool {
+	for _, section := range o.sections() {
+		if util.StringNotEmpty(section.text) {
+			return false
+		}
+	}
+	return true
+}
+
+func (o *Composition) BundleEntry() (*fhir.BundleEntry, error) {
+	return BundleEntry(o.Resource(), o.CompositionId, "Composition")
+}
+
+func (o *Composition) Resource() fhir.Composition {
+	composition := fhir.Composition{
+		Status: fhir.CompositionStatusFinal,
+		Type: fhir.CodeableConcept{
+			Coding: []fhir.Coding{
+				{
+					System:  util.StrPtr("http://loinc.org"),
+					Code:    util.StrPtr("11506-3"),
+					Display: util.StrPtr("Progress note"),
+				},
+			},
+		},
+		Subject: &fhir.Reference{
+			Reference: util.StrPtrFmt("Patient/%s", o.PatientSatuSehatId),
+			Display:   util.StrPtr(o.PatientName),
+		},
+		Encounter: &fhir.Reference{
+			Reference: util.StrPtrFmt("Encounter/%s", o.EncounterId),
+			Display:   util.StrPtrFmt("Kunjungan %s. Di tanggal %s", o.PatientName, o.Time),
+		},
+		Date: o.Time,
+		Author: []fhir.Reference{
+			{
+				Reference: util.StrPtrFmt("Practitioner/%s", o.PractitionerSatuSehatId),
+				Display:   util.StrPtr(o.PractitionerName),
+			},
+		},
+		Title: "Rencana Perawatan",
+	}
+
+	for _, section := range o.sections() {
+		if !util.StringNotEmpty(section.text) {
+			continue
+		}
+
+		composition.Section = append(composition.Section, fhir.CompositionSection{
+			Title: util.StrPtr(section.title),
+			Code: &fhir.CodeableConcept{
+				Coding: []fhir.Coding{
+					{
+						System:  util.StrPtr("http://loinc.org"),
+						Code:    util.StrPtr(section.loincCode),
+						Display: util.StrPtr(section.loincDisplay),
+					},
+				},
+			},
+			Text: narrative(section.text),
+		})
+	}
+
+	return composition
+}
+
+// narrative returns text as generated XHTML, line breaks are kept.
+func narrative(text string) *fhir.Narrative {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	for i := range lines {
+		lines[i] = html.EscapeString(strings.TrimSpace(lines[i]))
+	}
+
+	return &fhir.Narrative{
+		Status: fhir.NarrativeStatusGenerated,
+		Div:    fmt.Sprintf(`<div xmlns="http://www.w3.org/1999/xhtml">%s</div>`, strings.Join(lines, "<br/>")),
+	}
+}
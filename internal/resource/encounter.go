@@ -5,6 +5,24 @@ import (
 	"github.com/samply/golang-fhir-models/fhir-models/fhir"
 )
 
+const dischargeDispositionSystem = "http://terminology.hl7.org/CodeSystem/discharge-disposition"
+
+// dischargeDispositions maps the ways of discharge charted in SIMRS, lower cased, to HL7 discharge dispositions.
+var dischargeDispositions = map[string]fhir.Coding{
+	"pulang":                  {System: util.StrPtr(dischargeDispositionSystem), Code: util.StrPtr("home"), Display: util.StrPtr("Home")},
+	"dipulangkan":             {System: util.StrPtr(dischargeDispositionSystem), Code: util.StrPtr("home"), Display: util.StrPtr("Home")},
+	"sembuh":                  {System: util.StrPtr(dischargeDispositionSystem), Code: util.StrPtr("home"), Display: util.StrPtr("Home")},
+	"atas izin dokter":        {System: util.StrPtr(dischargeDispositionSystem), Code: util.StrPtr("home"), Display: util.StrPtr("Home")},
+	"rujuk":                   {System: util.StrPtr(dischargeDispositionSystem), Code: util.StrPtr("other-hcf"), Display: util.StrPtr("Other healthcare facility")},
+	"dirujuk":                 {System: util.StrPtr(dischargeDispositionSystem), Code: util.StrPtr("other-hcf"), Display: util.StrPtr("Other healthcare facility")},
+	"rujuk keluar":            {System: util.StrPtr(dischargeDispositionSystem), Code: util.StrPtr("other-hcf"), Display: util.StrPtr("Other healthcare facility")},
+	"meninggal":               {System: util.StrPtr(dischargeDispositionSystem), Code: util.StrPtr("exp"), Display: util.StrPtr("Expired")},
+	"meninggal dunia":         {System: util.StrPtr(dischargeDispositionSystem), Code: util.StrPtr("exp"), Display: util.StrPtr("Expired")},
+	"pulang paksa":            {System: util.StrPtr(dischargeDispositionSystem), Code: util.StrPtr("aadvice"), Display: util.StrPtr("Left against advice")},
+	"aps":                     {System: util.StrPtr(dischargeDispositionSystem), Code: util.StrPtr("aadvice"), Display: util.StrPtr("Left against advice")},
+	"atas permintaan sendiri": {System: util.StrPtr(dischargeDispositionSystem), Code: util.StrPtr("aadvice"), Display: util.StrPtr("Left against advice")},
+}
+
 type EncounterDiagnosis struct {
 	Id      string
 	Display string
@@ -28,6 +46,7 @@ type Encounter struct {
 	FinishStartTime         string `validate:"required"`
 	FinishEndTime           string `validate:"required"`
 	Diagnosis               []EncounterDiagnosis
+	DischargeDisposition    string // sent as text when not a known disposition
 }
 
 func (o *Encounter) BundleEntry() (*fhir.BundleEntry, error) {
@@ -135,6 +154,10 @@ func (o *Encounter) Resource() fhir.Encounter {
 
 	encounter.Diagnosis = encounterDiagnosis
 
+	if disposition := codeableConcept(dischargeDispositions, o.DischargeDisposition); disposition != nil {
+		encounter.Hospitalization = &fhir.EncounterHospitalization{DischargeDisposition: disposition}
+	}
+
 	return encounter
 
 }
@@ -0,0 +1,16 @@
+package resource
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestEncounter_Resource_DischargeDisposition(t *testing.T) {
+	resource := (&Encounter{DischargeDisposition: "Rujuk  Keluar"}).Resource()
+	assert.Equal(t, "other-hcf", *resource.Hospitalization.DischargeDisposition.Coding[0].Code)
+
+	resource = (&Encounter{DischargeDisposition: "rawat inap"}).Resource()
+	assert.Equal(t, "rawat inap", *resource.Hospitalization.DischargeDisposition.Text)
+
+	assert.Nil(t, (&Encounter{}).Resource().Hospitalization)
+}
@@ -0,0 +1,123 @@
+package resource
+
+import (
+	"github.com/jasoet/fhir-worker/pkg/util"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+const (
+	routeSystem            = "http://www.whocc.no/atc"
+	immunizationSiteSystem = "http://terminology.hl7.org/CodeSystem/v3-ActSite"
+)
+
+// administrationRoutes maps the routes of immunizations and medications charted in SIMRS, lower cased, to the route codes used by SatuSehat.
+var administrationRoutes = map[string]fhir.Coding{
+	"im":            {System: util.StrPtr(routeSystem), Code: util.StrPtr("inj.intramuscular"), Display: util.StrPtr("Injection Intramuscular")},
+	"intramuskular": {System: util.StrPtr(routeSystem), Code: util.StrPtr("inj.intramuscular"), Display: util.StrPtr("Injection Intramuscular")},
+	"intramuscular": {System: util.StrPtr(routeSystem), Code: util.StrPtr("inj.intramuscular"), Display: util.StrPtr("Injection Intramuscular")},
+	"sc":            {System: util.StrPtr(routeSystem), Code: util.StrPtr("inj.subcutaneous"), Display: util.StrPtr("Injection Subcutaneous")},
+	"subkutan":      {System: util.StrPtr(routeSystem), Code: util.StrPtr("inj.subcutaneous"), Display: util.StrPtr("Injection Subcutaneous")},
+	"subcutaneous":  {System: util.StrPtr(routeSystem), Code: util.StrPtr("inj.subcutaneous"), Display: util.StrPtr("Injection Subcutaneous")},
+	"ic":            {System: util.StrPtr(routeSystem), Code: util.StrPtr("inj.intradermal"), Display: util.StrPtr("Injection Intradermal")},
+	"id":            {System: util.StrPtr(routeSystem), Code: util.StrPtr("inj.intradermal"), Display: util.StrPtr("Injection Intradermal")},
+	"intrakutan":    {System: util.StrPtr(routeSystem), Code: util.StrPtr("inj.intradermal"), Display: util.StrPtr("Injection Intradermal")},
+	"intradermal":   {System: util.StrPtr(routeSystem), Code: util.StrPtr("inj.intradermal"), Display: util.StrPtr("Injection Intradermal")},
+	"oral":          {System: util.StrPtr(routeSystem), Code: util.StrPtr("O"), Display: util.StrPtr("Oral")},
+	"po":            {System: util.StrPtr(routeSystem), Code: util.StrPtr("O"), Display: util.StrPtr("Oral")},
+	"per oral":      {System: util.StrPtr(routeSystem), Code: util.StrPtr("O"), Display: util.StrPtr("Oral")},
+	"iv":            {System: util.StrPtr(routeSystem), Code: util.StrPtr("inj.intravenous"), Display: util.StrPtr("Injection Intravenous")},
+	"intravena":     {System: util.StrPtr(routeSystem), Code: util.StrPtr("inj.intravenous"), Display: util.StrPtr("Injection Intravenous")},
+	"intravenous":   {System: util.StrPtr(routeSystem), Code: util.StrPtr("inj.intravenous"), Display: util.StrPtr("Injection Intravenous")},
+	"rektal":        {System: util.StrPtr(routeSystem), Code: util.StrPtr("R"), Display: util.StrPtr("Rectal")},
+	"sublingual":    {System: util.StrPtr(routeSystem), Code: util.StrPtr("SL"), Display: util.StrPtr("Sublingual")},
+}
+
+// immunizationSites maps the injection sites charted in SIMRS, lower cased, to HL7 act sites.
+var immunizationSites = map[string]fhir.Coding{
+	"lengan kiri":   {System: util.StrPtr(immunizationSiteSystem), Code: util.StrPtr("LA"), Display: util.StrPtr("Left arm")},
+	"lengan kanan":  {System: util.StrPtr(immunizationSiteSystem), Code: util.StrPtr("RA"), Display: util.StrPtr("Right arm")},
+	"paha kiri":     {System: util.StrPtr(immunizationSiteSystem), Code: util.StrPtr("LT"), Display: util.StrPtr("Left thigh")},
+	"paha kanan":    {System: util.StrPtr(immunizationSiteSystem), Code: util.StrPtr("RT"), Display: util.StrPtr("Right thigh")},
+	"deltoid kiri":  {System: util.StrPtr(immunizationSiteSystem), Code: util.StrPtr("LD"), Display: util.StrPtr("Left deltoid")},
+	"deltoid kanan": {System: util.StrPtr(immunizationSiteSystem), Code: util.StrPtr("RD"), Display: util.StrPtr("Right deltoid")},
+}
+
+type Immunization struct {
+	ImmunizationId     string `validate:"required"`
+	EncounterId        string `validate:"required"`
+	PatientSatuSehatId string `validate:"required"`
+	PatientName        string `validate:"required"`
+	Time               string `validate:"required"`
+	PractitionerId     string `validate:"required"`
+	PractitionerName   string `validate:"required"`
+	KfaCode            string `validate:"required"`
+	KfaDisplay         string `validate:"required"`
+	LotNumber          string `validate:"required"`
+	ExpirationDate     string
+	DoseNumber         int    `validate:"required"`
+	Site               string // sent as text when not a known site
+	Route              string // sent as text when not a known route
+}
+
+func (o *Immunization) BundleEntry() (*fhir.BundleEntry, error) {
+	return BundleEntry(o.Resource(), o.ImmunizationId, "Immunization",
+		WithRemoveKey("occurrenceString"),
+		WithRemoveItemKey("protocolApplied", "doseNumberString"),
+	)
+}
+
+func (o *Immunization) Resource() fhir.Immunization {
+	primarySource := true
+	immunization := fhir.Immunization{
+		Status: fhir.ImmunizationStatusCodesCompleted,
+		VaccineCode: fhir.CodeableConcept{
+			Coding: []fhir.Coding{
+				{
+					System:  util.StrPtr(kfaSystem),
+					Code:    util.StrPtr(o.KfaCode),
+					Display: util.StrPtr(o.KfaDisplay),
+				},
+			},
+		},
+		Patient: fhir.Reference{
+			Reference: util.StrPtrFmt("Patient/%s", o.PatientSatuSehatId),
+			Display:   util.StrPtr(o.PatientName),
+		},
+		Encounter: &fhir.Reference{
+			Reference: util.StrPtrFmt("Encounter/%s", o.EncounterId),
+			Display:   util.StrPtrFmt("Kunjungan %s. Di tanggal %s", o.PatientName, o.Time),
+		},
+		OccurrenceDateTime: o.Time,
+		Recorded:           util.StrPtr(o.Time),
+		PrimarySource:      &primarySource,
+		LotNumber:          util.StrPtr(o.LotNumber),
+		Site:               codeableConcept(immunizationSites, o.Site),
+		Route:              codeableConcept(administrationRoutes, o.Route),
+		Performer: []fhir.ImmunizationPerformer{
+			{
+				Function: &fhir.CodeableConcept{
+					Coding: []fhir.Coding{
+						{
+							System:  util.StrPtr("http://terminology.hl7.org/CodeSystem/v2-0443"),
+							Code:    util.StrPtr("AP"),
+							Display: util.StrPtr("Administering Provider"),
+						},
+					},
+				},
+				Actor: fhir.Reference{
+					Reference: util.StrPtrFmt("Practitioner/%s", o.PractitionerId),
+					Display:   util.StrPtr(o.PractitionerName),
+				},
+			},
+		},
+		ProtocolApplied: []fhir.ImmunizationProtocolApplied{
+			{DoseNumberPositiveInt: o.DoseNumber},
+		},
+	}
+
+	if o.ExpirationDate != "" {
+		immunization.ExpirationDate = util.StrPtr(o.ExpirationDate)
+	}
+
+	return immunization
+}
@@ -0,0 +1,40 @@
+package resource
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestImmunization_BundleEntry(t *testing.T) {
+	immunization := Immunization{
+		ImmunizationId:     "immunization",
+		EncounterId:        "encounter",
+		PatientSatuSehatId: "P01",
+		PatientName:        "Budi",
+		Time:               "2024-01-02T08:00:00+07:00",
+		PractitionerId:     "N01",
+		PractitionerName:   "Bidan Ani",
+		KfaCode:            "93001019",
+		KfaDisplay:         "Vaksin DPT-HB-Hib",
+		LotNumber:          "LOT123",
+		ExpirationDate:     "2025-06-30",
+		DoseNumber:         2,
+		Site:               "Paha  Kiri",
+		Route:              "suntik",
+	}
+
+	entry, err := immunization.BundleEntry()
+	assert.NoError(t, err)
+
+	var resource map[string]any
+	assert.NoError(t, json.Unmarshal(entry.Resource, &resource))
+	assert.Equal(t, "completed", resource["status"])
+	assert.Equal(t, "2024-01-02T08:00:00+07:00", resource["occurrenceDateTime"])
+	assert.NotContains(t, resource, "occurrenceString")
+	assert.Equal(t, []any{map[string]any{"doseNumberPositiveInt": float64(2)}}, resource["protocolApplied"])
+
+	site := resource["site"].(map[string]any)["coding"].([]any)[0].(map[string]any)
+	assert.Equal(t, "LT", site["code"])
+	assert.Equal(t, map[string]any{"text": "suntik"}, resource["route"])
+}
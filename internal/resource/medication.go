@@ -0,0 +1,164 @@
+package resource
+
+import (
+	"encoding/json"
+	"github.com/jasoet/fhir-worker/pkg/ucum"
+	"github.com/jasoet/fhir-worker/pkg/util"
+	"github.com/jasoet/fhir-worker/shared/model"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const drugFormSystem = "http://terminology.hl7.org/CodeSystem/v3-orderableDrugForm"
+
+// drugForms maps the dosage forms and dispense units charted in SIMRS, lower cased, to HL7 orderable drug forms.
+var drugForms = map[string]fhir.Coding{
+	"tablet":      {System: util.StrPtr(drugFormSystem), Code: util.StrPtr("TAB"), Display: util.StrPtr("Tablet")},
+	"tab":         {System: util.StrPtr(drugFormSystem), Code: util.StrPtr("TAB"), Display: util.StrPtr("Tablet")},
+	"kaplet":      {System: util.StrPtr(drugFormSystem), Code: util.StrPtr("TAB"), Display: util.StrPtr("Tablet")},
+	"kapsul":      {System: util.StrPtr(drugFormSystem), Code: util.StrPtr("CAP"), Display: util.StrPtr("Capsule")},
+	"kaps":        {System: util.StrPtr(drugFormSystem), Code: util.StrPtr("CAP"), Display: util.StrPtr("Capsule")},
+	"caps":        {System: util.StrPtr(drugFormSystem), Code: util.StrPtr("CAP"), Display: util.StrPtr("Capsule")},
+	"puyer":       {System: util.StrPtr(drugFormSystem), Code: util.StrPtr("POWD"), Display: util.StrPtr("Powder")},
+	"pulv":        {System: util.StrPtr(drugFormSystem), Code: util.StrPtr("POWD"), Display: util.StrPtr("Powder")},
+	"serbuk":      {System: util.StrPtr(drugFormSystem), Code: util.StrPtr("POWD"), Display: util.StrPtr("Powder")},
+	"bungkus":     {System: util.StrPtr(drugFormSystem), Code: util.StrPtr("POWD"), Display: util.StrPtr("Powder")},
+	"sirup":       {System: util.StrPtr(drugFormSystem), Code: util.StrPtr("SYRUP"), Display: util.StrPtr("Syrup")},
+	"syr":         {System: util.StrPtr(drugFormSystem), Code: util.StrPtr("SYRUP"), Display: util.StrPtr("Syrup")},
+	"suspensi":    {System: util.StrPtr(drugFormSystem), Code: util.StrPtr("SUSP"), Display: util.StrPtr("Suspension")},
+	"salep":       {System: util.StrPtr(drugFormSystem), Code: util.StrPtr("OINT"), Display: util.StrPtr("Ointment")},
+	"krim":        {System: util.StrPtr(drugFormSystem), Code: util.StrPtr("CRM"), Display: util.StrPtr("Cream")},
+	"injeksi":     {System: util.StrPtr(drugFormSystem), Code: util.StrPtr("INJ"), Display: util.StrPtr("Injection")},
+	"ampul":       {System: util.StrPtr(drugFormSystem), Code: util.StrPtr("INJ"), Display: util.StrPtr("Injection")},
+	"vial":        {System: util.StrPtr(drugFormSystem), Code: util.StrPtr("INJ"), Display: util.StrPtr("Injection")},
+	"tetes":       {System: util.StrPtr(drugFormSystem), Code: util.StrPtr("DROP"), Display: util.StrPtr("Drops")},
+	"supositoria": {System: util.StrPtr(drugFormSystem), Code: util.StrPtr("SUPP"), Display: util.StrPtr("Suppository")},
+	"gel":         {System: util.StrPtr(drugFormSystem), Code: util.StrPtr("GEL"), Display: util.StrPtr("Gel")},
+}
+
+// usagePattern matches the times per day and the amount per administration of an aturan pakai, e.g. "3x1" or "2 x 1/2".
+var usagePattern = regexp.MustCompile(`(?i)(\d+)\s*[x×]\s*(\d+(?:[.,]\d+)?(?:/\d+)?)`)
+
+// Dosage is how a medication is to be taken as charted in SIMRS.
+type Dosage struct {
+	Text   string // aturan pakai, e.g. "3x1 sesudah makan"
+	Route  string // sent as text when not a known route
+	Amount float64
+	Unit   string
+}
+
+// Resource returns the dosage instruction, the frequency and the amount when not charted are read from Text.
+// Nil when nothing is charted.
+func (o Dosage) Resource() *fhir.Dosage {
+	if !util.StringNotEmpty(o.Text) && !util.StringNotEmpty(o.Route) && o.Amount <= 0 {
+		return nil
+	}
+
+	sequence := 1
+	dosage := &fhir.Dosage{
+		Sequence: &sequence,
+		Route:    codeableConcept(administrationRoutes, o.Route),
+	}
+
+	if util.StringNotEmpty(o.Text) {
+		dosage.Text = util.StrPtr(strings.TrimSpace(o.Text))
+	}
+
+	amount := o.Amount
+	if match := usagePattern.FindStringSubmatch(o.Text); match != nil {
+		frequency, _ := strconv.Atoi(match[1])
+		period := json.Number("1")
+		dosage.Timing = &fhir.Timing{
+			Repeat: &fhir.TimingRepeat{
+				Frequency:  &frequency,
+				Period:     &period,
+				PeriodUnit: util.StrPtr("d"),
+			},
+		}
+
+		if amount <= 0 {
+			amount = fraction(match[2])
+		}
+	}
+
+	if quantity := medicationQuantity(amount, o.Unit); quantity != nil {
+		dosage.DoseAndRate = []fhir.DosageDoseAndRate{{DoseQuantity: quantity}}
+	}
+
+	return dosage
+}
+
+// fraction returns the value of "1", "0,5" or "1/2", zero when malformed.
+func fraction(value string) float64 {
+	numerator, denominator, found := strings.Cut(strings.Replace(value, ",", ".", 1), "/")
+	result, err := strconv.ParseFloat(numerator, 64)
+	if err != nil {
+		return 0
+	}
+
+	if found {
+		divisor, err := strconv.ParseFloat(denominator, 64)
+		if err != nil || divisor == 0 {
+			return 0
+		}
+		result /= divisor
+	}
+	return result
+}
+
+// medicationQuantity returns amount in a drug form, or a UCUM unit for volumes and weights, the unit is sent as text otherwise.
+// Nil when amount isn't positive.
+func medicationQuantity(amount float64, unit string) *fhir.Quantity {
+	if amount <= 0 {
+		return nil
+	}
+
+	value := json.Number(strconv.FormatFloat(amount, 'f', -1, 64))
+	quantity := &fhir.Quantity{Value: &value}
+
+	if coding, ok := drugForms[strings.ToLower(strings.Join(strings.Fields(unit), " "))]; ok {
+		quantity.Unit = coding.Display
+		quantity.System = coding.System
+		quantity.Code = coding.Code
+	} else if found, ok := ucum.LookupUnit(unit); ok {
+		quantity.Unit = util.StrPtr(found.Display)
+		quantity.System = util.StrPtr(ucum.System)
+		quantity.Code = util.StrPtr(found.Code)
+	} else if util.StringNotEmpty(unit) {
+		quantity.Unit = util.StrPtr(strings.TrimSpace(unit))
+	}
+
+	return quantity
+}
+
+// medicationIngredients returns the ingredients of a compound medication with their strength per one unit of form.
+func medicationIngredients(ingredients []model.MedicationIngredient, form string) []fhir.MedicationIngredient {
+	var result []fhir.MedicationIngredient
+	for _, ingredient := range ingredients {
+		isActive := true
+		item := fhir.MedicationIngredient{
+			ItemCodeableConcept: fhir.CodeableConcept{
+				Coding: []fhir.Coding{
+					{
+						System:  util.StrPtr(kfaSystem),
+						Code:    ingredient.KfaCode,
+						Display: ingredient.KfaName,
+					},
+				},
+			},
+			IsActive: &isActive,
+		}
+
+		if numerator := medicationQuantity(ingredient.Strength, ingredient.StrengthUnit); numerator != nil {
+			item.Strength = &fhir.Ratio{
+				Numerator:   numerator,
+				Denominator: medicationQuantity(1, form),
+			}
+		}
+
+		result = append(result, item)
+	}
+	return result
+}
@@ -24,6 +24,11 @@ type MedicationDispense struct {
 	HandoverDate         string             `validate:"required"`
 	BatchNumber          string             `validate:"required"`
 	ExpirationDate       string             `validate:"required"`
+	Form                 string
+	Ingredients          []model.MedicationIngredient // compound medication only
+	Quantity             float64
+	QuantityUnit         string
+	Dosage               Dosage
 }
 
 func (o *MedicationDispense) PatientTypeCoding() fhir.Coding {
@@ -87,7 +92,9 @@ func (o *MedicationDispense) Resources() (*fhir.Medication, *fhir.MedicationDisp
 			LotNumber:      util.StrPtr(o.BatchNumber),
 			ExpirationDate: util.StrPtr(o.ExpirationDate),
 		},
-		Status: util.StrPtr("active"),
+		Status:     util.StrPtr("active"),
+		Form:       codeableConcept(drugForms, o.Form),
+		Ingredient: medicationIngredients(o.Ingredients, o.Form),
 		Extension: []fhir.Extension{
 			{
 				Url: "https://fhir.kemkes.go.id/r4/StructureDefinition/MedicationType",
@@ -135,6 +142,11 @@ func (o *MedicationDispense) Resources() (*fhir.Medication, *fhir.MedicationDisp
 		},
 		WhenPrepared:   util.StrPtr(o.PreparedDate),
 		WhenHandedOver: util.StrPtr(o.HandoverDate),
+		Quantity:       medicationQuantity(o.Quantity, o.QuantityUnit),
+	}
+
+	if dosage := o.Dosage.Resource(); dosage != nil {
+		medicationDispense.DosageInstruction = []fhir.Dosage{*dosage}
 	}
 
 	return medication, medicationDispense
@@ -144,7 +156,7 @@ func (o *MedicationDispense) BundleEntries() ([]fhir.BundleEntry, error) {
 	var result []fhir.BundleEntry
 	medication, medicationDispense := o.Resources()
 
-	medicationEntry, err := BundleEntry(medication, o.MedicationId, "Medication")
+	medicationEntry, err := BundleEntry(medication, o.MedicationId, "Medication", WithRemoveItemKey("ingredient", "itemReference"))
 	if err != nil {
 		return nil, err
 	}
@@ -21,6 +21,11 @@ type MedicationRequest struct {
 	PractitionerId      string             `validate:"required"`
 	PractitionerName    string             `validate:"required"`
 	Date                string             `validate:"required"`
+	Form                string
+	Ingredients         []model.MedicationIngredient // compound medication only
+	Quantity            float64
+	QuantityUnit        string
+	Dosage              Dosage
 }
 
 func (o *MedicationRequest) PatientTypeCoding() fhir.Coding {
@@ -82,7 +87,9 @@ func (o *MedicationRequest) Resources() (*fhir.Medication, *fhir.MedicationReque
 				},
 			},
 		},
-		Status: util.StrPtr("active"),
+		Status:     util.StrPtr("active"),
+		Form:       codeableConcept(drugForms, o.Form),
+		Ingredient: medicationIngredients(o.Ingredients, o.Form),
 		Extension: []fhir.Extension{
 			{
 				Url: "https://fhir.kemkes.go.id/r4/StructureDefinition/MedicationType",
@@ -122,6 +129,7 @@ func (o *MedicationRequest) Resources() (*fhir.Medication, *fhir.MedicationReque
 			Display:   util.StrPtr(o.PatientName),
 		},
 		DispenseRequest: &fhir.MedicationRequestDispenseRequest{
+			Quantity: medicationQuantity(o.Quantity, o.QuantityUnit),
 			Performer: &fhir.Reference{
 				Reference: util.StrPtrFmt("Organization/%s", o.OrganizationId),
 			},
@@ -136,6 +144,10 @@ func (o *MedicationRequest) Resources() (*fhir.Medication, *fhir.MedicationReque
 		},
 	}
 
+	if dosage := o.Dosage.Resource(); dosage != nil {
+		medicationRequest.DosageInstruction = []fhir.Dosage{*dosage}
+	}
+
 	return medication, medicationRequest
 }
 
@@ -143,7 +155,7 @@ func (o *MedicationRequest) BundleEntries() ([]fhir.BundleEntry, error) {
 	var result []fhir.BundleEntry
 	medication, medicationRequest := o.Resources()
 
-	medicationEntry, err := BundleEntry(medication, o.MedicationId, "Medication")
+	medicationEntry, err := BundleEntry(medication, o.MedicationId, "Medication", WithRemoveItemKey("ingredient", "itemReference"))
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,71 @@
+package resource
+
+import (
+	"encoding/json"
+	"github.com/jasoet/fhir-worker/pkg/util"
+	"github.com/jasoet/fhir-worker/shared/model"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDosage_Resource(t *testing.T) {
+	dosage := Dosage{Text: "3 x 1/2 sesudah makan", Route: "Oral", Unit: "tablet"}.Resource()
+
+	assert.Equal(t, "3 x 1/2 sesudah makan", *dosage.Text)
+	assert.Equal(t, 3, *dosage.Timing.Repeat.Frequency)
+	assert.Equal(t, "d", *dosage.Timing.Repeat.PeriodUnit)
+	assert.Equal(t, "O", *dosage.Route.Coding[0].Code)
+	assert.Equal(t, json.Number("0.5"), *dosage.DoseAndRate[0].DoseQuantity.Value)
+	assert.Equal(t, "TAB", *dosage.DoseAndRate[0].DoseQuantity.Code)
+
+	t.Run("ChartedAmount", func(t *testing.T) {
+		dosage := Dosage{Text: "2x1", Amount: 5, Unit: "ml"}.Resource()
+		assert.Equal(t, json.Number("5"), *dosage.DoseAndRate[0].DoseQuantity.Value)
+		assert.Equal(t, "mL", *dosage.DoseAndRate[0].DoseQuantity.Code)
+	})
+
+	t.Run("TextOnly", func(t *testing.T) {
+		dosage := Dosage{Text: "oleskan tipis"}.Resource()
+		assert.Nil(t, dosage.Timing)
+		assert.Nil(t, dosage.DoseAndRate)
+	})
+
+	t.Run("DropsRoute", func(t *testing.T) {
+		// tetes is a dosage form, eye, ear and oral drops share it so the route is sent as charted.
+		dosage := Dosage{Text: "3x2 tetes", Route: "Tetes"}.Resource()
+		assert.Nil(t, dosage.Route.Coding)
+		assert.Equal(t, "Tetes", *dosage.Route.Text)
+	})
+
+	assert.Nil(t, Dosage{}.Resource())
+}
+
+func TestMedicationRequest_Resources_Compound(t *testing.T) {
+	request := MedicationRequest{
+		MedicationId: "medication",
+		KfaCode:      "93000001",
+		Type:         model.Compound,
+		Form:         "Puyer",
+		Ingredients: []model.MedicationIngredient{
+			{KfaCode: util.StrPtr("93001019"), KfaName: util.StrPtr("Paracetamol 500 mg"), Strength: 250, StrengthUnit: "mg"},
+			{KfaCode: util.StrPtr("93002290"), KfaName: util.StrPtr("CTM 4 mg")},
+		},
+		Quantity:     10,
+		QuantityUnit: "bungkus",
+		Dosage:       Dosage{Text: "3x1"},
+	}
+
+	medication, medicationRequest := request.Resources()
+	assert.Equal(t, "POWD", *medication.Form.Coding[0].Code)
+	if assert.Len(t, medication.Ingredient, 2) {
+		assert.Equal(t, json.Number("250"), *medication.Ingredient[0].Strength.Numerator.Value)
+		assert.Equal(t, "POWD", *medication.Ingredient[0].Strength.Denominator.Code)
+		assert.Nil(t, medication.Ingredient[1].Strength)
+	}
+	assert.Equal(t, json.Number("10"), *medicationRequest.DispenseRequest.Quantity.Value)
+	assert.Equal(t, 3, *medicationRequest.DosageInstruction[0].Timing.Repeat.Frequency)
+
+	entries, err := request.BundleEntries()
+	assert.NoError(t, err)
+	assert.NotContains(t, string(entries[0].Resource), "itemReference")
+}
@@ -1,31 +1,40 @@
 package resource
 
 import (
+	"encoding/json"
+	"github.com/jasoet/fhir-worker/pkg/ucum"
 	"github.com/jasoet/fhir-worker/pkg/util"
 	"github.com/samply/golang-fhir-models/fhir-models/fhir"
 )
 
+const (
+	CategoryVitalSigns = "vital-signs"
+	CategoryExam       = "exam"
+)
+
+var categoryDisplays = map[string]string{
+	CategoryVitalSigns: "Vital Signs",
+	CategoryExam:       "Exam",
+}
+
 type Observation struct {
 	ObservationId           string
 	EncounterId             string
 	PatientSatuSehatId      string
 	PatientName             string
 	Time                    string
+	Category                string
 	LoincCode               string
 	LoincDisplay            string
 	PractitionerSatuSehatId string
 	PractitionerName        string
-	ValueQuantity           *ObservationValue
+	ValueQuantity           *ucum.Quantity
 	ValueCode               *ObservationValueCode
-}
-
-type ObservationValue struct {
-	Value string
-	Unit  string
-	Code  string
+	ValueString             *string
 }
 
 type ObservationValueCode struct {
+	System  string
 	Code    string
 	Display string
 }
@@ -63,20 +72,35 @@ func (o *Observation) Resource() fhir.Observation {
 		},
 	}
 
-	if o.ValueQuantity != nil {
-		observation.ValueQuantity = &fhir.Quantity{
-			System: util.StrPtr("http://unitsofmeasure.org"),
-			Value:  util.JsonNumber(o.ValueQuantity.Value),
-			Unit:   util.StrPtr(o.ValueQuantity.Unit),
-			Code:   util.StrPtr(o.ValueQuantity.Code),
+	if o.Category != "" {
+		observation.Category = []fhir.CodeableConcept{
+			{
+				Coding: []fhir.Coding{
+					{
+						System:  util.StrPtr("http://terminology.hl7.org/CodeSystem/observation-category"),
+						Code:    util.StrPtr(o.Category),
+						Display: util.StrPtr(categoryDisplays[o.Category]),
+					},
+				},
+			},
+		}
+	}
+
+	if o.ValueQuantity != nil && o.ValueQuantity.IsRange() {
+		observation.ValueRange = &fhir.Range{
+			Low:  quantity(o.ValueQuantity.Low, o.ValueQuantity.Unit),
+			High: quantity(o.ValueQuantity.High, o.ValueQuantity.Unit),
 		}
+	} else if o.ValueQuantity != nil {
+		observation.ValueQuantity = quantity(o.ValueQuantity.Value, o.ValueQuantity.Unit)
+		observation.ValueQuantity.Comparator = comparator(o.ValueQuantity.Comparator)
 	}
 
 	if o.ValueCode != nil {
 		observation.ValueCodeableConcept = &fhir.CodeableConcept{
 			Coding: []fhir.Coding{
 				{
-					System:  util.StrPtr("http://loinc.org"),
+					System:  util.StrPtr(o.ValueCode.System),
 					Code:    util.StrPtr(o.ValueCode.Code),
 					Display: util.StrPtr(o.ValueCode.Display),
 				},
@@ -84,6 +108,38 @@ func (o *Observation) Resource() fhir.Observation {
 		}
 	}
 
+	observation.ValueString = o.ValueString
+
 	return observation
 
 }
+
+func quantity(value json.Number, unit ucum.Unit) *fhir.Quantity {
+	result := &fhir.Quantity{
+		Value: &value,
+	}
+
+	if unit.Code != "" {
+		result.System = util.StrPtr(ucum.System)
+		result.Unit = util.StrPtr(unit.Display)
+		result.Code = util.StrPtr(unit.Code)
+	}
+	return result
+}
+
+func comparator(s string) *fhir.QuantityComparator {
+	var result fhir.QuantityComparator
+	switch s {
+	case "<":
+		result = fhir.QuantityComparatorLessThan
+	case "<=":
+		result = fhir.QuantityComparatorLessOrEquals
+	case ">=":
+		result = fhir.QuantityComparatorGreaterOrEquals
+	case ">":
+		result = fhir.QuantityComparatorGreaterThan
+	default:
+		return nil
+	}
+	return &result
+}
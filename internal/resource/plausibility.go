@@ -0,0 +1,51 @@
+package resource
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/jasoet/fhir-worker/pkg/ucum"
+)
+
+var ErrImplausible = errors.New("value is outside the physiologically plausible range")
+
+// Plausibility is the range of values an observation can physically have in the unit it is published in,
+// values outside are typing errors such as a temperature of 365.
+type Plausibility struct {
+	Min float64
+	Max float64
+}
+
+// DefaultPlausibility holds the rules of the vital signs by LOINC code, wide enough for children and critically ill patients.
+var DefaultPlausibility = map[string]Plausibility{
+	"8480-6":  {Min: 40, Max: 300},  // systolic blood pressure, mm[Hg]
+	"8462-4":  {Min: 20, Max: 200},  // diastolic blood pressure, mm[Hg]
+	"8310-5":  {Min: 30, Max: 45},   // body temperature, Cel
+	"8867-4":  {Min: 20, Max: 300},  // heart rate, /min
+	"9279-1":  {Min: 4, Max: 80},    // respiratory rate, /min
+	"2708-6":  {Min: 50, Max: 100},  // oxygen saturation, %
+	"29463-7": {Min: 0.3, Max: 350}, // body weight, kg
+	"8302-2":  {Min: 20, Max: 250},  // body height, cm
+	"39156-5": {Min: 8, Max: 100},   // body mass index, kg/m2
+	"72514-3": {Min: 0, Max: 10},    // pain severity, {score}
+	"9269-2":  {Min: 3, Max: 15},    // glasgow coma score total, {score}
+}
+
+// Check returns ErrImplausible when the value, or one of the bounds of a range, is outside Min and Max.
+func (p Plausibility) Check(quantity ucum.Quantity) error {
+	for _, value := range []json.Number{quantity.Value, quantity.Low, quantity.High} {
+		if value == "" {
+			continue
+		}
+
+		number, err := value.Float64()
+		if err != nil {
+			return fmt.Errorf("%w: %q", ucum.ErrValue, value)
+		}
+
+		if number < p.Min || number > p.Max {
+			return fmt.Errorf("%w: %s is not between %g and %g", ErrImplausible, value, p.Min, p.Max)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package resource
+
+import (
+	"github.com/jasoet/fhir-worker/pkg/util"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// Referral is the ServiceRequest referring the patient of a visit to another facility.
+type Referral struct {
+	ServiceRequestId         string `validate:"required"`
+	EncounterId              string `validate:"required"`
+	OrganizationId           string `validate:"required"`
+	PatientSatuSehatId       string `validate:"required"`
+	PatientName              string `validate:"required"`
+	Time                     string `validate:"required"`
+	PractitionerSatuSehatId  string `validate:"required"`
+	PractitionerName         string
+	ReferralOrganizationId   string `validate:"required"`
+	ReferralOrganizationName string
+	Reason                   string
+	Diagnosis                []EncounterDiagnosis
+}
+
+func (o *Referral) BundleEntry() (*fhir.BundleEntry, error) {
+	return BundleEntry(o.Resource(), o.ServiceRequestId, "ServiceRequest")
+}
+
+func (o *Referral) Resource() fhir.ServiceRequest {
+	serviceRequest := fhir.ServiceRequest{
+		Identifier: []fhir.Identifier{
+			{
+				System: util.StrPtrFmt("http://sys-ids.kemkes.go.id/servicerequest/%s", o.OrganizationId),
+				Value:  util.StrPtr(o.ServiceRequestId),
+			},
+		},
+		Status: fhir.RequestStatusActive,
+		Intent: fhir.RequestIntentOrder,
+		Category: []fhir.CodeableConcept{
+			{
+				Coding: []fhir.Coding{
+					{
+						System:  util.StrPtr(snomedSystem),
+						Code:    util.StrPtr("3457005"),
+						Display: util.StrPtr("Patient referral"),
+					},
+				},
+			},
+		},
+		Code: &fhir.CodeableConcept{
+			Coding: []fhir.Coding{
+				{
+					System:  util.StrPtr(snomedSystem),
+					Code:    util.StrPtr("306206005"),
+					Display: util.StrPtr("Referral to service"),
+				},
+			},
+		},
+		Subject: fhir.Reference{
+			Reference: util.StrPtrFmt("Patient/%s", o.PatientSatuSehatId),
+			Display:   util.StrPtr(o.PatientName),
+		},
+		Encounter: &fhir.Reference{
+			Reference: util.StrPtrFmt("Encounter/%s", o.EncounterId),
+			Display:   util.StrPtrFmt("Kunjungan %s. Di tanggal %s", o.PatientName, o.Time),
+		},
+		AuthoredOn: util.StrPtr(o.Time),
+		Requester: &fhir.Reference{
+			Reference: util.StrPtrFmt("Practitioner/%s", o.PractitionerSatuSehatId),
+			Display:   util.StrPtr(o.PractitionerName),
+		},
+		Performer: []fhir.Reference{
+			{
+				Reference: util.StrPtrFmt("Organization/%s", o.ReferralOrganizationId),
+				Display:   util.StrPtr(o.ReferralOrganizationName),
+			},
+		},
+	}
+
+	if util.StringNotEmpty(o.Reason) {
+		serviceRequest.ReasonCode = []fhir.CodeableConcept{{Text: util.StrPtr(o.Reason)}}
+	}
+
+	for _, diagnosis := range o.Diagnosis {
+		serviceRequest.ReasonReference = append(serviceRequest.ReasonReference, fhir.Reference{
+			Reference: util.StrPtrFmt("urn:uuid:%s", diagnosis.Id),
+			Display:   util.StrPtr(diagnosis.Display),
+		})
+	}
+
+	return serviceRequest
+}
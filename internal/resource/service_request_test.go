@@ -0,0 +1,34 @@
+package resource
+
+import (
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestReferral_Resource(t *testing.T) {
+	referral := Referral{
+		ServiceRequestId:         "service-request",
+		ReferralOrganizationId:   "organization",
+		ReferralOrganizationName: "RSUD Sleman",
+		Reason:                   "Butuh pemeriksaan spesialis",
+		Diagnosis:                []EncounterDiagnosis{{Id: "condition", Display: "Dyspepsia"}},
+	}
+
+	resource := referral.Resource()
+	assert.Equal(t, "Organization/organization", *resource.Performer[0].Reference)
+	assert.Equal(t, "Butuh pemeriksaan spesialis", *resource.ReasonCode[0].Text)
+	assert.Equal(t, "urn:uuid:condition", *resource.ReasonReference[0].Reference)
+
+	assert.Nil(t, (&Referral{}).Resource().ReasonCode)
+}
+
+func TestFollowUp_Resource(t *testing.T) {
+	resource := (&FollowUp{Start: "2024-05-01T00:00:00+07:00", ServiceRequestId: "service-request"}).Resource()
+	assert.Equal(t, fhir.AppointmentStatusProposed, resource.Status)
+	assert.Nil(t, resource.End)
+	assert.Equal(t, "urn:uuid:service-request", *resource.BasedOn[0].Reference)
+	assert.Len(t, resource.Participant, 3)
+
+	assert.Nil(t, (&FollowUp{}).Resource().BasedOn)
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"github.com/jasoet/fhir-worker/pkg/util"
 	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+	"strings"
 )
 
 type Option func([]byte) []byte
@@ -34,6 +35,27 @@ func WithRemoveKey(key string) Option {
 	}
 }
 
+// WithRemoveItemKey removes key from every object of the array field, e.g. the unused choice of a backbone element.
+func WithRemoveItemKey(field string, key string) Option {
+	return func(jsonValue []byte) []byte {
+		m := make(map[string]any)
+		if err := json.Unmarshal(jsonValue, &m); err != nil {
+			return errorJson(err)
+		}
+
+		if items, ok := m[field].([]any); ok {
+			for _, item := range items {
+				if object, ok := item.(map[string]any); ok {
+					delete(object, key)
+				}
+			}
+		}
+
+		result, _ := json.Marshal(m)
+		return result
+	}
+}
+
 func BundleEntry(resource Marshallable, id string, name string, options ...Option) (*fhir.BundleEntry, error) {
 	bytes, err := resource.MarshalJSON()
 	if err != nil {
@@ -56,3 +78,15 @@ func BundleEntry(resource Marshallable, id string, name string, options ...Optio
 	return entry, nil
 
 }
+
+// codeableConcept returns the coding of value, lower cased, in codings or value as text, nil when value is empty.
+func codeableConcept(codings map[string]fhir.Coding, value string) *fhir.CodeableConcept {
+	if !util.StringNotEmpty(value) {
+		return nil
+	}
+
+	if coding, ok := codings[strings.ToLower(strings.Join(strings.Fields(value), " "))]; ok {
+		return &fhir.CodeableConcept{Coding: []fhir.Coding{coding}}
+	}
+	return &fhir.CodeableConcept{Text: util.StrPtr(value)}
+}
@@ -1,10 +1,50 @@
 package resource
 
 import (
+	"fmt"
+	"github.com/jasoet/fhir-worker/pkg/ucum"
 	"github.com/jasoet/fhir-worker/pkg/util"
 	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+	"regexp"
+	"strconv"
+	"strings"
 )
 
+const snomedSystem = "http://snomed.info/sct"
+
+var (
+	kilogram   = ucum.Unit{Code: "kg", Display: "kg"}
+	centimeter = ucum.Unit{Code: "cm", Display: "cm"}
+	score      = ucum.Unit{Code: "{score}", Display: "{score}"}
+)
+
+// consciousnessLevels maps the levels of consciousness charted in SIMRS, lower cased, to the SNOMED CT responsiveness used by SatuSehat.
+var consciousnessLevels = map[string]ObservationValueCode{
+	"compos mentis": {snomedSystem, "248234008", "Mentally alert"},
+	"composmentis":  {snomedSystem, "248234008", "Mentally alert"},
+	"cm":            {snomedSystem, "248234008", "Mentally alert"},
+	"alert":         {snomedSystem, "248234008", "Mentally alert"},
+	"a":             {snomedSystem, "248234008", "Mentally alert"},
+	"apatis":        {snomedSystem, "300202002", "Responds to voice"},
+	"somnolen":      {snomedSystem, "300202002", "Responds to voice"},
+	"somnolent":     {snomedSystem, "300202002", "Responds to voice"},
+	"verbal":        {snomedSystem, "300202002", "Responds to voice"},
+	"v":             {snomedSystem, "300202002", "Responds to voice"},
+	"sopor":         {snomedSystem, "450847001", "Responds to pain"},
+	"stupor":        {snomedSystem, "450847001", "Responds to pain"},
+	"semi koma":     {snomedSystem, "450847001", "Responds to pain"},
+	"semikoma":      {snomedSystem, "450847001", "Responds to pain"},
+	"pain":          {snomedSystem, "450847001", "Responds to pain"},
+	"p":             {snomedSystem, "450847001", "Responds to pain"},
+	"koma":          {snomedSystem, "422768004", "Unresponsive"},
+	"coma":          {snomedSystem, "422768004", "Unresponsive"},
+	"unresponsive":  {snomedSystem, "422768004", "Unresponsive"},
+	"u":             {snomedSystem, "422768004", "Unresponsive"},
+}
+
+// gcsPattern matches a GCS charted by its components, e.g. "E4V5M6" or "E4 V5 M6".
+var gcsPattern = regexp.MustCompile(`(?i)^E\s*([1-4])\s*V\s*([1-5])\s*M\s*([1-6])$`)
+
 type VitalSign struct {
 	EncounterId             string `validate:"required"`
 	SystoleId               string `validate:"required"`
@@ -13,6 +53,12 @@ type VitalSign struct {
 	TemperatureId           string `validate:"required"`
 	OxygenSaturationId      string `validate:"required"`
 	RespirationRateId       string `validate:"required"`
+	WeightId                string
+	HeightId                string
+	BmiId                   string
+	PainScoreId             string
+	GcsId                   string
+	ConsciousnessId         string
 	PatientSatuSehatId      string `validate:"required"`
 	PatientName             string `validate:"required"`
 	Time                    string `validate:"required"`
@@ -24,149 +70,174 @@ type VitalSign struct {
 	Temperature             string
 	RespirationRate         string
 	OxygenSaturation        string
+	Weight                  string
+	Height                  string
+	Bmi                     string // derived from Weight and Height when empty
+	PainScore               string
+	Gcs                     string
+	Consciousness           string
+	Plausibility            map[string]Plausibility // rules by LOINC code, vital signs without rule aren't checked
 }
 
-func (o *VitalSign) BundleEntries() ([]fhir.BundleEntry, error) {
-	var bundleEntries []fhir.BundleEntry
-	observations := o.Observations()
-	for _, obs := range observations {
-		bundleEntry, err := obs.BundleEntry()
-		if err != nil {
-			return nil, err
-		}
-		bundleEntries = append(bundleEntries, *bundleEntry)
+// Exclusion is an observation left out of the bundle because its value isn't a valid and plausible quantity.
+type Exclusion struct {
+	LoincCode    string
+	LoincDisplay string
+	Value        string
+	Reason       error
+}
+
+// vitalSign describes one observation of VitalSign, Unit is the unit SIMRS records the value in.
+type vitalSign struct {
+	id           string
+	value        string
+	loincCode    string
+	loincDisplay string
+	unit         ucum.Unit
+}
+
+func (o *VitalSign) vitalSigns() []vitalSign {
+	return []vitalSign{
+		{o.SystoleId, o.Systole, "8480-6", "Systolic blood pressure", ucum.Unit{Code: "mm[Hg]", Display: "mmHg"}},
+		{o.DiastoleId, o.Diastole, "8462-4", "Diastolic blood pressure", ucum.Unit{Code: "mm[Hg]", Display: "mmHg"}},
+		{o.TemperatureId, o.Temperature, "8310-5", "Body temperature", ucum.Unit{Code: "Cel", Display: "C"}},
+		{o.HeartRateId, o.HeartRate, "8867-4", "Heart rate", ucum.Unit{Code: "/min", Display: "beats/min"}},
+		{o.RespirationRateId, o.RespirationRate, "9279-1", "Respiratory rate", ucum.Unit{Code: "/min", Display: "breaths/min"}},
+		{o.OxygenSaturationId, o.OxygenSaturation, "2708-6", "Oxygen saturation in Arterial blood", ucum.Unit{Code: "%", Display: "%"}},
+		{o.WeightId, o.Weight, "29463-7", "Body weight", kilogram},
+		{o.HeightId, o.Height, "8302-2", "Body height", centimeter},
+		{o.BmiId, o.bmi(), "39156-5", "Body mass index (BMI) [Ratio]", ucum.Unit{Code: "kg/m2", Display: "kg/m2"}},
+		{o.PainScoreId, o.PainScore, "72514-3", "Pain severity - 0-10 verbal numeric rating [Score] - Reported", score},
+		{o.GcsId, gcsTotal(o.Gcs), "9269-2", "Glasgow coma score total", score},
 	}
-	return bundleEntries, nil
 }
 
-func (o *VitalSign) Observations() []Observation {
-	var observations []Observation
-	if util.StringNotEmpty(o.Systole) {
-		obsrv := Observation{
-			EncounterId:             o.EncounterId,
-			ObservationId:           o.SystoleId,
-			PatientSatuSehatId:      o.PatientSatuSehatId,
-			PatientName:             o.PatientName,
-			Time:                    o.Time,
-			PractitionerName:        o.PractitionerName,
-			PractitionerSatuSehatId: o.PractitionerSatuSehatId,
-			LoincCode:               "8480-6",
-			LoincDisplay:            "Systolic blood pressure",
-			ValueQuantity: &ObservationValue{
-				Value: o.Systole,
-				Unit:  "mmHg",
-				Code:  "mm[Hg]",
-			},
-		}
+// bmi returns the recorded BMI, or derives it from a plausible weight and height when SIMRS doesn't record it.
+func (o *VitalSign) bmi() string {
+	if util.StringNotEmpty(o.Bmi) {
+		return o.Bmi
+	}
 
-		observations = append(observations, obsrv)
+	weight, err := o.quantity(vitalSign{value: o.Weight, loincCode: "29463-7", unit: kilogram})
+	if err != nil || weight.Value == "" || weight.Comparator != "" {
+		return ""
+	}
 
+	height, err := o.quantity(vitalSign{value: o.Height, loincCode: "8302-2", unit: centimeter})
+	if err != nil || height.Value == "" || height.Comparator != "" {
+		return ""
 	}
 
-	if util.StringNotEmpty(o.Diastole) {
-		obsrv := Observation{
-			ObservationId:           o.DiastoleId,
-			EncounterId:             o.EncounterId,
-			PatientSatuSehatId:      o.PatientSatuSehatId,
-			PatientName:             o.PatientName,
-			PractitionerName:        o.PractitionerName,
-			PractitionerSatuSehatId: o.PractitionerSatuSehatId,
-			Time:                    o.Time,
-			LoincCode:               "8462-4",
-			LoincDisplay:            "Diastolic blood pressure",
-			ValueQuantity: &ObservationValue{
-				Value: o.Diastole,
-				Unit:  "mmHg",
-				Code:  "mm[Hg]",
-			},
-		}
+	kg, _ := weight.Value.Float64()
+	cm, _ := height.Value.Float64()
+	return strconv.FormatFloat(kg/(cm*cm/10000), 'f', 1, 64)
+}
 
-		observations = append(observations, obsrv)
+// gcsTotal returns the sum of a GCS charted by its components, other values are returned as is.
+func gcsTotal(value string) string {
+	match := gcsPattern.FindStringSubmatch(strings.TrimSpace(value))
+	if match == nil {
+		return value
 	}
 
-	if util.StringNotEmpty(o.Temperature) {
-		obsrv := Observation{
-			ObservationId:           o.TemperatureId,
-			EncounterId:             o.EncounterId,
-			PatientSatuSehatId:      o.PatientSatuSehatId,
-			PatientName:             o.PatientName,
-			PractitionerName:        o.PractitionerName,
-			PractitionerSatuSehatId: o.PractitionerSatuSehatId,
-			Time:                    o.Time,
-			LoincCode:               "8310-5",
-			LoincDisplay:            "Body temperature",
-			ValueQuantity: &ObservationValue{
-				Value: o.Temperature,
-				Unit:  "C",
-				Code:  "Cel",
-			},
-		}
-		observations = append(observations, obsrv)
+	total := 0
+	for _, component := range match[1:] {
+		points, _ := strconv.Atoi(component)
+		total += points
+	}
+	return strconv.Itoa(total)
+}
+
+// quantity normalizes the value of a vital sign to its unit and checks its Plausibility.
+func (o *VitalSign) quantity(vital vitalSign) (ucum.Quantity, error) {
+	quantity, err := ucum.Normalize(vital.value, vital.unit.Code)
+	if err != nil {
+		return quantity, err
+	}
 
+	if quantity.Unit.Code != vital.unit.Code {
+		return quantity, fmt.Errorf("%w: %s instead of %s", ucum.ErrIncompatible, quantity.Unit.Code, vital.unit.Code)
 	}
 
-	if util.StringNotEmpty(o.HeartRate) {
-		obsrv := Observation{
-			ObservationId:           o.HeartRateId,
-			EncounterId:             o.EncounterId,
-			PatientSatuSehatId:      o.PatientSatuSehatId,
-			PatientName:             o.PatientName,
-			PractitionerName:        o.PractitionerName,
-			PractitionerSatuSehatId: o.PractitionerSatuSehatId,
-			Time:                    o.Time,
-			LoincCode:               "8867-4",
-			LoincDisplay:            "Heart rate",
-			ValueQuantity: &ObservationValue{
-				Value: o.HeartRate,
-				Unit:  "beats/min",
-				Code:  "/min",
-			},
+	if plausibility, ok := o.Plausibility[vital.loincCode]; ok {
+		if err := plausibility.Check(quantity); err != nil {
+			return quantity, err
 		}
-		observations = append(observations, obsrv)
+	}
 
+	quantity.Unit = vital.unit
+	return quantity, nil
+}
+
+func (o *VitalSign) observation(id string, loincCode string, loincDisplay string) Observation {
+	return Observation{
+		ObservationId:           id,
+		EncounterId:             o.EncounterId,
+		PatientSatuSehatId:      o.PatientSatuSehatId,
+		PatientName:             o.PatientName,
+		PractitionerName:        o.PractitionerName,
+		PractitionerSatuSehatId: o.PractitionerSatuSehatId,
+		Time:                    o.Time,
+		Category:                CategoryVitalSigns,
+		LoincCode:               loincCode,
+		LoincDisplay:            loincDisplay,
+	}
+}
+
+func (o *VitalSign) BundleEntries() ([]fhir.BundleEntry, []Exclusion, error) {
+	var bundleEntries []fhir.BundleEntry
+	observations, exclusions := o.Observations()
+	for _, obs := range observations {
+		bundleEntry, err := obs.BundleEntry()
+		if err != nil {
+			return nil, nil, err
+		}
+		bundleEntries = append(bundleEntries, *bundleEntry)
 	}
+	return bundleEntries, exclusions, nil
+}
 
-	if util.StringNotEmpty(o.RespirationRate) {
-		obsrv := Observation{
-			ObservationId:           o.RespirationRateId,
-			EncounterId:             o.EncounterId,
-			PatientSatuSehatId:      o.PatientSatuSehatId,
-			PatientName:             o.PatientName,
-			PractitionerName:        o.PractitionerName,
-			PractitionerSatuSehatId: o.PractitionerSatuSehatId,
-			Time:                    o.Time,
-			LoincCode:               "9279-1",
-			LoincDisplay:            "Respiratory rate",
-			ValueQuantity: &ObservationValue{
-				Value: o.RespirationRate,
-				Unit:  "breaths/min",
-				Code:  "/min",
-			},
+// Observations returns an Observation for every recorded vital sign, values that can't be normalized
+// to a quantity in the unit of the vital sign or fail their Plausibility are returned as Exclusion.
+func (o *VitalSign) Observations() ([]Observation, []Exclusion) {
+	var observations []Observation
+	var exclusions []Exclusion
+	for _, vital := range o.vitalSigns() {
+		if !util.StringNotEmpty(vital.value) {
+			continue
 		}
-		observations = append(observations, obsrv)
 
+		quantity, err := o.quantity(vital)
+		if err != nil {
+			exclusions = append(exclusions, Exclusion{
+				LoincCode:    vital.loincCode,
+				LoincDisplay: vital.loincDisplay,
+				Value:        vital.value,
+				Reason:       err,
+			})
+			continue
+		}
+
+		observation := o.observation(vital.id, vital.loincCode, vital.loincDisplay)
+		observation.ValueQuantity = &quantity
+		observations = append(observations, observation)
 	}
 
-	if util.StringNotEmpty(o.OxygenSaturation) {
-		obsrv := Observation{
-			ObservationId:           o.OxygenSaturationId,
-			EncounterId:             o.EncounterId,
-			PatientSatuSehatId:      o.PatientSatuSehatId,
-			PatientName:             o.PatientName,
-			PractitionerName:        o.PractitionerName,
-			PractitionerSatuSehatId: o.PractitionerSatuSehatId,
-			Time:                    o.Time,
-			LoincCode:               "2708-6",
-			LoincDisplay:            "Oxygen saturation in Arterial blood",
-			ValueQuantity: &ObservationValue{
-				Value: o.OxygenSaturation,
-				Unit:  "%",
-				Code:  "%",
-			},
+	if util.StringNotEmpty(o.Consciousness) {
+		level, ok := consciousnessLevels[strings.ToLower(strings.Join(strings.Fields(o.Consciousness), " "))]
+		if ok {
+			observation := o.observation(o.ConsciousnessId, "67775-7", "Level of responsiveness")
+			observation.ValueCode = &level
+			observations = append(observations, observation)
+		} else {
+			exclusions = append(exclusions, Exclusion{
+				LoincCode:    "67775-7",
+				LoincDisplay: "Level of responsiveness",
+				Value:        o.Consciousness,
+				Reason:       fmt.Errorf("%w: %q is not a known level of consciousness", ucum.ErrValue, o.Consciousness),
+			})
 		}
-		observations = append(observations, obsrv)
-
 	}
 
-	return observations
+	return observations, exclusions
 }
@@ -0,0 +1,86 @@
+package resource
+
+import (
+	"github.com/jasoet/fhir-worker/pkg/ucum"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestVitalSign_Observations(t *testing.T) {
+	vitalSign := VitalSign{
+		SystoleId:        "systole",
+		DiastoleId:       "diastole",
+		TemperatureId:    "temperature",
+		HeartRateId:      "heart-rate",
+		OxygenSaturation: "98 mmHg",
+		Systole:          "1200",
+		Diastole:         "120/80",
+		Temperature:      "36,5",
+		HeartRate:        "88 x/mnt",
+		Plausibility:     DefaultPlausibility,
+	}
+
+	observations, exclusions := vitalSign.Observations()
+
+	var ids []string
+	for _, observation := range observations {
+		ids = append(ids, observation.ObservationId)
+	}
+	assert.Equal(t, []string{"temperature", "heart-rate"}, ids)
+	assert.Equal(t, ucum.Quantity{Value: "36.5", Unit: ucum.Unit{Code: "Cel", Display: "C"}}, *observations[0].ValueQuantity)
+
+	reasons := map[string]error{}
+	for _, exclusion := range exclusions {
+		reasons[exclusion.LoincCode] = exclusion.Reason
+	}
+	assert.ErrorIs(t, reasons["8480-6"], ErrImplausible)
+	assert.ErrorIs(t, reasons["8462-4"], ucum.ErrValue)
+	assert.ErrorIs(t, reasons["2708-6"], ucum.ErrIncompatible)
+}
+
+func TestPlausibility_Check(t *testing.T) {
+	temperature := DefaultPlausibility["8310-5"]
+
+	assert.NoError(t, temperature.Check(ucum.Quantity{Value: "36.5"}))
+	assert.NoError(t, temperature.Check(ucum.Quantity{Low: "36", High: "38"}))
+	assert.ErrorIs(t, temperature.Check(ucum.Quantity{Value: "365"}), ErrImplausible)
+	assert.ErrorIs(t, temperature.Check(ucum.Quantity{Low: "36", High: "380"}), ErrImplausible)
+}
+
+func TestVitalSign_Observations_Additional(t *testing.T) {
+	vitalSign := VitalSign{
+		WeightId:        "weight",
+		HeightId:        "height",
+		BmiId:           "bmi",
+		PainScoreId:     "pain",
+		GcsId:           "gcs",
+		ConsciousnessId: "consciousness",
+		Weight:          "70",
+		Height:          "175",
+		PainScore:       "3",
+		Gcs:             "E4 V5 M6",
+		Consciousness:   "Compos Mentis",
+		Plausibility:    DefaultPlausibility,
+	}
+
+	observations, exclusions := vitalSign.Observations()
+	assert.Empty(t, exclusions)
+
+	byId := map[string]Observation{}
+	for _, observation := range observations {
+		assert.Equal(t, CategoryVitalSigns, observation.Category)
+		byId[observation.ObservationId] = observation
+	}
+
+	assert.Equal(t, "39156-5", byId["bmi"].LoincCode)
+	assert.Equal(t, ucum.Quantity{Value: "22.9", Unit: ucum.Unit{Code: "kg/m2", Display: "kg/m2"}}, *byId["bmi"].ValueQuantity)
+	assert.Equal(t, "15", byId["gcs"].ValueQuantity.Value.String())
+	assert.Equal(t, "248234008", byId["consciousness"].ValueCode.Code)
+
+	t.Run("UnknownConsciousness", func(t *testing.T) {
+		_, exclusions := (&VitalSign{Consciousness: "gelisah"}).Observations()
+		if assert.Len(t, exclusions, 1) {
+			assert.ErrorIs(t, exclusions[0].Reason, ucum.ErrValue)
+		}
+	})
+}
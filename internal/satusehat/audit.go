@@ -0,0 +1,120 @@
+package satusehat
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/jasoet/fhir-worker/internal/entity"
+	"github.com/jasoet/fhir-worker/pkg/redact"
+	"github.com/rs/zerolog/log"
+	"io"
+	"net/http"
+	"time"
+)
+
+// AuditFunc stores an audit entry, e.g. Repository.InsertAuditEntry.
+type AuditFunc func(ctx context.Context, entry entity.AuditEntry) error
+
+// WithAudit records every HTTP exchange with SatuSehat, retries included, through audit.
+func WithAudit(audit AuditFunc) ClientOption {
+	return func(client *Client) {
+		client.audit = audit
+	}
+}
+
+type visitIdKey struct{}
+
+// WithVisitID returns ctx attributing the SatuSehat requests made with it to visitId in the audit log.
+func WithVisitID(ctx context.Context, visitId string) context.Context {
+	return context.WithValue(ctx, visitIdKey{}, visitId)
+}
+
+func visitIdFrom(ctx context.Context) *string {
+	visitId, ok := ctx.Value(visitIdKey{}).(string)
+	if !ok {
+		return nil
+	}
+	return &visitId
+}
+
+// auditTransport records each round trip, it sees what resty hides: every retry and failures without a response.
+type auditTransport struct {
+	next     http.RoundTripper
+	audit    AuditFunc
+	clientId string
+}
+
+func (a *auditTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	requestBody, err := readRequestBody(request)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := entity.AuditEntry{
+		OccurredAt:  time.Now().UTC(),
+		Method:      request.Method,
+		Url:         redact.String(request.URL.String()),
+		RequestHash: RequestHash(request.Method, request.URL.String(), requestBody),
+		VisitID:     visitIdFrom(request.Context()),
+		ClientID:    a.clientId,
+	}
+
+	response, err := a.next.RoundTrip(request)
+	if err == nil {
+		var responseBody []byte
+		responseBody, err = io.ReadAll(response.Body)
+		_ = response.Body.Close()
+		response.Body = io.NopCloser(bytes.NewReader(responseBody))
+
+		entry.StatusCode = response.StatusCode
+		entry.ResponseHash = hash(responseBody)
+	}
+	entry.LatencyMs = time.Since(entry.OccurredAt).Milliseconds()
+	if err != nil {
+		message := redact.String(err.Error())
+		entry.Error = &message
+	}
+
+	// The exchange already happened, a failed audit write must not turn it into a failed request.
+	if auditErr := a.audit(context.WithoutCancel(request.Context()), entry); auditErr != nil {
+		log.Error().Ctx(request.Context()).Err(auditErr).Str("url", entry.Url).Msg("Failed to record SatuSehat audit entry")
+	}
+
+	return response, err
+}
+
+// readRequestBody returns the body request is about to send, leaving it readable for the transport.
+func readRequestBody(request *http.Request) ([]byte, error) {
+	if request.Body == nil || request.Body == http.NoBody {
+		return nil, nil
+	}
+
+	if request.GetBody != nil {
+		body, err := request.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		defer body.Close()
+		return io.ReadAll(body)
+	}
+
+	body, err := io.ReadAll(request.Body)
+	_ = request.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	request.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// RequestHash is the hex SHA-256 of "<method> <url>\n" followed by the body, auditors recompute it from the request
+// they hold, e.g. a bundle kept by the hospital.
+func RequestHash(method string, url string, body []byte) string {
+	return hash(append([]byte(method+" "+url+"\n"), body...))
+}
+
+func hash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
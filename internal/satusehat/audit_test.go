@@ -0,0 +1,86 @@
+package satusehat
+
+import (
+	"context"
+	"github.com/jasoet/fhir-worker/internal/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAudit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/fhir":
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(`{"resourceType":"Bundle"}`))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte("Internal Server Error"))
+		}
+	}))
+	defer server.Close()
+
+	var mu sync.Mutex
+	var entries []entity.AuditEntry
+	audit := func(ctx context.Context, entry entity.AuditEntry) error {
+		mu.Lock()
+		defer mu.Unlock()
+		entries = append(entries, entry)
+		return nil
+	}
+
+	client := NewClient(
+		WithCredential(Credential{AuthUrl: server.URL, BaseUrl: server.URL + "/fhir", ClientId: "client-1"}),
+		WithRestConfig(RestConfig{RetryCount: 1, RetryWaitTime: time.Millisecond, RetryMaxWaitTime: time.Millisecond, Timeout: time.Second}),
+		WithAudit(audit),
+	)
+	nowUtc := time.Now().UTC()
+	client.token = TokenDetail{ExpiresIn: nowUtc.Add(time.Hour), IssuedAt: nowUtc, AccessToken: "access token"}
+
+	_, err := client.PostBundle(WithVisitID(context.Background(), "V-1"), `{"resourceType":"Bundle"}`)
+	require.NoError(t, err)
+
+	_, err = client.GetPatientId(context.Background(), "3404000000000001")
+	require.Error(t, err)
+
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	client.credential.BaseUrl = closed.URL
+
+	_, err = client.GetPractitionerId(context.Background(), "3404000000000002")
+	require.Error(t, err)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	// The practitioner lookup failed without a response and was retried once, each attempt is recorded.
+	require.Len(t, entries, 4)
+
+	posted := entries[0]
+	assert.Equal(t, http.MethodPost, posted.Method)
+	assert.Equal(t, server.URL+"/fhir", posted.Url)
+	assert.Equal(t, http.StatusOK, posted.StatusCode)
+	assert.Equal(t, RequestHash(http.MethodPost, server.URL+"/fhir", []byte(`{"resourceType":"Bundle"}`)), posted.RequestHash)
+	assert.Equal(t, hash([]byte(`{"resourceType":"Bundle"}`)), posted.ResponseHash)
+	assert.Equal(t, "client-1", posted.ClientID)
+	require.NotNil(t, posted.VisitID)
+	assert.Equal(t, "V-1", *posted.VisitID)
+
+	lookup := entries[1]
+	assert.Equal(t, http.MethodGet, lookup.Method)
+	assert.Equal(t, http.StatusInternalServerError, lookup.StatusCode)
+	assert.NotContains(t, lookup.Url, "3404000000000001")
+	assert.Nil(t, lookup.VisitID)
+
+	for _, failed := range entries[2:] {
+		assert.Zero(t, failed.StatusCode)
+		assert.Empty(t, failed.ResponseHash)
+		require.NotNil(t, failed.Error)
+		assert.NotContains(t, *failed.Error, "3404000000000002")
+	}
+}
@@ -2,15 +2,25 @@ package satusehat
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/go-resty/resty/v2"
+	"github.com/jasoet/fhir-worker/internal/metrics"
+	"github.com/jasoet/fhir-worker/internal/tracing"
+	"github.com/jasoet/fhir-worker/pkg/redact"
 	"github.com/jasoet/fhir-worker/pkg/util"
 	"github.com/rs/zerolog/log"
 	"github.com/tidwall/gjson"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
 	"strings"
+	"sync"
 	"time"
 )
 
+var tracer = otel.Tracer("github.com/jasoet/fhir-worker/internal/satusehat")
+
 type TokenDetail struct {
 	OrganizationName string
 	DeveloperEmail   string
@@ -37,6 +47,8 @@ type Client struct {
 	credential *Credential
 	restConfig *RestConfig
 	token      TokenDetail
+	mu         sync.Mutex // guards token, the health checks read it concurrently to the jobs
+	audit      AuditFunc
 }
 
 type ClientOption func(*Client)
@@ -70,12 +82,23 @@ func NewClient(options ...ClientOption) *Client {
 		SetRetryMaxWaitTime(client.restConfig.RetryMaxWaitTime).
 		SetTimeout(client.restConfig.Timeout)
 
+	if client.audit != nil {
+		transport := httpClient.GetClient().Transport
+		if transport == nil {
+			transport = http.DefaultTransport
+		}
+		httpClient.SetTransport(&auditTransport{next: transport, audit: client.audit, clientId: client.credential.ClientId})
+	}
+
 	client.restClient = httpClient
 
 	return client
 }
 
-func (t *Client) RefreshToken(ctx context.Context) (*resty.Response, error) {
+func (t *Client) RefreshToken(ctx context.Context) (_ *resty.Response, err error) {
+	ctx, span := startSpan(ctx, "refresh_token")
+	defer func() { tracing.End(span, safeError(err)) }()
+
 	config := t.credential
 	getTokenURL := fmt.Sprintf("%s%s", config.AuthUrl, "/accesstoken?grant_type=client_credentials")
 
@@ -88,26 +111,34 @@ func (t *Client) RefreshToken(ctx context.Context) (*resty.Response, error) {
 
 	response, err := t.restClient.R().
 		SetContext(ctx).
+		EnableTrace().
 		SetFormData(params).
 		Post(getTokenURL)
 
+	observe(span, "refresh_token", response)
+	if err != nil || response.IsError() {
+		metrics.TokenRefreshes.WithLabelValues(metrics.Failure).Inc()
+	} else {
+		metrics.TokenRefreshes.WithLabelValues(metrics.Success).Inc()
+	}
+
 	if err != nil {
 		_log.Error().Err(err).Msg("Failed to refresh token")
 		return nil, NewExecutionError("Failed to refresh token", err)
 	}
 
 	if util.IsUnauthorized(response) {
-		_log.Error().Int("statusCode", response.StatusCode()).Str("body", response.String()).Msg("unauthorized")
+		_log.Error().Int("statusCode", response.StatusCode()).Str("body", redact.String(response.String())).Msg("unauthorized")
 		return nil, NewUnauthorizedError(response.StatusCode(), "Unauthorized access", response.String())
 	}
 
 	if util.IsServerError(response) {
-		_log.Error().Int("statusCode", response.StatusCode()).Str("body", response.String()).Msg("produce server error")
+		_log.Error().Int("statusCode", response.StatusCode()).Str("body", redact.String(response.String())).Msg("produce server error")
 		return response, NewServerError(response.StatusCode(), "server error", response.String())
 	}
 
 	if response.IsError() {
-		_log.Error().Int("statusCode", response.StatusCode()).Str("body", response.String()).Msg("response error")
+		_log.Error().Int("statusCode", response.StatusCode()).Str("body", redact.String(response.String())).Msg("response error")
 		return response, NewResponseError(response.StatusCode(), "response error", response.String())
 	}
 
@@ -130,24 +161,52 @@ func (t *Client) RefreshToken(ctx context.Context) (*resty.Response, error) {
 		Status:           gjsonResult.Get("status").String(),
 	}
 
+	t.mu.Lock()
 	t.token = tokenDetail
+	t.mu.Unlock()
 
 	return response, nil
 }
 
-func (t *Client) PostBundle(ctx context.Context, body string) (string, error) {
-	if t.token.IsExpired() {
-		_, err := t.RefreshToken(ctx)
-		if err != nil {
-			return "", err
-		}
+// Token returns the cached access token, refreshing it first when it is about to expire.
+func (t *Client) Token(ctx context.Context) (string, error) {
+	t.mu.Lock()
+	token := t.token
+	t.mu.Unlock()
+
+	if !token.IsExpired() {
+		return token.AccessToken, nil
+	}
+
+	if _, err := t.RefreshToken(ctx); err != nil {
+		return "", err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.token.AccessToken, nil
+}
+
+func (t *Client) expireToken() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.token.SetExpired()
+}
+
+func (t *Client) PostBundle(ctx context.Context, body string) (_ string, err error) {
+	ctx, span := startSpan(ctx, "post_bundle")
+	defer func() { tracing.End(span, safeError(err)) }()
+
+	accessToken, err := t.Token(ctx)
+	if err != nil {
+		return "", err
 	}
 
 	credential := t.credential
 
 	requestUrl := fmt.Sprintf("%s", credential.BaseUrl)
 	headers := map[string]string{
-		"Authorization": fmt.Sprintf("Bearer %s", t.token.AccessToken),
+		"Authorization": fmt.Sprintf("Bearer %s", accessToken),
 	}
 
 	_log := log.With().Ctx(ctx).Str("function", "PostBundleFn").Str("url", requestUrl).Logger()
@@ -159,46 +218,55 @@ func (t *Client) PostBundle(ctx context.Context, body string) (string, error) {
 		SetBody(body).
 		Post(requestUrl)
 
+	observe(span, "post_bundle", response)
+
 	if err != nil {
 		_log.Error().Err(err).Msg("Failed to post data")
+		metrics.PublishAttempts.WithLabelValues("execution_error", metrics.StatusCode(0)).Inc()
 		return "", NewExecutionError("Failed to post data", err)
 	}
 
 	responseBody := response.String()
+	statusCode := metrics.StatusCode(response.StatusCode())
 
 	if util.IsUnauthorized(response) {
-		t.token.SetExpired()
+		t.expireToken()
+		metrics.PublishAttempts.WithLabelValues("unauthorized", statusCode).Inc()
 		return responseBody, NewUnauthorizedError(response.StatusCode(), "Unauthorized access", responseBody)
 	}
 
 	if util.IsServerError(response) {
-		_log.Error().Int("statusCode", response.StatusCode()).Str("body", response.String()).Msg("produce server error")
+		_log.Error().Int("statusCode", response.StatusCode()).Str("body", redact.String(response.String())).Msg("produce server error")
+		metrics.PublishAttempts.WithLabelValues("server_error", statusCode).Inc()
 		return responseBody, NewServerError(response.StatusCode(), "server error", responseBody)
 	}
 
 	if response.IsError() {
-		_log.Error().Int("statusCode", response.StatusCode()).Str("body", response.String()).Msg("response error")
+		_log.Error().Int("statusCode", response.StatusCode()).Str("body", redact.String(response.String())).Msg("response error")
+		metrics.PublishAttempts.WithLabelValues("rejected", statusCode).Inc()
 		return responseBody, NewResponseError(response.StatusCode(), "response error", responseBody)
 	}
 
+	metrics.PublishAttempts.WithLabelValues(metrics.Success, statusCode).Inc()
 	return responseBody, err
 }
 
-func (t *Client) GetPatientId(ctx context.Context, nik string) (string, error) {
-	if t.token.IsExpired() {
-		_, err := t.RefreshToken(ctx)
-		if err != nil {
-			return "", err
-		}
+func (t *Client) GetPatientId(ctx context.Context, nik string) (_ string, err error) {
+	ctx, span := startSpan(ctx, "get_patient")
+	defer func() { tracing.End(span, safeError(err)) }()
+
+	accessToken, err := t.Token(ctx)
+	if err != nil {
+		return "", err
 	}
 
 	credential := t.credential
 	requestUrl := fmt.Sprintf("%s%s%s", credential.BaseUrl, "/Patient?identifier=https://fhir.kemkes.go.id/id/nik|", nik)
 	headers := map[string]string{
-		"Authorization": fmt.Sprintf("Bearer %s", t.token.AccessToken),
+		"Authorization": fmt.Sprintf("Bearer %s", accessToken),
 	}
 
-	_log := log.With().Ctx(ctx).Str("function", "GetPatientId").Str("url", requestUrl).Logger()
+	_log := log.With().Ctx(ctx).Str("function", "GetPatientId").Str("url", redact.String(requestUrl)).Logger()
 
 	response, err := t.restClient.R().
 		SetContext(ctx).
@@ -206,6 +274,8 @@ func (t *Client) GetPatientId(ctx context.Context, nik string) (string, error) {
 		EnableTrace().
 		Get(requestUrl)
 
+	observe(span, "get_patient", response)
+
 	if err != nil {
 		return "", err
 	}
@@ -216,17 +286,17 @@ func (t *Client) GetPatientId(ctx context.Context, nik string) (string, error) {
 	}
 
 	if util.IsUnauthorized(response) {
-		t.token.SetExpired()
+		t.expireToken()
 		return "", NewUnauthorizedError(response.StatusCode(), "Unauthorized access", response.String())
 	}
 
 	if util.IsServerError(response) {
-		_log.Error().Int("statusCode", response.StatusCode()).Str("body", response.String()).Msg("produce server error")
+		_log.Error().Int("statusCode", response.StatusCode()).Str("body", redact.String(response.String())).Msg("produce server error")
 		return "", NewServerError(response.StatusCode(), "server error", response.String())
 	}
 
 	if response.IsError() {
-		_log.Error().Int("statusCode", response.StatusCode()).Str("body", response.String()).Msg("response error")
+		_log.Error().Int("statusCode", response.StatusCode()).Str("body", redact.String(response.String())).Msg("response error")
 		return "", NewResponseError(response.StatusCode(), "response error", response.String())
 	}
 
@@ -242,21 +312,22 @@ func (t *Client) GetPatientId(ctx context.Context, nik string) (string, error) {
 
 }
 
-func (t *Client) GetPractitionerId(ctx context.Context, nik string) (string, error) {
-	if t.token.IsExpired() {
-		_, err := t.RefreshToken(ctx)
-		if err != nil {
-			return "", err
-		}
+func (t *Client) GetPractitionerId(ctx context.Context, nik string) (_ string, err error) {
+	ctx, span := startSpan(ctx, "get_practitioner")
+	defer func() { tracing.End(span, safeError(err)) }()
+
+	accessToken, err := t.Token(ctx)
+	if err != nil {
+		return "", err
 	}
 
 	credential := t.credential
 	requestUrl := fmt.Sprintf("%s%s%s", credential.BaseUrl, "/Practitioner?identifier=https://fhir.kemkes.go.id/id/nik|", nik)
 	headers := map[string]string{
-		"Authorization": fmt.Sprintf("Bearer %s", t.token.AccessToken),
+		"Authorization": fmt.Sprintf("Bearer %s", accessToken),
 	}
 
-	_log := log.With().Ctx(ctx).Str("function", "GetPractitionerId").Str("url", requestUrl).Logger()
+	_log := log.With().Ctx(ctx).Str("function", "GetPractitionerId").Str("url", redact.String(requestUrl)).Logger()
 
 	response, err := t.restClient.R().
 		SetContext(ctx).
@@ -264,6 +335,8 @@ func (t *Client) GetPractitionerId(ctx context.Context, nik string) (string, err
 		EnableTrace().
 		Get(requestUrl)
 
+	observe(span, "get_practitioner", response)
+
 	if err != nil {
 		return "", err
 	}
@@ -274,17 +347,17 @@ func (t *Client) GetPractitionerId(ctx context.Context, nik string) (string, err
 	}
 
 	if util.IsUnauthorized(response) {
-		t.token.SetExpired()
+		t.expireToken()
 		return "", NewUnauthorizedError(response.StatusCode(), "Unauthorized access", response.String())
 	}
 
 	if util.IsServerError(response) {
-		_log.Error().Int("statusCode", response.StatusCode()).Str("body", response.String()).Msg("produce server error")
+		_log.Error().Int("statusCode", response.StatusCode()).Str("body", redact.String(response.String())).Msg("produce server error")
 		return "", NewServerError(response.StatusCode(), "server error", response.String())
 	}
 
 	if response.IsError() {
-		_log.Error().Int("statusCode", response.StatusCode()).Str("body", response.String()).Msg("response error")
+		_log.Error().Int("statusCode", response.StatusCode()).Str("body", redact.String(response.String())).Msg("response error")
 		return "", NewResponseError(response.StatusCode(), "response error", response.String())
 	}
 
@@ -299,3 +372,32 @@ func (t *Client) GetPractitionerId(ctx context.Context, nik string) (string, err
 	return id, nil
 
 }
+
+// observe records the latency traced by resty of a SatuSehat request and its status code on span,
+// response is nil when the request couldn't be built.
+func observe(span trace.Span, operation string, response *resty.Response) {
+	if response == nil || response.Request == nil {
+		return
+	}
+
+	span.SetAttributes(tracing.StatusCode.Int(response.StatusCode()))
+	metrics.SatuSehatRequestDuration.
+		WithLabelValues(operation, metrics.StatusCode(response.StatusCode())).
+		Observe(response.Request.TraceInfo().TotalTime.Seconds())
+}
+
+func startSpan(ctx context.Context, operation string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "satusehat."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(tracing.Operation.String(operation)))
+}
+
+// safeError keeps errors that aren't from this package, e.g. resty's, out of the traces: their message may hold the
+// request URL, a NIK for patient lookups.
+func safeError(err error) error {
+	var safe tracing.SafeError
+	if err == nil || errors.As(err, &safe) {
+		return err
+	}
+	return NewExecutionError("request failed", err)
+}
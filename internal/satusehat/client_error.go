@@ -8,7 +8,8 @@ type UnauthorizedError struct {
 	RespBody   string
 }
 
-func (e *UnauthorizedError) Error() string { return e.Msg }
+func (e *UnauthorizedError) Error() string       { return e.Msg }
+func (e *UnauthorizedError) SafeMessage() string { return e.Msg }
 func NewUnauthorizedError(statusCode int, msg string, respBody string) *UnauthorizedError {
 	return &UnauthorizedError{
 		StatusCode: statusCode,
@@ -22,8 +23,9 @@ type ExecutionError struct {
 	Err error
 }
 
-func (e *ExecutionError) Error() string { return e.Msg }
-func (e *ExecutionError) Unwrap() error { return e.Err }
+func (e *ExecutionError) Error() string       { return e.Msg }
+func (e *ExecutionError) SafeMessage() string { return e.Msg }
+func (e *ExecutionError) Unwrap() error       { return e.Err }
 func NewExecutionError(msg string, err error) *ExecutionError {
 	return &ExecutionError{
 		Msg: msg,
@@ -37,7 +39,8 @@ type ServerError struct {
 	RespBody   string
 }
 
-func (e *ServerError) Error() string { return fmt.Sprintf("%s: %s", e.Msg, e.RespBody) }
+func (e *ServerError) Error() string       { return fmt.Sprintf("%s: %s", e.Msg, e.RespBody) }
+func (e *ServerError) SafeMessage() string { return e.Msg }
 func NewServerError(statusCode int, msg string, respBody string) *ServerError {
 	return &ServerError{
 		StatusCode: statusCode,
@@ -52,7 +55,8 @@ type ResponseError struct {
 	RespBody   string
 }
 
-func (e *ResponseError) Error() string { return fmt.Sprintf("%s: %s", e.Msg, e.RespBody) }
+func (e *ResponseError) Error() string       { return fmt.Sprintf("%s: %s", e.Msg, e.RespBody) }
+func (e *ResponseError) SafeMessage() string { return e.Msg }
 func NewResponseError(statusCode int, msg string, respBody string) *ResponseError {
 	return &ResponseError{
 		StatusCode: statusCode,
@@ -67,7 +71,8 @@ type ResourceNotFoundError struct {
 	RespBody   string
 }
 
-func (e *ResourceNotFoundError) Error() string { return fmt.Sprintf("%s: %s", e.Msg, e.RespBody) }
+func (e *ResourceNotFoundError) Error() string       { return fmt.Sprintf("%s: %s", e.Msg, e.RespBody) }
+func (e *ResourceNotFoundError) SafeMessage() string { return e.Msg }
 func NewResourceNotFoundError(statusCode int, msg string, respBody string) *ResourceNotFoundError {
 	return &ResourceNotFoundError{
 		StatusCode: statusCode,
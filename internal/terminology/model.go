@@ -0,0 +1,221 @@
+package terminology
+
+import (
+	"encoding/json"
+	"github.com/jasoet/fhir-worker/pkg/util"
+	"github.com/jasoet/fhir-worker/shared/model"
+	"strconv"
+)
+
+// The functions below do nothing on a nil Terminology, the mapping job calls them while terminology validation is disabled.
+
+func (t *Terminology) NormalizeDiagnosis(o *model.Diagnosis) {
+	if t == nil {
+		return
+	}
+
+	result := t.Lookup(ICD10, o.DiagnosisCode)
+	o.DiagnosisCode = result.Code
+	if result.Display != "" {
+		o.DiagnosisName = result.Display
+	}
+}
+
+func (t *Terminology) NormalizeProcedure(o *model.Procedure) {
+	if t == nil {
+		return
+	}
+
+	result := t.Lookup(ICD9CM, o.ProcedureCode)
+	o.ProcedureCode = result.Code
+	if result.Display != "" {
+		o.ProcedureName = result.Display
+	}
+}
+
+func (t *Terminology) NormalizeMedicationRequest(o *model.MedicationRequest) {
+	o.KfaCode, o.KfaName = t.normalizeKfa(o.KfaCode, o.KfaName)
+	t.normalizeIngredients(o.Ingredients)
+}
+
+func (t *Terminology) NormalizeMedicationDispense(o *model.MedicationDispense) {
+	o.KfaCode, o.KfaName = t.normalizeKfa(o.KfaCode, o.KfaName)
+	t.normalizeIngredients(o.Ingredients)
+}
+
+func (t *Terminology) normalizeIngredients(ingredients model.MedicationIngredients) {
+	for i := range ingredients {
+		ingredients[i].KfaCode, ingredients[i].KfaName = t.normalizeKfa(ingredients[i].KfaCode, ingredients[i].KfaName)
+	}
+}
+
+func (t *Terminology) NormalizeImmunization(o *model.Immunization) {
+	o.KfaCode, o.KfaName = t.normalizeKfa(o.KfaCode, o.KfaName)
+}
+
+func (t *Terminology) NormalizeLab(o *model.ObservationLab) {
+	o.LabLoincCode, o.LabLoincName = t.normalizeLoinc(o.LabLoincCode, o.LabLoincName)
+}
+
+func (t *Terminology) NormalizeRadiology(o *model.ObservationRadiology) {
+	o.LabLoincCode, o.LabLoincName = t.normalizeLoinc(o.LabLoincCode, o.LabLoincName)
+}
+
+func (t *Terminology) normalizeKfa(code *string, name *string) (*string, *string) {
+	if t == nil || code == nil {
+		return code, name
+	}
+
+	result := t.Lookup(KFA, *code)
+	if result.Display != "" {
+		name = &result.Display
+	}
+	return &result.Code, name
+}
+
+func (t *Terminology) normalizeLoinc(code *json.RawMessage, name *json.RawMessage) (*json.RawMessage, *json.RawMessage) {
+	if t == nil {
+		return code, name
+	}
+
+	codes, single := util.JsonStrings(code)
+	if codes == nil {
+		return code, name
+	}
+
+	names, _ := util.JsonStrings(name)
+	if len(names) != len(codes) {
+		names = make([]string, len(codes))
+	}
+
+	for i := range codes {
+		result := t.Lookup(LOINC, codes[i])
+		codes[i] = result.Code
+		if result.Display != "" {
+			names[i] = result.Display
+		}
+	}
+
+	return util.JsonStringsOf(codes, single), util.JsonStringsOf(names, single)
+}
+
+func (t *Terminology) DiagnosisIssues(list *model.DiagnosisList) []model.ValidationIssue {
+	if list == nil {
+		return nil
+	}
+
+	var issues []model.ValidationIssue
+	for _, o := range *list {
+		issues = t.appendIssue(issues, ICD10, "diagnosis", o.DiagnosisCode, "diagnosis_code", o.DiagnosisCode)
+	}
+	return issues
+}
+
+func (t *Terminology) ProcedureIssues(list *model.ProcedureList) []model.ValidationIssue {
+	if list == nil {
+		return nil
+	}
+
+	var issues []model.ValidationIssue
+	for _, o := range *list {
+		issues = t.appendIssue(issues, ICD9CM, "procedure", o.ProcedureCode, "procedure_code", o.ProcedureCode)
+	}
+	return issues
+}
+
+func (t *Terminology) MedicationRequestIssues(list *model.MedicationRequestList) []model.ValidationIssue {
+	if list == nil {
+		return nil
+	}
+
+	var issues []model.ValidationIssue
+	for _, o := range *list {
+		if o.KfaCode != nil {
+			issues = t.appendIssue(issues, KFA, "medication_request", strconv.Itoa(o.PrescriptionId), "kfa_code", *o.KfaCode)
+		}
+		issues = t.appendIngredientIssues(issues, "medication_request", strconv.Itoa(o.PrescriptionId), o.Ingredients)
+	}
+	return issues
+}
+
+func (t *Terminology) MedicationDispenseIssues(list *model.MedicationDispenseList) []model.ValidationIssue {
+	if list == nil {
+		return nil
+	}
+
+	var issues []model.ValidationIssue
+	for _, o := range *list {
+		if o.KfaCode != nil {
+			issues = t.appendIssue(issues, KFA, "medication_dispense", strconv.Itoa(o.PrescriptionId), "kfa_code", *o.KfaCode)
+		}
+		issues = t.appendIngredientIssues(issues, "medication_dispense", strconv.Itoa(o.PrescriptionId), o.Ingredients)
+	}
+	return issues
+}
+
+func (t *Terminology) ImmunizationIssues(list *model.ImmunizationList) []model.ValidationIssue {
+	if list == nil {
+		return nil
+	}
+
+	var issues []model.ValidationIssue
+	for _, o := range *list {
+		if o.KfaCode != nil {
+			issues = t.appendIssue(issues, KFA, "immunization", strconv.Itoa(o.ImmunizationId), "kfa_code", *o.KfaCode)
+		}
+	}
+	return issues
+}
+
+func (t *Terminology) LabIssues(list *model.ObservationLabList) []model.ValidationIssue {
+	if list == nil {
+		return nil
+	}
+
+	var issues []model.ValidationIssue
+	for _, o := range *list {
+		codes, _ := util.JsonStrings(o.LabLoincCode)
+		for _, code := range codes {
+			issues = t.appendIssue(issues, LOINC, "lab", o.LabName, "lab_loinc_code", code)
+		}
+	}
+	return issues
+}
+
+func (t *Terminology) RadiologyIssues(list *model.ObservationRadiologyList) []model.ValidationIssue {
+	if list == nil {
+		return nil
+	}
+
+	var issues []model.ValidationIssue
+	for _, o := range *list {
+		codes, _ := util.JsonStrings(o.LabLoincCode)
+		for _, code := range codes {
+			issues = t.appendIssue(issues, LOINC, "radiology", o.LabName, "lab_loinc_code", code)
+		}
+	}
+	return issues
+}
+
+func (t *Terminology) appendIngredientIssues(issues []model.ValidationIssue, resource string, recordId string, ingredients model.MedicationIngredients) []model.ValidationIssue {
+	for _, ingredient := range ingredients {
+		if ingredient.KfaCode != nil {
+			issues = t.appendIssue(issues, KFA, resource, recordId, "kfa_code", *ingredient.KfaCode)
+		}
+	}
+	return issues
+}
+
+// appendIssue adds an issue for codes rejected by Lookup, empty codes are left to the required struct validation.
+func (t *Terminology) appendIssue(issues []model.ValidationIssue, system System, resource string, recordId string, field string, code string) []model.ValidationIssue {
+	if t == nil || code == "" {
+		return issues
+	}
+
+	result := t.Lookup(system, code)
+	if result.Rule == "" {
+		return issues
+	}
+
+	return append(issues, model.NewValidationIssue(resource, recordId, field, result.Rule, string(system), code))
+}
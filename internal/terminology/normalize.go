@@ -0,0 +1,68 @@
+package terminology
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	icd10Pattern  = regexp.MustCompile(`^[A-Z][0-9]{2}(\.[0-9A-Z]{1,4})?$`)
+	icd9cmPattern = regexp.MustCompile(`^[0-9]{2}(\.[0-9]{1,2})?$`)
+	loincPattern  = regexp.MustCompile(`^[0-9]{1,7}-[0-9]$`)
+	kfaPattern    = regexp.MustCompile(`^[0-9]+$`)
+)
+
+// Normalize cleans up the way SIMRS usually stores a code: surrounding and inner spaces, lower case,
+// comma or missing decimal point, e.g. " a09,9" and "A099" both become "A09.9".
+func Normalize(system System, code string) string {
+	code = strings.ToUpper(strings.Join(strings.Fields(code), ""))
+
+	switch system {
+	case ICD10:
+		return withDecimalPoint(code, 3)
+	case ICD9CM:
+		return withDecimalPoint(code, 2)
+	default:
+		return code
+	}
+}
+
+func withDecimalPoint(code string, categoryLength int) string {
+	code = strings.TrimSuffix(strings.ReplaceAll(code, ",", "."), ".")
+	if len(code) > categoryLength && !strings.Contains(code, ".") {
+		return code[:categoryLength] + "." + code[categoryLength:]
+	}
+	return code
+}
+
+// WellFormed reports whether a normalized code follows the syntax of its code system,
+// LOINC codes are also checked against their mod 10 check digit.
+func WellFormed(system System, code string) bool {
+	switch system {
+	case ICD10:
+		return icd10Pattern.MatchString(code)
+	case ICD9CM:
+		return icd9cmPattern.MatchString(code)
+	case LOINC:
+		return loincPattern.MatchString(code) && loincCheckDigit(code[:len(code)-2]) == code[len(code)-1]
+	case KFA:
+		return kfaPattern.MatchString(code)
+	default:
+		return code != ""
+	}
+}
+
+func loincCheckDigit(number string) byte {
+	sum := 0
+	for i := 0; i < len(number); i++ {
+		digit := int(number[len(number)-1-i] - '0')
+		if i%2 == 0 {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+	}
+	return byte('0' + (10-sum%10)%10)
+}
@@ -0,0 +1,221 @@
+package terminology
+
+import (
+	"embed"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+type System string
+
+const (
+	ICD10  System = "icd10"
+	ICD9CM System = "icd9cm"
+	LOINC  System = "loinc"
+	KFA    System = "kfa"
+)
+
+const (
+	RuleFormat     = "format"          // code doesn't follow the syntax of its code system
+	RuleIncomplete = "incomplete_code" // code is a category, a more specific sub code is required
+	RuleUnknown    = "unknown_code"    // code is not part of a complete code table
+)
+
+// embedded holds subsets of every code system, they normalize display names but are too small to reject unknown codes.
+//
+//go:embed data/*.csv
+var embedded embed.FS
+
+// CodeSystem is a code table of a single System.
+// Complete is true when the table was loaded from a file and holds every valid code.
+type CodeSystem struct {
+	System   System
+	Complete bool
+	displays map[string]string
+}
+
+type Code struct {
+	Code    string
+	Display string
+}
+
+// Result is the outcome of looking up a code, Rule is empty when the code is valid.
+type Result struct {
+	Code    string
+	Display string
+	Rule    string
+}
+
+type Terminology struct {
+	systems map[System]*CodeSystem
+}
+
+type Option func(t *Terminology) error
+
+// WithCodeFile replaces the embedded subset of a code system by a complete CSV table with a code,display header.
+func WithCodeFile(system System, path string) Option {
+	return func(t *Terminology) error {
+		if path == "" {
+			return nil
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to open %s code file: %w", system, err)
+		}
+		defer file.Close()
+
+		codeSystem, err := readCodeSystem(system, file)
+		if err != nil {
+			return fmt.Errorf("failed to read %s code file %s: %w", system, path, err)
+		}
+
+		codeSystem.Complete = true
+		t.systems[system] = codeSystem
+		return nil
+	}
+}
+
+func New(options ...Option) (*Terminology, error) {
+	terminology := &Terminology{
+		systems: map[System]*CodeSystem{},
+	}
+
+	for _, system := range []System{ICD10, ICD9CM, LOINC, KFA} {
+		file, err := embedded.Open(fmt.Sprintf("data/%s.csv", system))
+		if err != nil {
+			return nil, err
+		}
+
+		codeSystem, err := readCodeSystem(system, file)
+		_ = file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read embedded %s codes: %w", system, err)
+		}
+
+		terminology.systems[system] = codeSystem
+	}
+
+	for _, option := range options {
+		if err := option(terminology); err != nil {
+			return nil, err
+		}
+	}
+
+	return terminology, nil
+}
+
+func readCodeSystem(system System, reader io.Reader) (*CodeSystem, error) {
+	// only the first two columns are read, exports of the code system sites usually carry a few more
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1
+
+	header, err := csvReader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(header) < 2 || strings.ToLower(strings.TrimSpace(header[0])) != "code" {
+		return nil, errors.New("header must start with the code and display columns")
+	}
+
+	codeSystem := &CodeSystem{
+		System:   system,
+		displays: map[string]string{},
+	}
+
+	for {
+		record, err := csvReader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if len(record) < 2 {
+			line, _ := csvReader.FieldPos(0)
+			return nil, fmt.Errorf("line %d: missing display column", line)
+		}
+
+		codeSystem.displays[Normalize(system, record[0])] = strings.TrimSpace(record[1])
+	}
+
+	return codeSystem, nil
+}
+
+// Len returns the number of codes in the table.
+func (c *CodeSystem) Len() int {
+	return len(c.displays)
+}
+
+// Codes returns every code of the table sorted by code.
+func (c *CodeSystem) Codes() []Code {
+	codes := make([]Code, 0, len(c.displays))
+	for code, display := range c.displays {
+		codes = append(codes, Code{Code: code, Display: display})
+	}
+
+	sort.Slice(codes, func(i, k int) bool {
+		return codes[i].Code < codes[k].Code
+	})
+	return codes
+}
+
+// hasSubCodes reports whether the table holds more specific codes of the given category, e.g. J06.9 for J06.
+func (c *CodeSystem) hasSubCodes(code string) bool {
+	prefix := code + "."
+	if strings.Contains(code, ".") {
+		prefix = code
+	}
+
+	for known := range c.displays {
+		if known != code && strings.HasPrefix(known, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// CodeSystem returns the table of a code system, nil when the Terminology is nil.
+func (t *Terminology) CodeSystem(system System) *CodeSystem {
+	if t == nil {
+		return nil
+	}
+	return t.systems[system]
+}
+
+// Lookup normalizes a code and checks it against its code system.
+// Unknown codes are only rejected when the code system is Complete, codes missing from an embedded subset are accepted.
+func (t *Terminology) Lookup(system System, code string) Result {
+	result := Result{Code: Normalize(system, code)}
+
+	if !WellFormed(system, result.Code) {
+		result.Rule = RuleFormat
+		return result
+	}
+
+	codeSystem := t.CodeSystem(system)
+	if codeSystem == nil {
+		return result
+	}
+
+	if display, ok := codeSystem.displays[result.Code]; ok {
+		result.Display = display
+		return result
+	}
+
+	switch {
+	case (system == ICD10 || system == ICD9CM) && codeSystem.hasSubCodes(result.Code):
+		result.Rule = RuleIncomplete
+	case codeSystem.Complete:
+		result.Rule = RuleUnknown
+	}
+
+	return result
+}
@@ -0,0 +1,126 @@
+package terminology
+
+import (
+	"encoding/json"
+	"github.com/jasoet/fhir-worker/pkg/util"
+	"github.com/jasoet/fhir-worker/shared/model"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		system System
+		code   string
+		want   string
+	}{
+		{ICD10, "A09.9 ", "A09.9"},
+		{ICD10, " a09,9", "A09.9"},
+		{ICD10, "A099", "A09.9"},
+		{ICD10, "J06", "J06"},
+		{ICD10, "I10.", "I10"},
+		{ICD9CM, "8952", "89.52"},
+		{ICD9CM, " 89.7 ", "89.7"},
+		{LOINC, " 718-7", "718-7"},
+		{KFA, "9300 1019", "93001019"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.system)+tt.code, func(t *testing.T) {
+			assert.Equal(t, tt.want, Normalize(tt.system, tt.code))
+		})
+	}
+}
+
+func TestWellFormed(t *testing.T) {
+	assert.True(t, WellFormed(ICD10, "A09.9"))
+	assert.True(t, WellFormed(ICD10, "I10"))
+	assert.False(t, WellFormed(ICD10, "LOKAL-01"))
+	assert.True(t, WellFormed(ICD9CM, "89.52"))
+	assert.False(t, WellFormed(ICD9CM, "A09"))
+	assert.True(t, WellFormed(LOINC, "8480-6"))
+	assert.False(t, WellFormed(LOINC, "8480-5"), "check digit mismatch")
+	assert.False(t, WellFormed(KFA, "KFA-1"))
+}
+
+func TestEmbeddedCodesAreWellFormed(t *testing.T) {
+	terminology, err := New()
+	assert.NoError(t, err)
+
+	for system, codeSystem := range terminology.systems {
+		assert.False(t, codeSystem.Complete)
+		for code := range codeSystem.displays {
+			assert.True(t, WellFormed(system, code), "%s %s", system, code)
+		}
+	}
+}
+
+func TestTerminology_Lookup(t *testing.T) {
+	terminology, err := New()
+	assert.NoError(t, err)
+
+	result := terminology.Lookup(ICD10, "a09.9 ")
+	assert.Equal(t, Result{Code: "A09.9", Display: "Gastroenteritis and colitis of unspecified origin"}, result)
+
+	assert.Equal(t, RuleIncomplete, terminology.Lookup(ICD10, "J06").Rule)
+	assert.Equal(t, RuleFormat, terminology.Lookup(ICD10, "LOKAL-01").Rule)
+	assert.Empty(t, terminology.Lookup(ICD10, "K52.9").Rule, "unknown codes are accepted by the embedded subset")
+
+	path := filepath.Join(t.TempDir(), "icd10.csv")
+	assert.NoError(t, os.WriteFile(path, []byte("code,display\nA09.9,Gastroenteritis\n"), 0o600))
+
+	terminology, err = New(WithCodeFile(ICD10, path))
+	assert.NoError(t, err)
+	assert.Equal(t, RuleUnknown, terminology.Lookup(ICD10, "K52.9").Rule, "unknown codes are rejected by a complete table")
+	assert.Equal(t, "Gastroenteritis", terminology.Lookup(ICD10, "A09.9").Display)
+}
+
+func TestTerminology_Normalize(t *testing.T) {
+	terminology, err := New()
+	assert.NoError(t, err)
+
+	diagnosis := model.Diagnosis{DiagnosisCode: "i10 ", DiagnosisName: "Hipertensi"}
+	terminology.NormalizeDiagnosis(&diagnosis)
+	assert.Equal(t, "I10", diagnosis.DiagnosisCode)
+	assert.Equal(t, "Essential (primary) hypertension", diagnosis.DiagnosisName)
+
+	lab := model.ObservationLab{
+		LabLoincCode: util.MarshalToJson([]string{" 718-7", "1234-5"}),
+		LabLoincName: util.MarshalToJson([]string{"HB", "Lokal"}),
+	}
+	terminology.NormalizeLab(&lab)
+
+	var codes, names []string
+	assert.NoError(t, json.Unmarshal(*lab.LabLoincCode, &codes))
+	assert.NoError(t, json.Unmarshal(*lab.LabLoincName, &names))
+	assert.Equal(t, []string{"718-7", "1234-5"}, codes)
+	assert.Equal(t, []string{"Hemoglobin [Mass/volume] in Blood", "Lokal"}, names)
+
+	var disabled *Terminology
+	diagnosis = model.Diagnosis{DiagnosisCode: "i10 "}
+	disabled.NormalizeDiagnosis(&diagnosis)
+	assert.Equal(t, "i10 ", diagnosis.DiagnosisCode)
+}
+
+func TestTerminology_DiagnosisIssues(t *testing.T) {
+	terminology, err := New()
+	assert.NoError(t, err)
+
+	list := model.DiagnosisList{
+		{DiagnosisCode: "A09.9"},
+		{DiagnosisCode: "J06"},
+		{DiagnosisCode: ""},
+	}
+
+	issues := terminology.DiagnosisIssues(&list)
+	if assert.Len(t, issues, 1) {
+		assert.Equal(t, RuleIncomplete, issues[0].Rule)
+		assert.Equal(t, "diagnosis_code", issues[0].Field)
+		assert.Equal(t, `ICD-10 code "J06" is a category, use a more specific sub code on diagnosis J06`, issues[0].MessageEn)
+	}
+
+	var disabled *Terminology
+	assert.Empty(t, disabled.DiagnosisIssues(&list))
+}
@@ -0,0 +1,140 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"os"
+)
+
+const (
+	ExporterOtlp = "otlp" // OTLP over HTTP, e.g. to an OpenTelemetry Collector, Jaeger or Tempo
+	ExporterFile = "file" // one JSON span per line in a local file, for hospitals without a collector
+)
+
+const defaultServiceName = "fhir-worker"
+
+// Span attributes. Only identifiers and counts are recorded, never patient data: no names, NIK, diagnoses or payloads.
+const (
+	VisitID        = attribute.Key("visit.id")
+	VisitCount     = attribute.Key("visit.count")
+	OrganizationID = attribute.Key("satusehat.organization_id")
+	Operation      = attribute.Key("satusehat.operation")
+	StatusCode     = semconv.HTTPResponseStatusCodeKey
+)
+
+type Config struct {
+	Exporter    string            `yaml:"exporter" mapstructure:"exporter"`
+	Endpoint    string            `yaml:"endpoint" mapstructure:"endpoint"`
+	Insecure    bool              `yaml:"insecure" mapstructure:"insecure"`
+	Headers     map[string]string `yaml:"headers" mapstructure:"headers"`
+	FilePath    string            `yaml:"file_path" mapstructure:"file_path"`
+	SampleRatio *float64          `yaml:"sample_ratio" mapstructure:"sample_ratio"`
+	ServiceName string            `yaml:"service_name" mapstructure:"service_name"`
+}
+
+// Setup installs the global tracer provider exporting to the configured exporter and returns the function flushing
+// and closing it. Tracing stays a no-op when config.Exporter is empty.
+func Setup(ctx context.Context, config Config, attributes ...attribute.KeyValue) (func(ctx context.Context) error, error) {
+	if config.Exporter == "" {
+		return func(ctx context.Context) error { return nil }, nil
+	}
+
+	var exporter sdktrace.SpanExporter
+	var closeFile func() error
+
+	switch config.Exporter {
+	case ExporterOtlp:
+		options := []otlptracehttp.Option{otlptracehttp.WithHeaders(config.Headers)}
+		if config.Endpoint != "" {
+			options = append(options, otlptracehttp.WithEndpoint(config.Endpoint))
+		}
+		if config.Insecure {
+			options = append(options, otlptracehttp.WithInsecure())
+		}
+
+		otlpExporter, err := otlptracehttp.New(ctx, options...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+		}
+		exporter = otlpExporter
+	case ExporterFile:
+		if config.FilePath == "" {
+			return nil, fmt.Errorf("tracing.file_path is required for the file exporter")
+		}
+
+		file, err := os.OpenFile(config.FilePath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open trace file: %w", err)
+		}
+
+		fileExporter, err := stdouttrace.New(stdouttrace.WithWriter(file))
+		if err != nil {
+			_ = file.Close()
+			return nil, fmt.Errorf("failed to create file exporter: %w", err)
+		}
+		exporter = fileExporter
+		closeFile = file.Close
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q, use %s or %s", config.Exporter, ExporterOtlp, ExporterFile)
+	}
+
+	serviceName := config.ServiceName
+	if serviceName == "" {
+		serviceName = defaultServiceName
+	}
+
+	sampleRatio := 1.0
+	if config.SampleRatio != nil {
+		sampleRatio = *config.SampleRatio
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL,
+			append([]attribute.KeyValue{semconv.ServiceName(serviceName)}, attributes...)...)),
+	)
+
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if closeFile != nil {
+			err = errors.Join(err, closeFile())
+		}
+		return err
+	}, nil
+}
+
+// SafeError is implemented by errors whose message may hold patient data, e.g. a SatuSehat response body,
+// End only records their SafeMessage.
+type SafeError interface {
+	error
+	SafeMessage() string
+}
+
+// End marks span failed with err, when not nil, and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		var safe SafeError
+		if errors.As(err, &safe) {
+			span.SetStatus(codes.Error, safe.SafeMessage())
+		} else {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}
+	span.End()
+}
@@ -0,0 +1,74 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type patientError struct{}
+
+func (patientError) Error() string       { return "patient with NIK 3404000000000001 not found" }
+func (patientError) SafeMessage() string { return "patient not found" }
+
+func TestSetupFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces.jsonl")
+
+	shutdown, err := Setup(context.Background(), Config{Exporter: ExporterFile, FilePath: path},
+		OrganizationID.String("ORG-1"))
+	require.NoError(t, err)
+
+	_, span := otel.Tracer("test").Start(context.Background(), "Publish.processInternal")
+	span.SetAttributes(VisitID.String("V-1"))
+	End(span, nil)
+
+	require.NoError(t, shutdown(context.Background()))
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), `"Name":"Publish.processInternal"`)
+	assert.Contains(t, string(content), `"V-1"`)
+	assert.Contains(t, string(content), `"ORG-1"`)
+}
+
+func TestSetupInvalid(t *testing.T) {
+	shutdown, err := Setup(context.Background(), Config{})
+	require.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+
+	_, err = Setup(context.Background(), Config{Exporter: "zipkin"})
+	assert.ErrorContains(t, err, "unknown tracing exporter")
+
+	_, err = Setup(context.Background(), Config{Exporter: ExporterFile})
+	assert.ErrorContains(t, err, "file_path")
+}
+
+func TestEnd(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+
+	_, span := tracer.Start(context.Background(), "safe")
+	End(span, errors.Join(errors.New("request failed"), patientError{}))
+
+	_, span = tracer.Start(context.Background(), "plain")
+	End(span, errors.New("connection refused"))
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Equal(t, "patient not found", spans[0].Status().Description)
+	assert.Empty(t, spans[0].Events())
+
+	assert.Equal(t, "connection refused", spans[1].Status().Description)
+	require.Len(t, spans[1].Events(), 1)
+	assert.Equal(t, "exception", spans[1].Events()[0].Name)
+}
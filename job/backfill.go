@@ -0,0 +1,193 @@
+package job
+
+import (
+	"context"
+	"fmt"
+	"github.com/jasoet/fhir-worker/internal/db"
+	"github.com/jasoet/fhir-worker/internal/entity"
+	"github.com/rs/zerolog/log"
+	"sort"
+	"time"
+)
+
+// Backfill walks a historical date range in fixed-size chunks and stores every visit found,
+// persisting each finished chunk so an interrupted run resumes where it stopped.
+type Backfill struct {
+	from       time.Time
+	to         time.Time
+	chunkSize  time.Duration
+	delay      time.Duration
+	mapping    *Mapping
+	repository *db.Repository
+}
+
+type BackfillOption func(b *Backfill) error
+
+func WithBackfillRange(from time.Time, to time.Time) BackfillOption {
+	return func(b *Backfill) error {
+		if !from.Before(to) {
+			return fmt.Errorf("backfill.from must be before backfill.to")
+		}
+
+		b.from = from
+		b.to = to
+		return nil
+	}
+}
+
+func WithChunkSize(chunkSize time.Duration) BackfillOption {
+	return func(b *Backfill) error {
+		if chunkSize <= 0 {
+			return fmt.Errorf("backfill.chunkSize must be positive")
+		}
+
+		b.chunkSize = chunkSize
+		return nil
+	}
+}
+
+func WithChunkDelay(delay time.Duration) BackfillOption {
+	return func(b *Backfill) error {
+		b.delay = delay
+		return nil
+	}
+}
+
+func WithMappingAndRepository(mapping *Mapping, repository *db.Repository) BackfillOption {
+	return func(b *Backfill) error {
+		b.mapping = mapping
+		b.repository = repository
+		return nil
+	}
+}
+
+func NewBackfill(options ...BackfillOption) (*Backfill, error) {
+	backfill := &Backfill{
+		chunkSize: 24 * time.Hour,
+		delay:     5 * time.Second,
+	}
+
+	for _, option := range options {
+		if err := option(backfill); err != nil {
+			return nil, err
+		}
+	}
+
+	if backfill.from.IsZero() || backfill.to.IsZero() {
+		return nil, fmt.Errorf("backfill.from and backfill.to are required")
+	}
+
+	if backfill.mapping == nil {
+		return nil, fmt.Errorf("backfill.mapping is required")
+	}
+
+	if backfill.repository == nil {
+		return nil, fmt.Errorf("backfill.repository is required")
+	}
+
+	return backfill, nil
+}
+
+// RangeKey identifies a backfill run, the same range and chunk size always resume the same checkpoints.
+func (b *Backfill) RangeKey() string {
+	return fmt.Sprintf("%s|%s|%s", b.from.Format(time.RFC3339), b.to.Format(time.RFC3339), b.chunkSize)
+}
+
+// Run processes every chunk that has no checkpoint yet or had failed visits and returns all chunks of the range,
+// including those finished by earlier runs.
+func (b *Backfill) Run(ctx context.Context) ([]entity.BackfillChunk, error) {
+	rangeKey := b.RangeKey()
+
+	_log := log.With().Ctx(ctx).Str("function", "Backfill").
+		Str("range-key", rangeKey).
+		Logger()
+
+	checkpoints, err := b.repository.BackfillChunks(ctx, rangeKey)
+	if err != nil {
+		_log.Error().Err(err).Msg("Failed to load backfill checkpoints.")
+		return nil, err
+	}
+
+	// A chunk with failed visits keeps its checkpoint for the counts but is fetched again, its checkpoint is replaced.
+	var finished []entity.BackfillChunk
+	done := make(map[int64]bool, len(checkpoints))
+	for _, chunk := range checkpoints {
+		if chunk.FailedCount == 0 {
+			done[chunk.ChunkStart.Unix()] = true
+			finished = append(finished, chunk)
+		}
+	}
+
+	_log.Info().
+		Int("finished-chunks", len(finished)).
+		Msg("backfill started")
+
+	first := true
+	for chunkStart := b.from; chunkStart.Before(b.to); chunkStart = chunkStart.Add(b.chunkSize) {
+		chunkEnd := chunkStart.Add(b.chunkSize)
+		if chunkEnd.After(b.to) {
+			chunkEnd = b.to
+		}
+
+		if done[chunkStart.Unix()] {
+			_log.Debug().Time("chunk-start", chunkStart).Msg("Chunk already processed, skipping...")
+			continue
+		}
+
+		if !first {
+			select {
+			case <-ctx.Done():
+				_log.Info().Msg("context done, backfill terminated")
+				return finished, ctx.Err()
+			case <-time.After(b.delay):
+			}
+		}
+		first = false
+
+		// GetVisitBetween is inclusive on both ends, stop just before the next chunk starts.
+		result, err := b.mapping.FetchVisitBetween(ctx, chunkStart, chunkEnd.Add(-time.Second))
+		if err != nil {
+			_log.Error().Err(err).Time("chunk-start", chunkStart).Msg("Failed to fetch visits for chunk.")
+			return finished, err
+		}
+
+		chunk := entity.BackfillChunk{
+			RangeKey:      rangeKey,
+			ChunkStart:    chunkStart,
+			ChunkEnd:      chunkEnd,
+			VisitCount:    result.VisitCount,
+			ValidCount:    result.ValidCount,
+			InvalidCount:  result.InvalidCount,
+			ExistingCount: result.ExistingCount,
+			FailedCount:   result.FailedCount,
+			FinishedAt:    time.Now(),
+		}
+
+		if _, err := b.repository.SaveBackfillChunk(ctx, chunk); err != nil {
+			_log.Error().Err(err).Time("chunk-start", chunkStart).Msg("Failed to save backfill checkpoint.")
+			return finished, err
+		}
+
+		finished = append(finished, chunk)
+
+		_log.Info().
+			Time("chunk-start", chunkStart).
+			Time("chunk-end", chunkEnd).
+			Int("visit-count", result.VisitCount).
+			Int("valid-count", result.ValidCount).
+			Int("invalid-count", result.InvalidCount).
+			Int("existing-count", result.ExistingCount).
+			Int("failed-count", result.FailedCount).
+			Msg("backfill chunk finished")
+	}
+
+	sort.Slice(finished, func(i, k int) bool {
+		return finished[i].ChunkStart.Before(finished[k].ChunkStart)
+	})
+
+	_log.Info().
+		Int("finished-chunks", len(finished)).
+		Msg("backfill finished")
+
+	return finished, nil
+}
@@ -0,0 +1,168 @@
+package job
+
+import (
+	"context"
+	"fmt"
+	"github.com/jasoet/fhir-worker/internal/entity"
+	"github.com/jasoet/fhir-worker/shared/model"
+	"strings"
+	"time"
+)
+
+type completenessList interface {
+	Invalid() bool
+	Validate() []model.ValidationIssue
+}
+
+// termList adds the issues found by the terminology code tables to the struct validation of a list.
+type termList struct {
+	completenessList
+	terms []model.ValidationIssue
+}
+
+func (t termList) Invalid() bool {
+	return t.completenessList.Invalid() || len(t.terms) > 0
+}
+
+func (t termList) Validate() []model.ValidationIssue {
+	return append(t.completenessList.Validate(), t.terms...)
+}
+
+func (j *Mapping) diagnosis(internal *entity.SatuSehatInternal) completenessList {
+	list := internal.Diagnosis()
+	return termList{list, j.terminology.DiagnosisIssues(list)}
+}
+
+func (j *Mapping) lab(internal *entity.SatuSehatInternal) completenessList {
+	list := internal.Lab()
+	return termList{list, j.terminology.LabIssues(list)}
+}
+
+func (j *Mapping) radiology(internal *entity.SatuSehatInternal) completenessList {
+	list := internal.Radiology()
+	return termList{list, j.terminology.RadiologyIssues(list)}
+}
+
+func (j *Mapping) medicationRequest(internal *entity.SatuSehatInternal) completenessList {
+	list := internal.MedicationRequest()
+	return termList{list, j.terminology.MedicationRequestIssues(list)}
+}
+
+func (j *Mapping) medicationDispense(internal *entity.SatuSehatInternal) completenessList {
+	list := internal.MedicationDispense()
+	return termList{list, j.terminology.MedicationDispenseIssues(list)}
+}
+
+func (j *Mapping) procedure(internal *entity.SatuSehatInternal) completenessList {
+	list := internal.Procedure()
+	return termList{list, j.terminology.ProcedureIssues(list)}
+}
+
+func (j *Mapping) immunization(internal *entity.SatuSehatInternal) completenessList {
+	list := internal.Immunization()
+	return termList{list, j.terminology.ImmunizationIssues(list)}
+}
+
+// optionalResources enrich the bundle when charted but most visits have none, an empty list doesn't hold a visit back.
+var optionalResources = map[string]bool{
+	"anamnesis":     true,
+	"allergy":       true,
+	"immunization":  true,
+	"clinical_note": true,
+}
+
+func resourceCompleteness(resource string, disabled bool, count int, list completenessList) entity.ResourceCompleteness {
+	result := entity.ResourceCompleteness{
+		Resource: resource,
+		Count:    count,
+	}
+
+	switch {
+	case disabled:
+		result.Status = entity.ResourceDisabled
+	case count == 0 && optionalResources[resource]:
+		result.Status = entity.ResourceNone
+	case count == 0:
+		result.Status = entity.ResourceMissing
+	case list.Invalid():
+		result.Status = entity.ResourceInvalid
+		result.Issues = list.Validate()
+	default:
+		result.Status = entity.ResourceComplete
+	}
+
+	return result
+}
+
+// Evaluate reports the completeness of every clinical resource of a visit and whether it is READY to publish.
+// A visit is READY once every enabled resource is complete or, for an optional resource, not charted at all, or when
+// markCompleteDays have passed since the visit.
+func (j *Mapping) Evaluate(internal *entity.SatuSehatInternal) entity.MappingReport {
+	resources := []entity.ResourceCompleteness{
+		resourceCompleteness("diagnosis", j.DisableDiagnosis, listLen(internal.Diagnosis()), j.diagnosis(internal)),
+		resourceCompleteness("lab", j.DisableLab, listLen(internal.Lab()), j.lab(internal)),
+		resourceCompleteness("radiology", j.DisableRadiology, listLen(internal.Radiology()), j.radiology(internal)),
+		resourceCompleteness("medication_request", j.DisableMedication, listLen(internal.MedicationRequest()), j.medicationRequest(internal)),
+		resourceCompleteness("medication_dispense", j.DisableMedication, listLen(internal.MedicationDispense()), j.medicationDispense(internal)),
+		resourceCompleteness("procedure", j.DisableProcedure, listLen(internal.Procedure()), j.procedure(internal)),
+		resourceCompleteness("anamnesis", j.DisableAnamnesis, listLen(internal.Anamnesis()), internal.Anamnesis()),
+		resourceCompleteness("allergy", j.DisableAllergy, listLen(internal.Allergy()), internal.Allergy()),
+		resourceCompleteness("immunization", j.DisableImmunization, listLen(internal.Immunization()), j.immunization(internal)),
+		resourceCompleteness("clinical_note", j.DisableClinicalNote, listLen(internal.ClinicalNote()), internal.ClinicalNote()),
+	}
+
+	report := entity.MappingReport{
+		CheckedAt: time.Now(),
+		Resources: resources,
+	}
+
+	var pending []string
+	for _, resource := range resources {
+		if !resource.Satisfied() {
+			pending = append(pending, fmt.Sprintf("%s (%s)", resource.Resource, resource.Status))
+		}
+	}
+
+	switch {
+	case len(pending) == 0:
+		report.Ready = true
+		report.Reason = "all enabled resources are complete"
+	case time.Since(internal.VisitDate) > time.Duration(j.markCompleteDays)*24*time.Hour:
+		report.Ready = true
+		report.Reason = fmt.Sprintf("visit is older than %d days, publishing valid records only, incomplete: %s", j.markCompleteDays, strings.Join(pending, ", "))
+	default:
+		report.Reason = fmt.Sprintf("waiting for %s", strings.Join(pending, ", "))
+	}
+
+	return report
+}
+
+// Explain evaluates a stored visit, it returns nil when the visit is not in the internal database.
+func (j *Mapping) Explain(ctx context.Context, visitId string) (*entity.SatuSehatInternal, *entity.MappingReport, error) {
+	internal, err := j.repository.GetByVisitId(ctx, visitId)
+	if err != nil || internal == nil {
+		return nil, nil, err
+	}
+
+	report := j.Evaluate(internal)
+	return internal, &report, nil
+}
+
+func listLen[S ~[]T, T any](list *S) int {
+	if list == nil {
+		return 0
+	}
+	return len(*list)
+}
+
+func mappingErrors(report entity.MappingReport) string {
+	return issueMessages(report.Issues())
+}
+
+func issueMessages(issues []model.ValidationIssue) string {
+	messages := make([]string, 0, len(issues))
+	for _, issue := range issues {
+		messages = append(messages, issue.MessageEn)
+	}
+	return strings.Join(messages, "\n")
+}
@@ -0,0 +1,147 @@
+package job
+
+import (
+	"encoding/json"
+	"github.com/jasoet/fhir-worker/internal/entity"
+	"github.com/jasoet/fhir-worker/pkg/util"
+	"github.com/jasoet/fhir-worker/shared/model"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestMapping_Evaluate(t *testing.T) {
+	validDiagnosis := util.MarshalToJson([]model.Diagnosis{
+		{VisitID: "1", DiagnosisCode: "A09.9", DiagnosisName: "Gastroenteritis", DiagnosisDate: time.Now()},
+	})
+	invalidDiagnosis := util.MarshalToJson([]model.Diagnosis{
+		{VisitID: "1", DiagnosisName: "Gastroenteritis", DiagnosisDate: time.Now()},
+	})
+
+	allDisabled := func(m *Mapping) {
+		m.DisableLab = true
+		m.DisableRadiology = true
+		m.DisableMedication = true
+		m.DisableProcedure = true
+		m.DisableAnamnesis = true
+		m.DisableAllergy = true
+		m.DisableImmunization = true
+		m.DisableClinicalNote = true
+	}
+
+	tests := []struct {
+		name       string
+		configure  func(m *Mapping)
+		internal   entity.SatuSehatInternal
+		wantReady  bool
+		wantStatus entity.ResourceStatus
+	}{
+		{
+			name:       "CompleteDiagnosisOthersDisabled",
+			configure:  allDisabled,
+			internal:   entity.SatuSehatInternal{VisitDate: time.Now(), DiagnosisJsonArr: validDiagnosis},
+			wantReady:  true,
+			wantStatus: entity.ResourceComplete,
+		},
+		{
+			name:       "MissingDiagnosis",
+			configure:  allDisabled,
+			internal:   entity.SatuSehatInternal{VisitDate: time.Now()},
+			wantReady:  false,
+			wantStatus: entity.ResourceMissing,
+		},
+		{
+			name:       "InvalidDiagnosis",
+			configure:  allDisabled,
+			internal:   entity.SatuSehatInternal{VisitDate: time.Now(), DiagnosisJsonArr: invalidDiagnosis},
+			wantReady:  false,
+			wantStatus: entity.ResourceInvalid,
+		},
+		{
+			name:       "InvalidDiagnosisAfterMarkCompleteDays",
+			configure:  allDisabled,
+			internal:   entity.SatuSehatInternal{VisitDate: time.Now().AddDate(0, 0, -8), DiagnosisJsonArr: invalidDiagnosis},
+			wantReady:  true,
+			wantStatus: entity.ResourceInvalid,
+		},
+		{
+			name: "DiagnosisDisabled",
+			configure: func(m *Mapping) {
+				allDisabled(m)
+				m.DisableDiagnosis = true
+			},
+			internal:   entity.SatuSehatInternal{VisitDate: time.Now()},
+			wantReady:  true,
+			wantStatus: entity.ResourceDisabled,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mapping := &Mapping{markCompleteDays: 7}
+			tt.configure(mapping)
+
+			report := mapping.Evaluate(&tt.internal)
+			assert.Equal(t, tt.wantReady, report.Ready, report.Reason)
+			assert.Equal(t, "diagnosis", report.Resources[0].Resource)
+			assert.Equal(t, tt.wantStatus, report.Resources[0].Status)
+
+			if tt.wantStatus == entity.ResourceInvalid {
+				assert.NotEmpty(t, report.Resources[0].Issues)
+				assert.Equal(t, "diagnosis_code", report.Resources[0].Issues[0].Field)
+			}
+
+			_, err := json.Marshal(report)
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestMapping_Evaluate_OptionalResources(t *testing.T) {
+	validDiagnosis := util.MarshalToJson([]model.Diagnosis{
+		{VisitID: "1", DiagnosisCode: "A09.9", DiagnosisName: "Gastroenteritis", DiagnosisDate: time.Now()},
+	})
+
+	tests := []struct {
+		name   string
+		enable func(m *Mapping)
+	}{
+		{"anamnesis", func(m *Mapping) { m.DisableAnamnesis = false }},
+		{"allergy", func(m *Mapping) { m.DisableAllergy = false }},
+		{"immunization", func(m *Mapping) { m.DisableImmunization = false }},
+		{"clinical_note", func(m *Mapping) { m.DisableClinicalNote = false }},
+		{"PlainOutpatientVisit", func(m *Mapping) {
+			m.DisableAnamnesis = false
+			m.DisableAllergy = false
+			m.DisableImmunization = false
+			m.DisableClinicalNote = false
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mapping := &Mapping{
+				markCompleteDays:    7,
+				DisableLab:          true,
+				DisableRadiology:    true,
+				DisableMedication:   true,
+				DisableProcedure:    true,
+				DisableAnamnesis:    true,
+				DisableAllergy:      true,
+				DisableImmunization: true,
+				DisableClinicalNote: true,
+			}
+			tt.enable(mapping)
+
+			// a visit charting none of the optional resources is READY as soon as it is filled
+			report := mapping.Evaluate(&entity.SatuSehatInternal{VisitDate: time.Now(), DiagnosisJsonArr: validDiagnosis})
+			assert.True(t, report.Ready, report.Reason)
+
+			for _, resource := range report.Resources {
+				if optionalResources[resource.Resource] && resource.Status != entity.ResourceDisabled {
+					assert.Equal(t, entity.ResourceNone, resource.Status, resource.Resource)
+				}
+			}
+		})
+	}
+}
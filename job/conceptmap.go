@@ -0,0 +1,49 @@
+package job
+
+import (
+	"context"
+	"github.com/jasoet/fhir-worker/internal/conceptmap"
+	"github.com/jasoet/fhir-worker/shared/model"
+	"github.com/rs/zerolog"
+	"strconv"
+	"time"
+)
+
+// conceptMapper fills the LOINC code of lab parameters SIMRS doesn't have from the active concept map,
+// local codes without an entry are recorded as the backlog for lab staff.
+type conceptMapper struct {
+	ctx        context.Context
+	logger     zerolog.Logger
+	mapping    *Mapping
+	conceptMap *conceptmap.ConceptMap
+}
+
+func (j *Mapping) newConceptMapper(ctx context.Context, logger zerolog.Logger) *conceptMapper {
+	mapper := &conceptMapper{
+		ctx:        ctx,
+		logger:     logger,
+		mapping:    j,
+		conceptMap: conceptmap.New(nil),
+	}
+
+	entries, err := j.repository.ActiveConceptMap(ctx)
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to load the active concept map, filling lab results without it.")
+		return mapper
+	}
+
+	mapper.conceptMap = conceptmap.New(entries)
+	return mapper
+}
+
+func (m *conceptMapper) normalizeLab(o *model.ObservationLab) {
+	for _, miss := range m.conceptMap.Apply(o) {
+		err := m.mapping.repository.RecordConceptMapMiss(m.ctx, miss.LocalCode, miss.Parameter, o.LabName, strconv.Itoa(o.VisitId), time.Now())
+		if err != nil {
+			m.logger.Error().Err(err).Str("local-code", miss.LocalCode).Str("parameter", miss.Parameter).
+				Msg("Failed to record unmapped lab code.")
+		}
+	}
+
+	m.mapping.terminology.NormalizeLab(o)
+}
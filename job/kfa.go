@@ -0,0 +1,83 @@
+package job
+
+import (
+	"context"
+	"github.com/jasoet/fhir-worker/internal/entity"
+	"github.com/jasoet/fhir-worker/pkg/util"
+	"github.com/jasoet/fhir-worker/shared/model"
+	"github.com/rs/zerolog"
+	"strconv"
+	"time"
+)
+
+// kfaMapper fills the KFA code SIMRS doesn't have from the local mapping table,
+// medicines without a mapping are recorded as the backlog for pharmacy staff.
+type kfaMapper struct {
+	ctx      context.Context
+	logger   zerolog.Logger
+	mapping  *Mapping
+	mappings map[string]entity.KfaMapping
+}
+
+func (j *Mapping) newKfaMapper(ctx context.Context, logger zerolog.Logger) *kfaMapper {
+	mapper := &kfaMapper{
+		ctx:      ctx,
+		logger:   logger,
+		mapping:  j,
+		mappings: map[string]entity.KfaMapping{},
+	}
+
+	mappings, err := j.repository.KfaMappings(ctx)
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to load KFA mappings, filling medication without them.")
+		return mapper
+	}
+
+	for _, mapping := range mappings {
+		mapper.mappings[mapping.MedicineCode] = mapping
+	}
+	return mapper
+}
+
+func (m *kfaMapper) apply(visitId int, medicineCode string, medicineName string, kfaCode *string, kfaName *string) (*string, *string) {
+	if util.NotEmpty(kfaCode) || medicineCode == "" {
+		return kfaCode, kfaName
+	}
+
+	if mapping, ok := m.mappings[medicineCode]; ok {
+		return &mapping.KfaCode, &mapping.KfaDisplay
+	}
+
+	err := m.mapping.repository.RecordUnmappedMedicine(m.ctx, medicineCode, strconv.Itoa(visitId), medicineName, time.Now())
+	if err != nil {
+		m.logger.Error().Err(err).Str("medicine-code", medicineCode).
+			Msg("Failed to record unmapped medicine.")
+	}
+
+	return kfaCode, kfaName
+}
+
+func (m *kfaMapper) normalizeMedicationRequest(o *model.MedicationRequest) {
+	o.KfaCode, o.KfaName = m.apply(o.VisitId, util.StringNotNil(o.MedicineCode), util.StringNotNil(o.MedicineName), o.KfaCode, o.KfaName)
+	m.applyIngredients(o.VisitId, o.Ingredients)
+	m.mapping.terminology.NormalizeMedicationRequest(o)
+}
+
+func (m *kfaMapper) normalizeMedicationDispense(o *model.MedicationDispense) {
+	o.KfaCode, o.KfaName = m.apply(o.VisitId, o.MedicineCode, o.MedicineName, o.KfaCode, o.KfaName)
+	m.applyIngredients(o.VisitId, o.Ingredients)
+	m.mapping.terminology.NormalizeMedicationDispense(o)
+}
+
+// applyIngredients fills the KFA code of every ingredient of a compound medication the way apply does for the medication itself.
+func (m *kfaMapper) applyIngredients(visitId int, ingredients model.MedicationIngredients) {
+	for i := range ingredients {
+		ingredient := &ingredients[i]
+		ingredient.KfaCode, ingredient.KfaName = m.apply(visitId, ingredient.MedicineCode, ingredient.MedicineName, ingredient.KfaCode, ingredient.KfaName)
+	}
+}
+
+func (m *kfaMapper) normalizeImmunization(o *model.Immunization) {
+	o.KfaCode, o.KfaName = m.apply(o.VisitId, o.MedicineCode, o.MedicineName, o.KfaCode, o.KfaName)
+	m.mapping.terminology.NormalizeImmunization(o)
+}
@@ -2,24 +2,37 @@ package job
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"github.com/jasoet/fhir-worker/internal/db"
 	"github.com/jasoet/fhir-worker/internal/entity"
+	"github.com/jasoet/fhir-worker/internal/terminology"
+	"github.com/jasoet/fhir-worker/internal/tracing"
+	"github.com/jasoet/fhir-worker/pkg/redact"
 	"github.com/jasoet/fhir-worker/simrs"
+	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"time"
 )
 
 type Mapping struct {
-	markCompleteDays  int
-	lastVisitDays     int
-	DisableDiagnosis  bool
-	DisableLab        bool
-	DisableRadiology  bool
-	DisableProcedure  bool
-	DisableMedication bool
-	queryOps          simrs.Query
-	repository        *db.Repository
+	markCompleteDays    int
+	lastVisitDays       int
+	fillBatchSize       int
+	DisableDiagnosis    bool
+	DisableLab          bool
+	DisableRadiology    bool
+	DisableProcedure    bool
+	DisableMedication   bool
+	DisableAnamnesis    bool
+	DisableAllergy      bool
+	DisableImmunization bool
+	DisableClinicalNote bool
+	queryOps            simrs.Query
+	repository          *db.Repository
+	terminology         *terminology.Terminology
 }
 
 type MappingOption func(o *Mapping) error
@@ -30,6 +43,10 @@ func WithDisableConfigs(
 	disableRadiology bool,
 	disableProcedure bool,
 	disableMedication bool,
+	disableAnamnesis bool,
+	disableAllergy bool,
+	disableImmunization bool,
+	disableClinicalNote bool,
 ) MappingOption {
 	return func(o *Mapping) error {
 		o.DisableDiagnosis = disableDiagnosis
@@ -37,6 +54,10 @@ func WithDisableConfigs(
 		o.DisableRadiology = disableRadiology
 		o.DisableProcedure = disableProcedure
 		o.DisableMedication = disableMedication
+		o.DisableAnamnesis = disableAnamnesis
+		o.DisableAllergy = disableAllergy
+		o.DisableImmunization = disableImmunization
+		o.DisableClinicalNote = disableClinicalNote
 		return nil
 	}
 }
@@ -50,6 +71,28 @@ func WithConfigDays(markCompleteDays int, lastVisitDays int) MappingOption {
 
 }
 
+func WithFillBatchSize(fillBatchSize int) MappingOption {
+	return func(o *Mapping) error {
+		if fillBatchSize > 0 {
+			o.fillBatchSize = fillBatchSize
+		}
+		return nil
+	}
+}
+
+// WithTerminology normalizes codes fetched from SIMRS and flags the ones rejected by the code tables.
+func WithTerminology(terminology *terminology.Terminology) MappingOption {
+	return func(o *Mapping) error {
+		o.terminology = terminology
+		return nil
+	}
+}
+
+// Terminology returns the code tables the mapping checks codes against, nil when terminology is disabled.
+func (j *Mapping) Terminology() *terminology.Terminology {
+	return j.terminology
+}
+
 func WithQueryAndRepository(queryOps simrs.Query, repository *db.Repository) MappingOption {
 	return func(o *Mapping) error {
 		o.queryOps = queryOps
@@ -62,6 +105,7 @@ func NewMapping(options ...MappingOption) (*Mapping, error) {
 	mapping := &Mapping{
 		markCompleteDays: 7,
 		lastVisitDays:    7,
+		fillBatchSize:    100,
 	}
 
 	var err error
@@ -94,27 +138,22 @@ func (j *Mapping) CheckComplete(ctx context.Context) error {
 		return err
 	}
 
-	for _, internal := range internals {
-		visitDate := internal.VisitDate
-		complete := false
-		if time.Since(visitDate) > time.Duration(j.markCompleteDays)*24*time.Hour {
-			complete = true
-		}
+	counter := counterFrom(ctx)
+	counter.Scanned(len(internals))
 
-		if !internal.Diagnosis().Invalid() && !internal.Lab().Invalid() && !internal.Radiology().Invalid() &&
-			!internal.MedicationRequest().Invalid() && !internal.MedicationDispense().Invalid() && !internal.Procedure().Invalid() {
-			complete = true
+	for _, internal := range internals {
+		report, err := j.check(ctx, &internal)
+		if err != nil {
+			_log.Error().Err(err).Str("visit-id", internal.VisitID).
+				Msg("Failed to check completeness.")
+			counter.Failed(1)
+			continue
 		}
 
-		if complete {
-			_, err := j.repository.UpdateMappingStatus(ctx, internal.VisitID, entity.Ready)
-			if err != nil {
-				_log.Error().Err(err).Str("visit-id", internal.VisitID).
-					Msg("Failed to update mapping status to 'Ready.'")
-				continue
-			}
+		counter.Processed(1)
 
-			_log.Debug().Str("visit-id", internal.VisitID).
+		if report.Ready {
+			_log.Debug().Str("visit-id", internal.VisitID).Str("reason", report.Reason).
 				Msg("Successfully updated mapping status to 'Ready.'")
 		}
 	}
@@ -122,6 +161,68 @@ func (j *Mapping) CheckComplete(ctx context.Context) error {
 	return nil
 }
 
+// check evaluates a visit, stores its mapping report and validation issues and marks it READY once it is.
+func (j *Mapping) check(ctx context.Context, internal *entity.SatuSehatInternal) (report entity.MappingReport, err error) {
+	ctx, span := tracer.Start(ctx, "Mapping.check", trace.WithAttributes(tracing.VisitID.String(internal.VisitID)))
+	defer func() {
+		span.SetAttributes(attribute.Bool("mapping.ready", report.Ready))
+		tracing.End(span, err)
+	}()
+
+	report = j.Evaluate(internal)
+
+	if _, err := j.repository.UpdateMappingReport(ctx, internal.VisitID, report, mappingErrors(report)); err != nil {
+		return report, fmt.Errorf("failed to update mapping report: %w", err)
+	}
+
+	if err := j.repository.ReplaceValidationIssues(ctx, internal.VisitID, report.Issues()); err != nil {
+		return report, fmt.Errorf("failed to save validation issues: %w", err)
+	}
+
+	if report.Ready {
+		if _, err := j.repository.UpdateMappingStatus(ctx, internal.VisitID, entity.Ready); err != nil {
+			return report, fmt.Errorf("failed to update mapping status to 'Ready': %w", err)
+		}
+	}
+
+	return report, nil
+}
+
+// Revalidate checks the completeness of a stored visit now instead of waiting for the next CheckComplete,
+// it returns nil when the visit is not in the internal database.
+func (j *Mapping) Revalidate(ctx context.Context, visitId string) (*entity.MappingReport, error) {
+	internal, err := j.repository.GetByVisitId(ctx, visitId)
+	if err != nil || internal == nil {
+		return nil, err
+	}
+
+	report, err := j.check(ctx, internal)
+	if err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// Refill fetches the incomplete clinical resources of a stored visit from SIMRS again and revalidates it,
+// it returns nil when the visit is not in the internal database.
+func (j *Mapping) Refill(ctx context.Context, visitId string) (*entity.MappingReport, error) {
+	_log := log.With().Ctx(ctx).Str("function", "Refill").Str("visit-id", visitId).
+		Logger()
+
+	internal, err := j.repository.GetByVisitId(ctx, visitId)
+	if err != nil || internal == nil {
+		return nil, err
+	}
+
+	if _, err := j.repository.UpdateMappingStatus(ctx, visitId, entity.Incomplete); err != nil {
+		return nil, err
+	}
+
+	j.fillBatch(ctx, []entity.SatuSehatInternal{*internal}, j.newConceptMapper(ctx, _log), _log)
+
+	return j.Revalidate(ctx, visitId)
+}
+
 func (j *Mapping) FillVisit(ctx context.Context) error {
 	_log := log.With().Ctx(ctx).Str("function", "FillVisit").
 		Logger()
@@ -135,148 +236,218 @@ func (j *Mapping) FillVisit(ctx context.Context) error {
 
 	_log.Info().
 		Int("visit-count", len(internals)).
+		Int("batch-size", j.fillBatchSize).
 		Bool("diagnosis-disabled", j.DisableDiagnosis).
 		Bool("lab-disabled", j.DisableLab).
 		Bool("radiology-disabled", j.DisableRadiology).
 		Bool("procedure-disabled", j.DisableProcedure).
 		Bool("medication-disabled", j.DisableMedication).
+		Bool("anamnesis-disabled", j.DisableAnamnesis).
+		Bool("allergy-disabled", j.DisableAllergy).
+		Bool("immunization-disabled", j.DisableImmunization).
+		Bool("clinical-note-disabled", j.DisableClinicalNote).
 		Msg("fill visit data job started")
 
-	for _, internal := range internals {
-		visitId := internal.VisitID
+	counterFrom(ctx).Scanned(len(internals))
 
-		_log = _log.With().
-			Str("visit-id", visitId).Logger()
+	// Loaded once per run, a concept map version activated meanwhile applies from the next run.
+	conceptMap := j.newConceptMapper(ctx, _log)
 
-		if !j.DisableDiagnosis && internal.Diagnosis().Invalid() {
-			_log.Debug().
-				Msg("process diagnosis data")
+	for start := 0; start < len(internals); start += j.fillBatchSize {
+		end := min(start+j.fillBatchSize, len(internals))
+		batch := internals[start:end]
 
-			diagnosis, err := j.queryOps.GetDiagnosisByVisitId(ctx, visitId)
-			if err != nil {
-				_log.Error().Err(err).
-					Msg("Failed to fetch diagnosis data.")
-				continue
-			}
+		select {
+		case <-ctx.Done():
+			_log.Info().Msg("context done, fill visit terminated")
+			return ctx.Err()
+		default:
+			j.fillBatch(ctx, batch, conceptMap, _log)
+			counterFrom(ctx).Processed(len(batch))
+		}
+	}
 
-			_, err = j.repository.UpdateDiagnosis(ctx, visitId, diagnosis)
-			if err != nil {
-				_log.Error().Err(err).
-					Msg("Failed to update diagnosis data.")
-				continue
-			}
+	_log.Info().
+		Int("visit-count", len(internals)).
+		Int("batch-size", j.fillBatchSize).
+		Bool("diagnosis-disabled", j.DisableDiagnosis).
+		Bool("lab-disabled", j.DisableLab).
+		Bool("radiology-disabled", j.DisableRadiology).
+		Bool("procedure-disabled", j.DisableProcedure).
+		Bool("medication-disabled", j.DisableMedication).
+		Bool("anamnesis-disabled", j.DisableAnamnesis).
+		Bool("allergy-disabled", j.DisableAllergy).
+		Bool("immunization-disabled", j.DisableImmunization).
+		Bool("clinical-note-disabled", j.DisableClinicalNote).
+		Msg("fill visit data job finished")
 
-			_log.Debug().Str("visit-id", visitId).
-				Msg("Successfully updated diagnosis data.")
-		}
+	return nil
+}
 
-		if !j.DisableLab && internal.Lab().Invalid() {
-			labs, err := j.queryOps.GetObservationLabByVisitId(ctx, visitId)
-			if err != nil {
-				_log.Error().Err(err).Str("visit-id", visitId).
-					Msg("Failed to fetch labs data.")
-				continue
-			}
+func (j *Mapping) fillBatch(ctx context.Context, batch []entity.SatuSehatInternal, conceptMap *conceptMapper, logger zerolog.Logger) {
+	visitIds := make([]string, 0, len(batch))
+	for _, internal := range batch {
+		visitIds = append(visitIds, internal.VisitID)
+	}
 
-			_, err = j.repository.UpdateLab(ctx, visitId, labs)
-			if err != nil {
-				_log.Error().Err(err).Str("visit-id", visitId).
-					Msg("Failed to update labs data.")
-				continue
-			}
+	ctx, span := tracer.Start(ctx, "Mapping.fillBatch", trace.WithAttributes(
+		tracing.VisitID.StringSlice(visitIds), tracing.VisitCount.Int(len(batch))))
+	defer span.End()
 
-			_log.Debug().Str("visit-id", visitId).
-				Msg("Successfully updated labs data.")
-		}
+	if !j.DisableDiagnosis {
+		visitIds := visitIdsWhere(batch, func(internal entity.SatuSehatInternal) bool {
+			return j.diagnosis(&internal).Invalid()
+		})
+		fetch := normalized(j.queryOps.GetDiagnosisByVisitIds, j.terminology.NormalizeDiagnosis)
+		fillResource(ctx, logger, "diagnosis", visitIds, fetch, j.repository.UpdateDiagnosis)
+	}
 
-		if !j.DisableRadiology && internal.Radiology().Invalid() {
-			radiologyData, err := j.queryOps.GetObservationRadiologyByVisitId(ctx, visitId)
-			if err != nil {
-				_log.Error().Err(err).Str("visit-id", visitId).
-					Msg("Failed to fetch radiology data.")
-				continue
-			}
+	if !j.DisableLab {
+		visitIds := visitIdsWhere(batch, func(internal entity.SatuSehatInternal) bool {
+			return j.lab(&internal).Invalid()
+		})
+		fetch := normalized(j.queryOps.GetObservationLabByVisitIds, conceptMap.normalizeLab)
+		fillResource(ctx, logger, "labs", visitIds, fetch, j.repository.UpdateLab)
+	}
 
-			_, err = j.repository.UpdateRadiology(ctx, visitId, radiologyData)
-			if err != nil {
-				_log.Error().Err(err).Str("visit-id", visitId).
-					Msg("Failed to update radiology data.")
-				continue
-			}
+	if !j.DisableRadiology {
+		visitIds := visitIdsWhere(batch, func(internal entity.SatuSehatInternal) bool {
+			return j.radiology(&internal).Invalid()
+		})
+		fetch := normalized(j.queryOps.GetObservationRadiologyByVisitIds, j.terminology.NormalizeRadiology)
+		fillResource(ctx, logger, "radiology", visitIds, fetch, j.repository.UpdateRadiology)
+	}
 
-			_log.Debug().Str("visit-id", visitId).
-				Msg("Successfully updated radiology data.")
-		}
+	if !j.DisableMedication {
+		kfa := j.newKfaMapper(ctx, logger)
 
-		if !j.DisableMedication && internal.MedicationRequest().Invalid() {
-			data, err := j.queryOps.GetMedicationRequestByVisitId(ctx, visitId)
-			if err != nil {
-				_log.Error().Err(err).Str("visit-id", visitId).
-					Msg("Failed to fetch MedicationRequest data.")
-				continue
-			}
+		visitIds := visitIdsWhere(batch, func(internal entity.SatuSehatInternal) bool {
+			return j.medicationRequest(&internal).Invalid()
+		})
+		fetchRequest := normalized(j.queryOps.GetMedicationRequestByVisitIds, kfa.normalizeMedicationRequest)
+		fillResource(ctx, logger, "MedicationRequest", visitIds, fetchRequest, j.repository.UpdateMedicationRequest)
 
-			_, err = j.repository.UpdateMedicationRequest(ctx, visitId, data)
-			if err != nil {
-				_log.Error().Err(err).Str("visit-id", visitId).
-					Msg("Failed to update MedicationRequest data.")
-				continue
-			}
+		visitIds = visitIdsWhere(batch, func(internal entity.SatuSehatInternal) bool {
+			return j.medicationDispense(&internal).Invalid()
+		})
+		fetchDispense := normalized(j.queryOps.GetMedicationDispenseByVisitIds, kfa.normalizeMedicationDispense)
+		fillResource(ctx, logger, "MedicationDispense", visitIds, fetchDispense, j.repository.UpdateMedicationDispense)
+	}
 
-			_log.Debug().Str("visit-id", visitId).
-				Msg("Successfully updated MedicationRequest data.")
-		}
+	if !j.DisableImmunization {
+		kfa := j.newKfaMapper(ctx, logger)
 
-		if !j.DisableMedication && internal.MedicationDispense().Invalid() {
-			data, err := j.queryOps.GetMedicationDispenseByVisitId(ctx, visitId)
-			if err != nil {
-				_log.Error().Err(err).Str("visit-id", visitId).
-					Msg("Failed to fetch MedicationDispense data.")
-				continue
-			}
+		visitIds := visitIdsWhere(batch, func(internal entity.SatuSehatInternal) bool {
+			return j.immunization(&internal).Invalid()
+		})
+		fetch := normalized(j.queryOps.GetImmunizationByVisitIds, kfa.normalizeImmunization)
+		fillResource(ctx, logger, "immunization", visitIds, fetch, j.repository.UpdateImmunization)
+	}
 
-			_, err = j.repository.UpdateMedicationDispense(ctx, visitId, data)
-			if err != nil {
-				_log.Error().Err(err).Str("visit-id", visitId).
-					Msg("Failed to update MedicationDispense data.")
-				continue
-			}
+	if !j.DisableProcedure {
+		visitIds := visitIdsWhere(batch, func(internal entity.SatuSehatInternal) bool {
+			return j.procedure(&internal).Invalid()
+		})
+		fetch := normalized(j.queryOps.GetProcedureByVisitIds, j.terminology.NormalizeProcedure)
+		fillResource(ctx, logger, "procedure", visitIds, fetch, j.repository.UpdateMedicalProcedure)
+	}
 
-			_log.Debug().Str("visit-id", visitId).
-				Msg("Successfully updated MedicationDispense data.")
+	if !j.DisableAnamnesis {
+		visitIds := visitIdsWhere(batch, func(internal entity.SatuSehatInternal) bool {
+			return internal.Anamnesis().Invalid()
+		})
+		fillResource(ctx, logger, "anamnesis", visitIds, j.queryOps.GetAnamnesisByVisitIds, j.repository.UpdateAnamnesis)
+	}
+
+	if !j.DisableAllergy {
+		visitIds := visitIdsWhere(batch, func(internal entity.SatuSehatInternal) bool {
+			return internal.Allergy().Invalid()
+		})
+		fillResource(ctx, logger, "allergy", visitIds, j.queryOps.GetAllergyByVisitIds, j.repository.UpdateAllergy)
+	}
+
+	if !j.DisableClinicalNote {
+		visitIds := visitIdsWhere(batch, func(internal entity.SatuSehatInternal) bool {
+			return internal.ClinicalNote().Invalid()
+		})
+		fillResource(ctx, logger, "clinical note", visitIds, j.queryOps.GetClinicalNoteByVisitIds, j.repository.UpdateClinicalNote)
+	}
+}
+
+func visitIdsWhere(internals []entity.SatuSehatInternal, predicate func(internal entity.SatuSehatInternal) bool) []string {
+	var visitIds []string
+	for _, internal := range internals {
+		if predicate(internal) {
+			visitIds = append(visitIds, internal.VisitID)
 		}
+	}
+	return visitIds
+}
 
-		if !j.DisableProcedure && internal.Procedure().Invalid() {
-			data, err := j.queryOps.GetProcedureByVisitId(ctx, visitId)
-			if err != nil {
-				_log.Error().Err(err).Str("visit-id", visitId).
-					Msg("Failed to fetch procedure data.")
-				continue
-			}
+// normalized applies normalize to every item returned by fetch before it is stored.
+func normalized[S ~[]T, T any](
+	fetch func(ctx context.Context, visitIds []string) (map[string]S, error),
+	normalize func(item *T),
+) func(ctx context.Context, visitIds []string) (map[string]S, error) {
+	return func(ctx context.Context, visitIds []string) (map[string]S, error) {
+		data, err := fetch(ctx, visitIds)
+		if err != nil {
+			return nil, err
+		}
 
-			_, err = j.repository.UpdateMedicalProcedure(ctx, visitId, data)
-			if err != nil {
-				_log.Error().Err(err).Str("visit-id", visitId).
-					Msg("Failed to update procedure data.")
-				continue
+		for _, items := range data {
+			for i := range items {
+				normalize(&items[i])
 			}
-
-			_log.Debug().Str("visit-id", visitId).
-				Msg("Successfully updated procedure data.")
 		}
+		return data, nil
+	}
+}
 
+// fillResource fetches one resource type for all visits with a single query and stores the result per visit.
+func fillResource[S ~[]T, T any](
+	ctx context.Context,
+	logger zerolog.Logger,
+	name string,
+	visitIds []string,
+	fetch func(ctx context.Context, visitIds []string) (map[string]S, error),
+	update func(ctx context.Context, visitId string, data []T) (sql.Result, error),
+) {
+	if len(visitIds) == 0 {
+		return
 	}
 
-	_log.Info().
-		Int("visit-count", len(internals)).
-		Bool("diagnosis-disabled", j.DisableDiagnosis).
-		Bool("lab-disabled", j.DisableLab).
-		Bool("radiology-disabled", j.DisableRadiology).
-		Bool("procedure-disabled", j.DisableProcedure).
-		Bool("medication-disabled", j.DisableMedication).
-		Msg("fill visit data job finished")
+	_log := logger.With().Str("resource", name).Int("visit-count", len(visitIds)).Logger()
 
-	return nil
+	_log.Debug().
+		Msgf("process %s data", name)
+
+	data, err := fetch(ctx, visitIds)
+	if err != nil {
+		_log.Error().Err(err).
+			Msgf("Failed to fetch %s data.", name)
+		return
+	}
+
+	for _, visitId := range visitIds {
+		_, err = update(ctx, visitId, data[visitId])
+		if err != nil {
+			_log.Error().Err(err).Str("visit-id", visitId).
+				Msgf("Failed to update %s data.", name)
+			continue
+		}
+
+		_log.Debug().Str("visit-id", visitId).
+			Msgf("Successfully updated %s data.", name)
+	}
+}
+
+type FetchResult struct {
+	VisitCount    int
+	ValidCount    int
+	InvalidCount  int
+	ExistingCount int
+	FailedCount   int
 }
 
 func (j *Mapping) FetchVisit(ctx context.Context) error {
@@ -287,7 +458,7 @@ func (j *Mapping) FetchVisit(ctx context.Context) error {
 		Time("startTime", startTime).Time("endTime", endTime).
 		Logger()
 
-	visits, err := j.queryOps.GetVisitBetween(ctx, startTime, endTime)
+	result, err := j.FetchVisitBetween(ctx, startTime, endTime)
 	if err != nil {
 		_log.Error().Err(err).
 			Msg("Failed to fetch visits.")
@@ -295,8 +466,48 @@ func (j *Mapping) FetchVisit(ctx context.Context) error {
 	}
 
 	_log.Info().
+		Int("visit-count", result.VisitCount).
+		Int("valid-count", result.ValidCount).
+		Int("invalid-count", result.InvalidCount).
+		Int("existing-count", result.ExistingCount).
+		Int("failed-count", result.FailedCount).
+		Msg("fetch visit data job finished")
+
+	counter := counterFrom(ctx)
+	counter.Scanned(result.VisitCount)
+	counter.Processed(result.ValidCount + result.InvalidCount)
+	counter.Failed(result.FailedCount)
+
+	return nil
+}
+
+func (j *Mapping) FetchVisitBetween(ctx context.Context, startTime time.Time, endTime time.Time) (result FetchResult, err error) {
+	ctx, span := tracer.Start(ctx, "Mapping.FetchVisitBetween")
+	defer func() {
+		span.SetAttributes(
+			tracing.VisitCount.Int(result.VisitCount),
+			attribute.Int("fetch.valid_count", result.ValidCount),
+			attribute.Int("fetch.invalid_count", result.InvalidCount),
+			attribute.Int("fetch.existing_count", result.ExistingCount),
+			attribute.Int("fetch.failed_count", result.FailedCount),
+		)
+		tracing.End(span, err)
+	}()
+
+	_log := log.With().Ctx(ctx).Str("function", "FetchVisitBetween").
+		Time("startTime", startTime).Time("endTime", endTime).
+		Logger()
+
+	visits, err := j.queryOps.GetVisitBetween(ctx, startTime, endTime)
+	if err != nil {
+		return result, err
+	}
+
+	result.VisitCount = len(visits)
+
+	_log.Debug().
 		Int("visit-count", len(visits)).
-		Msg("fetch visit data job started")
+		Msg("storing fetched visits")
 
 	for _, visit := range visits {
 		visitId := visit.VisitID
@@ -306,32 +517,41 @@ func (j *Mapping) FetchVisit(ctx context.Context) error {
 		if err != nil {
 			_log.Error().Err(err).Str("visit-id", visitId).
 				Msg("Visit check failed.")
+			result.FailedCount++
 			continue
 		}
 
 		if exists {
 			_log.Debug().Str("visit-id", visitId).
 				Msg("Visit exists, skipping...")
-
+			result.ExistingCount++
 			continue
 		}
 
-		validationErrors := visit.VisitDetail().Invalid()
+		issues := visit.VisitDetail().Validate()
 
-		if validationErrors != nil {
+		if len(issues) > 0 {
 			_log.Debug().Str("visit-id", visitId).
-				Any("VisitDetail", visit.VisitDetail()).
+				RawJSON("VisitDetail", redact.Value(visit.VisitDetail())).
 				Msg("Visit is invalid.")
 
-			_, err := j.repository.InsertInvalid(ctx, visitId, visit.PeriodStartDate, satusehatId, visit.VisitDetail(), visit.VitalSign(), validationErrors.Error())
+			_, err := j.repository.InsertInvalid(ctx, visitId, visit.PeriodStartDate, satusehatId, visit.VisitDetail(), visit.VitalSign(), issueMessages(issues))
 			if err != nil {
 				_log.Error().Err(err).Str("visit-id", visitId).
 					Msg("Failed to save invalid visit data.")
+				result.FailedCount++
 				continue
 			}
 
+			err = j.repository.ReplaceValidationIssues(ctx, visitId, issues)
+			if err != nil {
+				_log.Error().Err(err).Str("visit-id", visitId).
+					Msg("Failed to save validation issues.")
+			}
+
 			_log.Debug().Str("visit-id", visitId).
 				Msg("Saved visit successfully, but with 'Invalid' status.")
+			result.InvalidCount++
 			continue
 		}
 
@@ -339,16 +559,14 @@ func (j *Mapping) FetchVisit(ctx context.Context) error {
 		if err != nil {
 			_log.Error().Err(err).Str("visit-id", visitId).
 				Msg("Failed to save visit data.")
+			result.FailedCount++
 			continue
 		}
 
 		_log.Debug().Str("visit-id", visitId).
 			Msg("Successfully saved visit data.")
+		result.ValidCount++
 	}
 
-	_log.Info().
-		Int("visit-count", len(visits)).
-		Msg("fetch visit data job finished")
-
-	return nil
+	return result, nil
 }
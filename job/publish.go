@@ -6,27 +6,39 @@ import (
 	"fmt"
 	"github.com/jasoet/fhir-worker/shared/model"
 	"github.com/rs/zerolog"
+	"maps"
 	"os"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
 	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/jasoet/fhir-worker/internal/db"
 	"github.com/jasoet/fhir-worker/internal/entity"
+	"github.com/jasoet/fhir-worker/internal/metrics"
 	"github.com/jasoet/fhir-worker/internal/resource"
 	"github.com/jasoet/fhir-worker/internal/satusehat"
+	"github.com/jasoet/fhir-worker/internal/terminology"
+	"github.com/jasoet/fhir-worker/internal/tracing"
 	"github.com/jasoet/fhir-worker/pkg/file"
+	"github.com/jasoet/fhir-worker/pkg/redact"
+	"github.com/jasoet/fhir-worker/pkg/ucum"
 	"github.com/jasoet/fhir-worker/pkg/util"
 )
 
+const publishedAllergyAttempts = 3
+
 type Publish struct {
 	convertToUtc   bool
 	simulationMode bool
 	simulationDir  string
 	organizationId string
 	sendDelay      time.Duration
+	plausibility   map[string]resource.Plausibility
+	terminology    *terminology.Terminology
 	client         *satusehat.Client
 	repository     *db.Repository
 }
@@ -39,6 +51,7 @@ func NewPublish(options ...PublishOption) (*Publish, error) {
 		simulationMode: false,
 		convertToUtc:   false,
 		sendDelay:      2 * time.Second,
+		plausibility:   maps.Clone(resource.DefaultPlausibility),
 	}
 
 	for _, option := range options {
@@ -101,7 +114,23 @@ func WithSendDelay(delay time.Duration) PublishOption {
 	}
 }
 
+// WithPlausibility overrides the plausible range of observations by LOINC code, codes not given keep their default.
+func WithPlausibility(rules map[string]resource.Plausibility) PublishOption {
+	return func(p *Publish) error {
+		for code, rule := range rules {
+			if rule.Min > rule.Max {
+				return fmt.Errorf("publish.plausibility of %s: min %g is greater than max %g", code, rule.Min, rule.Max)
+			}
+			p.plausibility[code] = rule
+		}
+		return nil
+	}
+}
+
 func (p *Publish) Process(ctx context.Context) error {
+	ctx, span := tracer.Start(ctx, "Publish.Process")
+	defer span.End()
+
 	logger := log.With().Ctx(ctx).Str("function", "Publish Process").Logger()
 
 	internals, err := p.repository.ReadyToPublish(ctx)
@@ -110,11 +139,16 @@ func (p *Publish) Process(ctx context.Context) error {
 		return err
 	}
 
+	span.SetAttributes(tracing.VisitCount.Int(len(internals)), attribute.Bool("publish.simulation", p.simulationMode))
+
 	logger.Info().
 		Int("ready-visit-count", len(internals)).
 		Bool("simulation-mode", p.simulationMode).
 		Msg("publish process started")
 
+	counter := counterFrom(ctx)
+	counter.Scanned(len(internals))
+
 	if p.simulationMode {
 		logger = logger.With().Bool("simulation mode", p.simulationMode).Logger()
 		if err := os.MkdirAll(p.simulationDir, 0755); err != nil {
@@ -133,7 +167,10 @@ func (p *Publish) Process(ctx context.Context) error {
 
 			if err := p.processInternal(ctx, &internal, logger); err != nil {
 				logger.Error().Err(err).Str("VisitId", internal.VisitID).Msg("Failed to process internal")
+				counter.Failed(1)
+				continue
 			}
+			counter.Processed(1)
 		}
 	}
 
@@ -145,26 +182,131 @@ func (p *Publish) Process(ctx context.Context) error {
 	return nil
 }
 
-func (p *Publish) processInternal(ctx context.Context, internal *entity.SatuSehatInternal, logger zerolog.Logger) error {
-	bundle, err := p.generateBundle(internal)
+// WithPublishTerminology leaves records with a code rejected by the code tables out of the bundle, see WithTerminology.
+func WithPublishTerminology(terminology *terminology.Terminology) PublishOption {
+	return func(p *Publish) error {
+		p.terminology = terminology
+		return nil
+	}
+}
+
+func (p *Publish) processInternal(ctx context.Context, internal *entity.SatuSehatInternal, logger zerolog.Logger) (err error) {
+	ctx, span := tracer.Start(ctx, "Publish.processInternal", trace.WithAttributes(tracing.VisitID.String(internal.VisitID)))
+	defer func() { tracing.End(span, err) }()
+	ctx = satusehat.WithVisitID(ctx, internal.VisitID)
+
+	allergies, err := p.unpublishedAllergies(ctx, internal)
+	if err != nil {
+		logger.Error().Str("VisitId", internal.VisitID).Err(err).Msg("fetch published allergies failed")
+		return err
+	}
+
+	bundle, exclusions, err := p.generateBundle(internal, allergies)
 	if err != nil {
-		logger.Error().Any("bundle", bundle).Any("data", internal).Err(err).Msg("generate bundle failed")
+		logger.Error().RawJSON("bundle", redact.Value(bundle)).RawJSON("data", redact.Value(internal)).Err(err).Msg("generate bundle failed")
 		return err
 	}
 
+	if err := p.repository.ReplaceObservationExclusions(ctx, internal.VisitID, observationExclusions(exclusions)); err != nil {
+		logger.Error().Str("VisitId", internal.VisitID).Err(err).Msg("store observation exclusions failed")
+	}
+
 	payload, err := bundle.MarshalJSON()
 	if err != nil {
-		logger.Error().Any("data", internal).Err(err).Msg("marshalling JSON failed")
+		logger.Error().RawJSON("data", redact.Value(internal)).Err(err).Msg("marshalling JSON failed")
 		return err
 	}
 
 	logger.Debug().Str("VisitId", internal.VisitID).Int("payload_size", len(payload)).Msg("Processing data")
+	span.SetAttributes(attribute.Int("publish.payload_size", len(payload)), attribute.Int("publish.exclusion_count", len(exclusions)))
 
 	if p.simulationMode {
 		return p.simulateProcessing(internal.VisitID, payload, logger)
 	}
 
-	return p.sendToSatuSehat(ctx, internal.VisitID, payload, logger)
+	if err := p.sendToSatuSehat(ctx, internal.VisitID, payload, logger); err != nil {
+		return err
+	}
+
+	// The visit is published, failing it keeps a lost record in the job run, the allergies would be sent again with the
+	// next visit of the patient.
+	if err := p.storePublishedAllergies(ctx, internal, allergies); err != nil {
+		logger.Error().Str("VisitId", internal.VisitID).Err(err).Msg("store published allergies failed")
+		return err
+	}
+	return nil
+}
+
+// storePublishedAllergies records the allergies sent with a visit, retrying a few times before giving up.
+func (p *Publish) storePublishedAllergies(ctx context.Context, internal *entity.SatuSehatInternal, allergies model.AllergyList) error {
+	for attempt := 1; ; attempt++ {
+		err := p.repository.InsertPublishedAllergies(ctx, internal.SatusehatPatientID, internal.VisitID, allergyKeys(allergies))
+		if err == nil {
+			return nil
+		}
+		if attempt == publishedAllergyAttempts {
+			return fmt.Errorf("allergies of visit %s were published but not recorded: %w", internal.VisitID, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(time.Duration(attempt) * time.Second):
+		}
+	}
+}
+
+// Preview generates the bundle the next publish would send for a stored visit without sending it,
+// it returns nil when the visit is not in the internal database.
+func (p *Publish) Preview(ctx context.Context, visitId string) (*fhir.Bundle, []resource.Exclusion, error) {
+	internal, err := p.repository.GetByVisitId(ctx, visitId)
+	if err != nil || internal == nil {
+		return nil, nil, err
+	}
+
+	allergies, err := p.unpublishedAllergies(ctx, internal)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return p.generateBundle(internal, allergies)
+}
+
+// unpublishedAllergies returns the valid allergies of a visit that haven't been sent to SatuSehat for the patient yet,
+// an allergy recorded again in a later visit is published only once.
+func (p *Publish) unpublishedAllergies(ctx context.Context, internal *entity.SatuSehatInternal) (model.AllergyList, error) {
+	allergyList := internal.Allergy()
+	if allergyList == nil {
+		return nil, nil
+	}
+
+	keys, err := p.repository.PublishedAllergyKeys(ctx, internal.SatusehatPatientID)
+	if err != nil {
+		return nil, err
+	}
+
+	published := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		published[key] = true
+	}
+
+	var allergies model.AllergyList
+	for _, allergy := range *allergyList {
+		if allergy.Invalid() || published[allergy.Key()] {
+			continue
+		}
+		published[allergy.Key()] = true
+		allergies = append(allergies, allergy)
+	}
+	return allergies, nil
+}
+
+func allergyKeys(allergies model.AllergyList) []string {
+	keys := make([]string, 0, len(allergies))
+	for _, allergy := range allergies {
+		keys = append(keys, allergy.Key())
+	}
+	return keys
 }
 
 func (p *Publish) simulateProcessing(visitID string, payload []byte, logger zerolog.Logger) error {
@@ -175,6 +317,7 @@ func (p *Publish) simulateProcessing(visitID string, payload []byte, logger zero
 	}
 
 	logger.Debug().Int("payload_len", len(payload)).Str("filename", fileName).Msg("store payload data to file")
+	metrics.PublishAttempts.WithLabelValues("simulated", metrics.StatusCode(0)).Inc()
 	return nil
 }
 
@@ -203,47 +346,94 @@ func (p *Publish) sendToSatuSehat(ctx context.Context, visitID string, payload [
 	return nil
 }
 
-func (p *Publish) generateBundle(internal *entity.SatuSehatInternal) (*fhir.Bundle, error) {
+// termAccepted leaves out the records with a code rejected by the code tables, like the records failing validation.
+func termAccepted[L ~[]T, T any](list *L, issues func(*L) []model.ValidationIssue) *L {
+	if list == nil {
+		return nil
+	}
+
+	var accepted L
+	for _, o := range *list {
+		if len(issues(&L{o})) == 0 {
+			accepted = append(accepted, o)
+		}
+	}
+	return &accepted
+}
+
+// generateBundle builds the transaction bundle of a visit, observations that couldn't be normalized are left out and returned as exclusions.
+// Only the given allergies are added, see unpublishedAllergies.
+func (p *Publish) generateBundle(internal *entity.SatuSehatInternal, allergies model.AllergyList) (*fhir.Bundle, []resource.Exclusion, error) {
 	encounterUid := uuid.New().String()
 	visitDetail := internal.VisitDetail()
 
 	var entries []fhir.BundleEntry
-	diagnosisEntries, encounterDiagnosis, err := p.generateDiagnosisEntries(encounterUid, visitDetail, internal.Diagnosis())
+	diagnosisEntries, encounterDiagnosis, err := p.generateDiagnosisEntries(encounterUid, visitDetail, termAccepted(internal.Diagnosis(), p.terminology.DiagnosisIssues))
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	encounterEntry, err := p.generateEncounterEntry(encounterUid, visitDetail, encounterDiagnosis)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	entries = append(entries, *encounterEntry)
 
-	vitalSignEntries, err := p.generateVitalSignEntries(encounterUid, visitDetail, internal.VitalSign())
+	vitalSignEntries, exclusions, err := p.generateVitalSignEntries(encounterUid, visitDetail, internal.VitalSign())
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	entries = append(entries, vitalSignEntries...)
 
+	anamnesisEntries, err := p.generateAnamnesisEntries(encounterUid, visitDetail, internal.Anamnesis())
+	if err != nil {
+		return nil, nil, err
+	}
+	entries = append(entries, anamnesisEntries...)
+
+	allergyEntries, err := p.generateAllergyEntries(encounterUid, visitDetail, allergies)
+	if err != nil {
+		return nil, nil, err
+	}
+	entries = append(entries, allergyEntries...)
+
 	entries = append(entries, diagnosisEntries...)
 
-	medicationRequestEntries, err := p.generateMedicationRequestEntries(encounterUid, visitDetail, internal.MedicationRequest())
+	clinicalNoteEntries, err := p.generateClinicalNoteEntries(encounterUid, visitDetail, internal.ClinicalNote(), encounterDiagnosis)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	entries = append(entries, clinicalNoteEntries...)
+
+	referralEntries, err := p.generateReferralEntries(encounterUid, visitDetail, encounterDiagnosis)
+	if err != nil {
+		return nil, nil, err
+	}
+	entries = append(entries, referralEntries...)
+
+	medicationRequestEntries, err := p.generateMedicationRequestEntries(encounterUid, visitDetail, termAccepted(internal.MedicationRequest(), p.terminology.MedicationRequestIssues))
+	if err != nil {
+		return nil, nil, err
 	}
 	entries = append(entries, medicationRequestEntries...)
 
-	medicationDispenseEntries, err := p.generateMedicationDispenseEntries(encounterUid, visitDetail, internal.MedicationDispense())
+	medicationDispenseEntries, err := p.generateMedicationDispenseEntries(encounterUid, visitDetail, termAccepted(internal.MedicationDispense(), p.terminology.MedicationDispenseIssues))
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	entries = append(entries, medicationDispenseEntries...)
 
+	immunizationEntries, err := p.generateImmunizationEntries(encounterUid, visitDetail, termAccepted(internal.Immunization(), p.terminology.ImmunizationIssues))
+	if err != nil {
+		return nil, nil, err
+	}
+	entries = append(entries, immunizationEntries...)
+
 	return &fhir.Bundle{
 		Type:  fhir.BundleTypeTransaction,
 		Entry: entries,
-	}, err
+	}, exclusions, err
 }
 
 func (p *Publish) generateEncounterEntry(encounterUid string, visitDetail *model.VisitDetail, encounterDiagnosis []resource.EncounterDiagnosis) (*fhir.BundleEntry, error) {
@@ -265,11 +455,68 @@ func (p *Publish) generateEncounterEntry(encounterUid string, visitDetail *model
 		PractitionerSatuSehatId: visitDetail.PractitionerId,
 		PractitionerName:        visitDetail.PractitionerName,
 		Diagnosis:               encounterDiagnosis,
+		DischargeDisposition:    visitDetail.DischargeDisposition,
 	}
 	return encounter.BundleEntry()
 }
 
-func (p *Publish) generateVitalSignEntries(encounterUid string, visitDetail *model.VisitDetail, vitalSign *model.VitalSign) ([]fhir.BundleEntry, error) {
+// generateReferralEntries builds a referral ServiceRequest when the visit refers the patient to a facility known to SatuSehat,
+// and a follow-up Appointment at the clinic of the visit when a follow-up date is charted.
+func (p *Publish) generateReferralEntries(encounterUid string, visitDetail *model.VisitDetail, encounterDiagnosis []resource.EncounterDiagnosis) ([]fhir.BundleEntry, error) {
+	var entries []fhir.BundleEntry
+	visitTime := util.StdTimeToString(visitDetail.FinishEndTime, p.convertToUtc)
+
+	var serviceRequestUid string
+	if util.StringNotEmpty(visitDetail.ReferralOrganizationId) {
+		serviceRequestUid = uuid.New().String()
+		referral := resource.Referral{
+			ServiceRequestId:         serviceRequestUid,
+			EncounterId:              encounterUid,
+			OrganizationId:           p.organizationId,
+			PatientSatuSehatId:       visitDetail.PatientSatusehatId,
+			PatientName:              visitDetail.PatientName,
+			Time:                     visitTime,
+			PractitionerSatuSehatId:  visitDetail.PractitionerId,
+			PractitionerName:         visitDetail.PractitionerName,
+			ReferralOrganizationId:   visitDetail.ReferralOrganizationId,
+			ReferralOrganizationName: visitDetail.ReferralOrganizationName,
+			Reason:                   visitDetail.ReferralReason,
+			Diagnosis:                encounterDiagnosis,
+		}
+
+		entry, err := referral.BundleEntry()
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, *entry)
+	}
+
+	if visitDetail.FollowUpDate != nil {
+		followUp := resource.FollowUp{
+			AppointmentId:           uuid.New().String(),
+			ServiceRequestId:        serviceRequestUid,
+			PatientSatuSehatId:      visitDetail.PatientSatusehatId,
+			PatientName:             visitDetail.PatientName,
+			PractitionerSatuSehatId: visitDetail.PractitionerId,
+			PractitionerName:        visitDetail.PractitionerName,
+			LocationId:              visitDetail.ClinicSatuSehatId,
+			LocationName:            visitDetail.ClinicName,
+			Created:                 visitTime,
+			Start:                   util.StdTimeToString(visitDetail.FollowUpDate, p.convertToUtc),
+			Description:             fmt.Sprintf("Kontrol %s", visitDetail.ClinicName),
+		}
+
+		entry, err := followUp.BundleEntry()
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, *entry)
+	}
+
+	return entries, nil
+}
+
+func (p *Publish) generateVitalSignEntries(encounterUid string, visitDetail *model.VisitDetail, vitalSign *model.VitalSign) ([]fhir.BundleEntry, []resource.Exclusion, error) {
 	vitalSignResources := &resource.VitalSign{
 		EncounterId:             encounterUid,
 		SystoleId:               uuid.New().String(),
@@ -278,6 +525,12 @@ func (p *Publish) generateVitalSignEntries(encounterUid string, visitDetail *mod
 		TemperatureId:           uuid.New().String(),
 		RespirationRateId:       uuid.New().String(),
 		OxygenSaturationId:      uuid.New().String(),
+		WeightId:                uuid.New().String(),
+		HeightId:                uuid.New().String(),
+		BmiId:                   uuid.New().String(),
+		PainScoreId:             uuid.New().String(),
+		GcsId:                   uuid.New().String(),
+		ConsciousnessId:         uuid.New().String(),
 		PatientSatuSehatId:      visitDetail.PatientSatusehatId,
 		PatientName:             visitDetail.PatientName,
 		Time:                    util.StdTimeToString(&visitDetail.PeriodStartDate, p.convertToUtc),
@@ -289,10 +542,76 @@ func (p *Publish) generateVitalSignEntries(encounterUid string, visitDetail *mod
 		Temperature:             vitalSign.Temperature,
 		RespirationRate:         vitalSign.RespirationRate,
 		OxygenSaturation:        vitalSign.OxygenSaturation,
+		Weight:                  vitalSign.Weight,
+		Height:                  vitalSign.Height,
+		Bmi:                     vitalSign.Bmi,
+		PainScore:               vitalSign.PainScore,
+		Gcs:                     vitalSign.Gcs,
+		Consciousness:           vitalSign.Consciousness,
+		Plausibility:            p.plausibility,
 	}
 	return vitalSignResources.BundleEntries()
 }
 
+func (p *Publish) generateAnamnesisEntries(encounterUid string, visitDetail *model.VisitDetail, anamnesisList *model.AnamnesisList) ([]fhir.BundleEntry, error) {
+	var entries []fhir.BundleEntry
+	if anamnesisList != nil {
+		for _, anamnesis := range *anamnesisList {
+			if !anamnesis.Invalid() {
+				res := resource.Anamnesis{
+					ConditionId:             uuid.New().String(),
+					ExamObservationId:       uuid.New().String(),
+					EncounterId:             encounterUid,
+					PatientSatuSehatId:      visitDetail.PatientSatusehatId,
+					PatientName:             visitDetail.PatientName,
+					Time:                    util.StdTimeToString(&anamnesis.AnamnesisDate, p.convertToUtc),
+					PractitionerSatuSehatId: visitDetail.PractitionerId,
+					PractitionerName:        visitDetail.PractitionerName,
+					ChiefComplaint:          anamnesis.ChiefComplaint,
+					ChiefComplaintCode:      util.StringNotNil(anamnesis.ChiefComplaintCode),
+					ChiefComplaintDisplay:   util.StringNotNil(anamnesis.ChiefComplaintDisplay),
+					PresentIllness:          util.StringNotNil(anamnesis.PresentIllness),
+					PastIllness:             util.StringNotNil(anamnesis.PastIllness),
+					PhysicalExam:            util.StringNotNil(anamnesis.PhysicalExam),
+				}
+
+				anamnesisEntries, err := res.BundleEntries()
+				if err != nil {
+					return nil, err
+				}
+				entries = append(entries, anamnesisEntries...)
+			}
+		}
+	}
+	return entries, nil
+}
+
+func (p *Publish) generateAllergyEntries(encounterUid string, visitDetail *model.VisitDetail, allergies model.AllergyList) ([]fhir.BundleEntry, error) {
+	var entries []fhir.BundleEntry
+	for _, allergy := range allergies {
+		res := resource.AllergyIntolerance{
+			AllergyIntoleranceId:    uuid.New().String(),
+			EncounterId:             encounterUid,
+			PatientSatuSehatId:      visitDetail.PatientSatusehatId,
+			PatientName:             visitDetail.PatientName,
+			Time:                    util.StdTimeToString(&allergy.RecordedDate, p.convertToUtc),
+			PractitionerSatuSehatId: visitDetail.PractitionerId,
+			PractitionerName:        visitDetail.PractitionerName,
+			Category:                allergy.Category,
+			Code:                    util.StringNotNil(allergy.AllergyCode),
+			Name:                    allergy.AllergyName,
+			Reaction:                util.StringNotNil(allergy.Reaction),
+		}
+
+		entry, err := res.BundleEntry()
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, *entry)
+	}
+	return entries, nil
+}
+
 func (p *Publish) generateDiagnosisEntries(encounterUid string, visitDetail *model.VisitDetail, diagnosisList *model.DiagnosisList) ([]fhir.BundleEntry, []resource.EncounterDiagnosis, error) {
 	var encounterDiagnosis []resource.EncounterDiagnosis
 	var entries []fhir.BundleEntry
@@ -327,6 +646,64 @@ func (p *Publish) generateDiagnosisEntries(encounterUid string, visitDetail *mod
 	return entries, encounterDiagnosis, nil
 }
 
+// generateClinicalNoteEntries builds a ClinicalImpression with the diagnoses of the encounter as findings when the note
+// has an assessment, and a Composition when the note has a plan or instructions.
+func (p *Publish) generateClinicalNoteEntries(encounterUid string, visitDetail *model.VisitDetail, clinicalNoteList *model.ClinicalNoteList, encounterDiagnosis []resource.EncounterDiagnosis) ([]fhir.BundleEntry, error) {
+	var entries []fhir.BundleEntry
+	if clinicalNoteList != nil {
+		for _, note := range *clinicalNoteList {
+			if !note.Invalid() {
+				noteTime := util.StdTimeToString(&note.NoteDate, p.convertToUtc)
+
+				if assessment := util.StringNotNil(note.Assessment); util.StringNotEmpty(assessment) {
+					impression := resource.ClinicalImpression{
+						ClinicalImpressionId:    uuid.New().String(),
+						EncounterId:             encounterUid,
+						PatientSatuSehatId:      visitDetail.PatientSatusehatId,
+						PatientName:             visitDetail.PatientName,
+						Time:                    noteTime,
+						PractitionerSatuSehatId: visitDetail.PractitionerId,
+						PractitionerName:        visitDetail.PractitionerName,
+						Summary:                 assessment,
+						Prognosis:               util.StringNotNil(note.Prognosis),
+						Findings:                encounterDiagnosis,
+					}
+
+					impressionEntry, err := impression.BundleEntry()
+					if err != nil {
+						return nil, err
+					}
+					entries = append(entries, *impressionEntry)
+				}
+
+				composition := resource.Composition{
+					CompositionId:           uuid.New().String(),
+					EncounterId:             encounterUid,
+					PatientSatuSehatId:      visitDetail.PatientSatusehatId,
+					PatientName:             visitDetail.PatientName,
+					Time:                    noteTime,
+					PractitionerSatuSehatId: visitDetail.PractitionerId,
+					PractitionerName:        visitDetail.PractitionerName,
+					Plan:                    util.StringNotNil(note.Plan),
+					Diet:                    util.StringNotNil(note.Diet),
+					Education:               util.StringNotNil(note.Education),
+					FollowUp:                util.StringNotNil(note.FollowUp),
+				}
+				if composition.Empty() {
+					continue
+				}
+
+				compositionEntry, err := composition.BundleEntry()
+				if err != nil {
+					return nil, err
+				}
+				entries = append(entries, *compositionEntry)
+			}
+		}
+	}
+	return entries, nil
+}
+
 func (p *Publish) generateMedicationRequestEntries(encounterUid string, visitDetail *model.VisitDetail, medicationRequestList *model.MedicationRequestList) ([]fhir.BundleEntry, error) {
 	var entries []fhir.BundleEntry
 	if medicationRequestList != nil {
@@ -347,6 +724,11 @@ func (p *Publish) generateMedicationRequestEntries(encounterUid string, visitDet
 					KfaDisplay:          util.StringNotNil(request.KfaName),
 					Type:                request.Type,
 					PatientType:         request.PatientType,
+					Form:                request.Form,
+					Ingredients:         request.Ingredients,
+					Quantity:            request.Amount,
+					QuantityUnit:        request.Unit,
+					Dosage:              medicationDosage(request.Usage, request.Route, request.DoseAmount, request.DoseUnit, request.Form),
 				}
 
 				requestList, err := res.BundleEntries()
@@ -383,6 +765,11 @@ func (p *Publish) generateMedicationDispenseEntries(encounterUid string, visitDe
 					HandoverDate:         util.StdTimeToString(dispense.HandoverDate, p.convertToUtc),
 					BatchNumber:          dispense.BatchNumber,
 					ExpirationDate:       util.StdTimeToString(dispense.ExpiredDate, p.convertToUtc),
+					Form:                 dispense.Form,
+					Ingredients:          dispense.Ingredients,
+					Quantity:             dispense.Amount,
+					QuantityUnit:         dispense.Unit,
+					Dosage:               medicationDosage(dispense.Usage, dispense.Route, dispense.DoseAmount, dispense.DoseUnit, dispense.Form),
 				}
 
 				dispenseList, err := res.BundleEntries()
@@ -395,3 +782,71 @@ func (p *Publish) generateMedicationDispenseEntries(encounterUid string, visitDe
 	}
 	return entries, nil
 }
+
+// medicationDosage returns the dosage instruction of a prescription line, the dose is counted in the form when SIMRS has no dose unit.
+func medicationDosage(usage string, route string, amount float64, unit string, form string) resource.Dosage {
+	if !util.StringNotEmpty(unit) {
+		unit = form
+	}
+	return resource.Dosage{Text: usage, Route: route, Amount: amount, Unit: unit}
+}
+
+func (p *Publish) generateImmunizationEntries(encounterUid string, visitDetail *model.VisitDetail, immunizationList *model.ImmunizationList) ([]fhir.BundleEntry, error) {
+	var entries []fhir.BundleEntry
+	if immunizationList != nil {
+		for _, immunization := range *immunizationList {
+			if !immunization.Invalid() {
+				res := resource.Immunization{
+					ImmunizationId:     uuid.New().String(),
+					EncounterId:        encounterUid,
+					PatientSatuSehatId: visitDetail.PatientSatusehatId,
+					PatientName:        visitDetail.PatientName,
+					Time:               util.StdTimeToString(&immunization.OccurrenceDate, p.convertToUtc),
+					PractitionerId:     util.StringNotNil(immunization.PractitionerId),
+					PractitionerName:   util.StringNotNil(immunization.PractitionerName),
+					KfaCode:            util.StringNotNil(immunization.KfaCode),
+					KfaDisplay:         util.StringNotNil(immunization.KfaName),
+					LotNumber:          immunization.LotNumber,
+					DoseNumber:         immunization.DoseNumber,
+					Site:               util.StringNotNil(immunization.Site),
+					Route:              util.StringNotNil(immunization.Route),
+				}
+				if immunization.ExpiredDate != nil {
+					res.ExpirationDate = util.DateToString(*immunization.ExpiredDate)
+				}
+
+				entry, err := res.BundleEntry()
+				if err != nil {
+					return nil, err
+				}
+				entries = append(entries, *entry)
+			}
+		}
+	}
+	return entries, nil
+}
+
+func observationExclusions(exclusions []resource.Exclusion) []entity.ObservationExclusion {
+	excludedAt := time.Now()
+
+	var results []entity.ObservationExclusion
+	for _, exclusion := range exclusions {
+		reason := entity.ExclusionValue
+		switch {
+		case errors.Is(exclusion.Reason, ucum.ErrUnit), errors.Is(exclusion.Reason, ucum.ErrIncompatible):
+			reason = entity.ExclusionUnit
+		case errors.Is(exclusion.Reason, resource.ErrImplausible):
+			reason = entity.ExclusionRange
+		}
+
+		results = append(results, entity.ObservationExclusion{
+			LoincCode:    exclusion.LoincCode,
+			LoincDisplay: exclusion.LoincDisplay,
+			Value:        exclusion.Value,
+			Reason:       reason,
+			Message:      exclusion.Reason.Error(),
+			ExcludedAt:   excludedAt,
+		})
+	}
+	return results
+}
@@ -0,0 +1,59 @@
+package job
+
+import (
+	"github.com/jasoet/fhir-worker/internal/entity"
+	"github.com/jasoet/fhir-worker/internal/terminology"
+	"github.com/jasoet/fhir-worker/pkg/util"
+	"github.com/jasoet/fhir-worker/shared/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestPublish_GenerateClinicalNoteEntries(t *testing.T) {
+	publish := &Publish{}
+	visitDetail := &model.VisitDetail{PatientSatusehatId: "P-1", PatientName: "Budi", PractitionerId: "N-1", PractitionerName: "dr. Ani"}
+
+	notes := model.ClinicalNoteList{
+		{VisitId: 1, NoteDate: time.Now(), Assessment: util.StrPtr("ISPA"), Plan: util.StrPtr("kontrol 3 hari")},
+		{VisitId: 1, NoteDate: time.Now(), Plan: util.StrPtr("banyak minum air putih")},
+		{VisitId: 1, NoteDate: time.Now(), Assessment: util.StrPtr("  ")},
+	}
+
+	entries, err := publish.generateClinicalNoteEntries("E-1", visitDetail, &notes, nil)
+	require.NoError(t, err)
+
+	var resourceTypes []string
+	for _, entry := range entries {
+		resourceTypes = append(resourceTypes, entry.Request.Url)
+	}
+	assert.Equal(t, []string{"ClinicalImpression", "Composition", "Composition"}, resourceTypes)
+}
+
+func TestPublish_GenerateBundle_TerminologyIssues(t *testing.T) {
+	codeTables, err := terminology.New()
+	require.NoError(t, err)
+
+	publish := &Publish{terminology: codeTables}
+	internal := &entity.SatuSehatInternal{
+		VisitDetailJson: *util.MarshalToJson(model.VisitDetail{VisitId: "1", PatientSatusehatId: "P-1", PatientName: "Budi"}),
+		VitalSignJson:   *util.MarshalToJson(model.VitalSign{}),
+		DiagnosisJsonArr: util.MarshalToJson(model.DiagnosisList{
+			{VisitID: "1", DiagnosisCode: "A09.9", DiagnosisName: "Gastroenteritis", DiagnosisDate: time.Now()},
+			{VisitID: "1", DiagnosisCode: "GEA", DiagnosisName: "Gastroenteritis", DiagnosisDate: time.Now()},
+		}),
+	}
+
+	bundle, _, err := publish.generateBundle(internal, nil)
+	require.NoError(t, err)
+
+	var conditions []string
+	for _, entry := range bundle.Entry {
+		if entry.Request.Url == "ConditionDiagnosis" {
+			conditions = append(conditions, string(entry.Resource))
+		}
+	}
+	require.Len(t, conditions, 1)
+	assert.Contains(t, conditions[0], "A09.9")
+}
@@ -0,0 +1,163 @@
+package job
+
+import (
+	"context"
+	"fmt"
+	"github.com/jasoet/fhir-worker/internal/db"
+	"github.com/jasoet/fhir-worker/internal/entity"
+	"github.com/jasoet/fhir-worker/internal/metrics"
+	"github.com/jasoet/fhir-worker/internal/tracing"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	FetchVisitJob    = "fetch-visit"
+	FillVisitJob     = "fill-visit"
+	CheckCompleteJob = "check-complete"
+	PublishJob       = "publish"
+)
+
+var tracer = otel.Tracer("github.com/jasoet/fhir-worker/job")
+
+type runCounterKey struct{}
+
+// runCounter counts the items a job run went through, jobs read it from their context with counterFrom.
+type runCounter struct {
+	scanned   atomic.Int64
+	processed atomic.Int64
+	failed    atomic.Int64
+}
+
+// counterFrom returns the counter of the tracked run, or nil when the job isn't tracked. A nil counter ignores every count.
+func counterFrom(ctx context.Context) *runCounter {
+	counter, _ := ctx.Value(runCounterKey{}).(*runCounter)
+	return counter
+}
+
+func (c *runCounter) Scanned(n int) {
+	if c != nil {
+		c.scanned.Add(int64(n))
+	}
+}
+
+func (c *runCounter) Processed(n int) {
+	if c != nil {
+		c.processed.Add(int64(n))
+	}
+}
+
+func (c *runCounter) Failed(n int) {
+	if c != nil {
+		c.failed.Add(int64(n))
+	}
+}
+
+// Tracker records every run of the scheduled jobs in the job_runs table and removes the ones older than its retention.
+type Tracker struct {
+	repository *db.Repository
+	retention  time.Duration
+	createdAt  time.Time
+}
+
+// NewTracker returns a Tracker keeping the runs of the last retention, forever when it isn't positive.
+func NewTracker(repository *db.Repository, retention time.Duration) *Tracker {
+	return &Tracker{
+		repository: repository,
+		retention:  retention,
+		createdAt:  time.Now(),
+	}
+}
+
+// Track wraps task so each call is recorded as a run of name. Failing to record a run is logged and never fails the task.
+func (t *Tracker) Track(name string, task func(ctx context.Context) error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		logger := log.With().Ctx(ctx).Str("function", "Track").Str("job", name).Logger()
+
+		startedAt := time.Now()
+		id, err := t.repository.StartJobRun(ctx, name, startedAt)
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to record job run start.")
+		}
+
+		ctx, span := tracer.Start(ctx, "job."+name)
+
+		counter := &runCounter{}
+		taskErr := task(context.WithValue(ctx, runCounterKey{}, counter))
+		finishedAt := time.Now()
+
+		span.SetAttributes(
+			attribute.Int64("job.scanned", counter.scanned.Load()),
+			attribute.Int64("job.processed", counter.processed.Load()),
+			attribute.Int64("job.failed", counter.failed.Load()),
+		)
+		tracing.End(span, taskErr)
+
+		observeRun(name, counter, startedAt, finishedAt, taskErr)
+		if id == 0 {
+			return taskErr
+		}
+
+		run := entity.JobRun{
+			ID:         id,
+			JobName:    name,
+			StartedAt:  startedAt,
+			FinishedAt: &finishedAt,
+			Scanned:    int(counter.scanned.Load()),
+			Processed:  int(counter.processed.Load()),
+			Failed:     int(counter.failed.Load()),
+		}
+		if taskErr != nil {
+			message := taskErr.Error()
+			run.Error = &message
+		}
+
+		// the task context may be canceled by a shutdown, the run is recorded nevertheless.
+		recordCtx := context.WithoutCancel(ctx)
+		if _, err := t.repository.FinishJobRun(recordCtx, run); err != nil {
+			logger.Error().Err(err).Int64("run-id", id).Msg("Failed to record job run finish.")
+		}
+
+		if t.retention > 0 {
+			if _, err := t.repository.DeleteJobRuns(recordCtx, finishedAt.Add(-t.retention)); err != nil {
+				logger.Error().Err(err).Msg("Failed to remove expired job runs.")
+			}
+		}
+
+		return taskErr
+	}
+}
+
+// Progressing returns an error when no run of name made progress within threshold. A run made progress when it finished
+// without error and either found nothing to do or processed at least one item. Until threshold elapsed since the Tracker
+// was created, a worker that just started is considered progressing.
+func (t *Tracker) Progressing(ctx context.Context, name string, threshold time.Duration) error {
+	run, err := t.repository.LastProgressedJobRun(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	since := t.createdAt
+	if run != nil && run.FinishedAt.After(since) {
+		since = *run.FinishedAt
+	}
+
+	if stalled := time.Since(since); stalled > threshold {
+		return fmt.Errorf("%s made no progress for %s", name, stalled.Round(time.Second))
+	}
+	return nil
+}
+
+func observeRun(name string, counter *runCounter, startedAt time.Time, finishedAt time.Time, err error) {
+	metrics.JobDuration.WithLabelValues(name, metrics.Outcome(err)).Observe(finishedAt.Sub(startedAt).Seconds())
+	metrics.JobItems.WithLabelValues(name, "scanned").Add(float64(counter.scanned.Load()))
+	metrics.JobItems.WithLabelValues(name, "processed").Add(float64(counter.processed.Load()))
+	metrics.JobItems.WithLabelValues(name, "failed").Add(float64(counter.failed.Load()))
+
+	if err == nil {
+		metrics.JobLastSuccess.WithLabelValues(name).Set(float64(finishedAt.Unix()))
+	}
+}
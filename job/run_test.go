@@ -0,0 +1,85 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"github.com/jasoet/fhir-worker/internal/db/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestTracker_Track(t *testing.T) {
+	ctx := context.Background()
+	repository := dbtest.Repository(t)
+
+	_, err := repository.StartJobRun(ctx, PublishJob, time.Now().Add(-48*time.Hour))
+	require.NoError(t, err)
+
+	tracker := NewTracker(repository, 24*time.Hour)
+
+	err = tracker.Track(PublishJob, func(ctx context.Context) error {
+		counter := counterFrom(ctx)
+		counter.Scanned(3)
+		counter.Processed(2)
+		counter.Failed(1)
+		return nil
+	})(ctx)
+	require.NoError(t, err)
+
+	err = tracker.Track(FetchVisitJob, func(ctx context.Context) error {
+		return errors.New("SIMRS unreachable")
+	})(ctx)
+	require.Error(t, err)
+
+	runs, err := repository.JobRuns(ctx, "", 10)
+	require.NoError(t, err)
+	require.Len(t, runs, 2, "the run older than the retention is removed")
+
+	assert.Equal(t, FetchVisitJob, runs[0].JobName)
+	require.NotNil(t, runs[0].Error)
+	assert.Equal(t, "SIMRS unreachable", *runs[0].Error)
+
+	assert.Equal(t, PublishJob, runs[1].JobName)
+	assert.NotNil(t, runs[1].FinishedAt)
+	assert.Nil(t, runs[1].Error)
+	assert.Equal(t, 3, runs[1].Scanned)
+	assert.Equal(t, 2, runs[1].Processed)
+	assert.Equal(t, 1, runs[1].Failed)
+
+	publishRuns, err := repository.JobRuns(ctx, PublishJob, 10)
+	require.NoError(t, err)
+	assert.Len(t, publishRuns, 1)
+}
+
+func TestRunCounter_Untracked(t *testing.T) {
+	counter := counterFrom(context.Background())
+	assert.Nil(t, counter)
+	assert.NotPanics(t, func() { counter.Processed(1) })
+}
+
+func TestTracker_Progressing(t *testing.T) {
+	ctx := context.Background()
+	repository := dbtest.Repository(t)
+
+	tracker := NewTracker(repository, 0)
+	assert.NoError(t, tracker.Progressing(ctx, CheckCompleteJob, time.Hour), "a worker that just started is progressing")
+
+	tracker.createdAt = time.Now().Add(-2 * time.Hour)
+	assert.ErrorContains(t, tracker.Progressing(ctx, CheckCompleteJob, time.Hour), "check-complete made no progress")
+
+	stuck := tracker.Track(CheckCompleteJob, func(ctx context.Context) error {
+		counterFrom(ctx).Scanned(5)
+		counterFrom(ctx).Failed(5)
+		return nil
+	})
+	require.NoError(t, stuck(ctx))
+	assert.Error(t, tracker.Progressing(ctx, CheckCompleteJob, time.Hour), "failing every item isn't progress")
+
+	idle := tracker.Track(CheckCompleteJob, func(ctx context.Context) error {
+		return nil
+	})
+	require.NoError(t, idle(ctx))
+	assert.NoError(t, tracker.Progressing(ctx, CheckCompleteJob, time.Hour))
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"github.com/jasoet/fhir-worker/app"
+	"github.com/jasoet/fhir-worker/pkg/redact"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 
@@ -11,6 +12,7 @@ import (
 )
 
 func main() {
+	zerolog.ErrorMarshalFunc = redact.MarshalError
 	log.Logger = zerolog.New(zerolog.ConsoleWriter{Out: os.Stderr, TimeFormat: time.RFC3339}).
 		With().
 		Int("pid", os.Getpid()).
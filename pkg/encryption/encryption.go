@@ -0,0 +1,152 @@
+// Package encryption seals sensitive database columns with AES-256-GCM.
+//
+// A sealed value is "enc:v1:<key id>:<base64 nonce and ciphertext>", the key id being derived from the key itself so a
+// Keyring holding the current key and the previous ones opens values sealed before a rotation. Values without the
+// prefix are plaintext written before encryption was enabled and are returned as they are.
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+const (
+	prefix  = "enc:v1:"
+	KeySize = 32
+)
+
+type Keyring struct {
+	current string
+	keys    map[string]cipher.AEAD
+}
+
+// NewKeyring returns a Keyring sealing with the first key and opening with any of keys, each of KeySize bytes.
+func NewKeyring(keys ...[]byte) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("at least one encryption key is required")
+	}
+
+	keyring := &Keyring{keys: map[string]cipher.AEAD{}}
+	for i, key := range keys {
+		if len(key) != KeySize {
+			return nil, fmt.Errorf("encryption key %d is %d bytes, AES-256 needs %d", i+1, len(key), KeySize)
+		}
+
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+
+		id := KeyID(key)
+		if i == 0 {
+			keyring.current = id
+		}
+		keyring.keys[id] = aead
+	}
+
+	return keyring, nil
+}
+
+// ParseKeyring reads base64 keys separated by new lines or commas, the first one is current. Blank lines and lines
+// starting with '#' are ignored.
+func ParseKeyring(text string) (*Keyring, error) {
+	var keys [][]byte
+	for _, line := range strings.FieldsFunc(text, func(r rune) bool { return r == '\n' || r == ',' }) {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, err := base64.StdEncoding.DecodeString(line)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key %d is not base64: %w", len(keys)+1, err)
+		}
+		keys = append(keys, key)
+	}
+
+	return NewKeyring(keys...)
+}
+
+// KeyID identifies key without revealing it.
+func KeyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:4])
+}
+
+// CurrentKeyID is the id of the key new values are sealed with.
+func (k *Keyring) CurrentKeyID() string {
+	return k.current
+}
+
+// Seal encrypts plaintext with the current key. associatedData, e.g. the column and row it is stored in, is
+// authenticated but not stored: Open fails when the value is copied elsewhere.
+func (k *Keyring) Seal(plaintext []byte, associatedData []byte) (string, error) {
+	aead := k.keys[k.current]
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := aead.Seal(nonce, nonce, plaintext, associatedData)
+	return prefix + k.current + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts a value returned by Seal, plaintext values are returned unchanged.
+func (k *Keyring) Open(value []byte, associatedData []byte) ([]byte, error) {
+	id, sealed, ok := split(value)
+	if !ok {
+		return value, nil
+	}
+
+	aead, found := k.keys[id]
+	if !found {
+		return nil, fmt.Errorf("value is encrypted with key %s, which is not in the keyring", id)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return nil, fmt.Errorf("encrypted value is not base64: %w", err)
+	}
+	if len(data) < aead.NonceSize() {
+		return nil, fmt.Errorf("encrypted value is truncated")
+	}
+
+	plaintext, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], associatedData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt value with key %s: %w", id, err)
+	}
+	return plaintext, nil
+}
+
+// Current reports whether value is sealed with the current key, false for plaintext and older keys.
+func (k *Keyring) Current(value []byte) bool {
+	id, _, ok := split(value)
+	return ok && id == k.current
+}
+
+// Sealed reports whether value was returned by Seal, whichever key it used.
+func Sealed(value []byte) bool {
+	_, _, ok := split(value)
+	return ok
+}
+
+func split(value []byte) (id string, sealed string, ok bool) {
+	rest, found := strings.CutPrefix(string(value), prefix)
+	if !found {
+		return "", "", false
+	}
+
+	return strings.Cut(rest, ":")
+}
@@ -0,0 +1,140 @@
+package encryption
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, KeySize)
+}
+
+func TestSealAndOpen(t *testing.T) {
+	keyring, err := NewKeyring(testKey(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plaintext := []byte(`{"patient_nik":"3404000000000001"}`)
+	sealed, err := keyring.Seal(plaintext, []byte("visit_detail/V-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(sealed, "3404000000000001") || !strings.HasPrefix(sealed, "enc:v1:"+KeyID(testKey(1))+":") {
+		t.Errorf("Seal() = %v", sealed)
+	}
+	if !Sealed([]byte(sealed)) || !keyring.Current([]byte(sealed)) {
+		t.Errorf("Sealed() or Current() is false for %v", sealed)
+	}
+
+	opened, err := keyring.Open([]byte(sealed), []byte("visit_detail/V-1"))
+	if err != nil || !bytes.Equal(opened, plaintext) {
+		t.Errorf("Open() = %s, %v", opened, err)
+	}
+
+	if _, err := keyring.Open([]byte(sealed), []byte("visit_detail/V-2")); err == nil {
+		t.Error("Open() with other associated data succeeded")
+	}
+
+	opened, err = keyring.Open(plaintext, nil)
+	if err != nil || !bytes.Equal(opened, plaintext) || Sealed(plaintext) {
+		t.Errorf("Open() of plaintext = %s, %v", opened, err)
+	}
+}
+
+func TestRotation(t *testing.T) {
+	previous, _ := NewKeyring(testKey(1))
+	sealed, err := previous.Seal([]byte("bundle"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotated, err := NewKeyring(testKey(2), testKey(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rotated.Current([]byte(sealed)) {
+		t.Error("Current() is true for a value sealed with the previous key")
+	}
+	if opened, err := rotated.Open([]byte(sealed), nil); err != nil || string(opened) != "bundle" {
+		t.Errorf("Open() = %s, %v", opened, err)
+	}
+
+	current, _ := NewKeyring(testKey(2))
+	if _, err := current.Open([]byte(sealed), nil); err == nil || !strings.Contains(err.Error(), "not in the keyring") {
+		t.Errorf("Open() without the previous key = %v", err)
+	}
+}
+
+func TestParseKeyring(t *testing.T) {
+	first := base64.StdEncoding.EncodeToString(testKey(1))
+	second := base64.StdEncoding.EncodeToString(testKey(2))
+
+	tests := []struct {
+		name    string
+		text    string
+		current string
+		wantErr bool
+	}{
+		{"Lines", "# current\n" + first + "\n\n" + second + "\n", KeyID(testKey(1)), false},
+		{"Commas", second + "," + first, KeyID(testKey(2)), false},
+		{"Empty", "\n# no key\n", "", true},
+		{"NotBase64", "not a key", "", true},
+		{"ShortKey", base64.StdEncoding.EncodeToString([]byte("short")), "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keyring, err := ParseKeyring(tt.text)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseKeyring() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && keyring.CurrentKeyID() != tt.current {
+				t.Errorf("CurrentKeyID() = %v, want %v", keyring.CurrentKeyID(), tt.current)
+			}
+		})
+	}
+}
+
+func TestConfigKeyring(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString(testKey(3))
+	want := KeyID(testKey(3))
+
+	t.Setenv("TEST_DB_KEYS", key)
+
+	path := filepath.Join(t.TempDir(), "db-keys")
+	if err := os.WriteFile(path, []byte(key+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr bool
+	}{
+		{"Env", Config{Provider: ProviderEnv, Env: "TEST_DB_KEYS"}, false},
+		{"MissingEnv", Config{Provider: ProviderEnv, Env: "TEST_DB_KEYS_MISSING"}, true},
+		{"File", Config{Provider: ProviderFile, File: path}, false},
+		{"Command", Config{Provider: ProviderCommand, Command: []string{"echo", key}}, false},
+		{"FailingCommand", Config{Provider: ProviderCommand, Command: []string{"false"}}, true},
+		{"Unknown", Config{Provider: "kms"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keyring, err := tt.config.Keyring()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Keyring() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && keyring.CurrentKeyID() != want {
+				t.Errorf("CurrentKeyID() = %v, want %v", keyring.CurrentKeyID(), want)
+			}
+		})
+	}
+}
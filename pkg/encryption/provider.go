@@ -0,0 +1,86 @@
+package encryption
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+)
+
+const (
+	ProviderEnv     = "env"     // keys in an environment variable
+	ProviderFile    = "file"    // keys in a file readable only by the worker
+	ProviderCommand = "command" // keys printed by a command, e.g. a KMS or Vault CLI decrypting them
+)
+
+const (
+	DefaultEnv     = "FHIR_WORKER_DB_KEYS"
+	commandTimeout = 30 * time.Second
+)
+
+// Config selects where the keys come from, every provider yields the format read by ParseKeyring.
+type Config struct {
+	Provider string   `yaml:"provider" mapstructure:"provider"`
+	Env      string   `yaml:"env" mapstructure:"env"`
+	File     string   `yaml:"file" mapstructure:"file"`
+	Command  []string `yaml:"command" mapstructure:"command"`
+}
+
+// Keyring loads the keys from the configured provider.
+func (c Config) Keyring() (*Keyring, error) {
+	text, err := c.load()
+	if err != nil {
+		return nil, err
+	}
+
+	keyring, err := ParseKeyring(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid keys from the %s provider: %w", c.Provider, err)
+	}
+	return keyring, nil
+}
+
+func (c Config) load() (string, error) {
+	switch c.Provider {
+	case ProviderEnv:
+		name := c.Env
+		if name == "" {
+			name = DefaultEnv
+		}
+
+		text, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s holding the encryption keys is not set", name)
+		}
+		return text, nil
+	case ProviderFile:
+		if c.File == "" {
+			return "", fmt.Errorf("encryption.file is required for the file provider")
+		}
+
+		data, err := os.ReadFile(c.File)
+		if err != nil {
+			return "", fmt.Errorf("failed to read the encryption keys: %w", err)
+		}
+		return string(data), nil
+	case ProviderCommand:
+		if len(c.Command) == 0 {
+			return "", fmt.Errorf("encryption.command is required for the command provider")
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+		defer cancel()
+
+		command := exec.CommandContext(ctx, c.Command[0], c.Command[1:]...)
+		command.Stderr = os.Stderr
+		output, err := command.Output()
+		if err != nil {
+			return "", fmt.Errorf("failed to run %s for the encryption keys: %w", c.Command[0], err)
+		}
+		return string(output), nil
+	default:
+		return "", fmt.Errorf("unknown encryption key provider %q, use %s, %s or %s", c.Provider, ProviderEnv,
+			ProviderFile, ProviderCommand)
+	}
+}
@@ -0,0 +1,145 @@
+package fuzzy
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Matcher scores names by trigram similarity combined with whole token overlap,
+// tokens are first replaced by their synonym so abbreviations such as "tab" and "tablet" still match.
+type Matcher struct {
+	Synonyms map[string]string
+}
+
+// Match is a candidate ranked by Index.Rank, Position points into the candidates of the Index.
+type Match struct {
+	Position int
+	Score    float64
+}
+
+// Index holds prepared candidates so a large catalog is only normalized once.
+type Index struct {
+	matcher    Matcher
+	candidates []prepared
+}
+
+type prepared struct {
+	name     string
+	trigrams map[string]int
+	tokens   map[string]bool
+}
+
+// Normalize lower cases a name, splits numbers from units ("500MG" becomes "500 mg") and drops punctuation.
+func Normalize(s string) string {
+	var builder strings.Builder
+	var previous rune
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if previous != 0 && previous != ' ' && previous != '.' && unicode.IsDigit(r) != unicode.IsDigit(previous) {
+				builder.WriteRune(' ')
+			}
+			builder.WriteRune(r)
+			previous = r
+		case (r == '.' || r == ',') && unicode.IsDigit(previous):
+			// keep the decimal separator of numbers such as 0,5 mg
+			builder.WriteRune('.')
+			previous = '.'
+		default:
+			if previous != 0 && previous != ' ' {
+				builder.WriteRune(' ')
+				previous = ' '
+			}
+		}
+	}
+	return strings.TrimSpace(builder.String())
+}
+
+func (m Matcher) prepare(s string) prepared {
+	fields := strings.Fields(Normalize(s))
+	tokens := make(map[string]bool, len(fields))
+	for i, field := range fields {
+		if synonym, ok := m.Synonyms[field]; ok {
+			fields[i] = synonym
+		}
+		tokens[fields[i]] = true
+	}
+
+	name := strings.Join(fields, " ")
+	padded := []rune("  " + name + " ")
+	trigrams := make(map[string]int, len(padded))
+	for i := 0; i+3 <= len(padded); i++ {
+		trigrams[string(padded[i:i+3])]++
+	}
+
+	return prepared{name: name, trigrams: trigrams, tokens: tokens}
+}
+
+func similarity(a prepared, b prepared) float64 {
+	if a.name == "" || b.name == "" {
+		return 0
+	}
+	if a.name == b.name {
+		return 1
+	}
+
+	shared, total := 0, 0
+	for gram, count := range a.trigrams {
+		shared += min(count, b.trigrams[gram])
+		total += count
+	}
+	for _, count := range b.trigrams {
+		total += count
+	}
+	dice := 2 * float64(shared) / float64(total)
+
+	common := 0
+	for token := range a.tokens {
+		if b.tokens[token] {
+			common++
+		}
+	}
+	overlap := float64(common) / float64(max(len(a.tokens), len(b.tokens)))
+
+	return 0.7*dice + 0.3*overlap
+}
+
+// Similarity returns a score between 0 (nothing in common) and 1 (same normalized name).
+func (m Matcher) Similarity(a string, b string) float64 {
+	return similarity(m.prepare(a), m.prepare(b))
+}
+
+func (m Matcher) Index(candidates []string) *Index {
+	index := &Index{
+		matcher:    m,
+		candidates: make([]prepared, 0, len(candidates)),
+	}
+
+	for _, candidate := range candidates {
+		index.candidates = append(index.candidates, m.prepare(candidate))
+	}
+	return index
+}
+
+// Rank returns at most limit candidates scoring at least minScore, best match first.
+func (i *Index) Rank(query string, limit int, minScore float64) []Match {
+	preparedQuery := i.matcher.prepare(query)
+
+	var matches []Match
+	for position, candidate := range i.candidates {
+		score := similarity(preparedQuery, candidate)
+		if score >= minScore {
+			matches = append(matches, Match{Position: position, Score: score})
+		}
+	}
+
+	sort.SliceStable(matches, func(a, b int) bool {
+		return matches[a].Score > matches[b].Score
+	})
+
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
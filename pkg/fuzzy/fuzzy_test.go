@@ -0,0 +1,53 @@
+package fuzzy
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"PARACETAMOL 500MG TAB", "paracetamol 500 mg tab"},
+		{"Amoxicillin-Trihydrate (500 mg)", "amoxicillin trihydrate 500 mg"},
+		{"Salbutamol 0,5mg/ml", "salbutamol 0.5 mg ml"},
+		{"  ", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			assert.Equal(t, tt.want, Normalize(tt.input))
+		})
+	}
+}
+
+func TestMatcher_Similarity(t *testing.T) {
+	matcher := Matcher{Synonyms: map[string]string{"tab": "tablet"}}
+
+	assert.Equal(t, 1.0, matcher.Similarity("PARACETAMOL 500MG TAB", "Paracetamol 500 mg Tablet"))
+	assert.Equal(t, 0.0, matcher.Similarity("", "Paracetamol"))
+	assert.Greater(t,
+		matcher.Similarity("PARACETAMOL 500MG TAB", "Parasetamol 500 mg Tablet"),
+		matcher.Similarity("PARACETAMOL 500MG TAB", "Parasetamol 120 mg/5 ml Sirup"),
+	)
+}
+
+func TestIndex_Rank(t *testing.T) {
+	index := Matcher{}.Index([]string{
+		"Amoksisilin 500 mg Kapsul",
+		"Parasetamol 500 mg Tablet",
+		"Parasetamol 120 mg/5 ml Sirup",
+		"Omeprazol 20 mg Kapsul",
+	})
+
+	matches := index.Rank("paracetamol 500mg tablet", 2, 0.3)
+	if assert.Len(t, matches, 2) {
+		assert.Equal(t, 1, matches[0].Position)
+		assert.Equal(t, 2, matches[1].Position)
+		assert.GreaterOrEqual(t, matches[0].Score, matches[1].Score)
+	}
+
+	assert.Empty(t, index.Rank("insulin glargine", 3, 0.5))
+}
@@ -0,0 +1,165 @@
+// Package redact masks patient data and credentials before they reach the logs: NIK, names, addresses, contacts,
+// birth dates, access tokens and client secrets.
+package redact
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+const Mask = "[REDACTED]"
+
+// sensitiveKeys are JSON keys, lower cased without '_' and '-', whose values are always masked. They cover the SIMRS
+// visit detail (patient_nik, patient_name...), FHIR resources (name, address, telecom...) and OAuth payloads.
+var sensitiveKeys = map[string]bool{
+	"nik":              true,
+	"patientnik":       true,
+	"practitionernik":  true,
+	"name":             true,
+	"patientname":      true,
+	"practitionername": true,
+	"given":            true,
+	"family":           true,
+	"address":          true,
+	"patientaddress":   true,
+	"telecom":          true,
+	"birthdate":        true,
+	"patientbirthdate": true,
+	"token":            true,
+	"accesstoken":      true,
+	"refreshtoken":     true,
+	"clientsecret":     true,
+	"password":         true,
+	"authorization":    true,
+	"apikey":           true,
+}
+
+// referenceKeys hold FHIR references to a person, their display is the person's name.
+var referenceKeys = map[string]bool{
+	"subject":    true,
+	"patient":    true,
+	"individual": true,
+	"performer":  true,
+	"requester":  true,
+	"recorder":   true,
+	"asserter":   true,
+	"actor":      true,
+}
+
+var (
+	nikPattern    = regexp.MustCompile(`\b\d{16}\b`)
+	bearerPattern = regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9\-._~+/]+=*`)
+)
+
+var disabled atomic.Bool
+
+// SetEnabled switches redaction, it is only disabled for audited debug sessions.
+func SetEnabled(enabled bool) {
+	disabled.Store(!enabled)
+}
+
+func Enabled() bool {
+	return !disabled.Load()
+}
+
+// Value returns v as JSON with sensitive fields masked, for zerolog's RawJSON.
+func Value(v any) []byte {
+	data, err := json.Marshal(v)
+	if err != nil {
+		data, _ = json.Marshal(fmt.Sprintf("unmarshalable %T: %v", v, err))
+		return data
+	}
+	if !Enabled() {
+		return data
+	}
+
+	return maskJson(data)
+}
+
+// String masks s, e.g. a response body or a URL. JSON, alone or after a message prefix, has its sensitive fields
+// masked; NIK and bearer tokens are masked anywhere in the text.
+func String(s string) string {
+	if !Enabled() {
+		return s
+	}
+
+	if start := strings.IndexAny(s, "{["); start >= 0 && json.Valid([]byte(s[start:])) {
+		return maskText(s[:start]) + string(maskJson([]byte(s[start:])))
+	}
+
+	return maskText(s)
+}
+
+// MarshalError masks error messages, install it as zerolog.ErrorMarshalFunc: errors of the SatuSehat client carry the
+// response body.
+func MarshalError(err error) any {
+	if err == nil {
+		return nil
+	}
+
+	return String(err.Error())
+}
+
+func maskText(s string) string {
+	s = nikPattern.ReplaceAllString(s, Mask)
+	return bearerPattern.ReplaceAllString(s, "Bearer "+Mask)
+}
+
+func maskJson(data []byte) []byte {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return data
+	}
+
+	masked, err := json.Marshal(mask("", value))
+	if err != nil {
+		return data
+	}
+
+	return masked
+}
+
+func mask(parent string, value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, child := range v {
+			normalized := normalize(key)
+			switch {
+			case sensitiveKeys[normalized] && child != nil:
+				v[key] = Mask
+			case parent == "identifier" && normalized == "value":
+				v[key] = Mask
+			case referenceKeys[parent] && normalized == "display":
+				v[key] = Mask
+			default:
+				v[key] = mask(normalized, child)
+			}
+		}
+		return v
+	case []any:
+		for i, child := range v {
+			v[i] = mask(parent, child)
+		}
+		return v
+	case string:
+		// Raw columns such as publish_request hold a whole bundle as a JSON string.
+		trimmed := strings.TrimSpace(v)
+		if (strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[")) && json.Valid([]byte(trimmed)) {
+			return string(maskJson([]byte(trimmed)))
+		}
+		return maskText(v)
+	default:
+		return v
+	}
+}
+
+func normalize(key string) string {
+	return strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(key))
+}
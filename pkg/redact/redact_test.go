@@ -0,0 +1,80 @@
+package redact
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValue(t *testing.T) {
+	visit := map[string]any{
+		"VisitID":         "V-1",
+		"VisitDetailJson": json.RawMessage(`{"patient_nik":"3404000000000001","patient_name":"Budi","patient_address":"Jl. Kaliurang","clinic_name":"Poli Umum"}`),
+		"PublishRequest":  `{"resourceType":"Patient","identifier":[{"system":"https://fhir.kemkes.go.id/id/nik","value":"3404000000000001"}],"name":[{"text":"Budi"}]}`,
+		"Encounter":       map[string]any{"subject": map[string]any{"reference": "Patient/P-1", "display": "Budi"}},
+		"Credential":      map[string]any{"client_secret": "s3cret", "access_token": "abc"},
+	}
+
+	got := string(Value(visit))
+
+	for _, leaked := range []string{"3404000000000001", "Budi", "Kaliurang", "s3cret", `"abc"`} {
+		if strings.Contains(got, leaked) {
+			t.Errorf("Value() leaks %q: %s", leaked, got)
+		}
+	}
+	for _, kept := range []string{"V-1", "Poli Umum", "Patient/P-1", "https://fhir.kemkes.go.id/id/nik"} {
+		if !strings.Contains(got, kept) {
+			t.Errorf("Value() lost %q: %s", kept, got)
+		}
+	}
+	if !json.Valid([]byte(got)) {
+		t.Errorf("Value() is not JSON: %s", got)
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		want string
+	}{
+		{"Url", "https://api/Patient?identifier=https://fhir.kemkes.go.id/id/nik|3404000000000001",
+			"https://api/Patient?identifier=https://fhir.kemkes.go.id/id/nik|" + Mask},
+		{"Bearer", "Authorization: Bearer eyJhbGciOi.x-y_z==", "Authorization: Bearer " + Mask},
+		{"Json", `{"access_token":"abc","expires_in":"3599"}`, `{"access_token":"` + Mask + `","expires_in":"3599"}`},
+		{"PrefixedJson", `response error: {"name":"Budi"}`, `response error: {"name":"` + Mask + `"}`},
+		{"Plain", "internal server error", "internal server error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := String(tt.s); got != tt.want {
+				t.Errorf("String() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarshalError(t *testing.T) {
+	if got := MarshalError(nil); got != nil {
+		t.Errorf("MarshalError(nil) = %v, want nil", got)
+	}
+
+	got := MarshalError(errors.New("patient 3404000000000001 not found"))
+	if got != "patient "+Mask+" not found" {
+		t.Errorf("MarshalError() = %v", got)
+	}
+}
+
+func TestSetEnabled(t *testing.T) {
+	SetEnabled(false)
+	defer SetEnabled(true)
+
+	if got := String("nik 3404000000000001"); got != "nik 3404000000000001" {
+		t.Errorf("String() = %v, want unmasked", got)
+	}
+	if got := string(Value(map[string]string{"patient_name": "Budi"})); got != `{"patient_name":"Budi"}` {
+		t.Errorf("Value() = %v, want unmasked", got)
+	}
+}
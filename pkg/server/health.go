@@ -0,0 +1,95 @@
+package server
+
+import (
+	"context"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const checkTimeout = 5 * time.Second
+
+const (
+	StatusOk   = "ok"
+	StatusFail = "fail"
+)
+
+// Check reports whether a dependency is usable, the error explains why it isn't.
+type Check struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+type CheckResult struct {
+	Status   string `json:"status"`
+	Duration string `json:"duration"`
+	Error    string `json:"error,omitempty"`
+}
+
+type HealthResult struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+// Health serves /healthz from the liveness checks and /readyz from the liveness and readiness checks,
+// both respond 503 with the result of every check when one of them fails.
+func Health(liveness []Check, readiness []Check) Route {
+	return func(e *echo.Echo) {
+		e.GET("/healthz", healthHandler(liveness))
+		e.GET("/readyz", healthHandler(append(append([]Check{}, liveness...), readiness...)))
+	}
+}
+
+func healthHandler(checks []Check) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		result := RunChecks(c.Request().Context(), checks)
+
+		status := http.StatusOK
+		if result.Status != StatusOk {
+			status = http.StatusServiceUnavailable
+		}
+		return c.JSON(status, result)
+	}
+}
+
+// RunChecks runs checks concurrently, each one within 5 seconds.
+func RunChecks(ctx context.Context, checks []Check) HealthResult {
+	result := HealthResult{
+		Status: StatusOk,
+		Checks: make(map[string]CheckResult, len(checks)),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range checks {
+		wg.Add(1)
+		go func(check Check) {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, checkTimeout)
+			defer cancel()
+
+			start := time.Now()
+			err := check.Check(checkCtx)
+			checkResult := CheckResult{
+				Status:   StatusOk,
+				Duration: time.Since(start).Round(time.Millisecond).String(),
+			}
+			if err != nil {
+				checkResult.Status = StatusFail
+				checkResult.Error = err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			result.Checks[check.Name] = checkResult
+			if err != nil {
+				result.Status = StatusFail
+			}
+		}(check)
+	}
+	wg.Wait()
+
+	return result
+}
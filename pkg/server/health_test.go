@@ -0,0 +1,44 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHealth(t *testing.T) {
+	ok := func(ctx context.Context) error { return nil }
+	down := func(ctx context.Context) error { return errors.New("connection refused") }
+
+	e := echo.New()
+	Health(
+		[]Check{{Name: "sqlite", Check: ok}},
+		[]Check{{Name: "simrs", Check: down}},
+	)(e)
+
+	recorder := httptest.NewRecorder()
+	e.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	var live HealthResult
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &live))
+	assert.Equal(t, StatusOk, live.Status)
+	assert.Len(t, live.Checks, 1)
+
+	recorder = httptest.NewRecorder()
+	e.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+
+	var ready HealthResult
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &ready))
+	assert.Equal(t, StatusFail, ready.Status)
+	assert.Equal(t, StatusOk, ready.Checks["sqlite"].Status)
+	assert.Equal(t, StatusFail, ready.Checks["simrs"].Status)
+	assert.Equal(t, "connection refused", ready.Checks["simrs"].Error)
+}
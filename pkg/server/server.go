@@ -25,7 +25,10 @@ var requestCounter = prometheus.NewCounter(
 type Operation func(e *echo.Echo)
 type Shutdown func(e *echo.Echo)
 
-func Start(port int, operation Operation, shutdown Shutdown) {
+// Route registers additional endpoints before the server starts.
+type Route func(e *echo.Echo)
+
+func Start(port int, operation Operation, shutdown Shutdown, routes ...Route) {
 	e := echo.New()
 	e.HideBanner = true
 
@@ -57,6 +60,10 @@ func Start(port int, operation Operation, shutdown Shutdown) {
 		return c.String(http.StatusOK, "Home")
 	})
 
+	for _, route := range routes {
+		route(e)
+	}
+
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
 	defer stop()
 
@@ -0,0 +1,161 @@
+package ucum
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const System = "http://unitsofmeasure.org"
+
+var (
+	ErrEmpty        = errors.New("value is empty")
+	ErrValue        = errors.New("value is not a number or range")
+	ErrUnit         = errors.New("unit is not a known UCUM unit")
+	ErrIncompatible = errors.New("unit is not compatible with the observation")
+)
+
+// Unit is a UCUM code with the unit as shown to humans.
+type Unit struct {
+	Code    string
+	Display string
+}
+
+// Quantity is a parsed observation value, either a single Value with an optional Comparator or a Low to High range.
+type Quantity struct {
+	Comparator string
+	Value      json.Number
+	Low        json.Number
+	High       json.Number
+	Unit       Unit
+}
+
+func (q Quantity) IsRange() bool {
+	return q.Low != "" && q.High != ""
+}
+
+// units maps the spellings found in SIMRS, lower cased without spaces, to their UCUM unit.
+// Every UCUM code is registered as spelling as well.
+var units = map[string]Unit{}
+
+func init() {
+	for unit, spellings := range map[Unit][]string{
+		{"mm[Hg]", "mmHg"}:     {"mmhg", "mm/hg"},
+		{"Cel", "°C"}:          {"c", "°c", "oc", "celcius", "celsius", "derajatcelcius", "derajatcelsius"},
+		{"/min", "/min"}:       {"x/mnt", "x/menit", "x/min", "x/m", "/mnt", "/menit", "kali/mnt", "kali/menit", "bpm", "rpm"},
+		{"%", "%"}:             {"persen"},
+		{"kg", "kg"}:           {"kilogram", "kgs"},
+		{"g", "g"}:             {"gr", "gram"},
+		{"cm", "cm"}:           {"centimeter", "senti"},
+		{"m", "m"}:             {"meter"},
+		{"kg/m2", "kg/m²"}:     {"kg/m²", "kg/m^2"},
+		{"{score}", "score"}:   {"skor", "score"},
+		{"g/dL", "g/dL"}:       {"gr/dl", "gram/dl", "g%", "gr%"},
+		{"mg/dL", "mg/dL"}:     {"mg%"},
+		{"mg/L", "mg/L"}:       {},
+		{"ng/mL", "ng/mL"}:     {},
+		{"mmol/L", "mmol/L"}:   {},
+		{"meq/L", "mEq/L"}:     {},
+		{"U/L", "U/L"}:         {},
+		{"[IU]/L", "IU/L"}:     {"iu/l", "ui/l"},
+		{"fL", "fL"}:           {"fl"},
+		{"pg", "pg"}:           {},
+		{"mm/h", "mm/jam"}:     {"mm/jam", "mm/j", "mm/1jam"},
+		{"10*3/uL", "10³/µL"}:  {"rb/ul", "ribu/ul", "10^3/ul", "10³/ul", "x10^3/ul", "x10³/ul", "10^3/µl", "10³/µl", "k/ul"},
+		{"10*6/uL", "10⁶/µL"}:  {"jt/ul", "juta/ul", "10^6/ul", "10⁶/ul", "x10^6/ul", "x10⁶/ul", "10^6/µl", "10⁶/µl", "m/ul"},
+		{"/uL", "/µL"}:         {"/µl", "sel/ul"},
+		{"s", "detik"}:         {"dtk", "detik", "sec"},
+		{"min", "menit"}:       {"mnt", "menit"},
+		{"[pH]", "pH"}:         {"ph"},
+		{"1", "1"}:             {},
+		{"mL", "mL"}:           {"ml", "cc"},
+		{"L", "L"}:             {"liter"},
+		{"mg", "mg"}:           {"miligram"},
+		{"ug/dL", "µg/dL"}:     {"µg/dl", "mcg/dl"},
+		{"umol/L", "µmol/L"}:   {"µmol/l"},
+		{"[IU]/mL", "IU/mL"}:   {"iu/ml"},
+		{"mL/min", "mL/menit"}: {"ml/mnt", "ml/menit"},
+	} {
+		units[spelling(unit.Code)] = unit
+		for _, s := range spellings {
+			units[spelling(s)] = unit
+		}
+	}
+}
+
+func spelling(unit string) string {
+	return strings.ToLower(strings.Join(strings.Fields(unit), ""))
+}
+
+// LookupUnit returns the UCUM unit of a unit written in SIMRS, e.g. "x/mnt" or "C".
+func LookupUnit(unit string) (Unit, bool) {
+	found, ok := units[spelling(unit)]
+	return found, ok
+}
+
+const number = `-?\d+(?:[.,]\d+)?`
+
+// valuePattern matches an optional comparator, a number or range and an optional unit, e.g. "36,5 C", "120-130" or "<0.5".
+var valuePattern = regexp.MustCompile(`^(<=|>=|<|>)?\s*(` + number + `)(?:\s*(?:-|–|s/d|s\.d\.?|sd|to)\s*(` + number + `))?\s*(.*)$`)
+
+// decimal returns the number with a decimal point, SIMRS forms accept both "36,5" and "36.5".
+func decimal(s string) (json.Number, float64) {
+	s = strings.Replace(s, ",", ".", 1)
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return "", 0
+	}
+	return json.Number(s), value
+}
+
+// Normalize parses value into a Quantity, unit is used when value doesn't carry its own unit.
+// A value without unit at all results in a Quantity without Unit.
+func Normalize(value string, unit string) (Quantity, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return Quantity{}, ErrEmpty
+	}
+
+	match := valuePattern.FindStringSubmatch(value)
+	if match == nil {
+		return Quantity{}, fmt.Errorf("%w: %q", ErrValue, value)
+	}
+
+	comparator, first, second, suffix := match[1], match[2], match[3], strings.TrimSpace(match[4])
+
+	var quantity Quantity
+	if second == "" {
+		quantity.Comparator = comparator
+		quantity.Value, _ = decimal(first)
+	} else {
+		low, lowValue := decimal(first)
+		high, highValue := decimal(second)
+		if comparator != "" || lowValue > highValue {
+			return Quantity{}, fmt.Errorf("%w: %q", ErrValue, value)
+		}
+		quantity.Low, quantity.High = low, high
+	}
+
+	if suffix != "" {
+		unit = suffix
+	}
+
+	if strings.TrimSpace(unit) == "" {
+		return quantity, nil
+	}
+
+	found, ok := LookupUnit(unit)
+	if !ok && strings.ContainsAny(suffix, "0123456789") {
+		// e.g. blood pressure typed as "120/80" into a single field
+		return Quantity{}, fmt.Errorf("%w: %q", ErrValue, value)
+	}
+	if !ok {
+		return Quantity{}, fmt.Errorf("%w: %q", ErrUnit, unit)
+	}
+
+	quantity.Unit = found
+	return quantity, nil
+}
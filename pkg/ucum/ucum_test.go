@@ -0,0 +1,87 @@
+package ucum
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestLookupUnit(t *testing.T) {
+	tests := []struct {
+		unit     string
+		wantCode string
+		wantOk   bool
+	}{
+		{"mmHg", "mm[Hg]", true},
+		{"C", "Cel", true},
+		{"x/mnt", "/min", true},
+		{"X / Menit", "/min", true},
+		{"%", "%", true},
+		{"rb/ul", "10*3/uL", true},
+		{"g/dl", "g/dL", true},
+		{"mm[Hg]", "mm[Hg]", true},
+		{"kotak", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.unit, func(t *testing.T) {
+			unit, ok := LookupUnit(tt.unit)
+			assert.Equal(t, tt.wantOk, ok)
+			assert.Equal(t, tt.wantCode, unit.Code)
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		unit  string
+		want  Quantity
+	}{
+		{"Integer", "120", "mmHg", Quantity{Value: "120", Unit: Unit{"mm[Hg]", "mmHg"}}},
+		{"CommaDecimal", "36,5", "C", Quantity{Value: "36.5", Unit: Unit{"Cel", "°C"}}},
+		{"UnitInValue", "98 %", "", Quantity{Value: "98", Unit: Unit{"%", "%"}}},
+		{"UnitInValueWins", "36,5 C", "%", Quantity{Value: "36.5", Unit: Unit{"Cel", "°C"}}},
+		{"Range", "120-130", "mmHg", Quantity{Low: "120", High: "130", Unit: Unit{"mm[Hg]", "mmHg"}}},
+		{"RangeWithWords", "4,5 s/d 5,5", "jt/ul", Quantity{Low: "4.5", High: "5.5", Unit: Unit{"10*6/uL", "10⁶/µL"}}},
+		{"Comparator", "<0.5", "mg/dl", Quantity{Comparator: "<", Value: "0.5", Unit: Unit{"mg/dL", "mg/dL"}}},
+		{"WithoutUnit", "1.025", "", Quantity{Value: json.Number("1.025")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Normalize(tt.value, tt.unit)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestNormalize_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		unit    string
+		wantErr error
+	}{
+		{"Empty", " ", "mmHg", ErrEmpty},
+		{"Text", "normal", "mmHg", ErrValue},
+		{"BloodPressure", "120/80", "mmHg", ErrValue},
+		{"ReversedRange", "130-120", "mmHg", ErrValue},
+		{"RangeWithComparator", "<120-130", "mmHg", ErrValue},
+		{"UnknownUnit", "12", "kotak", ErrUnit},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Normalize(tt.value, tt.unit)
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestQuantity_IsRange(t *testing.T) {
+	assert.True(t, Quantity{Low: "1", High: "2"}.IsRange())
+	assert.False(t, Quantity{Value: "1"}.IsRange())
+}
@@ -1,6 +1,9 @@
 package util
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -88,3 +91,29 @@ func DateConvert(dateString string, convertToUtc bool) string {
 	return result
 
 }
+
+// ParseDuration extends time.ParseDuration with a day unit, e.g. "1d" or "1d12h".
+func ParseDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	days, rest, found := strings.Cut(s, "d")
+	if !found {
+		return time.ParseDuration(s)
+	}
+
+	dayCount, err := strconv.Atoi(days)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+
+	result := time.Duration(dayCount) * 24 * time.Hour
+	if rest == "" {
+		return result, nil
+	}
+
+	remaining, err := time.ParseDuration(rest)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+
+	return result + remaining, nil
+}
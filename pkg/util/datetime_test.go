@@ -223,3 +223,33 @@ func TestDateConvert(t *testing.T) {
 		})
 	}
 }
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"OneDay", "1d", 24 * time.Hour, false},
+		{"SevenDays", "7d", 7 * 24 * time.Hour, false},
+		{"DayAndHours", "1d12h", 36 * time.Hour, false},
+		{"StandardDuration", "6h", 6 * time.Hour, false},
+		{"InvalidDay", "xd", 0, true},
+		{"InvalidRemainder", "1dxx", 0, true},
+		{"Empty", "", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseDuration(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseDuration() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ParseDuration() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
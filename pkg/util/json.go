@@ -12,3 +12,29 @@ func MarshalToJson(o any) *json.RawMessage {
 	rawJson := json.RawMessage(jsonData)
 	return &rawJson
 }
+
+// JsonStrings decodes a JSON column holding either a single string or an array of strings,
+// single reports which of both it was so the value can be written back in the same shape with JsonStringsOf.
+func JsonStrings(raw *json.RawMessage) (values []string, single bool) {
+	if raw == nil {
+		return nil, false
+	}
+
+	var value string
+	if err := json.Unmarshal(*raw, &value); err == nil {
+		return []string{value}, true
+	}
+
+	if err := json.Unmarshal(*raw, &values); err == nil {
+		return values, false
+	}
+
+	return nil, false
+}
+
+func JsonStringsOf(values []string, single bool) *json.RawMessage {
+	if single && len(values) == 1 {
+		return MarshalToJson(values[0])
+	}
+	return MarshalToJson(values)
+}
@@ -0,0 +1,38 @@
+package util
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestJsonStrings(t *testing.T) {
+	raw := func(s string) *json.RawMessage {
+		message := json.RawMessage(s)
+		return &message
+	}
+
+	tests := []struct {
+		name       string
+		input      *json.RawMessage
+		wantValues []string
+		wantSingle bool
+	}{
+		{"Nil", nil, nil, false},
+		{"Single", raw(`"718-7"`), []string{"718-7"}, true},
+		{"Array", raw(`["718-7","777-3"]`), []string{"718-7", "777-3"}, false},
+		{"Number", raw(`12`), nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, single := JsonStrings(tt.input)
+			assert.Equal(t, tt.wantValues, values)
+			assert.Equal(t, tt.wantSingle, single)
+
+			if values != nil {
+				assert.JSONEq(t, string(*tt.input), string(*JsonStringsOf(values, single)))
+			}
+		})
+	}
+}
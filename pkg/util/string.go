@@ -3,6 +3,7 @@ package util
 import (
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -29,10 +30,11 @@ func IntToString(i *int) string {
 	return fmt.Sprintf("%v", *i)
 }
 
+// JsonNumber returns nil when s is not a number, an invalid json.Number would fail marshalling the whole bundle.
 func JsonNumber(s string) *json.Number {
-	s = strings.Replace(s, ",", ".", 1)
+	s = strings.TrimSpace(strings.Replace(s, ",", ".", 1))
 	s = strings.TrimRight(s, ".")
-	if s == "" {
+	if _, err := strconv.ParseFloat(s, 64); err != nil {
 		return nil
 	}
 
@@ -3,6 +3,7 @@ package util
 import (
 	"encoding/json"
 	"errors"
+	"reflect"
 	"testing"
 )
 
@@ -35,11 +36,14 @@ func TestJsonNumber(t *testing.T) {
 		{"ValidNumber", "123", jsonNumber("123")},
 		{"FloatNumber", "123.456", jsonNumber("123.456")},
 		{"NegativeNumber", "-123", jsonNumber("-123")},
+		{"CommaDecimal", "36,5", jsonNumber("36.5")},
+		{"Empty", "", nil},
+		{"NotANumber", "120/80", nil},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := JsonNumber(tt.s); *got != *tt.want {
+			if got := JsonNumber(tt.s); !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("JsonNumber() = %v, want %v", got, tt.want)
 			}
 		})
@@ -0,0 +1,33 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// Allergy is a drug, food or environmental allergy of the patient recorded during a visit.
+type Allergy struct {
+	VisitId      int       `db:"visit_id" json:"visit_id" validate:"required"`
+	AllergyId    int       `db:"allergy_id" json:"allergy_id" validate:"required"`
+	Category     string    `db:"category" json:"category" validate:"required,oneof=medication food environment"`
+	AllergyCode  *string   `db:"allergy_code" json:"allergy_code,omitempty"` // KFA for medication, SNOMED CT otherwise
+	AllergyName  string    `db:"allergy_name" json:"allergy_name" validate:"required"`
+	Reaction     *string   `db:"reaction" json:"reaction,omitempty"`
+	RecordedDate time.Time `db:"recorded_date" json:"recorded_date" validate:"required"`
+}
+
+func (o *Allergy) Validate() []ValidationIssue {
+	return validateStruct("allergy", "", o)
+}
+
+func (o *Allergy) Invalid() bool {
+	return len(o.Validate()) > 0
+}
+
+// Key identifies an allergy of a patient across visits, by its code or, when not coded, by its name.
+func (o *Allergy) Key() string {
+	if o.AllergyCode != nil && strings.TrimSpace(*o.AllergyCode) != "" {
+		return o.Category + ":" + strings.TrimSpace(*o.AllergyCode)
+	}
+	return o.Category + ":" + strings.ToLower(strings.Join(strings.Fields(o.AllergyName), " "))
+}
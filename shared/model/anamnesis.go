@@ -0,0 +1,25 @@
+package model
+
+import (
+	"time"
+)
+
+// Anamnesis is the chief complaint, history and physical examination charted by the practitioner during a visit.
+type Anamnesis struct {
+	VisitId               int       `db:"visit_id" json:"visit_id" validate:"required"`
+	AnamnesisDate         time.Time `db:"anamnesis_date" json:"anamnesis_date" validate:"required"`
+	ChiefComplaint        string    `db:"chief_complaint" json:"chief_complaint" validate:"required"`
+	ChiefComplaintCode    *string   `db:"chief_complaint_code" json:"chief_complaint_code,omitempty" validate:"omitempty,numeric,min=6,max=18"` // SNOMED CT
+	ChiefComplaintDisplay *string   `db:"chief_complaint_display" json:"chief_complaint_display,omitempty"`
+	PresentIllness        *string   `db:"present_illness" json:"present_illness,omitempty"`
+	PastIllness           *string   `db:"past_illness" json:"past_illness,omitempty"`
+	PhysicalExam          *string   `db:"physical_exam" json:"physical_exam,omitempty"`
+}
+
+func (o *Anamnesis) Validate() []ValidationIssue {
+	return validateStruct("anamnesis", "", o)
+}
+
+func (o *Anamnesis) Invalid() bool {
+	return len(o.Validate()) > 0
+}
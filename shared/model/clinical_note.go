@@ -0,0 +1,26 @@
+package model
+
+import (
+	"time"
+)
+
+// ClinicalNote is the assessment and plan (SOAP "A" and "P") charted by the practitioner during a visit, either may be
+// left empty.
+type ClinicalNote struct {
+	VisitId    int       `db:"visit_id" json:"visit_id" validate:"required"`
+	NoteDate   time.Time `db:"note_date" json:"note_date" validate:"required"`
+	Assessment *string   `db:"assessment" json:"assessment,omitempty"`
+	Prognosis  *string   `db:"prognosis" json:"prognosis,omitempty"`
+	Plan       *string   `db:"plan" json:"plan,omitempty"`
+	Diet       *string   `db:"diet" json:"diet,omitempty"`
+	Education  *string   `db:"education" json:"education,omitempty"`
+	FollowUp   *string   `db:"follow_up" json:"follow_up,omitempty"`
+}
+
+func (o *ClinicalNote) Validate() []ValidationIssue {
+	return validateStruct("clinical_note", "", o)
+}
+
+func (o *ClinicalNote) Invalid() bool {
+	return len(o.Validate()) > 0
+}
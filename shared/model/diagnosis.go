@@ -1,7 +1,6 @@
 package model
 
 import (
-	"github.com/go-playground/validator/v10"
 	"time"
 )
 
@@ -12,11 +11,10 @@ type Diagnosis struct {
 	DiagnosisDate time.Time `json:"diagnosis_date"  validate:"required"`
 }
 
+func (o *Diagnosis) Validate() []ValidationIssue {
+	return validateStruct("diagnosis", o.DiagnosisCode, o)
+}
+
 func (o *Diagnosis) Invalid() bool {
-	val := validator.New()
-	err := val.Struct(o)
-	if err != nil {
-		return true
-	}
-	return false
+	return len(o.Validate()) > 0
 }
@@ -0,0 +1,32 @@
+package model
+
+import (
+	"strconv"
+	"time"
+)
+
+// Immunization is a vaccine given during a visit of the immunization clinic.
+type Immunization struct {
+	VisitId          int        `db:"visit_id" json:"visit_id" validate:"required"`
+	ImmunizationId   int        `db:"immunization_id" json:"immunization_id" validate:"required"`
+	OccurrenceDate   time.Time  `db:"occurrence_date" json:"occurrence_date" validate:"required"`
+	MedicineCode     string     `db:"medicine_code" json:"medicine_code"`
+	MedicineName     string     `db:"medicine_name" json:"medicine_name"`
+	KfaCode          *string    `db:"kfa_code" json:"kfa_code" validate:"required"`
+	KfaName          *string    `db:"kfa_name" json:"kfa_name" validate:"required"`
+	LotNumber        string     `db:"lot_number" json:"lot_number" validate:"required"`
+	ExpiredDate      *time.Time `db:"expired_date" json:"expired_date"`
+	DoseNumber       int        `db:"dose_number" json:"dose_number" validate:"required,min=1"`
+	Site             *string    `db:"site" json:"site"`
+	Route            *string    `db:"route" json:"route"`
+	PractitionerId   *string    `db:"practitioner_id" json:"practitioner_id" validate:"required"`
+	PractitionerName *string    `db:"practitioner_name" json:"practitioner_name" validate:"required"`
+}
+
+func (o *Immunization) Validate() []ValidationIssue {
+	return validateStruct("immunization", strconv.Itoa(o.ImmunizationId), o)
+}
+
+func (o *Immunization) Invalid() bool {
+	return len(o.Validate()) > 0
+}
@@ -1,5 +1,29 @@
 package model
 
+import "fmt"
+
+type validated interface {
+	Validate() []ValidationIssue
+}
+
+// listIssues returns the issues of every invalid item, records without an identifier are named by their position.
+func listIssues[T any, PT interface {
+	*T
+	validated
+}](items []T) []ValidationIssue {
+	var issues []ValidationIssue
+	for i := range items {
+		for _, issue := range PT(&items[i]).Validate() {
+			if issue.RecordId == "" {
+				issue.RecordId = fmt.Sprintf("#%d", i+1)
+				issue.MessageId, issue.MessageEn = issueMessages(issue)
+			}
+			issues = append(issues, issue)
+		}
+	}
+	return issues
+}
+
 type DiagnosisList []Diagnosis
 
 func (dl *DiagnosisList) Invalid() bool {
@@ -83,3 +107,129 @@ func (orl *ObservationRadiologyList) Invalid() bool {
 	}
 	return false
 }
+
+type AnamnesisList []Anamnesis
+
+func (al *AnamnesisList) Invalid() bool {
+	if al == nil {
+		return true
+	}
+	for _, anamnesis := range *al {
+		if anamnesis.Invalid() {
+			return true
+		}
+	}
+	return false
+}
+
+type AllergyList []Allergy
+
+func (al *AllergyList) Invalid() bool {
+	if al == nil {
+		return true
+	}
+	for _, allergy := range *al {
+		if allergy.Invalid() {
+			return true
+		}
+	}
+	return false
+}
+
+type ImmunizationList []Immunization
+
+func (il *ImmunizationList) Invalid() bool {
+	if il == nil {
+		return true
+	}
+	for _, immunization := range *il {
+		if immunization.Invalid() {
+			return true
+		}
+	}
+	return false
+}
+
+type ClinicalNoteList []ClinicalNote
+
+func (cl *ClinicalNoteList) Invalid() bool {
+	if cl == nil {
+		return true
+	}
+	for _, note := range *cl {
+		if note.Invalid() {
+			return true
+		}
+	}
+	return false
+}
+
+func (dl *DiagnosisList) Validate() []ValidationIssue {
+	if dl == nil {
+		return nil
+	}
+	return listIssues(*dl)
+}
+
+func (mrl *MedicationRequestList) Validate() []ValidationIssue {
+	if mrl == nil {
+		return nil
+	}
+	return listIssues(*mrl)
+}
+
+func (mdl *MedicationDispenseList) Validate() []ValidationIssue {
+	if mdl == nil {
+		return nil
+	}
+	return listIssues(*mdl)
+}
+
+func (pl *ProcedureList) Validate() []ValidationIssue {
+	if pl == nil {
+		return nil
+	}
+	return listIssues(*pl)
+}
+
+func (oll *ObservationLabList) Validate() []ValidationIssue {
+	if oll == nil {
+		return nil
+	}
+	return listIssues(*oll)
+}
+
+func (orl *ObservationRadiologyList) Validate() []ValidationIssue {
+	if orl == nil {
+		return nil
+	}
+	return listIssues(*orl)
+}
+
+func (al *AnamnesisList) Validate() []ValidationIssue {
+	if al == nil {
+		return nil
+	}
+	return listIssues(*al)
+}
+
+func (al *AllergyList) Validate() []ValidationIssue {
+	if al == nil {
+		return nil
+	}
+	return listIssues(*al)
+}
+
+func (il *ImmunizationList) Validate() []ValidationIssue {
+	if il == nil {
+		return nil
+	}
+	return listIssues(*il)
+}
+
+func (cl *ClinicalNoteList) Validate() []ValidationIssue {
+	if cl == nil {
+		return nil
+	}
+	return listIssues(*cl)
+}
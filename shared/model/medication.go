@@ -1,56 +1,99 @@
 package model
 
 import (
-	"github.com/go-playground/validator/v10"
+	"encoding/json"
+	"fmt"
+	"strconv"
 	"time"
 )
 
+// MedicationIngredient is one component of a compound medication (racikan), Strength is per unit of the form.
+type MedicationIngredient struct {
+	MedicineCode string  `json:"medicine_code"`
+	MedicineName string  `json:"medicine_name"`
+	KfaCode      *string `json:"kfa_code" validate:"required"`
+	KfaName      *string `json:"kfa_name" validate:"required"`
+	Strength     float64 `json:"strength"`
+	StrengthUnit string  `json:"strength_unit"`
+}
+
+// MedicationIngredients are the ingredients SIMRS aggregates into a JSON array per prescription line.
+type MedicationIngredients []MedicationIngredient
+
+func (o *MedicationIngredients) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*o = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, o)
+	case string:
+		return json.Unmarshal([]byte(v), o)
+	default:
+		return fmt.Errorf("cannot scan %T into MedicationIngredients", src)
+	}
+}
+
 type MedicationRequest struct {
-	VisitId          int          `json:"visit_id"`
-	PatientType      PatientType  `json:"patient_type"  validate:"required"`
-	Date             *time.Time   `json:"date"  validate:"required"`
-	MedicineCode     *string      `json:"medicine_code"`
-	PrescriptionId   int          `json:"prescription_id" validate:"required"`
-	KfaCode          *string      `json:"kfa_code"`
-	KfaName          *string      `json:"kfa_name"`
-	Type             MedicineType `json:"type"  validate:"required"`
-	PractitionerId   *string      `json:"practitioner_id"  validate:"required"`
-	PractitionerName *string      `json:"practitioner_name"  validate:"required"`
-	Amount           float64      `json:"amount"`
-	Unit             string       `json:"unit"`
+	VisitId          int                   `json:"visit_id"`
+	PatientType      PatientType           `json:"patient_type"  validate:"required"`
+	Date             *time.Time            `json:"date"  validate:"required"`
+	MedicineCode     *string               `json:"medicine_code"`
+	MedicineName     *string               `json:"medicine_name"`
+	PrescriptionId   int                   `json:"prescription_id" validate:"required"`
+	KfaCode          *string               `json:"kfa_code" validate:"required"`
+	KfaName          *string               `json:"kfa_name" validate:"required"`
+	Type             MedicineType          `json:"type"  validate:"required"`
+	PractitionerId   *string               `json:"practitioner_id"  validate:"required"`
+	PractitionerName *string               `json:"practitioner_name"  validate:"required"`
+	Amount           float64               `json:"amount"`
+	Unit             string                `json:"unit"`
+	Form             string                `json:"form"`                  // dosage form, e.g. tablet or puyer
+	Usage            string                `db:"usage_text" json:"usage"` // aturan pakai as charted, e.g. "3x1 sesudah makan"
+	Route            string                `json:"route"`
+	DoseAmount       float64               `json:"dose_amount"` // per administration, read from Usage when zero
+	DoseUnit         string                `json:"dose_unit"`
+	Ingredients      MedicationIngredients `json:"ingredients" validate:"dive"`
+}
+
+func (o *MedicationRequest) Validate() []ValidationIssue {
+	return validateStruct("medication_request", strconv.Itoa(o.PrescriptionId), o)
 }
 
 func (o *MedicationRequest) Invalid() bool {
-	val := validator.New()
-	err := val.Struct(o)
-	if err != nil {
-		return true
-	}
-	return false
+	return len(o.Validate()) > 0
 }
 
 type MedicationDispense struct {
-	VisitId               int          `json:"visit_id"`
-	PatientType           PatientType  `json:"patient_type" validate:"required"`
-	Date                  *time.Time   `json:"date" validate:"required"`
-	MedicineCode          string       `json:"medicine_code"`
-	PrescriptionId        int          `json:"prescription_id" validate:"required"`
-	KfaCode               *string      `json:"kfa_code"`
-	KfaName               *string      `json:"kfa_name"`
-	Type                  MedicineType `json:"type" validate:"required"`
-	PractitionerId        *string      `json:"practitioner_id" validate:"required"`
-	PractitionerName      *string      `json:"practitioner_name" validate:"required"`
-	BatchNumber           string       `json:"batch_number" validate:"required"`
-	ExpiredDate           *time.Time   `json:"expired_date" validate:"required"`
-	PrescriptionStartDate *time.Time   `json:"prescription_start_date" validate:"required"`
-	HandoverDate          *time.Time   `json:"drug_received_date" validate:"required"`
+	VisitId               int                   `json:"visit_id"`
+	PatientType           PatientType           `json:"patient_type" validate:"required"`
+	Date                  *time.Time            `json:"date" validate:"required"`
+	MedicineCode          string                `json:"medicine_code"`
+	MedicineName          string                `json:"medicine_name"`
+	PrescriptionId        int                   `json:"prescription_id" validate:"required"`
+	KfaCode               *string               `json:"kfa_code" validate:"required"`
+	KfaName               *string               `json:"kfa_name" validate:"required"`
+	Type                  MedicineType          `json:"type" validate:"required"`
+	PractitionerId        *string               `json:"practitioner_id" validate:"required"`
+	PractitionerName      *string               `json:"practitioner_name" validate:"required"`
+	BatchNumber           string                `json:"batch_number" validate:"required"`
+	ExpiredDate           *time.Time            `json:"expired_date" validate:"required"`
+	PrescriptionStartDate *time.Time            `json:"prescription_start_date" validate:"required"`
+	HandoverDate          *time.Time            `json:"drug_received_date" validate:"required"`
+	Amount                float64               `json:"amount"`
+	Unit                  string                `json:"unit"`
+	Form                  string                `json:"form"`
+	Usage                 string                `db:"usage_text" json:"usage"`
+	Route                 string                `json:"route"`
+	DoseAmount            float64               `json:"dose_amount"`
+	DoseUnit              string                `json:"dose_unit"`
+	Ingredients           MedicationIngredients `json:"ingredients" validate:"dive"`
+}
+
+func (o *MedicationDispense) Validate() []ValidationIssue {
+	return validateStruct("medication_dispense", strconv.Itoa(o.PrescriptionId), o)
 }
 
 func (o *MedicationDispense) Invalid() bool {
-	val := validator.New()
-	err := val.Struct(o)
-	if err != nil {
-		return true
-	}
-	return false
+	return len(o.Validate()) > 0
 }
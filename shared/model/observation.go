@@ -2,11 +2,11 @@ package model
 
 import (
 	"encoding/json"
-	"github.com/go-playground/validator/v10"
 )
 
 type ObservationLab struct {
 	VisitId          int              `db:"visit_id"`
+	LabCode          string           `db:"lab_code"`
 	LabName          string           `db:"lab_name"`
 	LabParameter     *json.RawMessage `db:"lab_parameter"`
 	LabUnit          *json.RawMessage `db:"lab_unit"`
@@ -20,13 +20,12 @@ type ObservationLab struct {
 	PractitionerName string           `db:"practitioner_name" validate:"required"`
 }
 
+func (o *ObservationLab) Validate() []ValidationIssue {
+	return validateStruct("lab", o.LabName, o)
+}
+
 func (o *ObservationLab) Invalid() bool {
-	val := validator.New()
-	err := val.Struct(o)
-	if err != nil {
-		return true
-	}
-	return false
+	return len(o.Validate()) > 0
 }
 
 type ObservationRadiology struct {
@@ -44,11 +43,10 @@ type ObservationRadiology struct {
 	PractitionerName string           `db:"practitioner_name" validate:"required"`
 }
 
+func (o *ObservationRadiology) Validate() []ValidationIssue {
+	return validateStruct("radiology", o.LabName, o)
+}
+
 func (o *ObservationRadiology) Invalid() bool {
-	val := validator.New()
-	err := val.Struct(o)
-	if err != nil {
-		return true
-	}
-	return false
+	return len(o.Validate()) > 0
 }
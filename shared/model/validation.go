@@ -0,0 +1,219 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// validate is shared by every model, validator.Validate caches struct metadata and is safe for concurrent use.
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	val := validator.New()
+	val.RegisterTagNameFunc(func(field reflect.StructField) string {
+		for _, tag := range []string{"json", "db"} {
+			name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+			if name != "" && name != "-" {
+				return name
+			}
+		}
+		return field.Name
+	})
+	return val
+}
+
+// ValidationIssue describes a single field that failed validation, with messages for the dashboard in
+// Indonesian (MessageId) and English (MessageEn).
+type ValidationIssue struct {
+	Resource  string `json:"resource" db:"resource"`
+	RecordId  string `json:"record_id" db:"record_id"`
+	Field     string `json:"field" db:"field"`
+	Rule      string `json:"rule" db:"rule"`
+	Param     string `json:"param" db:"param"`
+	Value     string `json:"value" db:"value"`
+	MessageId string `json:"message_id" db:"message_id"`
+	MessageEn string `json:"message_en" db:"message_en"`
+}
+
+func (v ValidationIssue) Error() string {
+	return v.MessageEn
+}
+
+// NewValidationIssue builds an issue for checks done outside of struct tag validation.
+func NewValidationIssue(resource string, recordId string, field string, rule string, param string, value string) ValidationIssue {
+	issue := ValidationIssue{
+		Resource: resource,
+		RecordId: recordId,
+		Field:    field,
+		Rule:     rule,
+		Param:    param,
+		Value:    value,
+	}
+	issue.MessageId, issue.MessageEn = issueMessages(issue)
+	return issue
+}
+
+func validateStruct(resource string, recordId string, o any) []ValidationIssue {
+	err := validate.Struct(o)
+	if err == nil {
+		return nil
+	}
+
+	var fieldErrors validator.ValidationErrors
+	if !errors.As(err, &fieldErrors) {
+		return []ValidationIssue{NewValidationIssue(resource, recordId, "", "invalid", "", err.Error())}
+	}
+
+	issues := make([]ValidationIssue, 0, len(fieldErrors))
+	for _, fieldError := range fieldErrors {
+		issues = append(issues, NewValidationIssue(resource, recordId, fieldError.Field(), fieldError.Tag(), fieldError.Param(), issueValue(fieldError.Value())))
+	}
+	return issues
+}
+
+func issueValue(value any) string {
+	rv := reflect.ValueOf(value)
+	for rv.IsValid() && rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return ""
+		}
+		rv = rv.Elem()
+	}
+
+	if !rv.IsValid() {
+		return ""
+	}
+
+	switch v := rv.Interface().(type) {
+	case json.RawMessage:
+		return string(v)
+	case time.Time:
+		if v.IsZero() {
+			return ""
+		}
+		return v.Format(time.RFC3339)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// label holds the Indonesian and English display name of a resource or field.
+type label struct {
+	id string
+	en string
+}
+
+var resourceLabels = map[string]label{
+	"visit":               {"kunjungan", "visit"},
+	"vital_sign":          {"tanda vital", "vital sign"},
+	"diagnosis":           {"diagnosis", "diagnosis"},
+	"lab":                 {"pemeriksaan lab", "lab result"},
+	"radiology":           {"pemeriksaan radiologi", "radiology result"},
+	"medication_request":  {"resep", "prescription"},
+	"medication_dispense": {"penyerahan obat", "dispense"},
+	"procedure":           {"tindakan", "procedure"},
+	"anamnesis":           {"anamnesis", "anamnesis"},
+	"allergy":             {"alergi", "allergy"},
+	"immunization":        {"imunisasi", "immunization"},
+	"clinical_note":       {"catatan SOAP", "SOAP note"},
+}
+
+var fieldLabels = map[string]label{
+	"visit_id":                  {"ID kunjungan", "visit ID"},
+	"patient_satusehat_id":      {"ID SatuSehat pasien", "patient SatuSehat ID"},
+	"patient_name":              {"nama pasien", "patient name"},
+	"practitioner_satusehat_id": {"ID SatuSehat dokter", "practitioner SatuSehat ID"},
+	"practitioner_id":           {"ID SatuSehat dokter", "practitioner SatuSehat ID"},
+	"practitioner_name":         {"nama dokter", "practitioner name"},
+	"clinic_name":               {"nama poli", "clinic name"},
+	"clinic_id":                 {"ID lokasi SatuSehat poli", "clinic SatuSehat location ID"},
+	"period_start_date":         {"tanggal mulai kunjungan", "visit start date"},
+	"period_end_date":           {"tanggal selesai kunjungan", "visit end date"},
+	"arrived_start_time":        {"waktu kedatangan", "arrival time"},
+	"arrived_end_time":          {"waktu akhir kedatangan", "arrival end time"},
+	"in_progress_start_time":    {"waktu mulai pemeriksaan", "examination start time"},
+	"in_progress_end_time":      {"waktu akhir pemeriksaan", "examination end time"},
+	"finish_start_time":         {"waktu selesai", "finish time"},
+	"finish_end_time":           {"waktu akhir selesai", "finish end time"},
+	"diagnosis_code":            {"kode ICD-10", "ICD-10 code"},
+	"diagnosis_name":            {"nama diagnosis", "diagnosis name"},
+	"diagnosis_date":            {"tanggal diagnosis", "diagnosis date"},
+	"procedure_code":            {"kode ICD-9-CM", "ICD-9-CM code"},
+	"procedure_name":            {"nama tindakan", "procedure name"},
+	"patient_type":              {"jenis pasien", "patient type"},
+	"date":                      {"tanggal", "date"},
+	"prescription_id":           {"nomor resep", "prescription number"},
+	"kfa_code":                  {"kode KFA", "KFA code"},
+	"kfa_name":                  {"nama KFA", "KFA name"},
+	"type":                      {"jenis obat", "medication type"},
+	"batch_number":              {"nomor batch", "batch number"},
+	"expired_date":              {"tanggal kedaluwarsa", "expiry date"},
+	"prescription_start_date":   {"tanggal penyiapan obat", "preparation date"},
+	"drug_received_date":        {"tanggal penyerahan obat", "handover date"},
+	"lab_loinc_code":            {"kode LOINC", "LOINC code"},
+	"lab_loinc_name":            {"nama LOINC", "LOINC name"},
+	"anamnesis_date":            {"tanggal anamnesis", "anamnesis date"},
+	"chief_complaint":           {"keluhan utama", "chief complaint"},
+	"chief_complaint_code":      {"kode SNOMED keluhan utama", "chief complaint SNOMED code"},
+	"allergy_id":                {"ID alergi", "allergy ID"},
+	"category":                  {"kategori", "category"},
+	"allergy_name":              {"nama alergen", "allergen name"},
+	"recorded_date":             {"tanggal pencatatan", "recorded date"},
+	"immunization_id":           {"ID imunisasi", "immunization ID"},
+	"occurrence_date":           {"tanggal imunisasi", "immunization date"},
+	"lot_number":                {"nomor lot vaksin", "vaccine lot number"},
+	"dose_number":               {"dosis ke", "dose number"},
+	"note_date":                 {"tanggal catatan", "note date"},
+	"assessment":                {"asesmen", "assessment"},
+}
+
+func lookupLabel(labels map[string]label, key string) label {
+	if l, ok := labels[key]; ok {
+		return l
+	}
+	return label{id: key, en: key}
+}
+
+func issueMessages(issue ValidationIssue) (string, string) {
+	field := lookupLabel(fieldLabels, issue.Field)
+	resource := lookupLabel(resourceLabels, issue.Resource)
+
+	var messageId, messageEn string
+	switch issue.Rule {
+	case "required":
+		messageId = fmt.Sprintf("%s belum diisi", field.id)
+		messageEn = fmt.Sprintf("%s missing", field.en)
+	case "format":
+		messageId = fmt.Sprintf("format %s %q tidak valid", field.id, issue.Value)
+		messageEn = fmt.Sprintf("%s %q is malformed", field.en, issue.Value)
+	case "incomplete_code":
+		messageId = fmt.Sprintf("%s %q adalah kategori, gunakan subkode yang lebih spesifik", field.id, issue.Value)
+		messageEn = fmt.Sprintf("%s %q is a category, use a more specific sub code", field.en, issue.Value)
+	case "unknown_code":
+		messageId = fmt.Sprintf("%s %q tidak dikenal", field.id, issue.Value)
+		messageEn = fmt.Sprintf("%s %q is unknown", field.en, issue.Value)
+	default:
+		messageId = fmt.Sprintf("%s tidak valid (%s)", field.id, ruleDescription(issue))
+		messageEn = fmt.Sprintf("%s is invalid (%s)", field.en, ruleDescription(issue))
+	}
+
+	if issue.RecordId != "" {
+		return fmt.Sprintf("%s pada %s %s", messageId, resource.id, issue.RecordId),
+			fmt.Sprintf("%s on %s %s", messageEn, resource.en, issue.RecordId)
+	}
+
+	return fmt.Sprintf("%s pada %s", messageId, resource.id),
+		fmt.Sprintf("%s on %s", messageEn, resource.en)
+}
+
+func ruleDescription(issue ValidationIssue) string {
+	if issue.Param == "" {
+		return issue.Rule
+	}
+	return fmt.Sprintf("%s=%s", issue.Rule, issue.Param)
+}
@@ -0,0 +1,108 @@
+package model
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestMedicationRequest_Validate(t *testing.T) {
+	now := time.Now()
+	request := MedicationRequest{
+		VisitId:        1,
+		Date:           &now,
+		PrescriptionId: 1234,
+	}
+
+	issues := request.Validate()
+	assert.True(t, request.Invalid())
+
+	var kfaCode *ValidationIssue
+	for i := range issues {
+		if issues[i].Field == "kfa_code" {
+			kfaCode = &issues[i]
+		}
+	}
+
+	if assert.NotNil(t, kfaCode) {
+		assert.Equal(t, "medication_request", kfaCode.Resource)
+		assert.Equal(t, "1234", kfaCode.RecordId)
+		assert.Equal(t, "required", kfaCode.Rule)
+		assert.Equal(t, "KFA code missing on prescription 1234", kfaCode.MessageEn)
+		assert.Equal(t, "kode KFA belum diisi pada resep 1234", kfaCode.MessageId)
+	}
+}
+
+func TestDiagnosisList_Validate(t *testing.T) {
+	list := DiagnosisList{
+		{VisitID: "1", DiagnosisCode: "A09.9", DiagnosisName: "Gastroenteritis", DiagnosisDate: time.Now()},
+		{VisitID: "1", DiagnosisName: "Gastroenteritis", DiagnosisDate: time.Now()},
+	}
+
+	issues := list.Validate()
+	if assert.Len(t, issues, 1) {
+		assert.Equal(t, "diagnosis_code", issues[0].Field)
+		assert.Equal(t, "#2", issues[0].RecordId)
+		assert.Equal(t, "ICD-10 code missing on diagnosis #2", issues[0].MessageEn)
+	}
+}
+
+func TestAnamnesisList_Validate(t *testing.T) {
+	code := "21522001"
+	invalid := "abdominal pain"
+	list := AnamnesisList{
+		{VisitId: 1, AnamnesisDate: time.Now(), ChiefComplaint: "Nyeri perut", ChiefComplaintCode: &code},
+		{VisitId: 1, AnamnesisDate: time.Now(), ChiefComplaintCode: &invalid},
+	}
+
+	issues := list.Validate()
+	fields := map[string]string{}
+	for _, issue := range issues {
+		assert.Equal(t, "#2", issue.RecordId)
+		fields[issue.Field] = issue.Rule
+	}
+	assert.Equal(t, map[string]string{"chief_complaint": "required", "chief_complaint_code": "numeric"}, fields)
+	assert.True(t, list.Invalid())
+}
+
+func TestAllergy_Key(t *testing.T) {
+	code := " 93001019 "
+	coded := Allergy{Category: "medication", AllergyCode: &code, AllergyName: "Amoxicillin"}
+	named := Allergy{Category: "food", AllergyName: "  Udang   Windu "}
+
+	assert.Equal(t, "medication:93001019", coded.Key())
+	assert.Equal(t, "food:udang windu", named.Key())
+	assert.NotEqual(t, named.Key(), (&Allergy{Category: "environment", AllergyName: "Udang Windu"}).Key())
+}
+
+func TestMedicationRequest_Validate_Ingredients(t *testing.T) {
+	var ingredients MedicationIngredients
+	err := ingredients.Scan([]byte(`[{"medicine_code":"PCT","medicine_name":"Paracetamol","kfa_code":"93001019","kfa_name":"Paracetamol 500 mg","strength":250,"strength_unit":"mg"},{"medicine_code":"CTM","kfa_code":null}]`))
+	assert.NoError(t, err)
+	if assert.Len(t, ingredients, 2) {
+		assert.Equal(t, 250.0, ingredients[0].Strength)
+	}
+
+	now := time.Now()
+	kfaCode, kfaName, practitioner := "93000001", "Racikan", "N10000001"
+	request := MedicationRequest{
+		PatientType:      Outpatient,
+		Date:             &now,
+		PrescriptionId:   1234,
+		KfaCode:          &kfaCode,
+		KfaName:          &kfaName,
+		Type:             Compound,
+		PractitionerId:   &practitioner,
+		PractitionerName: &practitioner,
+		Ingredients:      ingredients,
+	}
+
+	fields := map[string]bool{}
+	for _, issue := range request.Validate() {
+		fields[issue.Field] = true
+	}
+	assert.Equal(t, map[string]bool{"kfa_code": true, "kfa_name": true}, fields)
+
+	assert.NoError(t, ingredients.Scan(nil))
+	assert.Nil(t, ingredients)
+}
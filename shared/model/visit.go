@@ -1,65 +1,83 @@
 package model
 
 import (
-	"github.com/go-playground/validator/v10"
 	"time"
 )
 
 type Visit struct {
-	VisitID                 string
-	PatientSatusehatID      string
-	PatientNIK              string
-	PatientName             string
-	PatientSex              string
-	PatientBirthDate        *time.Time
-	PatientAddress          string
-	PractitionerNIK         string
-	PractitionerSatusehatID string
-	PractitionerName        string
-	ClinicSatusehatID       string
-	ClinicName              string
-	Systole                 string
-	Diastole                string
-	HeartRate               string
-	RespirationRate         string
-	OxygenSaturation        string
-	Temperature             string
-	PeriodStartDate         time.Time
-	PeriodEndDate           time.Time
-	ArrivedStartTime        *time.Time
-	ArrivedEndTime          *time.Time
-	InProgressStartTime     *time.Time
-	InProgressEndTime       *time.Time
-	FinishStartTime         *time.Time
-	FinishEndTime           *time.Time
+	VisitID                  string
+	PatientSatusehatID       string
+	PatientNIK               string
+	PatientName              string
+	PatientSex               string
+	PatientBirthDate         *time.Time
+	PatientAddress           string
+	PractitionerNIK          string
+	PractitionerSatusehatID  string
+	PractitionerName         string
+	ClinicSatusehatID        string
+	ClinicName               string
+	Systole                  string
+	Diastole                 string
+	HeartRate                string
+	RespirationRate          string
+	OxygenSaturation         string
+	Temperature              string
+	Weight                   string
+	Height                   string
+	Bmi                      string
+	PainScore                string
+	Gcs                      string
+	Consciousness            string
+	PeriodStartDate          time.Time
+	PeriodEndDate            time.Time
+	ArrivedStartTime         *time.Time
+	ArrivedEndTime           *time.Time
+	InProgressStartTime      *time.Time
+	InProgressEndTime        *time.Time
+	FinishStartTime          *time.Time
+	FinishEndTime            *time.Time
+	DischargeDisposition     string
+	ReferralOrganizationID   string
+	ReferralOrganizationName string
+	ReferralReason           string
+	FollowUpDate             *time.Time
 }
 
 type VisitDetail struct {
-	VisitId             string     `json:"visit_id" validate:"required"`
-	PatientSatusehatId  string     `json:"patient_satusehat_id" validate:"required"`
-	PatientNik          string     `json:"patient_nik" `
-	PatientName         string     `json:"patient_name" validate:"required"`
-	PatientSex          string     `json:"patient_sex"`
-	PatientBirthDate    *time.Time `json:"patient_birth_date"`
-	PatientAddress      string     `json:"patient_address"`
-	PractitionerNik     string     `json:"practitioner_nik"`
-	PractitionerId      string     `json:"practitioner_satusehat_id" validate:"required"`
-	PractitionerName    string     `json:"practitioner_name" validate:"required"`
-	ClinicName          string     `json:"clinic_name" validate:"required"`
-	ClinicSatuSehatId   string     `json:"clinic_id" validate:"required"`
-	PeriodStartDate     time.Time  `json:"period_start_date" validate:"required"`
-	PeriodEndDate       time.Time  `json:"period_end_date" validate:"required"`
-	ArrivedStartTime    *time.Time `json:"arrived_start_time"  validate:"required"`
-	ArrivedEndTime      *time.Time `json:"arrived_end_time"  validate:"required"`
-	InProgressStartTime *time.Time `json:"in_progress_start_time"  validate:"required"`
-	InProgressEndTime   *time.Time `json:"in_progress_end_time"  validate:"required"`
-	FinishStartTime     *time.Time `json:"finish_start_time"  validate:"required"`
-	FinishEndTime       *time.Time `json:"finish_end_time"  validate:"required"`
+	VisitId                  string     `json:"visit_id" validate:"required"`
+	PatientSatusehatId       string     `json:"patient_satusehat_id" validate:"required"`
+	PatientNik               string     `json:"patient_nik" `
+	PatientName              string     `json:"patient_name" validate:"required"`
+	PatientSex               string     `json:"patient_sex"`
+	PatientBirthDate         *time.Time `json:"patient_birth_date"`
+	PatientAddress           string     `json:"patient_address"`
+	PractitionerNik          string     `json:"practitioner_nik"`
+	PractitionerId           string     `json:"practitioner_satusehat_id" validate:"required"`
+	PractitionerName         string     `json:"practitioner_name" validate:"required"`
+	ClinicName               string     `json:"clinic_name" validate:"required"`
+	ClinicSatuSehatId        string     `json:"clinic_id" validate:"required"`
+	PeriodStartDate          time.Time  `json:"period_start_date" validate:"required"`
+	PeriodEndDate            time.Time  `json:"period_end_date" validate:"required"`
+	ArrivedStartTime         *time.Time `json:"arrived_start_time"  validate:"required"`
+	ArrivedEndTime           *time.Time `json:"arrived_end_time"  validate:"required"`
+	InProgressStartTime      *time.Time `json:"in_progress_start_time"  validate:"required"`
+	InProgressEndTime        *time.Time `json:"in_progress_end_time"  validate:"required"`
+	FinishStartTime          *time.Time `json:"finish_start_time"  validate:"required"`
+	FinishEndTime            *time.Time `json:"finish_end_time"  validate:"required"`
+	DischargeDisposition     string     `json:"discharge_disposition"`
+	ReferralOrganizationId   string     `json:"referral_organization_id"`
+	ReferralOrganizationName string     `json:"referral_organization_name"`
+	ReferralReason           string     `json:"referral_reason"`
+	FollowUpDate             *time.Time `json:"follow_up_date"`
 }
 
-func (v VisitDetail) Invalid() error {
-	val := validator.New()
-	return val.Struct(v)
+func (v VisitDetail) Validate() []ValidationIssue {
+	return validateStruct("visit", v.VisitId, v)
+}
+
+func (v VisitDetail) Invalid() bool {
+	return len(v.Validate()) > 0
 }
 
 type VitalSign struct {
@@ -69,6 +87,12 @@ type VitalSign struct {
 	RespirationRate  string `json:"respiration_rate"`
 	Temperature      string `json:"temperature"`
 	OxygenSaturation string `json:"oxygen_saturation"`
+	Weight           string `json:"weight"`
+	Height           string `json:"height"`
+	Bmi              string `json:"bmi"`
+	PainScore        string `json:"pain_score"`
+	Gcs              string `json:"gcs"`
+	Consciousness    string `json:"consciousness"`
 }
 
 func (v *Visit) VitalSign() VitalSign {
@@ -79,30 +103,41 @@ func (v *Visit) VitalSign() VitalSign {
 		RespirationRate:  v.RespirationRate,
 		Temperature:      v.Temperature,
 		OxygenSaturation: v.OxygenSaturation,
+		Weight:           v.Weight,
+		Height:           v.Height,
+		Bmi:              v.Bmi,
+		PainScore:        v.PainScore,
+		Gcs:              v.Gcs,
+		Consciousness:    v.Consciousness,
 	}
 }
 
 func (v *Visit) VisitDetail() VisitDetail {
 	return VisitDetail{
-		VisitId:             v.VisitID,
-		PatientSatusehatId:  v.PatientSatusehatID,
-		PatientNik:          v.PatientNIK,
-		PatientName:         v.PatientName,
-		PatientSex:          v.PatientSex,
-		PatientBirthDate:    v.PatientBirthDate,
-		PatientAddress:      v.PatientAddress,
-		ClinicName:          v.ClinicName,
-		ClinicSatuSehatId:   v.ClinicSatusehatID,
-		PeriodStartDate:     v.PeriodStartDate,
-		PeriodEndDate:       v.PeriodEndDate,
-		PractitionerNik:     v.PractitionerNIK,
-		PractitionerId:      v.PractitionerSatusehatID,
-		PractitionerName:    v.PractitionerName,
-		ArrivedStartTime:    v.ArrivedStartTime,
-		ArrivedEndTime:      v.ArrivedEndTime,
-		InProgressStartTime: v.InProgressStartTime,
-		InProgressEndTime:   v.InProgressEndTime,
-		FinishStartTime:     v.FinishStartTime,
-		FinishEndTime:       v.FinishEndTime,
+		VisitId:                  v.VisitID,
+		PatientSatusehatId:       v.PatientSatusehatID,
+		PatientNik:               v.PatientNIK,
+		PatientName:              v.PatientName,
+		PatientSex:               v.PatientSex,
+		PatientBirthDate:         v.PatientBirthDate,
+		PatientAddress:           v.PatientAddress,
+		ClinicName:               v.ClinicName,
+		ClinicSatuSehatId:        v.ClinicSatusehatID,
+		PeriodStartDate:          v.PeriodStartDate,
+		PeriodEndDate:            v.PeriodEndDate,
+		PractitionerNik:          v.PractitionerNIK,
+		PractitionerId:           v.PractitionerSatusehatID,
+		PractitionerName:         v.PractitionerName,
+		ArrivedStartTime:         v.ArrivedStartTime,
+		ArrivedEndTime:           v.ArrivedEndTime,
+		InProgressStartTime:      v.InProgressStartTime,
+		InProgressEndTime:        v.InProgressEndTime,
+		FinishStartTime:          v.FinishStartTime,
+		FinishEndTime:            v.FinishEndTime,
+		DischargeDisposition:     v.DischargeDisposition,
+		ReferralOrganizationId:   v.ReferralOrganizationID,
+		ReferralOrganizationName: v.ReferralOrganizationName,
+		ReferralReason:           v.ReferralReason,
+		FollowUpDate:             v.FollowUpDate,
 	}
 }
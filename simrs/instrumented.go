@@ -0,0 +1,173 @@
+package simrs
+
+import (
+	"context"
+	"github.com/jasoet/fhir-worker/internal/metrics"
+	"github.com/jasoet/fhir-worker/internal/tracing"
+	"github.com/jasoet/fhir-worker/shared/model"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"time"
+)
+
+var tracer = otel.Tracer("github.com/jasoet/fhir-worker/simrs")
+
+// instrumentedQuery traces every SIMRS query of the wrapped Query and records its latency.
+type instrumentedQuery struct {
+	query Query
+}
+
+// Instrumented wraps query to trace each method and observe its duration in metrics.SimrsQueryDuration.
+func Instrumented(query Query) Query {
+	return &instrumentedQuery{query: query}
+}
+
+func observe[T any](ctx context.Context, method string, attributes []attribute.KeyValue, fn func(ctx context.Context) (T, error)) (T, error) {
+	ctx, span := tracer.Start(ctx, "simrs."+method)
+	span.SetAttributes(attributes...)
+
+	start := time.Now()
+	result, err := fn(ctx)
+	metrics.ObserveSince(metrics.SimrsQueryDuration.WithLabelValues(method, metrics.Outcome(err)), start)
+
+	tracing.End(span, err)
+	return result, err
+}
+
+func visitAttributes(visitId string) []attribute.KeyValue {
+	return []attribute.KeyValue{tracing.VisitID.String(visitId)}
+}
+
+func batchAttributes(visitIds []string) []attribute.KeyValue {
+	return []attribute.KeyValue{tracing.VisitID.StringSlice(visitIds), tracing.VisitCount.Int(len(visitIds))}
+}
+
+func (i *instrumentedQuery) GetVisitBetween(ctx context.Context, startDate time.Time, endDate time.Time) ([]model.Visit, error) {
+	attributes := []attribute.KeyValue{
+		attribute.String("simrs.start_date", startDate.Format(time.RFC3339)),
+		attribute.String("simrs.end_date", endDate.Format(time.RFC3339)),
+	}
+	return observe(ctx, "GetVisitBetween", attributes, func(ctx context.Context) ([]model.Visit, error) {
+		return i.query.GetVisitBetween(ctx, startDate, endDate)
+	})
+}
+
+func (i *instrumentedQuery) GetDiagnosisByVisitId(ctx context.Context, visitId string) (model.DiagnosisList, error) {
+	return observe(ctx, "GetDiagnosisByVisitId", visitAttributes(visitId), func(ctx context.Context) (model.DiagnosisList, error) {
+		return i.query.GetDiagnosisByVisitId(ctx, visitId)
+	})
+}
+
+func (i *instrumentedQuery) GetMedicationRequestByVisitId(ctx context.Context, visitId string) (model.MedicationRequestList, error) {
+	return observe(ctx, "GetMedicationRequestByVisitId", visitAttributes(visitId), func(ctx context.Context) (model.MedicationRequestList, error) {
+		return i.query.GetMedicationRequestByVisitId(ctx, visitId)
+	})
+}
+
+func (i *instrumentedQuery) GetMedicationDispenseByVisitId(ctx context.Context, visitId string) (model.MedicationDispenseList, error) {
+	return observe(ctx, "GetMedicationDispenseByVisitId", visitAttributes(visitId), func(ctx context.Context) (model.MedicationDispenseList, error) {
+		return i.query.GetMedicationDispenseByVisitId(ctx, visitId)
+	})
+}
+
+func (i *instrumentedQuery) GetProcedureByVisitId(ctx context.Context, visitId string) (model.ProcedureList, error) {
+	return observe(ctx, "GetProcedureByVisitId", visitAttributes(visitId), func(ctx context.Context) (model.ProcedureList, error) {
+		return i.query.GetProcedureByVisitId(ctx, visitId)
+	})
+}
+
+func (i *instrumentedQuery) GetObservationLabByVisitId(ctx context.Context, visitId string) (model.ObservationLabList, error) {
+	return observe(ctx, "GetObservationLabByVisitId", visitAttributes(visitId), func(ctx context.Context) (model.ObservationLabList, error) {
+		return i.query.GetObservationLabByVisitId(ctx, visitId)
+	})
+}
+
+func (i *instrumentedQuery) GetObservationRadiologyByVisitId(ctx context.Context, visitId string) (model.ObservationRadiologyList, error) {
+	return observe(ctx, "GetObservationRadiologyByVisitId", visitAttributes(visitId), func(ctx context.Context) (model.ObservationRadiologyList, error) {
+		return i.query.GetObservationRadiologyByVisitId(ctx, visitId)
+	})
+}
+
+func (i *instrumentedQuery) GetAnamnesisByVisitId(ctx context.Context, visitId string) (model.AnamnesisList, error) {
+	return observe(ctx, "GetAnamnesisByVisitId", visitAttributes(visitId), func(ctx context.Context) (model.AnamnesisList, error) {
+		return i.query.GetAnamnesisByVisitId(ctx, visitId)
+	})
+}
+
+func (i *instrumentedQuery) GetAllergyByVisitId(ctx context.Context, visitId string) (model.AllergyList, error) {
+	return observe(ctx, "GetAllergyByVisitId", visitAttributes(visitId), func(ctx context.Context) (model.AllergyList, error) {
+		return i.query.GetAllergyByVisitId(ctx, visitId)
+	})
+}
+
+func (i *instrumentedQuery) GetImmunizationByVisitId(ctx context.Context, visitId string) (model.ImmunizationList, error) {
+	return observe(ctx, "GetImmunizationByVisitId", visitAttributes(visitId), func(ctx context.Context) (model.ImmunizationList, error) {
+		return i.query.GetImmunizationByVisitId(ctx, visitId)
+	})
+}
+
+func (i *instrumentedQuery) GetClinicalNoteByVisitId(ctx context.Context, visitId string) (model.ClinicalNoteList, error) {
+	return observe(ctx, "GetClinicalNoteByVisitId", visitAttributes(visitId), func(ctx context.Context) (model.ClinicalNoteList, error) {
+		return i.query.GetClinicalNoteByVisitId(ctx, visitId)
+	})
+}
+
+func (i *instrumentedQuery) GetDiagnosisByVisitIds(ctx context.Context, visitIds []string) (map[string]model.DiagnosisList, error) {
+	return observe(ctx, "GetDiagnosisByVisitIds", batchAttributes(visitIds), func(ctx context.Context) (map[string]model.DiagnosisList, error) {
+		return i.query.GetDiagnosisByVisitIds(ctx, visitIds)
+	})
+}
+
+func (i *instrumentedQuery) GetMedicationRequestByVisitIds(ctx context.Context, visitIds []string) (map[string]model.MedicationRequestList, error) {
+	return observe(ctx, "GetMedicationRequestByVisitIds", batchAttributes(visitIds), func(ctx context.Context) (map[string]model.MedicationRequestList, error) {
+		return i.query.GetMedicationRequestByVisitIds(ctx, visitIds)
+	})
+}
+
+func (i *instrumentedQuery) GetMedicationDispenseByVisitIds(ctx context.Context, visitIds []string) (map[string]model.MedicationDispenseList, error) {
+	return observe(ctx, "GetMedicationDispenseByVisitIds", batchAttributes(visitIds), func(ctx context.Context) (map[string]model.MedicationDispenseList, error) {
+		return i.query.GetMedicationDispenseByVisitIds(ctx, visitIds)
+	})
+}
+
+func (i *instrumentedQuery) GetProcedureByVisitIds(ctx context.Context, visitIds []string) (map[string]model.ProcedureList, error) {
+	return observe(ctx, "GetProcedureByVisitIds", batchAttributes(visitIds), func(ctx context.Context) (map[string]model.ProcedureList, error) {
+		return i.query.GetProcedureByVisitIds(ctx, visitIds)
+	})
+}
+
+func (i *instrumentedQuery) GetObservationLabByVisitIds(ctx context.Context, visitIds []string) (map[string]model.ObservationLabList, error) {
+	return observe(ctx, "GetObservationLabByVisitIds", batchAttributes(visitIds), func(ctx context.Context) (map[string]model.ObservationLabList, error) {
+		return i.query.GetObservationLabByVisitIds(ctx, visitIds)
+	})
+}
+
+func (i *instrumentedQuery) GetObservationRadiologyByVisitIds(ctx context.Context, visitIds []string) (map[string]model.ObservationRadiologyList, error) {
+	return observe(ctx, "GetObservationRadiologyByVisitIds", batchAttributes(visitIds), func(ctx context.Context) (map[string]model.ObservationRadiologyList, error) {
+		return i.query.GetObservationRadiologyByVisitIds(ctx, visitIds)
+	})
+}
+
+func (i *instrumentedQuery) GetAnamnesisByVisitIds(ctx context.Context, visitIds []string) (map[string]model.AnamnesisList, error) {
+	return observe(ctx, "GetAnamnesisByVisitIds", batchAttributes(visitIds), func(ctx context.Context) (map[string]model.AnamnesisList, error) {
+		return i.query.GetAnamnesisByVisitIds(ctx, visitIds)
+	})
+}
+
+func (i *instrumentedQuery) GetAllergyByVisitIds(ctx context.Context, visitIds []string) (map[string]model.AllergyList, error) {
+	return observe(ctx, "GetAllergyByVisitIds", batchAttributes(visitIds), func(ctx context.Context) (map[string]model.AllergyList, error) {
+		return i.query.GetAllergyByVisitIds(ctx, visitIds)
+	})
+}
+
+func (i *instrumentedQuery) GetImmunizationByVisitIds(ctx context.Context, visitIds []string) (map[string]model.ImmunizationList, error) {
+	return observe(ctx, "GetImmunizationByVisitIds", batchAttributes(visitIds), func(ctx context.Context) (map[string]model.ImmunizationList, error) {
+		return i.query.GetImmunizationByVisitIds(ctx, visitIds)
+	})
+}
+
+func (i *instrumentedQuery) GetClinicalNoteByVisitIds(ctx context.Context, visitIds []string) (map[string]model.ClinicalNoteList, error) {
+	return observe(ctx, "GetClinicalNoteByVisitIds", batchAttributes(visitIds), func(ctx context.Context) (map[string]model.ClinicalNoteList, error) {
+		return i.query.GetClinicalNoteByVisitIds(ctx, visitIds)
+	})
+}
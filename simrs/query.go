@@ -3,6 +3,8 @@ package simrs
 import (
 	"context"
 	"github.com/jasoet/fhir-worker/shared/model"
+	"github.com/jmoiron/sqlx"
+	"sync"
 	"time"
 )
 
@@ -14,4 +16,76 @@ type Query interface {
 	GetProcedureByVisitId(ctx context.Context, visitId string) (model.ProcedureList, error)
 	GetObservationLabByVisitId(ctx context.Context, visitId string) (model.ObservationLabList, error)
 	GetObservationRadiologyByVisitId(ctx context.Context, visitId string) (model.ObservationRadiologyList, error)
+	GetAnamnesisByVisitId(ctx context.Context, visitId string) (model.AnamnesisList, error)
+	GetAllergyByVisitId(ctx context.Context, visitId string) (model.AllergyList, error)
+	GetImmunizationByVisitId(ctx context.Context, visitId string) (model.ImmunizationList, error)
+	GetClinicalNoteByVisitId(ctx context.Context, visitId string) (model.ClinicalNoteList, error)
+
+	// Batch variants return the same data for many visits in a single round trip, keyed by visit id.
+
+	GetDiagnosisByVisitIds(ctx context.Context, visitIds []string) (map[string]model.DiagnosisList, error)
+	GetMedicationRequestByVisitIds(ctx context.Context, visitIds []string) (map[string]model.MedicationRequestList, error)
+	GetMedicationDispenseByVisitIds(ctx context.Context, visitIds []string) (map[string]model.MedicationDispenseList, error)
+	GetProcedureByVisitIds(ctx context.Context, visitIds []string) (map[string]model.ProcedureList, error)
+	GetObservationLabByVisitIds(ctx context.Context, visitIds []string) (map[string]model.ObservationLabList, error)
+	GetObservationRadiologyByVisitIds(ctx context.Context, visitIds []string) (map[string]model.ObservationRadiologyList, error)
+	GetAnamnesisByVisitIds(ctx context.Context, visitIds []string) (map[string]model.AnamnesisList, error)
+	GetAllergyByVisitIds(ctx context.Context, visitIds []string) (map[string]model.AllergyList, error)
+	GetImmunizationByVisitIds(ctx context.Context, visitIds []string) (map[string]model.ImmunizationList, error)
+	GetClinicalNoteByVisitIds(ctx context.Context, visitIds []string) (map[string]model.ClinicalNoteList, error)
+}
+
+// queryIn expands the :visit_ids parameter of a named query into an IN clause bound for the pool driver.
+func queryIn(db *sqlx.DB, query string, visitIds []string) (string, []any, error) {
+	query, args, err := sqlx.Named(query, map[string]any{
+		"visit_ids": visitIds,
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	query, args, err = sqlx.In(query, args...)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return db.Rebind(query), args, nil
+}
+
+func groupByVisit[S ~[]T, T any](items []T, visitId func(T) string) map[string]S {
+	results := make(map[string]S)
+	for _, item := range items {
+		key := visitId(item)
+		results[key] = append(results[key], item)
+	}
+
+	return results
+}
+
+// lazyStmt prepares its named query on first use, a SIMRS without the tables of a disabled resource still starts.
+type lazyStmt struct {
+	db    *sqlx.DB
+	query string
+	mu    sync.Mutex
+	stmt  *sqlx.NamedStmt
+}
+
+func newLazyStmt(db *sqlx.DB, query string) *lazyStmt {
+	return &lazyStmt{db: db, query: query}
+}
+
+func (s *lazyStmt) SelectContext(ctx context.Context, dest any, arg any) error {
+	s.mu.Lock()
+	if s.stmt == nil {
+		stmt, err := s.db.PrepareNamedContext(ctx, s.query)
+		if err != nil {
+			s.mu.Unlock()
+			return err
+		}
+		s.stmt = stmt
+	}
+	stmt := s.stmt
+	s.mu.Unlock()
+
+	return stmt.SelectContext(ctx, dest, arg)
 }
@@ -0,0 +1,71 @@
+package simrs
+
+import (
+	"context"
+	"fmt"
+	"github.com/jasoet/fhir-worker/shared/model"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+	"testing"
+)
+
+func TestQueryIn(t *testing.T) {
+	tests := []struct {
+		name       string
+		driverName string
+		want       string
+	}{
+		{"Mysql", "mysql", "SELECT 1 FROM visits WHERE id IN (?, ?, ?)"},
+		{"Postgres", "postgres", "SELECT 1 FROM visits WHERE id IN ($1, $2, $3)"},
+		{"MSSQL", "mssql", "SELECT 1 FROM visits WHERE id IN (?, ?, ?)"},
+		{"SqlServer", "sqlserver", "SELECT 1 FROM visits WHERE id IN (@p1, @p2, @p3)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := sqlx.NewDb(nil, tt.driverName)
+			query, args, err := queryIn(pool, "SELECT 1 FROM visits WHERE id IN (:visit_ids)", []string{"1", "2", "3"})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, query)
+			assert.Equal(t, []any{"1", "2", "3"}, args)
+		})
+	}
+}
+
+func TestGroupByVisit(t *testing.T) {
+	procedures := []model.Procedure{
+		{VisitId: 1, ProcedureCode: "89.03"},
+		{VisitId: 2, ProcedureCode: "93.94"},
+		{VisitId: 1, ProcedureCode: "99.21"},
+	}
+
+	grouped := groupByVisit[model.ProcedureList](procedures, func(o model.Procedure) string {
+		return fmt.Sprint(o.VisitId)
+	})
+
+	assert.Len(t, grouped, 2)
+	assert.Len(t, grouped["1"], 2)
+	assert.Len(t, grouped["2"], 1)
+	assert.Nil(t, grouped["3"])
+}
+
+func TestLazyStmt(t *testing.T) {
+	pool, err := sqlx.Open("sqlite", ":memory:")
+	require.NoError(t, err)
+	defer pool.Close()
+	pool.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	stmt := newLazyStmt(pool, "SELECT note FROM anamnesis WHERE visit_id = :visit_id")
+
+	var notes []string
+	assert.ErrorContains(t, stmt.SelectContext(ctx, &notes, map[string]any{"visit_id": 1}), "no such table")
+
+	_, err = pool.Exec("CREATE TABLE anamnesis (visit_id INTEGER, note TEXT); INSERT INTO anamnesis VALUES (1, 'batuk');")
+	require.NoError(t, err)
+
+	require.NoError(t, stmt.SelectContext(ctx, &notes, map[string]any{"visit_id": 1}))
+	assert.Equal(t, []string{"batuk"}, notes)
+}
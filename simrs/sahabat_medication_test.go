@@ -0,0 +1,72 @@
+//go:build !sleman
+
+package simrs
+
+import (
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+	"strings"
+	"testing"
+)
+
+const medicationSchema = `
+	CREATE TABLE ref_drugs (code TEXT, name TEXT, satusehat_kfa_code TEXT, satusehat_kfa_name TEXT, sediaan TEXT);
+	CREATE TABLE ref_paramedics (id INTEGER, name TEXT, satusehat_practitioner_id TEXT);
+	CREATE TABLE prescriptions_temp (id INTEGER, visit_id INTEGER, jenis_pasien TEXT, date TEXT, doctor_id INTEGER);
+	CREATE TABLE prescriptions_temp_detail (id INTEGER, prescription_id INTEGER, drug_code TEXT, jenis TEXT,
+		jumlah REAL, satuan TEXT, aturan_pakai TEXT, rute TEXT, dosis REAL, satuan_dosis TEXT);
+	CREATE TABLE prescriptions_temp_detail_racikan (prescription_detail_id INTEGER, drug_code TEXT, dosis REAL,
+		satuan TEXT);
+
+	INSERT INTO ref_paramedics VALUES (1, 'dr. Budi', 'P-1');
+	INSERT INTO ref_drugs VALUES ('PCT500', 'Paracetamol 500 mg', '93000001', 'Paracetamol 500 mg Tablet', 'tablet');
+	INSERT INTO ref_drugs VALUES ('SALEP-X', 'Salep Racik Lokal', NULL, NULL, 'salep');
+	INSERT INTO ref_drugs VALUES ('RAC-1', 'Puyer Batuk', NULL, NULL, 'puyer');
+	INSERT INTO prescriptions_temp VALUES (10, 1, 'UMUM', '2024-01-02', 1);
+	INSERT INTO prescriptions_temp_detail VALUES (100, 10, 'PCT500', 'NON RACIKAN', 10, 'tablet', '3x1', 'oral', 500, 'mg');
+	INSERT INTO prescriptions_temp_detail VALUES (101, 10, 'SALEP-X', 'NON RACIKAN', 1, 'tube', '2x1', 'topikal', 1, 'tube');
+	INSERT INTO prescriptions_temp_detail VALUES (102, 10, 'RAC-1', 'RACIKAN', 10, 'bungkus', '3x1', 'oral', 1, 'bungkus');
+	INSERT INTO prescriptions_temp_detail_racikan VALUES (102, 'PCT500', 250, 'mg');
+`
+
+// TestGetMedicationRequestByVisitIds_Unmapped runs the MySQL query on SQLite, swapping JSON_ARRAYAGG for its SQLite
+// equivalent, to check drugs without a KFA mapping and racikan lines are still fetched with their local code.
+func TestGetMedicationRequestByVisitIds_Unmapped(t *testing.T) {
+	pool, err := sqlx.Open("sqlite", ":memory:")
+	require.NoError(t, err)
+	defer pool.Close()
+
+	_, err = pool.Exec(medicationSchema)
+	require.NoError(t, err)
+
+	query, args, err := queryIn(pool, strings.ReplaceAll(GetMedicationRequestByVisitIds, "JSON_ARRAYAGG(", "json_group_array("), []string{"1"})
+	require.NoError(t, err)
+
+	rows, err := pool.Queryx(query, args...)
+	require.NoError(t, err)
+	defer rows.Close()
+
+	var results []map[string]any
+	for rows.Next() {
+		result := make(map[string]any)
+		require.NoError(t, rows.MapScan(result))
+		results = append(results, result)
+	}
+	require.NoError(t, rows.Err())
+
+	require.Len(t, results, 3)
+
+	assert.Equal(t, "PCT500", results[0]["medicine_code"])
+	assert.Equal(t, "93000001", results[0]["kfa_code"])
+	assert.Equal(t, "3x1", results[0]["usage_text"])
+
+	assert.Equal(t, "SALEP-X", results[1]["medicine_code"])
+	assert.Equal(t, "Salep Racik Lokal", results[1]["medicine_name"])
+	assert.Nil(t, results[1]["kfa_code"])
+
+	assert.Equal(t, "RAC-1", results[2]["medicine_code"])
+	assert.Nil(t, results[2]["kfa_code"])
+	assert.Contains(t, results[2]["ingredients"], `"medicine_code":"PCT500"`)
+}
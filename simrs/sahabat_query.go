@@ -4,6 +4,7 @@ package simrs
 
 import (
 	"context"
+	"fmt"
 	"github.com/jasoet/fhir-worker/shared/model"
 	"github.com/jmoiron/sqlx"
 	"time"
@@ -23,6 +24,74 @@ const (
 			ORDER BY ad.ordering
 			`
 
+	GetAnamnesisByVisitId = `
+			SELECT v.id AS visit_id,
+				   a.created_at AS anamnesis_date,
+				   a.chief_complaint AS chief_complaint,
+				   a.chief_complaint_snomed_code AS chief_complaint_code,
+				   a.chief_complaint_snomed_name AS chief_complaint_display,
+				   a.present_illness AS present_illness,
+				   a.past_illness AS past_illness,
+				   a.physical_examination AS physical_exam
+			FROM visits v
+					 JOIN anamneses a ON (a.visit_id = v.id)
+			WHERE v.id = :visit_id
+			ORDER BY a.id
+			`
+
+	GetAllergyByVisitId = `
+			SELECT v.id AS visit_id,
+				   pa.id AS allergy_id,
+				   pa.category AS category,
+				   COALESCE(rd.satusehat_kfa_code, pa.snomed_code) AS allergy_code,
+				   COALESCE(rd.satusehat_kfa_name, pa.allergen) AS allergy_name,
+				   pa.reaction AS reaction,
+				   pa.created_at AS recorded_date
+			FROM visits v
+					 JOIN patient_allergies pa ON (pa.visit_id = v.id)
+					 LEFT JOIN ref_drugs rd ON (rd.code = pa.drug_code)
+			WHERE v.id = :visit_id
+			ORDER BY pa.id
+			`
+
+	GetImmunizationByVisitId = `
+			SELECT v.id AS visit_id,
+				   im.id AS immunization_id,
+				   im.date AS occurrence_date,
+				   rd.code AS medicine_code,
+				   rd.name AS medicine_name,
+				   rd.satusehat_kfa_code AS kfa_code,
+				   rd.satusehat_kfa_name AS kfa_name,
+				   im.batch_number AS lot_number,
+				   im.expired_date AS expired_date,
+				   im.dose_number AS dose_number,
+				   im.site AS site,
+				   im.route AS route,
+				   rp.satusehat_practitioner_id AS practitioner_id,
+				   rp.name AS practitioner_name
+			FROM visits v
+					 JOIN immunizations im ON (im.visit_id = v.id)
+					 JOIN ref_drugs rd ON (rd.code = im.drug_code)
+					 JOIN ref_paramedics rp ON (rp.id = im.paramedic_id)
+			WHERE v.id = :visit_id
+			ORDER BY im.id
+			`
+
+	GetClinicalNoteByVisitId = `
+			SELECT v.id AS visit_id,
+				   s.created_at AS note_date,
+				   s.assessment AS assessment,
+				   s.prognosis AS prognosis,
+				   s.plan AS plan,
+				   s.diet AS diet,
+				   s.education AS education,
+				   s.follow_up AS follow_up
+			FROM visits v
+					 JOIN soaps s ON (s.visit_id = v.id)
+			WHERE v.id = :visit_id
+			ORDER BY s.id
+			`
+
 	GetProcedureByVisitId = `
 			SELECT
 				v.id as visit_id,
@@ -61,11 +130,22 @@ const (
                 v.respiration_rate AS visit_respiration_rate,
                 v.temperature AS visit_temperature,
                 v.spo2 AS visit_spo2,
+                v.weight AS visit_weight,
+                v.height AS visit_height,
+                v.bmi AS visit_bmi,
+                v.pain_scale AS visit_pain_scale,
+                v.gcs AS visit_gcs,
+                v.consciousness AS visit_consciousness,
                 v.date AS visit_date,
                 v.registration_start_date AS registration_start_date,
                 v.registration_date AS registration_date,
                 v.pemeriksaan_start_date AS examination_start_date,
-                v.pemeriksaan_end_date AS examination_end_date
+                v.pemeriksaan_end_date AS examination_end_date,
+                v.discharge_disposition AS visit_discharge_disposition,
+                ro.satusehat_organization_id AS visit_referral_organization_id,
+                ro.name AS visit_referral_organization_name,
+                v.referral_reason AS visit_referral_reason,
+                v.follow_up_date AS visit_follow_up_date
 			FROM 
                 visits v
                 JOIN patients p ON (p.id = v.patient_id )
@@ -79,6 +159,7 @@ const (
                 LEFT JOIN ref_educations re ON (re.id = p.education_id)
                 LEFT JOIN ref_jobs rj ON (rj.id = p.job_id)
                 LEFT JOIN ref_marital_status rmt ON (rmt.id = p.marital_status_id)
+                LEFT JOIN ref_organizations ro ON (ro.id = v.referral_organization_id)
  			WHERE
 				 v.date BETWEEN :start_date AND :end_date 
 --                 AND rpar.satusehat_practitioner_id IS NOT NULL
@@ -92,6 +173,7 @@ const (
 
 	GetObservationLabByVisitId = `
 			SELECT kd.visit_id AS visit_id,
+				   CAST(rkbi.id AS CHAR) AS lab_code,
 				   kd.name AS lab_name,
 				   kd.lab_parameter AS lab_parameter,
 				   kd.lab_satuan AS lab_unit,
@@ -135,7 +217,8 @@ const (
 				pt.visit_id as visit_id,
 				pt.jenis_pasien as patient_type,
 				pt.date as date,
-				rd.code as drug_code,
+				rd.code AS medicine_code,
+				rd.name AS medicine_name,
 				pt.id as prescription_id,
 				ptd.id as prescription_detail_id,
 				rd.satusehat_kfa_code as kfa_code,
@@ -144,16 +227,29 @@ const (
 				rp.satusehat_practitioner_id as practitioner_id,
 				rp.name as paramedic_name,
 				ptd.jumlah as amount,
-				ptd.satuan as unit
+				ptd.satuan as unit,
+				rd.sediaan as form,
+				ptd.aturan_pakai AS usage_text,
+				ptd.rute as route,
+				ptd.dosis as dose_amount,
+				ptd.satuan_dosis as dose_unit,
+				(SELECT JSON_ARRAYAGG(JSON_OBJECT(
+						'medicine_code', rdr.code,
+						'medicine_name', rdr.name,
+						'kfa_code', rdr.satusehat_kfa_code,
+						'kfa_name', rdr.satusehat_kfa_name,
+						'strength', ptdr.dosis,
+						'strength_unit', ptdr.satuan))
+				 FROM prescriptions_temp_detail_racikan ptdr
+					  JOIN ref_drugs rdr ON (rdr.code = ptdr.drug_code)
+				 WHERE ptdr.prescription_detail_id = ptd.id) as ingredients
 			FROM 
 				prescriptions_temp pt
 				JOIN prescriptions_temp_detail ptd ON (ptd.prescription_id = pt.id)
 				JOIN ref_drugs rd ON (rd.code = ptd.drug_code)
 				JOIN ref_paramedics rp ON (rp.id = pt.doctor_id)
 			WHERE 
-				pt.visit_id=:visit_id 
-				AND rd.satusehat_kfa_code IS NOT NULL 
-				AND rd.satusehat_kfa_name IS NOT NULL
+				pt.visit_id=:visit_id
 			ORDER BY ptd.id
     `
 
@@ -162,7 +258,8 @@ const (
 				pt.visit_id as visit_id,
 				pt.jenis_pasien as patient_type,
 				pt.date as date,
-				rd.code as drug_code,
+				rd.code AS medicine_code,
+				rd.name AS medicine_name,
 				pt.id as prescription_id,
 				ptd.id as prescription_detail_id,
 				rd.satusehat_kfa_code as kfa_code,
@@ -173,7 +270,24 @@ const (
 				dtd.batch_number as batch_number,
 				dtd.expired_date as expired_date,
 				v.prescription_start_date as prescription_start_date,
-				v.drug_received_by_patient_date as drug_received_date
+				v.drug_received_by_patient_date as drug_received_date,
+				ptd.jumlah as amount,
+				ptd.satuan as unit,
+				rd.sediaan as form,
+				ptd.aturan_pakai AS usage_text,
+				ptd.rute as route,
+				ptd.dosis as dose_amount,
+				ptd.satuan_dosis as dose_unit,
+				(SELECT JSON_ARRAYAGG(JSON_OBJECT(
+						'medicine_code', rdr.code,
+						'medicine_name', rdr.name,
+						'kfa_code', rdr.satusehat_kfa_code,
+						'kfa_name', rdr.satusehat_kfa_name,
+						'strength', ptdr.dosis,
+						'strength_unit', ptdr.satuan))
+				 FROM prescriptions_detail_racikan ptdr
+					  JOIN ref_drugs rdr ON (rdr.code = ptdr.drug_code)
+				 WHERE ptdr.prescription_detail_id = ptd.id) as ingredients
 			FROM 
 				prescriptions pt
 				JOIN prescriptions_detail ptd ON (ptd.prescription_id = pt.id)
@@ -185,6 +299,227 @@ const (
 				pt.visit_id=:visit_id 
 			ORDER BY ptd.id
     `
+
+	GetDiagnosisByVisitIds = `
+			SELECT v.id AS visit_id,
+				   ad.icd_code AS diagnosis_code,
+				   ad.icd_name AS diagnosis_name,
+				   ad.case AS diagnosis_case,
+				   ad.jenis AS diagnosis_type,
+				   ad.date as diagnosis_date
+			FROM visits v
+					 JOIN anamnese_diagnoses ad ON (ad.visit_id = v.id)
+			WHERE v.id IN (:visit_ids)
+			ORDER BY ad.ordering
+			`
+
+	GetAnamnesisByVisitIds = `
+			SELECT v.id AS visit_id,
+				   a.created_at AS anamnesis_date,
+				   a.chief_complaint AS chief_complaint,
+				   a.chief_complaint_snomed_code AS chief_complaint_code,
+				   a.chief_complaint_snomed_name AS chief_complaint_display,
+				   a.present_illness AS present_illness,
+				   a.past_illness AS past_illness,
+				   a.physical_examination AS physical_exam
+			FROM visits v
+					 JOIN anamneses a ON (a.visit_id = v.id)
+			WHERE v.id IN (:visit_ids)
+			ORDER BY a.id
+			`
+
+	GetAllergyByVisitIds = `
+			SELECT v.id AS visit_id,
+				   pa.id AS allergy_id,
+				   pa.category AS category,
+				   COALESCE(rd.satusehat_kfa_code, pa.snomed_code) AS allergy_code,
+				   COALESCE(rd.satusehat_kfa_name, pa.allergen) AS allergy_name,
+				   pa.reaction AS reaction,
+				   pa.created_at AS recorded_date
+			FROM visits v
+					 JOIN patient_allergies pa ON (pa.visit_id = v.id)
+					 LEFT JOIN ref_drugs rd ON (rd.code = pa.drug_code)
+			WHERE v.id IN (:visit_ids)
+			ORDER BY pa.id
+			`
+
+	GetImmunizationByVisitIds = `
+			SELECT v.id AS visit_id,
+				   im.id AS immunization_id,
+				   im.date AS occurrence_date,
+				   rd.code AS medicine_code,
+				   rd.name AS medicine_name,
+				   rd.satusehat_kfa_code AS kfa_code,
+				   rd.satusehat_kfa_name AS kfa_name,
+				   im.batch_number AS lot_number,
+				   im.expired_date AS expired_date,
+				   im.dose_number AS dose_number,
+				   im.site AS site,
+				   im.route AS route,
+				   rp.satusehat_practitioner_id AS practitioner_id,
+				   rp.name AS practitioner_name
+			FROM visits v
+					 JOIN immunizations im ON (im.visit_id = v.id)
+					 JOIN ref_drugs rd ON (rd.code = im.drug_code)
+					 JOIN ref_paramedics rp ON (rp.id = im.paramedic_id)
+			WHERE v.id IN (:visit_ids)
+			ORDER BY im.id
+			`
+
+	GetClinicalNoteByVisitIds = `
+			SELECT v.id AS visit_id,
+				   s.created_at AS note_date,
+				   s.assessment AS assessment,
+				   s.prognosis AS prognosis,
+				   s.plan AS plan,
+				   s.diet AS diet,
+				   s.education AS education,
+				   s.follow_up AS follow_up
+			FROM visits v
+					 JOIN soaps s ON (s.visit_id = v.id)
+			WHERE v.id IN (:visit_ids)
+			ORDER BY s.id
+			`
+
+	GetProcedureByVisitIds = `
+			SELECT
+				v.id as visit_id,
+				vicd.icd9cm_code AS procedure_code,
+				vicd.icd9cm_name AS procedure_name
+			FROM 
+				visits v
+				JOIN visits_icd9cm vicd ON (vicd.visit_id = v.id)
+			WHERE v.id IN (:visit_ids)
+			`
+
+	GetObservationLabByVisitIds = `
+			SELECT v.parent_id AS visit_id,
+				   CAST(rkbi.id AS CHAR) AS lab_code,
+				   kd.name AS lab_name,
+				   kd.lab_parameter AS lab_parameter,
+				   kd.lab_satuan AS lab_unit,
+				   kd.lab_normal AS lab_normal,
+				   kd.lab_result AS lab_result,
+				   kd.lab_flag AS lab_flag,
+				   kd.lab_metode AS lab_method,
+				   rkbi.lab_loinc_code AS lab_loinc_code,
+				   rkbi.lab_loinc_name AS lab_loinc_name,
+				   rp.satusehat_practitioner_id AS practitioner_id,
+				   rp.name AS practitioner_name
+			FROM visits v
+					 JOIN kwitansi_detail kd ON (kd.visit_id = v.id)
+					 JOIN ref_komponen_biaya_item rkbi ON (rkbi.id = kd.komponen_biaya_item_id)
+					 JOIN ref_paramedics rp ON (rp.id = v.paramedic_id)
+			WHERE v.parent_id IN (:visit_ids) AND rkbi.lab_group_id IS NOT NULL 
+			`
+
+	GetObservationRadiologyByVisitIds = `
+			SELECT v.parent_id AS visit_id,
+				   kd.name AS lab_name,
+				   kd.lab_parameter AS lab_parameter,
+				   kd.lab_satuan AS lab_unit,
+				   kd.lab_normal AS lab_normal,
+				   kd.lab_result AS lab_result,
+				   kd.lab_flag AS lab_flag,
+				   kd.lab_metode AS lab_method,
+				   rkbi.lab_loinc_code AS lab_loinc_code,
+				   rkbi.lab_loinc_name AS lab_loinc_name,
+				   rp.satusehat_practitioner_id AS practitioner_id,
+				   rp.name AS practitioner_name
+			FROM visits v
+					 JOIN kwitansi_detail kd ON (kd.visit_id = v.id)
+					 JOIN ref_komponen_biaya_item rkbi ON (rkbi.id = kd.komponen_biaya_item_id)
+					 JOIN ref_paramedics rp ON (rp.id = v.paramedic_id)
+			WHERE v.parent_id IN (:visit_ids) AND rkbi.radiologi_group_id IS NOT NULL
+			`
+
+	GetMedicationRequestByVisitIds = `
+			SELECT
+				pt.visit_id as visit_id,
+				pt.jenis_pasien as patient_type,
+				pt.date as date,
+				rd.code AS medicine_code,
+				rd.name AS medicine_name,
+				pt.id as prescription_id,
+				ptd.id as prescription_detail_id,
+				rd.satusehat_kfa_code as kfa_code,
+				rd.satusehat_kfa_name as kfa_name,
+				ptd.jenis as type,
+				rp.satusehat_practitioner_id as practitioner_id,
+				rp.name as paramedic_name,
+				ptd.jumlah as amount,
+				ptd.satuan as unit,
+				rd.sediaan as form,
+				ptd.aturan_pakai AS usage_text,
+				ptd.rute as route,
+				ptd.dosis as dose_amount,
+				ptd.satuan_dosis as dose_unit,
+				(SELECT JSON_ARRAYAGG(JSON_OBJECT(
+						'medicine_code', rdr.code,
+						'medicine_name', rdr.name,
+						'kfa_code', rdr.satusehat_kfa_code,
+						'kfa_name', rdr.satusehat_kfa_name,
+						'strength', ptdr.dosis,
+						'strength_unit', ptdr.satuan))
+				 FROM prescriptions_temp_detail_racikan ptdr
+					  JOIN ref_drugs rdr ON (rdr.code = ptdr.drug_code)
+				 WHERE ptdr.prescription_detail_id = ptd.id) as ingredients
+			FROM 
+				prescriptions_temp pt
+				JOIN prescriptions_temp_detail ptd ON (ptd.prescription_id = pt.id)
+				JOIN ref_drugs rd ON (rd.code = ptd.drug_code)
+				JOIN ref_paramedics rp ON (rp.id = pt.doctor_id)
+			WHERE 
+				pt.visit_id IN (:visit_ids)
+			ORDER BY ptd.id
+    `
+
+	GetMedicationDispenseByVisitIds = `
+			SELECT
+				pt.visit_id as visit_id,
+				pt.jenis_pasien as patient_type,
+				pt.date as date,
+				rd.code AS medicine_code,
+				rd.name AS medicine_name,
+				pt.id as prescription_id,
+				ptd.id as prescription_detail_id,
+				rd.satusehat_kfa_code as kfa_code,
+				rd.satusehat_kfa_name as kfa_name,
+				ptd.jenis as type,
+				rp.satusehat_practitioner_id as practitioner_id,
+				rp.name as paramedic_name,
+				dtd.batch_number as batch_number,
+				dtd.expired_date as expired_date,
+				v.prescription_start_date as prescription_start_date,
+				v.drug_received_by_patient_date as drug_received_date,
+				ptd.jumlah as amount,
+				ptd.satuan as unit,
+				rd.sediaan as form,
+				ptd.aturan_pakai AS usage_text,
+				ptd.rute as route,
+				ptd.dosis as dose_amount,
+				ptd.satuan_dosis as dose_unit,
+				(SELECT JSON_ARRAYAGG(JSON_OBJECT(
+						'medicine_code', rdr.code,
+						'medicine_name', rdr.name,
+						'kfa_code', rdr.satusehat_kfa_code,
+						'kfa_name', rdr.satusehat_kfa_name,
+						'strength', ptdr.dosis,
+						'strength_unit', ptdr.satuan))
+				 FROM prescriptions_detail_racikan ptdr
+					  JOIN ref_drugs rdr ON (rdr.code = ptdr.drug_code)
+				 WHERE ptdr.prescription_detail_id = ptd.id) as ingredients
+			FROM 
+				prescriptions pt
+				JOIN prescriptions_detail ptd ON (ptd.prescription_id = pt.id)
+				JOIN ref_drugs rd ON (rd.code = ptd.drug_code)
+				JOIN ref_paramedics rp ON (rp.id = pt.doctor_id)
+				JOIN drugs_transaction_detail dtd ON (dtd.prescription_detail_id = ptd.id)
+				JOIN visits v ON (v.id = pt.visit_id)
+			WHERE 
+				pt.visit_id IN (:visit_ids) 
+			ORDER BY ptd.id
+    `
 )
 
 type SahabatQuery struct {
@@ -196,6 +531,10 @@ type SahabatQuery struct {
 	getProcedureByVisitStmt          *sqlx.NamedStmt
 	getObservationLabByVisitId       *sqlx.NamedStmt
 	getObservationRadiologyByVisitId *sqlx.NamedStmt
+	getAnamnesisByVisitStmt          *lazyStmt
+	getAllergyByVisitStmt            *lazyStmt
+	getImmunizationByVisitStmt       *lazyStmt
+	getClinicalNoteByVisitStmt       *lazyStmt
 }
 
 func NewQuery(pool *sqlx.DB) (Query, error) {
@@ -239,16 +578,22 @@ func NewQuery(pool *sqlx.DB) (Query, error) {
 		return nil, err
 	}
 
+	// Optional resources, prepared on first use as their tables may be missing when the resource is disabled.
+	queryOps.getAnamnesisByVisitStmt = newLazyStmt(queryOps.DB, GetAnamnesisByVisitId)
+	queryOps.getAllergyByVisitStmt = newLazyStmt(queryOps.DB, GetAllergyByVisitId)
+	queryOps.getImmunizationByVisitStmt = newLazyStmt(queryOps.DB, GetImmunizationByVisitId)
+	queryOps.getClinicalNoteByVisitStmt = newLazyStmt(queryOps.DB, GetClinicalNoteByVisitId)
+
 	return queryOps, nil
 }
 
-func (f *SahabatQuery) GetVisitBetween(ctx context.Context, startDate time.Time, endDate time.Time) ([]entity.Visit, error) {
+func (f *SahabatQuery) GetVisitBetween(ctx context.Context, startDate time.Time, endDate time.Time) ([]model.Visit, error) {
 	parameter := map[string]any{
 		"start_date": startDate,
 		"end_date":   endDate,
 	}
 
-	var results []entity.Visit
+	var results []model.Visit
 
 	rows, err := f.getVisitStmt.QueryxContext(ctx, parameter)
 	if err != nil {
@@ -269,12 +614,12 @@ func (f *SahabatQuery) GetVisitBetween(ctx context.Context, startDate time.Time,
 	return results, nil
 }
 
-func (f *SahabatQuery) GetDiagnosisByVisitId(ctx context.Context, visitId string) ([]entity.Diagnosis, error) {
+func (f *SahabatQuery) GetDiagnosisByVisitId(ctx context.Context, visitId string) (model.DiagnosisList, error) {
 	parameter := map[string]any{
 		"visit_id": visitId,
 	}
 
-	var results []entity.Diagnosis
+	var results model.DiagnosisList
 
 	err := f.getDiagnosisByVisitStmt.SelectContext(ctx, &results, parameter)
 
@@ -285,12 +630,12 @@ func (f *SahabatQuery) GetDiagnosisByVisitId(ctx context.Context, visitId string
 	return results, nil
 }
 
-func (f *SahabatQuery) GetMedicationRequestByVisitId(ctx context.Context, visitId string) ([]entity.MedicationRequest, error) {
+func (f *SahabatQuery) GetMedicationRequestByVisitId(ctx context.Context, visitId string) (model.MedicationRequestList, error) {
 	parameter := map[string]any{
 		"visit_id": visitId,
 	}
 
-	var results []entity.MedicationRequest
+	var results model.MedicationRequestList
 
 	err := f.getMedicationRequestByVisitStmt.SelectContext(ctx, &results, parameter)
 
@@ -301,12 +646,12 @@ func (f *SahabatQuery) GetMedicationRequestByVisitId(ctx context.Context, visitI
 	return results, nil
 }
 
-func (f *SahabatQuery) GetMedicationDispenseByVisitId(ctx context.Context, visitId string) ([]entity.MedicationDispense, error) {
+func (f *SahabatQuery) GetMedicationDispenseByVisitId(ctx context.Context, visitId string) (model.MedicationDispenseList, error) {
 	parameter := map[string]any{
 		"visit_id": visitId,
 	}
 
-	var results []entity.MedicationDispense
+	var results model.MedicationDispenseList
 
 	err := f.getMedicationDispenseByVisitStmt.SelectContext(ctx, &results, parameter)
 
@@ -317,12 +662,12 @@ func (f *SahabatQuery) GetMedicationDispenseByVisitId(ctx context.Context, visit
 	return results, nil
 }
 
-func (f *SahabatQuery) GetProcedureByVisitId(ctx context.Context, visitId string) ([]entity.Procedure, error) {
+func (f *SahabatQuery) GetProcedureByVisitId(ctx context.Context, visitId string) (model.ProcedureList, error) {
 	parameter := map[string]any{
 		"visit_id": visitId,
 	}
 
-	var results []entity.Procedure
+	var results model.ProcedureList
 
 	err := f.getProcedureByVisitStmt.SelectContext(ctx, &results, parameter)
 
@@ -333,12 +678,12 @@ func (f *SahabatQuery) GetProcedureByVisitId(ctx context.Context, visitId string
 	return results, nil
 }
 
-func (f *SahabatQuery) GetObservationLabByVisitId(ctx context.Context, visitId string) ([]entity.ObservationLab, error) {
+func (f *SahabatQuery) GetObservationLabByVisitId(ctx context.Context, visitId string) (model.ObservationLabList, error) {
 	parameter := map[string]any{
 		"visit_id": visitId,
 	}
 
-	var results []entity.ObservationLab
+	var results model.ObservationLabList
 
 	err := f.getObservationLabByVisitId.SelectContext(ctx, &results, parameter)
 
@@ -349,12 +694,12 @@ func (f *SahabatQuery) GetObservationLabByVisitId(ctx context.Context, visitId s
 	return results, nil
 }
 
-func (f *SahabatQuery) GetObservationRadiologyByVisitId(ctx context.Context, visitId string) ([]entity.ObservationRadiology, error) {
+func (f *SahabatQuery) GetObservationRadiologyByVisitId(ctx context.Context, visitId string) (model.ObservationRadiologyList, error) {
 	parameter := map[string]any{
 		"visit_id": visitId,
 	}
 
-	var results []entity.ObservationRadiology
+	var results model.ObservationRadiologyList
 
 	err := f.getObservationLabByVisitId.SelectContext(ctx, &results, parameter)
 
@@ -364,3 +709,247 @@ func (f *SahabatQuery) GetObservationRadiologyByVisitId(ctx context.Context, vis
 
 	return results, nil
 }
+
+func (f *SahabatQuery) GetDiagnosisByVisitIds(ctx context.Context, visitIds []string) (map[string]model.DiagnosisList, error) {
+	query, args, err := queryIn(f.DB, GetDiagnosisByVisitIds, visitIds)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []model.Diagnosis
+
+	err = f.DB.SelectContext(ctx, &results, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return groupByVisit[model.DiagnosisList](results, func(o model.Diagnosis) string {
+		return o.VisitID
+	}), nil
+}
+
+func (f *SahabatQuery) GetMedicationRequestByVisitIds(ctx context.Context, visitIds []string) (map[string]model.MedicationRequestList, error) {
+	query, args, err := queryIn(f.DB, GetMedicationRequestByVisitIds, visitIds)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []model.MedicationRequest
+
+	err = f.DB.SelectContext(ctx, &results, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return groupByVisit[model.MedicationRequestList](results, func(o model.MedicationRequest) string {
+		return fmt.Sprint(o.VisitId)
+	}), nil
+}
+
+func (f *SahabatQuery) GetMedicationDispenseByVisitIds(ctx context.Context, visitIds []string) (map[string]model.MedicationDispenseList, error) {
+	query, args, err := queryIn(f.DB, GetMedicationDispenseByVisitIds, visitIds)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []model.MedicationDispense
+
+	err = f.DB.SelectContext(ctx, &results, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return groupByVisit[model.MedicationDispenseList](results, func(o model.MedicationDispense) string {
+		return fmt.Sprint(o.VisitId)
+	}), nil
+}
+
+func (f *SahabatQuery) GetProcedureByVisitIds(ctx context.Context, visitIds []string) (map[string]model.ProcedureList, error) {
+	query, args, err := queryIn(f.DB, GetProcedureByVisitIds, visitIds)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []model.Procedure
+
+	err = f.DB.SelectContext(ctx, &results, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return groupByVisit[model.ProcedureList](results, func(o model.Procedure) string {
+		return fmt.Sprint(o.VisitId)
+	}), nil
+}
+
+func (f *SahabatQuery) GetObservationLabByVisitIds(ctx context.Context, visitIds []string) (map[string]model.ObservationLabList, error) {
+	query, args, err := queryIn(f.DB, GetObservationLabByVisitIds, visitIds)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []model.ObservationLab
+
+	err = f.DB.SelectContext(ctx, &results, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return groupByVisit[model.ObservationLabList](results, func(o model.ObservationLab) string {
+		return fmt.Sprint(o.VisitId)
+	}), nil
+}
+
+func (f *SahabatQuery) GetObservationRadiologyByVisitIds(ctx context.Context, visitIds []string) (map[string]model.ObservationRadiologyList, error) {
+	query, args, err := queryIn(f.DB, GetObservationRadiologyByVisitIds, visitIds)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []model.ObservationRadiology
+
+	err = f.DB.SelectContext(ctx, &results, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return groupByVisit[model.ObservationRadiologyList](results, func(o model.ObservationRadiology) string {
+		return fmt.Sprint(o.VisitId)
+	}), nil
+}
+
+func (f *SahabatQuery) GetAnamnesisByVisitId(ctx context.Context, visitId string) (model.AnamnesisList, error) {
+	parameter := map[string]any{
+		"visit_id": visitId,
+	}
+
+	var results []model.Anamnesis
+
+	err := f.getAnamnesisByVisitStmt.SelectContext(ctx, &results, parameter)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func (f *SahabatQuery) GetAnamnesisByVisitIds(ctx context.Context, visitIds []string) (map[string]model.AnamnesisList, error) {
+	query, args, err := queryIn(f.DB, GetAnamnesisByVisitIds, visitIds)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []model.Anamnesis
+
+	err = f.DB.SelectContext(ctx, &results, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return groupByVisit[model.AnamnesisList](results, func(o model.Anamnesis) string {
+		return fmt.Sprint(o.VisitId)
+	}), nil
+}
+
+func (f *SahabatQuery) GetAllergyByVisitId(ctx context.Context, visitId string) (model.AllergyList, error) {
+	parameter := map[string]any{
+		"visit_id": visitId,
+	}
+
+	var results []model.Allergy
+
+	err := f.getAllergyByVisitStmt.SelectContext(ctx, &results, parameter)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func (f *SahabatQuery) GetAllergyByVisitIds(ctx context.Context, visitIds []string) (map[string]model.AllergyList, error) {
+	query, args, err := queryIn(f.DB, GetAllergyByVisitIds, visitIds)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []model.Allergy
+
+	err = f.DB.SelectContext(ctx, &results, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return groupByVisit[model.AllergyList](results, func(o model.Allergy) string {
+		return fmt.Sprint(o.VisitId)
+	}), nil
+}
+
+func (f *SahabatQuery) GetImmunizationByVisitId(ctx context.Context, visitId string) (model.ImmunizationList, error) {
+	parameter := map[string]any{
+		"visit_id": visitId,
+	}
+
+	var results []model.Immunization
+
+	err := f.getImmunizationByVisitStmt.SelectContext(ctx, &results, parameter)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func (f *SahabatQuery) GetImmunizationByVisitIds(ctx context.Context, visitIds []string) (map[string]model.ImmunizationList, error) {
+	query, args, err := queryIn(f.DB, GetImmunizationByVisitIds, visitIds)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []model.Immunization
+
+	err = f.DB.SelectContext(ctx, &results, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return groupByVisit[model.ImmunizationList](results, func(o model.Immunization) string {
+		return fmt.Sprint(o.VisitId)
+	}), nil
+}
+
+func (f *SahabatQuery) GetClinicalNoteByVisitId(ctx context.Context, visitId string) (model.ClinicalNoteList, error) {
+	parameter := map[string]any{
+		"visit_id": visitId,
+	}
+
+	var results []model.ClinicalNote
+
+	err := f.getClinicalNoteByVisitStmt.SelectContext(ctx, &results, parameter)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func (f *SahabatQuery) GetClinicalNoteByVisitIds(ctx context.Context, visitIds []string) (map[string]model.ClinicalNoteList, error) {
+	query, args, err := queryIn(f.DB, GetClinicalNoteByVisitIds, visitIds)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []model.ClinicalNote
+
+	err = f.DB.SelectContext(ctx, &results, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return groupByVisit[model.ClinicalNoteList](results, func(o model.ClinicalNote) string {
+		return fmt.Sprint(o.VisitId)
+	}), nil
+}
@@ -11,7 +11,8 @@ import (
 )
 
 func TestQueryOps_Fetch(t *testing.T) {
-	//t.Skip()
+	// Queries the SIMRS database of the hospital, only reachable from its network: run it by hand like the sleman one.
+	t.Skip()
 	ctx := context.Background()
 	config := &db.ConnectionConfig{
 		DbType:       db.Mysql,
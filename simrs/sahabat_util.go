@@ -8,8 +8,8 @@ import (
 	"time"
 )
 
-func BuildVisit(m map[string]any) entity.Visit {
-	v := entity.Visit{}
+func BuildVisit(m map[string]any) model.Visit {
+	v := model.Visit{}
 
 	v.VisitID = util.GetMapValueString(m, "visit_id", int64(0))
 	v.PatientSatusehatID = util.GetMapValue(m, "patient_satusehat_id", "")
@@ -32,10 +32,16 @@ func BuildVisit(m map[string]any) entity.Visit {
 	v.RespirationRate = util.GetMapValueString[int64](m, "visit_respiration_rate", 0)
 	v.Temperature = util.GetMapValueAsString(m, "visit_temperature", "")
 	v.OxygenSaturation = util.GetMapValueString[int64](m, "visit_spo2", 0)
+	v.Weight = util.GetMapValueAsString(m, "visit_weight", "")
+	v.Height = util.GetMapValueAsString(m, "visit_height", "")
+	v.Bmi = util.GetMapValueAsString(m, "visit_bmi", "")
+	v.PainScore = util.GetMapValueAsString(m, "visit_pain_scale", "")
+	v.Gcs = util.GetMapValueAsString(m, "visit_gcs", "")
+	v.Consciousness = util.GetMapValueAsString(m, "visit_consciousness", "")
 
 	visitDate := util.GetMapValue(m, "visit_date", time.Time{})
-	v.PeriodStartDate = &visitDate
-	v.PeriodEndDate = &visitDate
+	v.PeriodStartDate = visitDate
+	v.PeriodEndDate = visitDate
 
 	arrivedTime := util.GetMapNullableValue[time.Time](m, "registration_date")
 	v.ArrivedStartTime = arrivedTime
@@ -49,5 +55,11 @@ func BuildVisit(m map[string]any) entity.Visit {
 	v.InProgressStartTime = inProgressDate
 	v.InProgressEndTime = inProgressDate
 
+	v.DischargeDisposition = util.GetMapValueAsString(m, "visit_discharge_disposition", "")
+	v.ReferralOrganizationID = util.GetMapValueAsString(m, "visit_referral_organization_id", "")
+	v.ReferralOrganizationName = util.GetMapValueAsString(m, "visit_referral_organization_name", "")
+	v.ReferralReason = util.GetMapValueAsString(m, "visit_referral_reason", "")
+	v.FollowUpDate = util.GetMapNullableValue[time.Time](m, "visit_follow_up_date")
+
 	return v
 }
@@ -4,6 +4,7 @@ package simrs
 
 import (
 	"context"
+	"fmt"
 	"github.com/jasoet/fhir-worker/shared/model"
 	"github.com/jmoiron/sqlx"
 	"time"
@@ -24,6 +25,71 @@ const (
 		where pd.VISIT_ID = :visit_id
 			`
 
+	GetAnamnesisByVisitId = `
+		select rr.visit_id as visit_id,
+			   rr.tgl_pengkajian as anamnesis_date,
+			   rr.keluhan_utama as chief_complaint,
+			   NULL as chief_complaint_code,
+			   NULL as chief_complaint_display,
+			   rr.riwayat_penyakit_sekarang as present_illness,
+			   rr.riwayat_penyakit_dahulu as past_illness,
+			   rr.pemeriksaan_fisik as physical_exam
+		from riwayat_rajal rr
+		where rr.visit_id = :visit_id
+			`
+
+	GetAllergyByVisitId = `
+		select pa.VISIT_ID as visit_id,
+			   pa.ID as allergy_id,
+			   case pa.JENIS_ALERGI
+				   when 'OBAT' then 'medication'
+				   when 'MAKANAN' then 'food'
+				   else 'environment'
+				   end as category,
+			   NULL as allergy_code,
+			   coalesce(g.NAME, pa.ALERGEN) as allergy_name,
+			   pa.REAKSI as reaction,
+			   pa.CREATED_DATE as recorded_date
+		from PASIEN_ALERGI pa
+				 left join GOODS g on g.BRAND_ID = pa.BRAND_ID
+		where pa.VISIT_ID = :visit_id
+			`
+
+	GetImmunizationByVisitId = `
+		select pi.VISIT_ID as visit_id,
+			   pi.ID as immunization_id,
+			   pi.TGL_IMUNISASI as occurrence_date,
+			   g.BRAND_ID as medicine_code,
+			   g.NAME as medicine_name,
+			   NULL as kfa_code,
+			   NULL as kfa_name,
+			   pi.NO_BATCH as lot_number,
+			   pi.EXPIRED_DATE as expired_date,
+			   pi.DOSIS_KE as dose_number,
+			   pi.LOKASI_SUNTIK as site,
+			   pi.RUTE as route,
+			   e.ihs_no as practitioner_id,
+			   e.FULLNAME as practitioner_name
+		from PASIEN_IMUNISASI pi
+				 join PASIEN_VISITATION pv on pv.VISIT_ID = pi.VISIT_ID
+				 join GOODS g on g.BRAND_ID = pi.BRAND_ID
+				 join EMPLOYEE_ALL e on e.EMPLOYEE_ID = pi.EMPLOYEE_ID
+		where pi.VISIT_ID = :visit_id
+			`
+
+	GetClinicalNoteByVisitId = `
+		select rr.visit_id as visit_id,
+			   rr.tgl_pengkajian as note_date,
+			   rr.asesmen as assessment,
+			   rr.prognosis as prognosis,
+			   rr.rencana_terapi as plan,
+			   rr.diet as diet,
+			   rr.edukasi as education,
+			   rr.rencana_tindak_lanjut as follow_up
+		from riwayat_rajal rr
+		where rr.visit_id = :visit_id
+			`
+
 	GetProcedureByVisitId = `
 	    SELECT 1
 			`
@@ -48,11 +114,22 @@ const (
 				rr.nafas AS respiration_rate,
 				rr.tensi AS blood_pressure,
 				rr.nadi AS heart_rate,
+				rr.berat_badan AS weight,
+				rr.tinggi_badan AS height,
+				rr.imt AS bmi,
+				rr.skala_nyeri AS pain_score,
+				rr.gcs AS gcs,
+				rr.kesadaran AS consciousness,
 				pv.VISIT_DATE AS visit_date, -- date only 
 				rr.created_date AS visit_arrived_time,	
 				rr.tgl_pengkajian AS visit_inprogress_date, 
 				rr.jam_pengkajian AS visit_inprogress_hour, 
-				rr.modi_date AS visit_end_time
+				rr.modi_date AS visit_end_time,
+				rr.cara_pulang AS discharge_disposition,
+				rr.rujuk_ke AS referral_organization_name,
+				rr.rujuk_ke_ihs AS referral_organization_id,
+				rr.alasan_rujuk AS referral_reason,
+				rr.tgl_kontrol AS follow_up_date
 			FROM 
 				PASIEN_VISITATION pv
 			JOIN 
@@ -85,7 +162,7 @@ const (
 			   bo.RESEP_NO as prescription_id,
 			   bo.TREAT_DATE as date,
 			   bo.TREATMENT as treatment,
-			   bo.aturan_pakai as usage,
+			   bo.aturan_pakai AS usage_text,
 			   bo.MODIFIED_DATE as modified_date,
 			   bo.posting_date as posting_date,
 			   g.BRAND_ID as medication_id,
@@ -105,7 +182,7 @@ const (
 			   bo.RESEP_NO as prescription_id,
 			   bo.TREAT_DATE as date,
 			   bo.TREATMENT as treatment,
-			   bo.aturan_pakai as usage,
+			   bo.aturan_pakai AS usage_text,
 			   bo.MODIFIED_DATE as modified_date,
 			   bo.posting_date as posting_date,
 			   g.BRAND_ID as medication_id,
@@ -119,6 +196,137 @@ const (
 				 inner join EMPLOYEE_ALL e on e.EMPLOYEE_ID = bo.EMPLOYEE_ID
 		WHERE pv.VISIT_ID = :visit_id
     `
+
+	GetDiagnosisByVisitIds = `
+		select pv.VISIT_ID as visit_id,
+			   pd.DATE_OF_DIAGNOSA as diagnosis_date,
+			   pd.DIAGNOSA_ID as diagnosis_code,
+			   d.NAME_OF_DIAGNOSA as diagnosis_name,
+			   e.ihs_no as practitioner_satusehat_id,
+			   e.FULLNAME as practitioner_name
+		from PASIEN_DIAGNOSA pd
+				 join PASIEN_VISITATION pv on pv.VISIT_ID = pd.VISIT_ID
+				 join EMPLOYEE_ALL e on e.EMPLOYEE_ID = pd.EMPLOYEE_ID
+				 join DIAGNOSA d on pd.DIAGNOSA_ID = d.DIAGNOSA_ID
+		where pd.VISIT_ID in (:visit_ids)
+			`
+
+	GetAnamnesisByVisitIds = `
+		select rr.visit_id as visit_id,
+			   rr.tgl_pengkajian as anamnesis_date,
+			   rr.keluhan_utama as chief_complaint,
+			   NULL as chief_complaint_code,
+			   NULL as chief_complaint_display,
+			   rr.riwayat_penyakit_sekarang as present_illness,
+			   rr.riwayat_penyakit_dahulu as past_illness,
+			   rr.pemeriksaan_fisik as physical_exam
+		from riwayat_rajal rr
+		where rr.visit_id in (:visit_ids)
+			`
+
+	GetAllergyByVisitIds = `
+		select pa.VISIT_ID as visit_id,
+			   pa.ID as allergy_id,
+			   case pa.JENIS_ALERGI
+				   when 'OBAT' then 'medication'
+				   when 'MAKANAN' then 'food'
+				   else 'environment'
+				   end as category,
+			   NULL as allergy_code,
+			   coalesce(g.NAME, pa.ALERGEN) as allergy_name,
+			   pa.REAKSI as reaction,
+			   pa.CREATED_DATE as recorded_date
+		from PASIEN_ALERGI pa
+				 left join GOODS g on g.BRAND_ID = pa.BRAND_ID
+		where pa.VISIT_ID in (:visit_ids)
+			`
+
+	GetImmunizationByVisitIds = `
+		select pi.VISIT_ID as visit_id,
+			   pi.ID as immunization_id,
+			   pi.TGL_IMUNISASI as occurrence_date,
+			   g.BRAND_ID as medicine_code,
+			   g.NAME as medicine_name,
+			   NULL as kfa_code,
+			   NULL as kfa_name,
+			   pi.NO_BATCH as lot_number,
+			   pi.EXPIRED_DATE as expired_date,
+			   pi.DOSIS_KE as dose_number,
+			   pi.LOKASI_SUNTIK as site,
+			   pi.RUTE as route,
+			   e.ihs_no as practitioner_id,
+			   e.FULLNAME as practitioner_name
+		from PASIEN_IMUNISASI pi
+				 join PASIEN_VISITATION pv on pv.VISIT_ID = pi.VISIT_ID
+				 join GOODS g on g.BRAND_ID = pi.BRAND_ID
+				 join EMPLOYEE_ALL e on e.EMPLOYEE_ID = pi.EMPLOYEE_ID
+		where pi.VISIT_ID in (:visit_ids)
+			`
+
+	GetClinicalNoteByVisitIds = `
+		select rr.visit_id as visit_id,
+			   rr.tgl_pengkajian as note_date,
+			   rr.asesmen as assessment,
+			   rr.prognosis as prognosis,
+			   rr.rencana_terapi as plan,
+			   rr.diet as diet,
+			   rr.edukasi as education,
+			   rr.rencana_tindak_lanjut as follow_up
+		from riwayat_rajal rr
+		where rr.visit_id in (:visit_ids)
+			`
+
+	GetProcedureByVisitIds = `
+	    SELECT 1
+			`
+
+	GetObservationLabByVisitIds = `
+	    SELECT 1
+			`
+
+	GetObservationRadiologyByVisitIds = `
+	    SELECT 1
+			`
+
+	GetMedicationRequestByVisitIds = `
+		select bo.VISIT_ID as visit_id,
+			   bo.RESEP_NO as prescription_id,
+			   bo.TREAT_DATE as date,
+			   bo.TREATMENT as treatment,
+			   bo.aturan_pakai AS usage_text,
+			   bo.MODIFIED_DATE as modified_date,
+			   bo.posting_date as posting_date,
+			   g.BRAND_ID as medication_id,
+			   g.NAME as medication_name,
+			   e.nik as practitioner_name,
+			   e.ihs_no as practitioner_satusehat_id,
+			   e.FULLNAME as practitioner_name
+		from bill_apotik bo
+				 inner join PASIEN_VISITATION pv on bo.VISIT_ID = pv.VISIT_ID
+				 inner join GOODS g on bo.BRAND_ID = g.BRAND_ID
+				 inner join EMPLOYEE_ALL e on e.EMPLOYEE_ID = bo.EMPLOYEE_ID
+		WHERE pv.VISIT_ID in (:visit_ids)
+    `
+
+	GetMedicationDispenseByVisitIds = `
+		select bo.VISIT_ID as visit_id,
+			   bo.RESEP_NO as prescription_id,
+			   bo.TREAT_DATE as date,
+			   bo.TREATMENT as treatment,
+			   bo.aturan_pakai AS usage_text,
+			   bo.MODIFIED_DATE as modified_date,
+			   bo.posting_date as posting_date,
+			   g.BRAND_ID as medication_id,
+			   g.NAME as medication_name,
+			   e.nik as practitioner_name,
+			   e.ihs_no as practitioner_satusehat_id,
+			   e.FULLNAME as practitioner_name
+		from bill_apotik bo
+				 inner join PASIEN_VISITATION pv on bo.VISIT_ID = pv.VISIT_ID
+				 inner join GOODS g on bo.BRAND_ID = g.BRAND_ID
+				 inner join EMPLOYEE_ALL e on e.EMPLOYEE_ID = bo.EMPLOYEE_ID
+		WHERE pv.VISIT_ID in (:visit_ids)
+    `
 )
 
 type slemanQuery struct {
@@ -130,6 +338,10 @@ type slemanQuery struct {
 	getProcedureByVisitStmt          *sqlx.NamedStmt
 	getObservationLabByVisitId       *sqlx.NamedStmt
 	getObservationRadiologyByVisitId *sqlx.NamedStmt
+	getAnamnesisByVisitStmt          *lazyStmt
+	getAllergyByVisitStmt            *lazyStmt
+	getImmunizationByVisitStmt       *lazyStmt
+	getClinicalNoteByVisitStmt       *lazyStmt
 }
 
 func NewQuery(pool *sqlx.DB) (Query, error) {
@@ -173,6 +385,12 @@ func NewQuery(pool *sqlx.DB) (Query, error) {
 		return nil, err
 	}
 
+	// Optional resources, prepared on first use as their tables may be missing when the resource is disabled.
+	queryOps.getAnamnesisByVisitStmt = newLazyStmt(queryOps.DB, GetAnamnesisByVisitId)
+	queryOps.getAllergyByVisitStmt = newLazyStmt(queryOps.DB, GetAllergyByVisitId)
+	queryOps.getImmunizationByVisitStmt = newLazyStmt(queryOps.DB, GetImmunizationByVisitId)
+	queryOps.getClinicalNoteByVisitStmt = newLazyStmt(queryOps.DB, GetClinicalNoteByVisitId)
+
 	return queryOps, nil
 }
 
@@ -318,3 +536,267 @@ func (f *slemanQuery) GetObservationRadiologyByVisitId(ctx context.Context, visi
 
 	return results, nil
 }
+
+func (f *slemanQuery) GetDiagnosisByVisitIds(ctx context.Context, visitIds []string) (map[string]model.DiagnosisList, error) {
+	query, args, err := queryIn(f.DB, GetDiagnosisByVisitIds, visitIds)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := f.DB.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []model.Diagnosis
+	for rows.Next() {
+		result := make(map[string]any)
+		err := rows.MapScan(result)
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, BuildDiagnosis(result))
+	}
+
+	return groupByVisit[model.DiagnosisList](results, func(o model.Diagnosis) string {
+		return o.VisitID
+	}), nil
+}
+
+func (f *slemanQuery) GetMedicationRequestByVisitIds(ctx context.Context, visitIds []string) (map[string]model.MedicationRequestList, error) {
+	query, args, err := queryIn(f.DB, GetMedicationRequestByVisitIds, visitIds)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := f.DB.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []model.MedicationRequest
+	for rows.Next() {
+		result := make(map[string]any)
+		err := rows.MapScan(result)
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, BuildMedicationRequest(result))
+	}
+
+	return groupByVisit[model.MedicationRequestList](results, func(o model.MedicationRequest) string {
+		return fmt.Sprint(o.VisitId)
+	}), nil
+}
+
+func (f *slemanQuery) GetMedicationDispenseByVisitIds(ctx context.Context, visitIds []string) (map[string]model.MedicationDispenseList, error) {
+	query, args, err := queryIn(f.DB, GetMedicationDispenseByVisitIds, visitIds)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []model.MedicationDispense
+
+	err = f.DB.SelectContext(ctx, &results, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return groupByVisit[model.MedicationDispenseList](results, func(o model.MedicationDispense) string {
+		return fmt.Sprint(o.VisitId)
+	}), nil
+}
+
+func (f *slemanQuery) GetProcedureByVisitIds(ctx context.Context, visitIds []string) (map[string]model.ProcedureList, error) {
+	query, args, err := queryIn(f.DB, GetProcedureByVisitIds, visitIds)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []model.Procedure
+
+	err = f.DB.SelectContext(ctx, &results, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return groupByVisit[model.ProcedureList](results, func(o model.Procedure) string {
+		return fmt.Sprint(o.VisitId)
+	}), nil
+}
+
+func (f *slemanQuery) GetObservationLabByVisitIds(ctx context.Context, visitIds []string) (map[string]model.ObservationLabList, error) {
+	query, args, err := queryIn(f.DB, GetObservationLabByVisitIds, visitIds)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []model.ObservationLab
+
+	err = f.DB.SelectContext(ctx, &results, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return groupByVisit[model.ObservationLabList](results, func(o model.ObservationLab) string {
+		return fmt.Sprint(o.VisitId)
+	}), nil
+}
+
+func (f *slemanQuery) GetObservationRadiologyByVisitIds(ctx context.Context, visitIds []string) (map[string]model.ObservationRadiologyList, error) {
+	query, args, err := queryIn(f.DB, GetObservationRadiologyByVisitIds, visitIds)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []model.ObservationRadiology
+
+	err = f.DB.SelectContext(ctx, &results, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return groupByVisit[model.ObservationRadiologyList](results, func(o model.ObservationRadiology) string {
+		return fmt.Sprint(o.VisitId)
+	}), nil
+}
+
+func (f *slemanQuery) GetAnamnesisByVisitId(ctx context.Context, visitId string) (model.AnamnesisList, error) {
+	parameter := map[string]any{
+		"visit_id": visitId,
+	}
+
+	var results []model.Anamnesis
+
+	err := f.getAnamnesisByVisitStmt.SelectContext(ctx, &results, parameter)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func (f *slemanQuery) GetAnamnesisByVisitIds(ctx context.Context, visitIds []string) (map[string]model.AnamnesisList, error) {
+	query, args, err := queryIn(f.DB, GetAnamnesisByVisitIds, visitIds)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []model.Anamnesis
+
+	err = f.DB.SelectContext(ctx, &results, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return groupByVisit[model.AnamnesisList](results, func(o model.Anamnesis) string {
+		return fmt.Sprint(o.VisitId)
+	}), nil
+}
+
+func (f *slemanQuery) GetAllergyByVisitId(ctx context.Context, visitId string) (model.AllergyList, error) {
+	parameter := map[string]any{
+		"visit_id": visitId,
+	}
+
+	var results []model.Allergy
+
+	err := f.getAllergyByVisitStmt.SelectContext(ctx, &results, parameter)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func (f *slemanQuery) GetAllergyByVisitIds(ctx context.Context, visitIds []string) (map[string]model.AllergyList, error) {
+	query, args, err := queryIn(f.DB, GetAllergyByVisitIds, visitIds)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []model.Allergy
+
+	err = f.DB.SelectContext(ctx, &results, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return groupByVisit[model.AllergyList](results, func(o model.Allergy) string {
+		return fmt.Sprint(o.VisitId)
+	}), nil
+}
+
+func (f *slemanQuery) GetImmunizationByVisitId(ctx context.Context, visitId string) (model.ImmunizationList, error) {
+	parameter := map[string]any{
+		"visit_id": visitId,
+	}
+
+	var results []model.Immunization
+
+	err := f.getImmunizationByVisitStmt.SelectContext(ctx, &results, parameter)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func (f *slemanQuery) GetImmunizationByVisitIds(ctx context.Context, visitIds []string) (map[string]model.ImmunizationList, error) {
+	query, args, err := queryIn(f.DB, GetImmunizationByVisitIds, visitIds)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []model.Immunization
+
+	err = f.DB.SelectContext(ctx, &results, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return groupByVisit[model.ImmunizationList](results, func(o model.Immunization) string {
+		return fmt.Sprint(o.VisitId)
+	}), nil
+}
+
+func (f *slemanQuery) GetClinicalNoteByVisitId(ctx context.Context, visitId string) (model.ClinicalNoteList, error) {
+	parameter := map[string]any{
+		"visit_id": visitId,
+	}
+
+	var results []model.ClinicalNote
+
+	err := f.getClinicalNoteByVisitStmt.SelectContext(ctx, &results, parameter)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func (f *slemanQuery) GetClinicalNoteByVisitIds(ctx context.Context, visitIds []string) (map[string]model.ClinicalNoteList, error) {
+	query, args, err := queryIn(f.DB, GetClinicalNoteByVisitIds, visitIds)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []model.ClinicalNote
+
+	err = f.DB.SelectContext(ctx, &results, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return groupByVisit[model.ClinicalNoteList](results, func(o model.ClinicalNote) string {
+		return fmt.Sprint(o.VisitId)
+	}), nil
+}
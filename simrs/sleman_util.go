@@ -42,6 +42,12 @@ func BuildVisit(m map[string]any) model.Visit {
 	v.HeartRate = util.GetMapValue(m, "heart_rate", "")
 	v.RespirationRate = util.GetMapValue(m, "respiration_rate", "")
 	v.Temperature = util.GetMapValue(m, "temperature", "")
+	v.Weight = util.GetMapValue(m, "weight", "")
+	v.Height = util.GetMapValue(m, "height", "")
+	v.Bmi = util.GetMapValue(m, "bmi", "")
+	v.PainScore = util.GetMapValue(m, "pain_score", "")
+	v.Gcs = util.GetMapValue(m, "gcs", "")
+	v.Consciousness = util.GetMapValue(m, "consciousness", "")
 
 	visitDate := util.GetMapValue(m, "visit_date", time.Time{})
 	v.PeriodStartDate = visitDate
@@ -68,6 +74,12 @@ func BuildVisit(m map[string]any) model.Visit {
 		}
 	}
 
+	v.DischargeDisposition = util.GetMapValue(m, "discharge_disposition", "")
+	v.ReferralOrganizationID = util.GetMapValue(m, "referral_organization_id", "")
+	v.ReferralOrganizationName = util.GetMapValue(m, "referral_organization_name", "")
+	v.ReferralReason = util.GetMapValue(m, "referral_reason", "")
+	v.FollowUpDate = util.GetMapNullableValue[time.Time](m, "follow_up_date")
+
 	return v
 }
 